@@ -1,41 +1,350 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
-	"context"
 
-	_ "github.com/lib/pq" 
+	_ "github.com/lib/pq"
 
 	"service/internal/handler"
+	"service/internal/metrics"
+	"service/internal/notifier"
+	"service/internal/ratelimit"
+	"service/internal/repository"
+	"service/internal/service"
 )
 
-func connectToDB() (*sql.DB, error) {
-	dbHost := "db"
-	dbPort := "5432"
-	dbUser := "reviewer_user"
-	dbPassword := "password"
-	dbName := "reviewer"
-	dbSSL := "disable"
-	db, err := sql.Open("postgres", fmt.Sprintf(
+func getEnv(getenv func(string) string, key, fallback string) string {
+	if value := getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func databaseDSN(getenv func(string) string) string {
+	if url := getenv("DATABASE_URL"); url != "" {
+		return url
+	}
+	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		dbHost, dbPort, dbUser, dbPassword, dbName, dbSSL,
-	))
+		getEnv(getenv, "DB_HOST", "db"),
+		getEnv(getenv, "DB_PORT", "5432"),
+		getEnv(getenv, "DB_USER", "reviewer_user"),
+		getEnv(getenv, "DB_PASSWORD", "password"),
+		getEnv(getenv, "DB_NAME", "reviewer"),
+		getEnv(getenv, "DB_SSLMODE", "disable"),
+	)
+}
+
+func maxReviewerLoad(getenv func(string) string) (int, error) {
+	value := getenv("MAX_REVIEWER_LOAD")
+	if value == "" {
+		return 0, nil
+	}
+	load, err := strconv.Atoi(value)
+	if err != nil || load < 1 {
+		return 0, fmt.Errorf("MAX_REVIEWER_LOAD must be a positive integer, got %q", value)
+	}
+	return load, nil
+}
+
+func maxReviewersPerPR(getenv func(string) string) (int, error) {
+	value := getenv("MAX_REVIEWERS_PER_PR")
+	if value == "" {
+		return repository.DefaultMaxReviewersPerPR, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("MAX_REVIEWERS_PER_PR must be a positive integer, got %q", value)
+	}
+	return limit, nil
+}
+
+func assignmentStrategy(getenv func(string) string) (repository.AssignmentStrategy, error) {
+	switch value := getenv("ASSIGNMENT_STRATEGY"); value {
+	case "", "least_loaded":
+		return repository.LeastLoaded{}, nil
+	case "round_robin":
+		return repository.RoundRobin{}, nil
+	default:
+		return nil, fmt.Errorf("ASSIGNMENT_STRATEGY must be least_loaded or round_robin, got %q", value)
+	}
+}
+
+func enforceUniqueUsernames(getenv func(string) string) (bool, error) {
+	value := getenv("ENFORCE_UNIQUE_USERNAMES")
+	if value == "" {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("ENFORCE_UNIQUE_USERNAMES must be a boolean, got %q", value)
+	}
+	return enabled, nil
+}
+
+const defaultMaxBodyBytes = 1 << 20
+
+func maxBodyBytes(getenv func(string) string) (int64, error) {
+	value := getenv("MAX_BODY_BYTES")
+	if value == "" {
+		return defaultMaxBodyBytes, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("MAX_BODY_BYTES must be a positive integer, got %q", value)
+	}
+	return limit, nil
+}
+
+// reviewersCount reads DEFAULT_REVIEWERS_COUNT, the number of reviewers
+// CreatePR asks for; service.DefaultReviewersCount when unset.
+func reviewersCount(getenv func(string) string) (int, error) {
+	value := getenv("DEFAULT_REVIEWERS_COUNT")
+	if value == "" {
+		return service.DefaultReviewersCount, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 1 || count > service.MaxReviewersCount {
+		return 0, fmt.Errorf("DEFAULT_REVIEWERS_COUNT must be an integer between 1 and 10, got %q", value)
+	}
+	return count, nil
+}
+
+func minTeamSize(getenv func(string) string) (int, error) {
+	value := getenv("MIN_TEAM_SIZE")
+	if value == "" {
+		return service.DefaultMinTeamSize, nil
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 1 {
+		return 0, fmt.Errorf("MIN_TEAM_SIZE must be a positive integer, got %q", value)
+	}
+	return size, nil
+}
+
+// prIDPattern compiles PR_ID_PATTERN, the format pull request ids must match;
+// service.DefaultPRIDPattern when unset.
+func prIDPattern(getenv func(string) string) (*regexp.Regexp, error) {
+	value := getEnv(getenv, "PR_ID_PATTERN", service.DefaultPRIDPattern)
+	pattern, err := regexp.Compile(value)
+	if err != nil {
+		return nil, fmt.Errorf("PR_ID_PATTERN must be a valid regular expression, got %q", value)
+	}
+	return pattern, nil
+}
+
+// rateLimiter builds the per-client limiter from RATE_LIMIT_PER_MINUTE; nil
+// (no limiting) when the variable is unset.
+func rateLimiter(getenv func(string) string) (*ratelimit.Limiter, error) {
+	value := getenv("RATE_LIMIT_PER_MINUTE")
+	if value == "" {
+		return nil, nil
+	}
+	perMinute, err := strconv.Atoi(value)
+	if err != nil || perMinute < 1 {
+		return nil, fmt.Errorf("RATE_LIMIT_PER_MINUTE must be a positive integer, got %q", value)
+	}
+	return ratelimit.New(perMinute), nil
+}
+
+func debugEndpointsEnabled(getenv func(string) string) (bool, error) {
+	value := getenv("ENABLE_DEBUG_ENDPOINTS")
+	if value == "" {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("ENABLE_DEBUG_ENDPOINTS must be a boolean, got %q", value)
+	}
+	return enabled, nil
+}
+
+// newLogger builds the structured logger used for error responses; LOG_LEVEL
+// accepts debug, info, warn or error and defaults to info.
+func newLogger(getenv func(string) string) (*slog.Logger, error) {
+	var level slog.Level
+	if value := getenv("LOG_LEVEL"); value != "" {
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return nil, fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error, got %q", value)
+		}
+	}
+	return slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})), nil
+}
+
+// versionHandler reports the build from the ldflags variables, falling back to
+// "dev" and "unknown" for local builds.
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"version":    orDefault(Version, "dev"),
+		"commit":     orDefault(Commit, "unknown"),
+		"build_time": orDefault(BuildTime, "unknown"),
+	})
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// registerDebugRoutes adds GET /debug/db, reporting connection pool stats,
+// only when enabled; otherwise the path falls through to a 404.
+func registerDebugRoutes(mux *http.ServeMux, enabled bool, stats func() sql.DBStats) {
+	if !enabled {
+		return
+	}
+	mux.HandleFunc("/debug/db", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s := stats()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"open_connections":      s.OpenConnections,
+			"in_use":                s.InUse,
+			"idle":                  s.Idle,
+			"wait_count":            s.WaitCount,
+			"wait_duration_seconds": s.WaitDuration.Seconds(),
+		})
+	})
+}
+
+func newNotifier(getenv func(string) string) notifier.Notifier {
+	if url := getenv("WEBHOOK_URL"); url != "" {
+		return notifier.NewHTTPNotifier(url)
+	}
+	return notifier.NopNotifier{}
+}
+
+func corsAllowedOrigins(getenv func(string) string) []string {
+	var origins []string
+	for _, origin := range strings.Split(getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// withCORS answers preflight requests and sets Access-Control-* headers for
+// the configured origins ("*" allows any). With no origins it returns next
+// unchanged, so no CORS headers are emitted.
+func withCORS(origins []string, next http.HandlerFunc) http.HandlerFunc {
+	if len(origins) == 0 {
+		return next
+	}
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		if origin != "" && (allowed["*"] || allowed[origin]) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Idempotency-Key, If-None-Match")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, Server-Timing, X-Request-Id")
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+	}
+}
+
+const (
+	defaultDBConnectAttempts  = 5
+	defaultDBConnectBaseDelay = 500 * time.Millisecond
+	maxDBConnectDelay         = 30 * time.Second
+)
+
+// dbRetry is how long startup keeps pinging a database that is not up yet.
+type dbRetry struct {
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// dbRetryConfig reads DB_CONNECT_ATTEMPTS and DB_CONNECT_BASE_DELAY (a Go
+// duration such as "500ms").
+func dbRetryConfig(getenv func(string) string) (dbRetry, error) {
+	retry := dbRetry{maxAttempts: defaultDBConnectAttempts, baseDelay: defaultDBConnectBaseDelay}
+	if value := getenv("DB_CONNECT_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts < 1 {
+			return dbRetry{}, fmt.Errorf("DB_CONNECT_ATTEMPTS must be a positive integer, got %q", value)
+		}
+		retry.maxAttempts = attempts
+	}
+	if value := getenv("DB_CONNECT_BASE_DELAY"); value != "" {
+		delay, err := time.ParseDuration(value)
+		if err != nil || delay <= 0 {
+			return dbRetry{}, fmt.Errorf("DB_CONNECT_BASE_DELAY must be a positive duration, got %q", value)
+		}
+		retry.baseDelay = delay
+	}
+	return retry, nil
+}
+
+// pingWithRetry calls ping until it succeeds or retry.maxAttempts is used up,
+// doubling the wait after each failure up to maxDBConnectDelay. It returns the
+// number of attempts made.
+func pingWithRetry(ping func() error, retry dbRetry, sleep func(time.Duration)) (int, error) {
+	delay := retry.baseDelay
+	for attempt := 1; ; attempt++ {
+		err := ping()
+		if err == nil {
+			return attempt, nil
+		}
+		if attempt >= retry.maxAttempts {
+			return attempt, err
+		}
+		log.Printf("Database not ready (attempt %d/%d): %v; retrying in %s", attempt, retry.maxAttempts, err, delay)
+		sleep(delay)
+		delay *= 2
+		if delay > maxDBConnectDelay {
+			delay = maxDBConnectDelay
+		}
+	}
+}
+
+func connectToDB(retry dbRetry) (*sql.DB, error) {
+	db, err := sql.Open("postgres", databaseDSN(os.Getenv))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 	db.SetMaxOpenConns(10)
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(30 * time.Minute)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if err := db.PingContext(ctx); err != nil {
+	ping := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return db.PingContext(ctx)
+	}
+	if attempts, err := pingWithRetry(ping, retry, time.Sleep); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return nil, fmt.Errorf("failed to ping database after %d attempts: %w", attempts, err)
 	}
 	return db, nil
 }
@@ -47,17 +356,67 @@ func getPort() string {
 	return "8080"
 }
 
-func setupRoutes(h *handlers.Handlers) {
+func newMetrics(svc service.Service) *metrics.Registry {
+	reg := metrics.NewRegistry()
+	reg.RegisterGauge("pull_requests_open", "Pull requests currently in OPEN status.", func(ctx context.Context) (float64, error) {
+		counts, err := svc.GetOperationalCounts(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return float64(counts.OpenPRs), nil
+	})
+	reg.RegisterGauge("reviewers_active", "Users with is_active=true who can be assigned as reviewers.", func(ctx context.Context) (float64, error) {
+		counts, err := svc.GetOperationalCounts(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return float64(counts.ActiveReviewers), nil
+	})
+	return reg
+}
+
+func setupRoutes(h *handlers.Handlers, reg *metrics.Registry, corsOrigins []string, limiter *ratelimit.Limiter, maxBodyBytes int64) {
 	if h == nil {
 		log.Fatal("Handlers is nil in setup")
 	}
-	http.HandleFunc("/team/add", h.AddTeam)
-	http.HandleFunc("/team/get", h.GetTeam)
-	http.HandleFunc("/users/setIsActive", h.SetUserActive)
-	http.HandleFunc("/users/getReview", h.GetUserReviewPRs)
-	http.HandleFunc("/pullRequest/create", h.CreatePR)
-	http.HandleFunc("/pullRequest/merge", h.MergePR)
-	http.HandleFunc("/pullRequest/reassign", h.ReassignReviewer)
-	http.HandleFunc("/stats", h.GetStats)
-	http.HandleFunc("/health", h.Health)
-}
\ No newline at end of file
+	route := func(pattern string, handler http.HandlerFunc) {
+		http.HandleFunc(pattern, withCORS(corsOrigins, h.Envelope(reg.Instrument(pattern, limiter.Middleware(h.LimitBody(maxBodyBytes, handler))))))
+	}
+	route("/team/add", h.AddTeam)
+	route("/team/get", h.GetTeam)
+	route("/team/rename", h.RenameTeam)
+	route("/team/setReviewerCount", h.SetTeamReviewerCount)
+	route("/team/setActive", h.SetTeamActive)
+	route("/team/requiredSize", h.RequiredTeamSize)
+	route("/teams/import", h.ImportTeams)
+	route("/users/get", h.GetUser)
+	route("/users/load", h.GetUserLoad)
+	route("/users/teams", h.GetUserTeams)
+	route("/users/setIsActive", h.SetUserActive)
+	route("/users/getReview", h.GetUserReviewPRs)
+	route("/users/retire", h.RetireUser)
+	route("/users/reassignAll", h.ReassignAllForUser)
+	route("/pullRequests", h.ListPRs)
+	route("/pullRequests/search", h.SearchPRs)
+	route("/pullRequest/create", h.CreatePR)
+	route("/pullRequest/get", h.GetPR)
+	route("/pullRequest/merge", h.MergePR)
+	route("/pullRequest/close", h.ClosePR)
+	route("/pullRequest/reopen", h.ReopenPR)
+	route("/pullRequest/reassign", h.ReassignReviewer)
+	route("/pullRequest/addReviewer", h.AddReviewer)
+	route("/pullRequest/removeReviewer", h.RemoveReviewer)
+	route("/pullRequest/respondReview", h.RespondReview)
+	route("/pullRequest/previewReassign", h.PreviewReassign)
+	route("/pullRequest/previewReviewers", h.PreviewReviewers)
+	route("/pullRequest/history", h.GetReassignmentHistory)
+	route("/pullRequest/timeline", h.GetPRTimeline)
+	route("/stats", h.GetStats)
+	route("/stats/team", h.GetTeamStats)
+	route("/stats/user", h.GetUserStats)
+	route("/stats/concentration", h.GetConcentration)
+	route("/stats/reviewerWeekly", h.GetReviewerWeeklySummary)
+	route("/health", h.Health)
+	route("/version", versionHandler)
+	http.Handle("/metrics", reg)
+}
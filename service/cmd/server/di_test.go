@@ -0,0 +1,609 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"service/internal/repository"
+)
+
+func TestDatabaseDSN(t *testing.T) {
+	testCases := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{
+			name:     "defaults",
+			env:      map[string]string{},
+			expected: "host=db port=5432 user=reviewer_user password=password dbname=reviewer sslmode=disable",
+		},
+		{
+			name: "discrete variables",
+			env: map[string]string{
+				"DB_HOST":     "localhost",
+				"DB_PORT":     "5433",
+				"DB_USER":     "admin",
+				"DB_PASSWORD": "secret",
+				"DB_NAME":     "reviews",
+				"DB_SSLMODE":  "require",
+			},
+			expected: "host=localhost port=5433 user=admin password=secret dbname=reviews sslmode=require",
+		},
+		{
+			name: "database url takes precedence",
+			env: map[string]string{
+				"DATABASE_URL": "postgres://u:p@remote:5432/reviewer?sslmode=require",
+				"DB_HOST":      "localhost",
+			},
+			expected: "postgres://u:p@remote:5432/reviewer?sslmode=require",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dsn := databaseDSN(func(key string) string { return tc.env[key] })
+			if dsn != tc.expected {
+				t.Errorf("Expected DSN %q, got %q", tc.expected, dsn)
+			}
+		})
+	}
+}
+
+func TestMaxReviewerLoad(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected int
+		wantErr  bool
+	}{
+		{value: "", expected: 0},
+		{value: "3", expected: 3},
+		{value: "0", wantErr: true},
+		{value: "-1", wantErr: true},
+		{value: "many", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			load, err := maxReviewerLoad(func(key string) string {
+				if key == "MAX_REVIEWER_LOAD" {
+					return tc.value
+				}
+				return ""
+			})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if load != tc.expected {
+				t.Errorf("Expected %d, got %d", tc.expected, load)
+			}
+		})
+	}
+}
+
+func TestMaxReviewersPerPR(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected int
+		wantErr  bool
+	}{
+		{name: "unset", value: "", expected: repository.DefaultMaxReviewersPerPR},
+		{name: "configured", value: "3", expected: 3},
+		{name: "zero", value: "0", wantErr: true},
+		{name: "not a number", value: "many", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := maxReviewersPerPR(func(key string) string {
+				if key == "MAX_REVIEWERS_PER_PR" {
+					return tc.value
+				}
+				return ""
+			})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestEnforceUniqueUsernames(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected bool
+		wantErr  bool
+	}{
+		{value: "", expected: false},
+		{value: "true", expected: true},
+		{value: "false", expected: false},
+		{value: "sometimes", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			enabled, err := enforceUniqueUsernames(func(key string) string {
+				if key == "ENFORCE_UNIQUE_USERNAMES" {
+					return tc.value
+				}
+				return ""
+			})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if enabled != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, enabled)
+			}
+		})
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "", want: 1 << 20},
+		{value: "4096", want: 4096},
+		{value: "0", wantErr: true},
+		{value: "big", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := maxBodyBytes(func(key string) string {
+				if key == "MAX_BODY_BYTES" {
+					return tc.value
+				}
+				return ""
+			})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestReviewersCount(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 2},
+		{value: "1", want: 1},
+		{value: "10", want: 10},
+		{value: "0", wantErr: true},
+		{value: "11", wantErr: true},
+		{value: "two", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := reviewersCount(func(key string) string {
+				if key == "DEFAULT_REVIEWERS_COUNT" {
+					return tc.value
+				}
+				return ""
+			})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMinTeamSize(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 2},
+		{value: "3", want: 3},
+		{value: "0", wantErr: true},
+		{value: "many", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := minTeamSize(func(key string) string {
+				if key == "MIN_TEAM_SIZE" {
+					return tc.value
+				}
+				return ""
+			})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPRIDPattern(t *testing.T) {
+	testCases := []struct {
+		name    string
+		value   string
+		id      string
+		match   bool
+		wantErr bool
+	}{
+		{name: "default accepts", value: "", id: "pr-1001", match: true},
+		{name: "default rejects", value: "", id: "PROJ/42", match: false},
+		{name: "override", value: `^[A-Z]+/[0-9]+$`, id: "PROJ/42", match: true},
+		{name: "invalid", value: "[", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := prIDPattern(func(key string) string {
+				if key == "PR_ID_PATTERN" {
+					return tc.value
+				}
+				return ""
+			})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.MatchString(tc.id) != tc.match {
+				t.Errorf("Expected match %v for %q", tc.match, tc.id)
+			}
+		})
+	}
+}
+
+func TestDBRetryConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		env      map[string]string
+		expected dbRetry
+		wantErr  bool
+	}{
+		{name: "defaults", env: map[string]string{}, expected: dbRetry{maxAttempts: 5, baseDelay: 500 * time.Millisecond}},
+		{name: "configured", env: map[string]string{"DB_CONNECT_ATTEMPTS": "10", "DB_CONNECT_BASE_DELAY": "2s"}, expected: dbRetry{maxAttempts: 10, baseDelay: 2 * time.Second}},
+		{name: "zero attempts", env: map[string]string{"DB_CONNECT_ATTEMPTS": "0"}, wantErr: true},
+		{name: "delay without unit", env: map[string]string{"DB_CONNECT_BASE_DELAY": "500"}, wantErr: true},
+		{name: "negative delay", env: map[string]string{"DB_CONNECT_BASE_DELAY": "-1s"}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := dbRetryConfig(func(key string) string { return tc.env[key] })
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestPingWithRetry(t *testing.T) {
+	testCases := []struct {
+		name         string
+		failures     int
+		maxAttempts  int
+		wantAttempts int
+		wantDelays   []time.Duration
+		wantErr      bool
+	}{
+		{name: "ready at once", failures: 0, maxAttempts: 5, wantAttempts: 1},
+		{name: "ready after three failures", failures: 3, maxAttempts: 5, wantAttempts: 4, wantDelays: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}},
+		{name: "budget exhausted", failures: 10, maxAttempts: 3, wantAttempts: 3, wantDelays: []time.Duration{time.Second, 2 * time.Second}, wantErr: true},
+		{name: "delay capped", failures: 7, maxAttempts: 8, wantAttempts: 8, wantDelays: []time.Duration{
+			time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second,
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pings := 0
+			ping := func() error {
+				pings++
+				if pings <= tc.failures {
+					return errors.New("connection refused")
+				}
+				return nil
+			}
+			var delays []time.Duration
+			sleep := func(d time.Duration) { delays = append(delays, d) }
+			attempts, err := pingWithRetry(ping, dbRetry{maxAttempts: tc.maxAttempts, baseDelay: time.Second}, sleep)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if attempts != tc.wantAttempts || pings != tc.wantAttempts {
+				t.Errorf("expected %d attempts, got %d (%d pings)", tc.wantAttempts, attempts, pings)
+			}
+			if !reflect.DeepEqual(delays, tc.wantDelays) {
+				t.Errorf("expected delays %v, got %v", tc.wantDelays, delays)
+			}
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	testCases := []struct {
+		value   string
+		enabled bool
+		wantErr bool
+	}{
+		{value: ""},
+		{value: "120", enabled: true},
+		{value: "0", wantErr: true},
+		{value: "fast", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			limiter, err := rateLimiter(func(key string) string {
+				if key == "RATE_LIMIT_PER_MINUTE" {
+					return tc.value
+				}
+				return ""
+			})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if (limiter != nil) != tc.enabled {
+				t.Errorf("Expected enabled=%v, got limiter %v", tc.enabled, limiter)
+			}
+		})
+	}
+}
+
+func TestAssignmentStrategy(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected repository.AssignmentStrategy
+		wantErr  bool
+	}{
+		{value: "", expected: repository.LeastLoaded{}},
+		{value: "least_loaded", expected: repository.LeastLoaded{}},
+		{value: "round_robin", expected: repository.RoundRobin{}},
+		{value: "random", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			strategy, err := assignmentStrategy(func(key string) string {
+				if key == "ASSIGNMENT_STRATEGY" {
+					return tc.value
+				}
+				return ""
+			})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strategy != tc.expected {
+				t.Errorf("Expected %T, got %T", tc.expected, strategy)
+			}
+		})
+	}
+}
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	origins := corsAllowedOrigins(func(key string) string {
+		if key == "CORS_ALLOWED_ORIGINS" {
+			return "https://dash.example.com, http://localhost:3000,,"
+		}
+		return ""
+	})
+	if len(origins) != 2 || origins[0] != "https://dash.example.com" || origins[1] != "http://localhost:3000" {
+		t.Errorf("Unexpected origins: %v", origins)
+	}
+	if origins := corsAllowedOrigins(func(string) string { return "" }); len(origins) != 0 {
+		t.Errorf("Expected no origins when unset, got %v", origins)
+	}
+}
+
+func TestWithCORS(t *testing.T) {
+	called := false
+	next := func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}
+	origins := []string{"https://dash.example.com"}
+	testCases := []struct {
+		name          string
+		origins       []string
+		method        string
+		origin        string
+		expectedCode  int
+		expectedAllow string
+		expectCalled  bool
+	}{
+		{name: "allowed origin", origins: origins, method: "GET", origin: "https://dash.example.com", expectedCode: http.StatusOK, expectedAllow: "https://dash.example.com", expectCalled: true},
+		{name: "disallowed origin", origins: origins, method: "GET", origin: "https://evil.example.com", expectedCode: http.StatusOK, expectCalled: true},
+		{name: "preflight", origins: origins, method: "OPTIONS", origin: "https://dash.example.com", expectedCode: http.StatusNoContent, expectedAllow: "https://dash.example.com"},
+		{name: "disabled", method: "GET", origin: "https://dash.example.com", expectedCode: http.StatusOK, expectCalled: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			called = false
+			req := httptest.NewRequest(tc.method, "/team/get", nil)
+			req.Header.Set("Origin", tc.origin)
+			w := httptest.NewRecorder()
+			withCORS(tc.origins, next)(w, req)
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedCode, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.expectedAllow {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tc.expectedAllow, got)
+			}
+			if tc.expectedAllow == "" && w.Header().Get("Access-Control-Allow-Methods") != "" {
+				t.Error("Expected no Access-Control-Allow-Methods header")
+			}
+			if called != tc.expectCalled {
+				t.Errorf("Expected handler called=%v, got %v", tc.expectCalled, called)
+			}
+		})
+	}
+}
+
+func TestRegisterDebugRoutes(t *testing.T) {
+	stats := func() sql.DBStats {
+		return sql.DBStats{OpenConnections: 4, InUse: 1, Idle: 3, WaitCount: 7, WaitDuration: 1500 * time.Millisecond}
+	}
+	enabled := http.NewServeMux()
+	registerDebugRoutes(enabled, true, stats)
+	w := httptest.NewRecorder()
+	enabled.ServeHTTP(w, httptest.NewRequest("GET", "/debug/db", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var body map[string]float64
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	expected := map[string]float64{
+		"open_connections":      4,
+		"in_use":                1,
+		"idle":                  3,
+		"wait_count":            7,
+		"wait_duration_seconds": 1.5,
+	}
+	for key, value := range expected {
+		if got, ok := body[key]; !ok || got != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, body[key])
+		}
+	}
+
+	disabled := http.NewServeMux()
+	registerDebugRoutes(disabled, false, stats)
+	w = httptest.NewRecorder()
+	disabled.ServeHTTP(w, httptest.NewRequest("GET", "/debug/db", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 when disabled, got %d", w.Code)
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	testCases := []struct {
+		value   string
+		debug   bool
+		wantErr bool
+	}{
+		{value: "", debug: false},
+		{value: "info", debug: false},
+		{value: "debug", debug: true},
+		{value: "verbose", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			logger, err := newLogger(func(key string) string {
+				if key == "LOG_LEVEL" {
+					return tc.value
+				}
+				return ""
+			})
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := logger.Enabled(context.Background(), slog.LevelDebug); got != tc.debug {
+				t.Errorf("expected debug enabled %v, got %v", tc.debug, got)
+			}
+		})
+	}
+}
+
+func TestVersionHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	versionHandler(w, httptest.NewRequest("GET", "/version", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", got)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	expected := map[string]string{"version": "dev", "commit": "unknown", "build_time": "unknown"}
+	if !reflect.DeepEqual(body, expected) {
+		t.Errorf("Expected %v, got %v", expected, body)
+	}
+
+	w = httptest.NewRecorder()
+	versionHandler(w, httptest.NewRequest("POST", "/version", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
@@ -1,35 +1,104 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
-	_ "github.com/lib/pq" 
+	_ "github.com/lib/pq"
 
-	"service/internal/service"
 	"service/internal/handler"
 	"service/internal/repository"
+	"service/internal/service"
 )
 
+// Version, Commit and BuildTime are set at build time with
+// -ldflags "-X main.Version=... -X main.Commit=... -X main.BuildTime=...".
+var Version, Commit, BuildTime string
+
 func main() {
-	db, err := connectToDB()
+	retry, err := dbRetryConfig(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	db, err := connectToDB(retry)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 	defer db.Close()
-	repo := repository.NewRepository(db)
+	maxLoad, err := maxReviewerLoad(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	strategy, err := assignmentStrategy(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	uniqueUsernames, err := enforceUniqueUsernames(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	maxReviewers, err := maxReviewersPerPR(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	repo := repository.NewRepositoryWithConfig(db, repository.Config{
+		MaxReviewerLoad:   maxLoad,
+		Strategy:          strategy,
+		UniqueUsernames:   uniqueUsernames,
+		MaxReviewersPerPR: maxReviewers,
+	})
 	if repo == nil {
 		log.Fatal("Repository is nil")
 	}
-	svc := service.NewService(repo)
+	reviewers, err := reviewersCount(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	minSize, err := minTeamSize(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	prIDs, err := prIDPattern(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	svc := service.NewServiceWithConfig(repo, service.Config{
+		Notifier:       newNotifier(os.Getenv),
+		ReviewersCount: reviewers,
+		MinTeamSize:    minSize,
+		PRIDPattern:    prIDs,
+	})
 	if svc == nil {
 		log.Fatal("Service is nil")
 	}
-	handlers := handlers.NewHandlers(svc)
+	debugEnabled, err := debugEndpointsEnabled(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	logger, err := newLogger(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	handlers := handlers.NewHandlersWithConfig(svc, handlers.Config{Debug: debugEnabled, Logger: logger})
 	if handlers == nil {
 		log.Fatal("Handlers is nil")
 	}
-	setupRoutes(handlers)
+	limiter, err := rateLimiter(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	if limiter != nil {
+		limiter.StartSweeper(context.Background(), time.Minute)
+	}
+	bodyLimit, err := maxBodyBytes(os.Getenv)
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	setupRoutes(handlers, newMetrics(svc), corsAllowedOrigins(os.Getenv), limiter, bodyLimit)
+	registerDebugRoutes(http.DefaultServeMux, debugEnabled, db.Stats)
 	port := getPort()
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
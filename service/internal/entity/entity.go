@@ -1,15 +1,35 @@
 package entity
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type User struct {
-    ID       string `db:"user_id" json:"user_id"`
-    Username string `db:"username" json:"username"`
-    IsActive bool   `db:"is_active" json:"is_active"`
-    TeamName string `db:"team_name,omitempty" json:"team_name,omitempty"`
+	ID         string  `db:"user_id" json:"user_id"`
+	Username   string  `db:"username" json:"username"`
+	IsActive   bool    `db:"is_active" json:"is_active"`
+	TeamName   string  `db:"team_name,omitempty" json:"team_name,omitempty"`
+	AssignedAt *string `db:"assigned_at,omitempty" json:"assigned_at,omitempty"`
 }
 
 type Team struct {
 	ID   string `db:"team_id"`
 	Name string `db:"team_name"`
+	// DefaultReviewers is how many reviewers the team's PRs get; 0 means the
+	// team has no setting of its own.
+	DefaultReviewers int `db:"default_reviewers"`
+}
+
+type TeamWithMembers struct {
+	TeamName string `json:"team_name"`
+	Members  []User `json:"members"`
+}
+
+type ImportResult struct {
+	TeamName string
+	TeamID   string
+	Err      error
 }
 
 type PullRequest struct {
@@ -18,24 +38,189 @@ type PullRequest struct {
 	AuthorID          string  `db:"author_id"`
 	Status            string  `db:"status"`
 	AssignedReviewers []User  `db:"-"`
+	ReviewerCount     int     `db:"reviewer_count"`
 	CreatedAt         *string `db:"created_at,omitempty"`
 	MergedAt          *string `db:"merged_at,omitempty"`
+	// TeamName is the team whose members review the PR; CreatePR falls back to
+	// the author's team when it is empty.
+	TeamName string `db:"-"`
+	// ReviewerLoads is only filled in by CreatePR and records each reviewer's
+	// open review count at the moment they were picked.
+	ReviewerLoads []CandidateReviewer `db:"-"`
+	// RequestedReviewers is only filled in by CreatePR and is how many
+	// reviewers were asked for, which may exceed len(AssignedReviewers).
+	RequestedReviewers int `db:"-"`
+	// Reassignment is only filled in by ReassignReviewer.
+	Reassignment *Reassignment `db:"-"`
+	// CandidateEvaluations is only filled in by CreatePR with Debug set.
+	CandidateEvaluations []CandidateEvaluation `db:"-"`
+}
+
+type CandidateReviewer struct {
+	UserID string `json:"user_id"`
+	Load   int    `json:"load_at_assignment"`
+}
+
+// Reasons a teammate evaluated by CreatePR was not assigned.
+const (
+	SkipAuthor      = "author"
+	SkipInactive    = "inactive"
+	SkipOverCap     = "over_cap"
+	SkipExcluded    = "excluded"
+	SkipRankedLower = "ranked_lower"
+)
+
+// CandidateEvaluation is one teammate as CreatePR saw it: either selected or
+// skipped for SkipReason.
+type CandidateEvaluation struct {
+	UserID     string `json:"user_id"`
+	Load       int    `json:"load"`
+	Selected   bool   `json:"selected"`
+	SkipReason string `json:"skip_reason,omitempty"`
+}
+
+type Reassignment struct {
+	PRID         string  `json:"pull_request_id"`
+	OldUserID    string  `json:"old_user_id,omitempty"`
+	NewUserID    string  `json:"replaced_by,omitempty"`
+	ReassignedAt *string `json:"reassigned_at,omitempty"`
+	OldUsername  string  `json:"old_username,omitempty"`
+	NewUsername  string  `json:"new_username,omitempty"`
+}
+
+// Review states of a reviewer row. Only PENDING and ACCEPTED rows are active.
+const (
+	ReviewPending  = "PENDING"
+	ReviewAccepted = "ACCEPTED"
+	ReviewDeclined = "DECLINED"
+)
+
+const (
+	TimelineCreated    = "created"
+	TimelineReassigned = "reassigned"
+	TimelineMerged     = "merged"
+)
+
+// TimelineEvent is one entry of a PR's history; the user ids are only set for
+// reassignments.
+type TimelineEvent struct {
+	Type      string  `json:"type"`
+	At        *string `json:"at"`
+	OldUserID string  `json:"old_user_id,omitempty"`
+	NewUserID string  `json:"replaced_by,omitempty"`
+}
+
+// ReassignResult is the outcome of moving one PR off a departing reviewer:
+// either the replacement or, when none was found, an error code.
+type ReassignResult struct {
+	PRID      string `json:"pull_request_id"`
+	NewUserID string `json:"replaced_by,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+const (
+	EventReviewersAssigned  = "reviewers_assigned"
+	EventReviewerReassigned = "reviewer_reassigned"
+)
+
+// Event is a row of the events outbox, written in the same transaction as the
+// change it describes and drained by a publisher.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt string          `json:"created_at"`
 }
 
 type Stats struct {
-    UserAssignmentCounts []UserAssignmentCount `json:"user_assignment_counts"`
-    PRAssignmentCounts   []PRAssignmentCount   `json:"pr_assignment_counts"`
-    TotalAssignments     int                   `json:"total_assignments"`
+	UserAssignmentCounts      []UserAssignmentCount `json:"user_assignment_counts"`
+	PRAssignmentCounts        []PRAssignmentCount   `json:"pr_assignment_counts"`
+	TotalAssignments          int                   `json:"total_assignments"`
+	AverageTimeToMergeSeconds float64               `json:"average_time_to_merge_seconds"`
+	// FairnessGini is the Gini coefficient of Count over the active users in
+	// scope, not just the returned page: 0 when the load is perfectly even.
+	FairnessGini float64 `json:"fairness_gini"`
 }
 
 type UserAssignmentCount struct {
-    UserID  string `json:"user_id" db:"user_id"`
-    Username string `json:"username" db:"username"`
-    Count   int    `json:"count" db:"assignment_count"`
+	UserID      string `json:"user_id" db:"user_id"`
+	Username    string `json:"username" db:"username"`
+	Count       int    `json:"count" db:"assignment_count"`
+	OpenCount   int    `json:"open_count" db:"open_count"`
+	MergedCount int    `json:"merged_count" db:"merged_count"`
+	IsActive    bool   `json:"-" db:"is_active"`
 }
 
 type PRAssignmentCount struct {
-    PRID   string `json:"pull_request_id" db:"pull_request_id"`
-    Title  string `json:"pull_request_name" db:"pull_request_name"`
-    Count  int    `json:"count" db:"assignment_count"`
+	PRID  string `json:"pull_request_id" db:"pull_request_id"`
+	Title string `json:"pull_request_name" db:"pull_request_name"`
+	Count int    `json:"count" db:"assignment_count"`
+}
+
+type Concentration struct {
+	GiniCoefficient float64               `json:"gini_coefficient"`
+	UserLoads       []UserAssignmentCount `json:"user_loads"`
+}
+
+type OperationalCounts struct {
+	OpenPRs         int
+	ActiveReviewers int
+}
+
+type CreatePROptions struct {
+	ExcludeReviewers []string
+	// AvoidRecentPairings ranks reviewers of the author's last N PRs after
+	// everyone else; 0 disables it.
+	AvoidRecentPairings int
+	// TeamName pins the reviewer pool for an author in several teams.
+	TeamName string
+	// ReviewersCount asks for that many reviewers when the team has no
+	// setting; 0 uses the global default.
+	ReviewersCount int
+	// AuthorAliases are other user ids of the author, e.g. left over from an
+	// account migration; they are never picked as reviewers.
+	AuthorAliases []string
+	// Debug records why each teammate was or was not picked.
+	Debug bool
+}
+
+// ReviewCursor identifies the last pull request of a page by its keyset
+// (created_at, pull_request_id).
+type ReviewCursor struct {
+	CreatedAt string
+	PRID      string
+}
+
+type ReviewPage struct {
+	// Limit caps the page size; 0 returns every row.
+	Limit int
+	After *ReviewCursor
+}
+
+type ReviewPolicy struct {
+	DesiredReviewers int `json:"desired_reviewers"`
+	Reserve          int `json:"reserve"`
+	Cap              int `json:"cap"`
+}
+
+type WeekCount struct {
+	ISOYear   int    `json:"iso_year"`
+	ISOWeek   int    `json:"iso_week"`
+	WeekStart string `json:"week_start"`
+	Count     int    `json:"count"`
+}
+
+type StatsSort string
+
+const (
+	StatsSortCountDesc StatsSort = "count_desc"
+	StatsSortCountAsc  StatsSort = "count_asc"
+	StatsSortName      StatsSort = "name"
+)
+
+type StatsFilter struct {
+	From *time.Time
+	To   *time.Time
+	// Sort orders both stats lists; empty means StatsSortCountDesc.
+	Sort StatsSort
 }
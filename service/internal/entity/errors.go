@@ -5,10 +5,30 @@ import (
 )
 
 var (
-	ErrTeamExists    = errors.New("team already exists")
-	ErrPRExists      = errors.New("pull request already exists")
-	ErrPRMerged      = errors.New("pull request is merged")
-	ErrNotAssigned   = errors.New("reviewer is not assigned")
-	ErrNoCandidate   = errors.New("no active replacement candidate")
-	ErrNotFound      = errors.New("resource not found")
-)
\ No newline at end of file
+	ErrTeamExists           = errors.New("team already exists")
+	ErrPRExists             = errors.New("pull request already exists")
+	ErrPRMerged             = errors.New("pull request is merged")
+	ErrPRClosed             = errors.New("pull request is closed")
+	ErrNotAssigned          = errors.New("reviewer is not assigned")
+	ErrAlreadyAssigned      = errors.New("reviewer is already assigned")
+	ErrLastReviewer         = errors.New("cannot remove the last active reviewer")
+	ErrNoCandidate          = errors.New("no active replacement candidate")
+	ErrAuthorNoTeam         = errors.New("pull request author is not a member of any team")
+	ErrSoloAuthor           = errors.New("author has no eligible teammates to review")
+	ErrAllInactive          = errors.New("all of the author's teammates are inactive")
+	ErrNotFound             = errors.New("resource not found")
+	ErrAuthorNotFound       = errors.New("author not found")
+	ErrUnknownUser          = errors.New("unknown user id")
+	ErrInvalidPolicy        = errors.New("invalid review policy")
+	ErrInvalidReviewerCount = errors.New("reviewer count must be positive")
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different pull request")
+	ErrSelfReview           = errors.New("pull request author cannot review their own pull request")
+	ErrDuplicateUsername    = errors.New("team members must have unique usernames")
+	ErrAmbiguousTeam        = errors.New("author belongs to several teams; team_name is required")
+	ErrAuthorNotInTeam      = errors.New("author is not a member of the requested team")
+	ErrEmptyTeamName        = errors.New("team name must not be empty")
+	ErrTeamTooSmall         = errors.New("team has fewer active members than required")
+	ErrBlankUserID          = errors.New("user id must not be blank")
+	ErrReviewerLimit        = errors.New("pull request already has the maximum number of reviewers")
+	ErrInvalidPRID          = errors.New("pull request id has an invalid format")
+)
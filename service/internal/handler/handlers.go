@@ -1,42 +1,287 @@
 package handlers
 
 import (
-    "encoding/json"
-    "net/http"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
-    "service/internal/service"
 	"service/internal/entity"
+	"service/internal/service"
+)
+
+const (
+	defaultStatsLimit    = 50
+	defaultStatsOffset   = 0
+	defaultSummaryWeeks  = 8
+	maxSummaryWeeks      = 52
+	maxPRNameLength      = 200
+	minSearchQueryLength = 2
+	maxSearchResults     = 100
+	// envelopeMediaType in Accept, or ?envelope=true, asks for enveloped
+	// responses.
+	envelopeMediaType = "application/vnd.api+json"
 )
 
 type ErrorResponse struct {
-    Error struct {
-        Code    string `json:"code"`
-        Message string `json:"message"`
-    } `json:"error"`
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		// Fields maps each invalid request field to its problem; only set for
+		// validation failures.
+		Fields map[string]string `json:"fields,omitempty"`
+		// RequestID matches the X-Request-Id header and the server log entry;
+		// only set for 5xx responses.
+		RequestID string `json:"request_id,omitempty"`
+	} `json:"error"`
+}
+
+// fieldErrors collects validation problems in the order they were found, so
+// the first one can still serve as the top-level message.
+type fieldErrors struct {
+	first    string
+	messages map[string]string
+}
+
+func (f *fieldErrors) add(field, message string) {
+	if f.messages == nil {
+		f.messages = map[string]string{}
+		f.first = message
+	}
+	if _, exists := f.messages[field]; !exists {
+		f.messages[field] = message
+	}
 }
 
 type Handlers struct {
-    service service.Service  
+	service service.Service
+	debug   bool
+	logger  *slog.Logger
+}
+
+type Config struct {
+	// Debug honours ?debug=true on CreatePR; it follows ENABLE_DEBUG_ENDPOINTS.
+	Debug bool
+	// Logger receives error responses; JSON on stderr when nil.
+	Logger *slog.Logger
+}
+
+func NewHandlers(service service.Service) *Handlers {
+	return NewHandlersWithConfig(service, Config{})
+}
+
+func NewHandlersWithConfig(service service.Service, cfg Config) *Handlers {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	}
+	return &Handlers{service: service, debug: cfg.Debug, logger: cfg.Logger}
+}
+
+// writeError writes an error body and logs it: 5xx at error level under a
+// fresh request id that is also returned to the client, anything else at
+// debug level.
+func (h *Handlers) writeError(w http.ResponseWriter, r *http.Request, code int, errorCode, message string) {
+	var response ErrorResponse
+	response.Error.Code = errorCode
+	response.Error.Message = message
+	attrs := []any{"method", r.Method, "endpoint", r.URL.Path, "status", code, "code", errorCode, "message", message}
+	if code >= http.StatusInternalServerError {
+		requestID := newRequestID()
+		response.Error.RequestID = requestID
+		w.Header().Set("X-Request-Id", requestID)
+		h.logger.Error("request failed", append(attrs, "request_id", requestID)...)
+	} else {
+		h.logger.Debug("request rejected", attrs...)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
+
+// newRequestID returns a random 128-bit hex id for correlating a response with
+// its log entry.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// decodeJSON decodes the request body into v, rejecting keys v does not
+// declare so a misspelled field is not silently dropped.
+func decodeJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// writeBodyError reports a request body that could not be decoded: 413
+// PAYLOAD_TOO_LARGE when it ran past the LimitBody cap, 400 naming the field
+// for an unexpected key, and a generic 400 otherwise.
+func (h *Handlers) writeBodyError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.writeError(w, r, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "request body is too large")
+		return
+	}
+	// encoding/json has no typed error for this case, only the message.
+	if quoted, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field, unquoteErr := strconv.Unquote(quoted)
+		if unquoteErr != nil {
+			field = quoted
+		}
+		var errs fieldErrors
+		errs.add(field, "unknown field "+quoted)
+		h.writeFieldErrors(w, errs)
+		return
+	}
+	h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
+}
+
+// LimitBody caps the request body at maxBytes. A declared Content-Length over
+// the cap is rejected up front; a streamed body fails once it is read past it.
+func (h *Handlers) LimitBody(maxBytes int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			h.writeError(w, r, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "request body is too large")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next(w, r)
+	}
+}
+
+// Envelope wraps next's JSON responses as {data, meta} on success and
+// {errors: [...]} on failure when the client asks for it; other clients get the
+// bare shapes. Empty bodies, such as 304s, pass through untouched.
+func (h *Handlers) Envelope(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !wantsEnvelope(r) {
+			next(w, r)
+			return
+		}
+		rec := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		body := rec.body.Bytes()
+		if len(bytes.TrimSpace(body)) > 0 && json.Valid(body) {
+			body = envelopeBody(rec.status, body)
+			w.Header().Del("Content-Length")
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(rec.status)
+		w.Write(body)
+	}
+}
+
+func wantsEnvelope(r *http.Request) bool {
+	if envelope, err := strconv.ParseBool(r.URL.Query().Get("envelope")); err == nil {
+		return envelope
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted)); err == nil && mediaType == envelopeMediaType {
+			return true
+		}
+	}
+	return false
+}
+
+type envelopeError struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+func envelopeBody(status int, body []byte) []byte {
+	var wrapped interface{}
+	var errResponse ErrorResponse
+	if status >= http.StatusBadRequest && json.Unmarshal(body, &errResponse) == nil && errResponse.Error.Code != "" {
+		wrapped = map[string][]envelopeError{"errors": {{
+			Code:      errResponse.Error.Code,
+			Message:   errResponse.Error.Message,
+			Fields:    errResponse.Error.Fields,
+			RequestID: errResponse.Error.RequestID,
+		}}}
+	} else {
+		wrapped = map[string]interface{}{
+			"data": json.RawMessage(body),
+			"meta": map[string]string{"timestamp": time.Now().UTC().Format(time.RFC3339)},
+		}
+	}
+	out, err := json.Marshal(wrapped)
+	if err != nil {
+		return body
+	}
+	return append(out, '\n')
+}
+
+// bufferedResponse holds a handler's status and body so Envelope can rewrite
+// them; headers still go straight to the underlying writer.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if !b.wroteHeader {
+		b.status = code
+		b.wroteHeader = true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
 }
 
-func NewHandlers(service service.Service) *Handlers {  
-    return &Handlers{service: service}
+// writeFieldErrors writes 400 INVALID_REQUEST listing every invalid field and
+// reports whether there was anything to write.
+func (h *Handlers) writeFieldErrors(w http.ResponseWriter, errs fieldErrors) bool {
+	if len(errs.messages) == 0 {
+		return false
+	}
+	var response ErrorResponse
+	response.Error.Code = "INVALID_REQUEST"
+	response.Error.Message = errs.first
+	response.Error.Fields = errs.messages
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(response)
+	return true
 }
 
-func (h *Handlers) writeError(w http.ResponseWriter, code int, errorCode, message string) {
-    w.WriteHeader(code)
-    json.NewEncoder(w).Encode(ErrorResponse{
-        Error: struct {
-            Code    string `json:"code"`
-            Message string `json:"message"`
-        }{
-            Code:    errorCode,
-            Message: message,
-        },
-    })
+// requireMethod writes 405 METHOD_NOT_ALLOWED with an Allow header unless the
+// request uses method.
+func (h *Handlers) requireMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	allowed := strings.Join(methods, ", ")
+	w.Header().Set("Allow", allowed)
+	h.writeError(w, r, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "method "+r.Method+" is not allowed, use "+allowed)
+	return false
 }
 
 func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status": "OK",
@@ -44,55 +289,244 @@ func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) AddTeam(w http.ResponseWriter, r *http.Request) {
-    var request struct {
-        TeamName string            `json:"team_name"`
-        Members  []entity.User `json:"members"`
-    }
-    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-        h.writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
-        return
-    }
-    team, err := h.service.CreateTeam(request.TeamName, request.Members)
-    if err != nil {
-        switch err {
-        case entity.ErrTeamExists:
-            h.writeError(w, http.StatusBadRequest, "TEAM_EXISTS", "team already exists")
-        default:
-            h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
-        }
-        return
-    }
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		TeamName         string        `json:"team_name"`
+		Members          []entity.User `json:"members"`
+		DefaultReviewers int           `json:"default_reviewers"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	var errs fieldErrors
+	if request.TeamName == "" {
+		errs.add("team_name", "team_name is required")
+	}
+	if request.DefaultReviewers < 0 || request.DefaultReviewers > service.MaxReviewersCount {
+		errs.add("default_reviewers", "default_reviewers must be between 1 and 10, or 0 for the default")
+	}
+	for i, member := range request.Members {
+		prefix := "members[" + strconv.Itoa(i) + "]"
+		if member.ID == "" {
+			errs.add(prefix+".user_id", "user_id is required")
+		}
+		if member.Username == "" {
+			errs.add(prefix+".username", "username is required")
+		}
+	}
+	if h.writeFieldErrors(w, errs) {
+		return
+	}
+	team, err := h.service.CreateTeam(r.Context(), request.TeamName, request.Members, request.DefaultReviewers)
+	if err != nil {
+		switch err {
+		case entity.ErrTeamExists:
+			h.writeError(w, r, http.StatusBadRequest, "TEAM_EXISTS", "team already exists")
+		case entity.ErrDuplicateUsername:
+			h.writeError(w, r, http.StatusBadRequest, "DUPLICATE_USERNAME", "team members must have unique usernames")
+		case entity.ErrEmptyTeamName:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "team_name must not be blank")
+		case entity.ErrBlankUserID:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "user_id must not be blank")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
 	type TeamResponse struct {
-		TeamName string        `json:"team_name"`
-		Members  []entity.User `json:"members"`
+		TeamName         string        `json:"team_name"`
+		Members          []entity.User `json:"members"`
+		DefaultReviewers int           `json:"default_reviewers,omitempty"`
 	}
 	type AddTeamResponse struct {
 		Team TeamResponse `json:"team"`
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(AddTeamResponse{
 		Team: TeamResponse{
-			TeamName: team.Name,
-			Members:  request.Members,
+			TeamName:         team.Name,
+			Members:          request.Members,
+			DefaultReviewers: team.DefaultReviewers,
 		},
 	})
 }
 
+func (h *Handlers) SetTeamReviewerCount(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		TeamName         string `json:"team_name"`
+		DefaultReviewers int    `json:"default_reviewers"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	var errs fieldErrors
+	if request.TeamName == "" {
+		errs.add("team_name", "team_name is required")
+	}
+	if request.DefaultReviewers < 1 || request.DefaultReviewers > service.MaxReviewersCount {
+		errs.add("default_reviewers", "default_reviewers must be between 1 and 10")
+	}
+	if h.writeFieldErrors(w, errs) {
+		return
+	}
+	err := h.service.SetTeamReviewerCount(r.Context(), request.TeamName, request.DefaultReviewers)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "team not found")
+		case entity.ErrInvalidReviewerCount:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "default_reviewers must be between 1 and 10")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type TeamResponse struct {
+		TeamName         string `json:"team_name"`
+		DefaultReviewers int    `json:"default_reviewers"`
+	}
+	type SetReviewerCountResponse struct {
+		Team TeamResponse `json:"team"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SetReviewerCountResponse{Team: TeamResponse{
+		TeamName:         request.TeamName,
+		DefaultReviewers: request.DefaultReviewers,
+	}})
+}
+
+func (h *Handlers) RenameTeam(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		OldName string `json:"old_name"`
+		NewName string `json:"new_name"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	var errs fieldErrors
+	if request.OldName == "" {
+		errs.add("old_name", "old_name is required")
+	}
+	if request.NewName == "" {
+		errs.add("new_name", "new_name is required")
+	}
+	if h.writeFieldErrors(w, errs) {
+		return
+	}
+	team, err := h.service.RenameTeam(r.Context(), request.OldName, request.NewName)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "team not found")
+		case entity.ErrTeamExists:
+			h.writeError(w, r, http.StatusBadRequest, "TEAM_EXISTS", "team already exists")
+		case entity.ErrEmptyTeamName:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "team names must not be blank")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type TeamResponse struct {
+		TeamName string `json:"team_name"`
+	}
+	type RenameTeamResponse struct {
+		Team TeamResponse `json:"team"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RenameTeamResponse{Team: TeamResponse{TeamName: team.Name}})
+}
+
+func (h *Handlers) ImportTeams(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		Teams []entity.TeamWithMembers `json:"teams"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	if len(request.Teams) == 0 {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "teams is required")
+		return
+	}
+	results, err := h.service.ImportTeams(r.Context(), request.Teams)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	type ImportError struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	type ImportResultResponse struct {
+		TeamName string       `json:"team_name"`
+		Success  bool         `json:"success"`
+		Error    *ImportError `json:"error,omitempty"`
+	}
+	response := make([]ImportResultResponse, 0, len(results))
+	for _, result := range results {
+		item := ImportResultResponse{TeamName: result.TeamName, Success: result.Err == nil}
+		switch result.Err {
+		case nil:
+		case entity.ErrTeamExists:
+			item.Error = &ImportError{Code: "TEAM_EXISTS", Message: "team already exists"}
+		case entity.ErrDuplicateUsername:
+			item.Error = &ImportError{Code: "DUPLICATE_USERNAME", Message: "team members must have unique usernames"}
+		case entity.ErrEmptyTeamName:
+			item.Error = &ImportError{Code: "INVALID_REQUEST", Message: "team_name must not be blank"}
+		case entity.ErrBlankUserID:
+			item.Error = &ImportError{Code: "INVALID_REQUEST", Message: "user_id must not be blank"}
+		default:
+			item.Error = &ImportError{Code: "INTERNAL_ERROR", Message: result.Err.Error()}
+		}
+		response = append(response, item)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": response,
+	})
+}
+
 func (h *Handlers) GetTeam(w http.ResponseWriter, r *http.Request) {
-    teamName := r.URL.Query().Get("team_name")
-    if teamName == "" {
-        h.writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "team_name is required")
-        return
-    }
-    team, members, err := h.service.GetTeam(teamName)
-    if err != nil {
-        if err == entity.ErrNotFound {
-            h.writeError(w, http.StatusNotFound, "NOT_FOUND", "team not found")
-        } else {
-            h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
-        }
-        return
-    }
+	if !h.requireMethod(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	var body struct {
+		TeamName string `json:"team_name"`
+	}
+	if err := decodeOptionalJSON(r, &body); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	teamName, problem := queryOrBodyParam(r, "team_name", body.TeamName)
+	if problem != "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", problem)
+		return
+	}
+	team, members, err := h.service.GetTeam(r.Context(), teamName)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "team not found")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
 	type TeamResponse struct {
 		TeamName string        `json:"team_name"`
 		Members  []entity.User `json:"members"`
@@ -105,28 +539,136 @@ func (h *Handlers) GetTeam(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *Handlers) GetUser(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "user_id is required")
+		return
+	}
+	user, err := h.service.GetUser(r.Context(), userID)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "user not found")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type UserResponse struct {
+		UserID   string `json:"user_id"`
+		Username string `json:"username"`
+		TeamName string `json:"team_name"`
+		IsActive bool   `json:"is_active"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UserResponse{
+		UserID:   user.ID,
+		Username: user.Username,
+		TeamName: user.TeamName,
+		IsActive: user.IsActive,
+	})
+}
+
+func (h *Handlers) GetUserLoad(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "user_id is required")
+		return
+	}
+	open, total, err := h.service.GetUserLoad(r.Context(), userID)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "user not found")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type UserLoadResponse struct {
+		UserID           string `json:"user_id"`
+		OpenAssignments  int    `json:"open_assignments"`
+		TotalAssignments int    `json:"total_assignments"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UserLoadResponse{
+		UserID:           userID,
+		OpenAssignments:  open,
+		TotalAssignments: total,
+	})
+}
+
+func (h *Handlers) GetUserTeams(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "user_id is required")
+		return
+	}
+	teams, err := h.service.GetUserTeams(r.Context(), userID)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "user not found")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type TeamResponse struct {
+		TeamName         string `json:"team_name"`
+		DefaultReviewers int    `json:"default_reviewers,omitempty"`
+	}
+	type UserTeamsResponse struct {
+		UserID string         `json:"user_id"`
+		Teams  []TeamResponse `json:"teams"`
+	}
+	response := UserTeamsResponse{UserID: userID, Teams: make([]TeamResponse, len(teams))}
+	for i, team := range teams {
+		response.Teams[i] = TeamResponse{TeamName: team.Name, DefaultReviewers: team.DefaultReviewers}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 func (h *Handlers) SetUserActive(w http.ResponseWriter, r *http.Request) {
-    var request struct {
-        UserID   string `json:"user_id"`
-        IsActive *bool   `json:"is_active"`
-    }
-    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-        h.writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
-        return
-    }
-    if request.UserID == "" {
-        h.writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "user_id is required")
-        return
-    }
-    user, err := h.service.SetUserActive(request.UserID, *request.IsActive)
-    if err != nil {
-        if err == entity.ErrNotFound {
-            h.writeError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
-        } else {
-            h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
-        }
-        return
-    }
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		UserID   string `json:"user_id"`
+		IsActive *bool  `json:"is_active"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	if request.UserID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "user_id is required")
+		return
+	}
+	if request.IsActive == nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "is_active is required")
+		return
+	}
+	user, affectedPRs, err := h.service.SetUserActive(r.Context(), request.UserID, *request.IsActive)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "user not found")
+		case entity.ErrBlankUserID:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "user_id must not be blank")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
 	type UserResponse struct {
 		UserID   string `json:"user_id"`
 		Username string `json:"username"`
@@ -134,8 +676,10 @@ func (h *Handlers) SetUserActive(w http.ResponseWriter, r *http.Request) {
 		IsActive bool   `json:"is_active"`
 	}
 	type SetUserActiveResponse struct {
-		User UserResponse `json:"user"`
+		User        UserResponse `json:"user"`
+		AffectedPRs []string     `json:"affected_prs"`
 	}
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(SetUserActiveResponse{
 		User: UserResponse{
 			UserID:   user.ID,
@@ -143,168 +687,815 @@ func (h *Handlers) SetUserActive(w http.ResponseWriter, r *http.Request) {
 			TeamName: user.TeamName,
 			IsActive: user.IsActive,
 		},
+		AffectedPRs: affectedPRs,
+	})
+}
+
+func (h *Handlers) RetireUser(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		UserID string `json:"user_id"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	if request.UserID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "user_id is required")
+		return
+	}
+	reassignments, err := h.service.RetireUser(r.Context(), request.UserID)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "user not found")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type RetireUserResponse struct {
+		UserID     string                `json:"user_id"`
+		Reassigned []entity.Reassignment `json:"reassigned_prs"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RetireUserResponse{
+		UserID:     request.UserID,
+		Reassigned: reassignments,
+	})
+}
+
+func (h *Handlers) ReassignAllForUser(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		UserID     string `json:"user_id"`
+		BestEffort bool   `json:"best_effort"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	if request.UserID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "user_id is required")
+		return
+	}
+	results, err := h.service.ReassignAllForUser(r.Context(), request.UserID, request.BestEffort)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "user not found")
+		case entity.ErrNoCandidate:
+			message := "no active replacement candidate; nothing was reassigned"
+			if len(results) > 0 {
+				message = "no active replacement candidate for " + results[len(results)-1].PRID + "; nothing was reassigned"
+			}
+			h.writeError(w, r, http.StatusConflict, "NO_CANDIDATE", message)
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type ReassignAllResponse struct {
+		UserID  string                  `json:"user_id"`
+		Results []entity.ReassignResult `json:"results"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReassignAllResponse{
+		UserID:  request.UserID,
+		Results: results,
+	})
+}
+
+func (h *Handlers) SetTeamActive(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		TeamName string `json:"team_name"`
+		IsActive *bool  `json:"is_active"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	var errs fieldErrors
+	if request.TeamName == "" {
+		errs.add("team_name", "team_name is required")
+	}
+	if request.IsActive == nil {
+		errs.add("is_active", "is_active is required")
+	}
+	if h.writeFieldErrors(w, errs) {
+		return
+	}
+	users, reassignments, err := h.service.SetTeamActive(r.Context(), request.TeamName, *request.IsActive)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "team not found")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type SetTeamActiveResponse struct {
+		TeamName   string                `json:"team_name"`
+		Members    []entity.User         `json:"members"`
+		Reassigned []entity.Reassignment `json:"reassigned_prs"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SetTeamActiveResponse{
+		TeamName:   request.TeamName,
+		Members:    users,
+		Reassigned: reassignments,
 	})
 }
 
 func (h *Handlers) CreatePR(w http.ResponseWriter, r *http.Request) {
-    var request struct {
-        PRID     string `json:"pull_request_id"`
-        PRName   string `json:"pull_request_name"`
-        AuthorID string `json:"author_id"`
-    }
-    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-        h.writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
-        return
-    }
-    pr, err := h.service.CreatePR(request.PRID, request.PRName, request.AuthorID)
-    if err != nil {
-        switch err {
-        case entity.ErrPRExists:
-            h.writeError(w, http.StatusConflict, "PR_EXISTS", "pull request already exists")
-        case entity.ErrNotFound:
-            h.writeError(w, http.StatusNotFound, "NOT_FOUND", "author or team not found")
-        case entity.ErrNoCandidate:
-            h.writeError(w, http.StatusNotFound, "NO_CANDIDATE", "no active reviewers available in team")
-        default:
-            h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
-        }
-        return
-    }
-	type PRResponse struct {
-		PullRequestID    string   `json:"pull_request_id"`
-		PullRequestName  string   `json:"pull_request_name"`
-		AuthorID         string   `json:"author_id"`
-		Status           string   `json:"status"`
-		AssignedReviewers []string `json:"assigned_reviewers"`
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		PRID                string   `json:"pull_request_id"`
+		PRName              string   `json:"pull_request_name"`
+		AuthorID            string   `json:"author_id"`
+		ExcludeReviewers    []string `json:"exclude_reviewers"`
+		AvoidRecentPairings int      `json:"avoid_recent_pairings"`
+		TeamName            string   `json:"team_name"`
+		ReviewersCount      int      `json:"reviewers_count"`
+		AuthorAliases       []string `json:"author_aliases"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	var errs fieldErrors
+	if request.PRID == "" {
+		errs.add("pull_request_id", "pull_request_id is required")
+	}
+	if request.PRName == "" {
+		errs.add("pull_request_name", "pull_request_name is required")
+	}
+	if request.AuthorID == "" {
+		errs.add("author_id", "author_id is required")
+	}
+	if utf8.RuneCountInString(request.PRName) > maxPRNameLength {
+		errs.add("pull_request_name", "pull_request_name must be at most 200 characters")
+	}
+	if request.AvoidRecentPairings < 0 {
+		errs.add("avoid_recent_pairings", "avoid_recent_pairings must not be negative")
+	}
+	if request.ReviewersCount < 0 || request.ReviewersCount > service.MaxReviewersCount {
+		errs.add("reviewers_count", "reviewers_count must be between 1 and 10, or 0 for the default")
+	}
+	if h.writeFieldErrors(w, errs) {
+		return
+	}
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if idempotencyKey != "" {
+		stored, err := h.service.GetIdempotentResponse(r.Context(), idempotencyKey, request.PRID)
+		switch err {
+		case nil:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write(stored)
+			return
+		case entity.ErrNotFound:
+		case entity.ErrIdempotencyKeyReused:
+			h.writeError(w, r, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", "idempotency key was used for a different pull request")
+			return
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+			return
+		}
+	}
+	pr, err := h.service.CreatePR(r.Context(), request.PRID, request.PRName, request.AuthorID, entity.CreatePROptions{
+		ExcludeReviewers:    request.ExcludeReviewers,
+		AvoidRecentPairings: request.AvoidRecentPairings,
+		TeamName:            request.TeamName,
+		ReviewersCount:      request.ReviewersCount,
+		AuthorAliases:       request.AuthorAliases,
+		Debug:               h.debug && r.URL.Query().Get("debug") == "true",
+	})
+	if err != nil {
+		switch err {
+		case entity.ErrPRExists:
+			h.writeError(w, r, http.StatusConflict, "PR_EXISTS", "pull request already exists")
+		case entity.ErrInvalidPRID:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id has an invalid format")
+		case entity.ErrAuthorNotFound:
+			h.writeError(w, r, http.StatusNotFound, "AUTHOR_NOT_FOUND", "author not found")
+		case entity.ErrBlankUserID:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "author_id, exclude_reviewers and author_aliases must not contain blank ids")
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "author or team not found")
+		case entity.ErrNoCandidate:
+			h.writeError(w, r, http.StatusNotFound, "NO_CANDIDATE", "no active reviewers available in team")
+		case entity.ErrInvalidReviewerCount:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "reviewer count must be positive")
+		case entity.ErrUnknownUser:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "exclude_reviewers contains unknown user ids")
+		case entity.ErrSelfReview:
+			h.writeError(w, r, http.StatusConflict, "SELF_REVIEW", "pull request author cannot review their own pull request")
+		case entity.ErrSoloAuthor:
+			h.writeError(w, r, http.StatusUnprocessableEntity, "SOLO_AUTHOR", "author has no eligible teammates to review")
+		case entity.ErrAllInactive:
+			h.writeError(w, r, http.StatusUnprocessableEntity, "ALL_INACTIVE", "all of the author's teammates are inactive")
+		case entity.ErrAmbiguousTeam:
+			h.writeError(w, r, http.StatusBadRequest, "AMBIGUOUS_TEAM", "author belongs to several teams; team_name is required")
+		case entity.ErrAuthorNotInTeam:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "author is not a member of team_name")
+		case entity.ErrTeamTooSmall:
+			h.writeError(w, r, http.StatusUnprocessableEntity, "TEAM_TOO_SMALL", "team has fewer active members than the minimum team size")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type CreatedPRResponse struct {
+		prResponse
+		ReviewerAssignments []entity.CandidateReviewer `json:"reviewer_assignments"`
+		RequestedReviewers  int                        `json:"requested_reviewers"`
+		ReviewersSatisfied  bool                       `json:"reviewers_satisfied"`
+	}
+	type DebugResponse struct {
+		Candidates []entity.CandidateEvaluation `json:"candidates"`
 	}
 	type CreatePRResponse struct {
-		PR PRResponse `json:"pr"`
+		PR    CreatedPRResponse `json:"pr"`
+		Debug *DebugResponse    `json:"debug,omitempty"`
 	}
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(CreatePRResponse{
-		PR: PRResponse{
-			PullRequestID:    pr.ID,
-			PullRequestName:  pr.Title,
-			AuthorID:         pr.AuthorID,
-			Status:           pr.Status,
-			AssignedReviewers: getReviewerIDs(pr.AssignedReviewers),
+	var debug *DebugResponse
+	if pr.CandidateEvaluations != nil {
+		debug = &DebugResponse{Candidates: pr.CandidateEvaluations}
+	}
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(CreatePRResponse{
+		PR: CreatedPRResponse{
+			prResponse:          newPRResponse(r, pr),
+			ReviewerAssignments: reviewerAssignments(pr.ReviewerLoads),
+			RequestedReviewers:  pr.RequestedReviewers,
+			ReviewersSatisfied:  len(pr.AssignedReviewers) >= pr.RequestedReviewers,
 		},
+		Debug: debug,
 	})
+	if idempotencyKey != "" {
+		// The pull request is already created, so a failure to record the key
+		// only loses replay for retries and must not fail this request. Log it
+		// so a duplicate from a later retry can be traced back.
+		if err := h.service.SaveIdempotentResponse(r.Context(), idempotencyKey, pr.ID, body.Bytes()); err != nil {
+			h.logger.Error("failed to save idempotent response", "idempotency_key", idempotencyKey, "pull_request_id", pr.ID, "error", err.Error())
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body.Bytes())
+}
+
+func (h *Handlers) GetPR(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id is required")
+		return
+	}
+	pr, err := h.service.GetPR(r.Context(), prID)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "pull request not found")
+		} else if err == entity.ErrInvalidPRID {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id has an invalid format")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type GetPRResponse struct {
+		PR mergedPRResponse `json:"pr"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetPRResponse{PR: newMergedPRResponse(r, pr)})
 }
 
 func (h *Handlers) MergePR(w http.ResponseWriter, r *http.Request) {
-    var request struct {
-        PRID string `json:"pull_request_id"`
-    }
-    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-        h.writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
-        return
-    }
-    pr, err := h.service.MergePR(request.PRID)
-    if err != nil {
-        if err == entity.ErrNotFound {
-            h.writeError(w, http.StatusNotFound, "NOT_FOUND", "pull request not found")
-        } else {
-            h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
-        }
-        return
-    }
-	json.NewEncoder(w).Encode(struct {
-		PR struct {
-			PullRequestID    string   `json:"pull_request_id"`
-			PullRequestName  string   `json:"pull_request_name"`
-			AuthorID         string   `json:"author_id"`
-			Status           string   `json:"status"`
-			AssignedReviewers []string `json:"assigned_reviewers"`
-			MergedAt         interface{} `json:"mergedAt"`
-		} `json:"pr"`
-	}{
-		PR: struct {
-			PullRequestID    string   `json:"pull_request_id"`
-			PullRequestName  string   `json:"pull_request_name"`
-			AuthorID         string   `json:"author_id"`
-			Status           string   `json:"status"`
-			AssignedReviewers []string `json:"assigned_reviewers"`
-			MergedAt         interface{} `json:"mergedAt"`
-		}{
-			PullRequestID:    pr.ID,
-			PullRequestName:  pr.Title,
-			AuthorID:         pr.AuthorID,
-			Status:           pr.Status,
-			AssignedReviewers: getReviewerIDs(pr.AssignedReviewers),
-			MergedAt:         pr.MergedAt,
-		},
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		PRID string `json:"pull_request_id"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	pr, err := h.service.MergePR(r.Context(), request.PRID)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "pull request not found")
+		case entity.ErrInvalidPRID:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id has an invalid format")
+		case entity.ErrPRClosed:
+			h.writeError(w, r, http.StatusConflict, "PR_CLOSED", "cannot merge closed PR")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type MergePRResponse struct {
+		PR mergedPRResponse `json:"pr"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MergePRResponse{PR: newMergedPRResponse(r, pr)})
+}
+
+func (h *Handlers) ClosePR(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		PRID string `json:"pull_request_id"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	pr, err := h.service.ClosePR(r.Context(), request.PRID)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "pull request not found")
+		case entity.ErrPRMerged:
+			h.writeError(w, r, http.StatusConflict, "PR_MERGED", "cannot close merged PR")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type ClosePRResponse struct {
+		PR prResponse `json:"pr"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ClosePRResponse{
+		PR: newPRResponse(r, pr),
+	})
+}
+
+func (h *Handlers) ReopenPR(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		PRID string `json:"pull_request_id"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	pr, err := h.service.ReopenPR(r.Context(), request.PRID)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "pull request not found")
+		case entity.ErrPRClosed:
+			h.writeError(w, r, http.StatusConflict, "PR_CLOSED", "cannot reopen closed PR")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type ReopenPRResponse struct {
+		PR prResponse `json:"pr"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReopenPRResponse{
+		PR: newPRResponse(r, pr),
 	})
 }
 
 func (h *Handlers) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
-    var request struct {
-        PRID      string `json:"pull_request_id"`
-        OldUserID string `json:"old_user_id"`
-    }
-    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-        h.writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body")
-        return
-    }
-    pr, newUserID, err := h.service.ReassignReviewer(request.PRID, request.OldUserID)
-    if err != nil {
-        switch err {
-        case entity.ErrNotFound:
-            h.writeError(w, http.StatusNotFound, "NOT_FOUND", "pull request or user not found")
-        case entity.ErrPRMerged:
-            h.writeError(w, http.StatusConflict, "PR_MERGED", "cannot reassign on merged PR")
-        case entity.ErrNotAssigned:
-            h.writeError(w, http.StatusConflict, "NOT_ASSIGNED", "reviewer is not assigned to this PR")
-        case entity.ErrNoCandidate:
-            h.writeError(w, http.StatusConflict, "NO_CANDIDATE", "no active replacement candidate in team")
-        default:
-            h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
-        }
-        return
-    }
-	type PRResponse struct {
-		PullRequestID    string   `json:"pull_request_id"`
-		PullRequestName  string   `json:"pull_request_name"`
-		AuthorID         string   `json:"author_id"`
-		Status           string   `json:"status"`
-		AssignedReviewers []string `json:"assigned_reviewers"`
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		PRID      string `json:"pull_request_id"`
+		OldUserID string `json:"old_user_id"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	pr, newUserID, err := h.service.ReassignReviewer(r.Context(), request.PRID, request.OldUserID)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "pull request or user not found")
+		case entity.ErrPRMerged:
+			h.writeError(w, r, http.StatusConflict, "PR_MERGED", "cannot reassign on merged PR")
+		case entity.ErrPRClosed:
+			h.writeError(w, r, http.StatusConflict, "PR_CLOSED", "cannot reassign on closed PR")
+		case entity.ErrNotAssigned:
+			h.writeError(w, r, http.StatusConflict, "NOT_ASSIGNED", "reviewer is not assigned to this PR")
+		case entity.ErrNoCandidate:
+			h.writeError(w, r, http.StatusConflict, "NO_CANDIDATE", "no active replacement candidate in team")
+		case entity.ErrAuthorNoTeam:
+			h.writeError(w, r, http.StatusConflict, "AUTHOR_NO_TEAM", "pull request author is not a member of any team")
+		case entity.ErrBlankUserID:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "old_user_id must not be blank")
+		case entity.ErrInvalidPRID:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id has an invalid format")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
 	}
 	type ReassignReviewerResponse struct {
-		PR         PRResponse `json:"pr"`
-		ReplacedBy string     `json:"replaced_by"`
-	}
-	json.NewEncoder(w).Encode(ReassignReviewerResponse{
-		PR: PRResponse{
-			PullRequestID:    pr.ID,
-			PullRequestName:  pr.Title,
-			AuthorID:         pr.AuthorID,
-			Status:           pr.Status,
-			AssignedReviewers: getReviewerIDs(pr.AssignedReviewers),
-		},
+		PR                 prResponse `json:"pr"`
+		Replaced           string     `json:"replaced"`
+		ReplacedUsername   string     `json:"replaced_username,omitempty"`
+		ReplacedBy         string     `json:"replaced_by"`
+		ReplacedByUsername string     `json:"replaced_by_username,omitempty"`
+	}
+	response := ReassignReviewerResponse{
+		PR:         newPRResponse(r, pr),
+		Replaced:   request.OldUserID,
 		ReplacedBy: newUserID,
+	}
+	if pr.Reassignment != nil {
+		response.ReplacedUsername = pr.Reassignment.OldUsername
+		response.ReplacedByUsername = pr.Reassignment.NewUsername
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) RespondReview(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		PRID   string `json:"pull_request_id"`
+		UserID string `json:"user_id"`
+		Accept *bool  `json:"accept"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	if request.PRID == "" || request.UserID == "" || request.Accept == nil {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id, user_id and accept are required")
+		return
+	}
+	newUserID, err := h.service.RespondReview(r.Context(), request.PRID, request.UserID, *request.Accept)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "pull request not found")
+		case entity.ErrPRMerged:
+			h.writeError(w, r, http.StatusConflict, "PR_MERGED", "cannot respond to a review on merged PR")
+		case entity.ErrPRClosed:
+			h.writeError(w, r, http.StatusConflict, "PR_CLOSED", "cannot respond to a review on closed PR")
+		case entity.ErrNotAssigned:
+			h.writeError(w, r, http.StatusConflict, "NOT_ASSIGNED", "reviewer is not assigned to this PR")
+		case entity.ErrBlankUserID:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "user_id must not be blank")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type RespondReviewResponse struct {
+		PullRequestID string `json:"pull_request_id"`
+		UserID        string `json:"user_id"`
+		State         string `json:"state"`
+		ReplacedBy    string `json:"replaced_by,omitempty"`
+	}
+	response := RespondReviewResponse{
+		PullRequestID: request.PRID,
+		UserID:        request.UserID,
+		State:         entity.ReviewAccepted,
+		ReplacedBy:    newUserID,
+	}
+	if !*request.Accept {
+		response.State = entity.ReviewDeclined
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) AddReviewer(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		PRID   string `json:"pull_request_id"`
+		UserID string `json:"user_id"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	if request.PRID == "" || request.UserID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id and user_id are required")
+		return
+	}
+	pr, err := h.service.AddReviewer(r.Context(), request.PRID, request.UserID)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "pull request or user not found")
+		case entity.ErrPRMerged:
+			h.writeError(w, r, http.StatusConflict, "PR_MERGED", "cannot add reviewer to merged PR")
+		case entity.ErrPRClosed:
+			h.writeError(w, r, http.StatusConflict, "PR_CLOSED", "cannot add reviewer to closed PR")
+		case entity.ErrSelfReview:
+			h.writeError(w, r, http.StatusConflict, "SELF_REVIEW", "pull request author cannot review their own pull request")
+		case entity.ErrAlreadyAssigned:
+			h.writeError(w, r, http.StatusConflict, "ALREADY_ASSIGNED", "reviewer is already assigned to this PR")
+		case entity.ErrNoCandidate:
+			h.writeError(w, r, http.StatusConflict, "NO_CANDIDATE", "user is not an active member of the author's team")
+		case entity.ErrReviewerLimit:
+			h.writeError(w, r, http.StatusConflict, "REVIEWER_LIMIT", "pull request already has the maximum number of reviewers")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type AddReviewerResponse struct {
+		PR prResponse `json:"pr"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AddReviewerResponse{
+		PR: newPRResponse(r, pr),
+	})
+}
+
+func (h *Handlers) RemoveReviewer(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var request struct {
+		PRID   string `json:"pull_request_id"`
+		UserID string `json:"user_id"`
+	}
+	if err := decodeJSON(r, &request); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	if request.PRID == "" || request.UserID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id and user_id are required")
+		return
+	}
+	pr, err := h.service.RemoveReviewer(r.Context(), request.PRID, request.UserID)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "pull request not found")
+		case entity.ErrPRMerged:
+			h.writeError(w, r, http.StatusConflict, "PR_MERGED", "cannot remove reviewer from merged PR")
+		case entity.ErrPRClosed:
+			h.writeError(w, r, http.StatusConflict, "PR_CLOSED", "cannot remove reviewer from closed PR")
+		case entity.ErrNotAssigned:
+			h.writeError(w, r, http.StatusConflict, "NOT_ASSIGNED", "reviewer is not assigned to this PR")
+		case entity.ErrLastReviewer:
+			h.writeError(w, r, http.StatusConflict, "LAST_REVIEWER", "cannot remove the last active reviewer")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type RemoveReviewerResponse struct {
+		PR prResponse `json:"pr"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RemoveReviewerResponse{
+		PR: newPRResponse(r, pr),
+	})
+}
+
+func (h *Handlers) PreviewReassign(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	prID := r.URL.Query().Get("pull_request_id")
+	oldUserID := r.URL.Query().Get("old_user_id")
+	if prID == "" || oldUserID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id and old_user_id are required")
+		return
+	}
+	newUserID, err := h.service.PreviewReassign(r.Context(), prID, oldUserID)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "pull request or user not found")
+		case entity.ErrPRMerged:
+			h.writeError(w, r, http.StatusConflict, "PR_MERGED", "cannot reassign on merged PR")
+		case entity.ErrPRClosed:
+			h.writeError(w, r, http.StatusConflict, "PR_CLOSED", "cannot reassign on closed PR")
+		case entity.ErrNotAssigned:
+			h.writeError(w, r, http.StatusConflict, "NOT_ASSIGNED", "reviewer is not assigned to this PR")
+		case entity.ErrNoCandidate:
+			h.writeError(w, r, http.StatusConflict, "NO_CANDIDATE", "no active replacement candidate in team")
+		case entity.ErrAuthorNoTeam:
+			h.writeError(w, r, http.StatusConflict, "AUTHOR_NO_TEAM", "pull request author is not a member of any team")
+		case entity.ErrBlankUserID:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "old_user_id must not be blank")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pull_request_id": prID,
+		"old_user_id":     oldUserID,
+		"replaced_by":     newUserID,
+	})
+}
+
+func (h *Handlers) PreviewReviewers(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	authorID := r.URL.Query().Get("author_id")
+	if authorID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "author_id is required")
+		return
+	}
+	count := 0
+	if value := r.URL.Query().Get("count"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "count must be a positive integer")
+			return
+		}
+		count = parsed
+	}
+	candidates, err := h.service.PreviewReviewers(r.Context(), authorID, count)
+	if err != nil {
+		switch err {
+		case entity.ErrNotFound:
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "author not found")
+		case entity.ErrNoCandidate:
+			h.writeError(w, r, http.StatusNotFound, "NO_CANDIDATE", "no active reviewers available in team")
+		case entity.ErrAmbiguousTeam:
+			h.writeError(w, r, http.StatusBadRequest, "AMBIGUOUS_TEAM", "author belongs to several teams")
+		case entity.ErrInvalidReviewerCount:
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "count must be at most 10")
+		default:
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"author_id":            authorID,
+		"reviewer_assignments": reviewerAssignments(candidates),
 	})
 }
 
+func (h *Handlers) GetReassignmentHistory(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id is required")
+		return
+	}
+	history, err := h.service.GetReassignmentHistory(r.Context(), prID)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "pull request not found")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type HistoryEntry struct {
+		OldUserID    string  `json:"old_user_id"`
+		NewUserID    string  `json:"replaced_by"`
+		ReassignedAt *string `json:"reassigned_at"`
+	}
+	type HistoryResponse struct {
+		PullRequestID string         `json:"pull_request_id"`
+		History       []HistoryEntry `json:"history"`
+	}
+	entries := make([]HistoryEntry, len(history))
+	for i, entry := range history {
+		entries[i] = HistoryEntry{
+			OldUserID:    entry.OldUserID,
+			NewUserID:    entry.NewUserID,
+			ReassignedAt: formatTimestamp(entry.ReassignedAt),
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HistoryResponse{PullRequestID: prID, History: entries})
+}
+
+func (h *Handlers) GetPRTimeline(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id is required")
+		return
+	}
+	timeline, err := h.service.GetPRTimeline(r.Context(), prID)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "pull request not found")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	type TimelineResponse struct {
+		PullRequestID string                 `json:"pull_request_id"`
+		Timeline      []entity.TimelineEvent `json:"timeline"`
+	}
+	for i := range timeline {
+		timeline[i].At = formatTimestamp(timeline[i].At)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TimelineResponse{PullRequestID: prID, Timeline: timeline})
+}
+
 func (h *Handlers) GetUserReviewPRs(w http.ResponseWriter, r *http.Request) {
-    userID := r.URL.Query().Get("user_id")
-    if userID == "" {
-        h.writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "user_id is required")
-        return
-    }
-    prs, err := h.service.GetUserReviewPRs(userID)
-    if err != nil {
-        h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
-        return
-    }
+	if !h.requireMethod(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	var body struct {
+		UserID string `json:"user_id"`
+	}
+	if err := decodeOptionalJSON(r, &body); err != nil {
+		h.writeBodyError(w, r, err)
+		return
+	}
+	userID, problem := queryOrBodyParam(r, "user_id", body.UserID)
+	if problem != "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", problem)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", "OPEN", "MERGED", "CLOSED":
+	default:
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "status must be one of OPEN, MERGED, CLOSED")
+		return
+	}
+	var page entity.ReviewPage
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "limit must be a positive integer")
+			return
+		}
+		page.Limit = parsed
+	}
+	if value := r.URL.Query().Get("cursor"); value != "" {
+		cursor, ok := decodeReviewCursor(value)
+		if !ok {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "cursor is invalid")
+			return
+		}
+		page.After = cursor
+	}
+	includeReviewers := r.URL.Query().Get("include_reviewers") == "true"
+	prs, next, err := h.service.GetUserReviewPRs(r.Context(), userID, status, page)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	var reviewersByPR map[string][]entity.User
+	if includeReviewers {
+		prIDs := make([]string, len(prs))
+		for i, pr := range prs {
+			prIDs[i] = pr.ID
+		}
+		reviewersByPR, err = h.service.GetReviewersForPRs(r.Context(), prIDs)
+		if err != nil {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+			return
+		}
+	}
 	type PullRequestShort struct {
-		PullRequestID   string `json:"pull_request_id"`
-		PullRequestName string `json:"pull_request_name"`
-		AuthorID        string `json:"author_id"`
-		Status          string `json:"status"`
+		PullRequestID     string         `json:"pull_request_id"`
+		PullRequestName   string         `json:"pull_request_name"`
+		AuthorID          string         `json:"author_id"`
+		Status            string         `json:"status"`
+		CreatedAt         *string        `json:"created_at"`
+		AssignedReviewers *[]entity.User `json:"assigned_reviewers,omitempty"`
 	}
 	type UserReviewResponse struct {
 		UserID       string             `json:"user_id"`
 		PullRequests []PullRequestShort `json:"pull_requests"`
+		NextCursor   string             `json:"next_cursor,omitempty"`
 	}
 	shortPRs := make([]PullRequestShort, len(prs))
 	for i, pr := range prs {
@@ -313,32 +1504,493 @@ func (h *Handlers) GetUserReviewPRs(w http.ResponseWriter, r *http.Request) {
 			PullRequestName: pr.Title,
 			AuthorID:        pr.AuthorID,
 			Status:          pr.Status,
+			CreatedAt:       formatTimestamp(pr.CreatedAt),
+		}
+		if includeReviewers {
+			reviewers := reviewersByPR[pr.ID]
+			if reviewers == nil {
+				reviewers = []entity.User{}
+			}
+			shortPRs[i].AssignedReviewers = &reviewers
 		}
 	}
 	response := UserReviewResponse{
-        UserID:       userID,
-        PullRequests: shortPRs,
-    }
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(response)
+		UserID:       userID,
+		PullRequests: shortPRs,
+	}
+	if next != nil {
+		response.NextCursor = encodeReviewCursor(next)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handlers) ListPRs(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", "OPEN", "MERGED", "CLOSED":
+	default:
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "status must be one of OPEN, MERGED, CLOSED")
+		return
+	}
+	limit, offset, ok := h.parsePagination(w, r)
+	if !ok {
+		return
+	}
+	prs, err := h.service.ListPRs(r.Context(), status, r.URL.Query().Get("author_id"), limit, offset)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pull_requests": pullRequestListItems(prs),
+	})
+}
+
+func (h *Handlers) SearchPRs(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if utf8.RuneCountInString(query) < minSearchQueryLength {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "q must be at least 2 characters")
+		return
+	}
+	prs, err := h.service.SearchPRs(r.Context(), query, maxSearchResults)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pull_requests": pullRequestListItems(prs),
+	})
+}
+
+type pullRequestListItem struct {
+	PullRequestID   string  `json:"pull_request_id"`
+	PullRequestName string  `json:"pull_request_name"`
+	AuthorID        string  `json:"author_id"`
+	Status          string  `json:"status"`
+	CreatedAt       *string `json:"created_at"`
+	ReviewerCount   int     `json:"reviewer_count"`
+}
+
+func pullRequestListItems(prs []entity.PullRequest) []pullRequestListItem {
+	items := make([]pullRequestListItem, len(prs))
+	for i, pr := range prs {
+		items[i] = pullRequestListItem{
+			PullRequestID:   pr.ID,
+			PullRequestName: pr.Title,
+			AuthorID:        pr.AuthorID,
+			Status:          pr.Status,
+			CreatedAt:       formatTimestamp(pr.CreatedAt),
+			ReviewerCount:   pr.ReviewerCount,
+		}
+	}
+	return items
+}
+
+// assignedReviewers returns reviewer ids by default, or full user objects when
+// the request sets verbose=true.
+func assignedReviewers(r *http.Request, reviewers []entity.User) interface{} {
+	if r.URL.Query().Get("verbose") != "true" {
+		return getReviewerIDs(reviewers)
+	}
+	if reviewers == nil {
+		return []entity.User{}
+	}
+	return reviewers
+}
+
+// prResponse is the pull request shape shared by the endpoints that return
+// one.
+type prResponse struct {
+	PullRequestID     string      `json:"pull_request_id"`
+	PullRequestName   string      `json:"pull_request_name"`
+	AuthorID          string      `json:"author_id"`
+	Status            string      `json:"status"`
+	AssignedReviewers interface{} `json:"assigned_reviewers"`
+	CreatedAt         *string     `json:"created_at"`
+}
+
+func newPRResponse(r *http.Request, pr *entity.PullRequest) prResponse {
+	return prResponse{
+		PullRequestID:     pr.ID,
+		PullRequestName:   pr.Title,
+		AuthorID:          pr.AuthorID,
+		Status:            pr.Status,
+		AssignedReviewers: assignedReviewers(r, pr.AssignedReviewers),
+		CreatedAt:         formatTimestamp(pr.CreatedAt),
+	}
+}
+
+// mergedPRResponse adds mergedAt, which GetPR and MergePR report.
+type mergedPRResponse struct {
+	prResponse
+	MergedAt *string `json:"mergedAt"`
+}
+
+func newMergedPRResponse(r *http.Request, pr *entity.PullRequest) mergedPRResponse {
+	return mergedPRResponse{prResponse: newPRResponse(r, pr), MergedAt: pr.MergedAt}
+}
+
+func reviewerAssignments(loads []entity.CandidateReviewer) []entity.CandidateReviewer {
+	if loads == nil {
+		return []entity.CandidateReviewer{}
+	}
+	return loads
+}
+
+// encodeReviewCursor packs the keyset into an opaque URL-safe token; the
+// timestamp never contains "|", so the PR id may.
+func encodeReviewCursor(cursor *entity.ReviewCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor.CreatedAt + "|" + cursor.PRID))
+}
+
+func decodeReviewCursor(value string) (*entity.ReviewCursor, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, false
+	}
+	createdAt, prID, found := strings.Cut(string(raw), "|")
+	if !found || prID == "" {
+		return nil, false
+	}
+	if _, err := time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, false
+	}
+	return &entity.ReviewCursor{CreatedAt: createdAt, PRID: prID}, true
+}
+
+// decodeOptionalJSON decodes an application/json body into v with
+// decodeJSON. Other content types and an empty body leave v untouched.
+func decodeOptionalJSON(r *http.Request, v interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" || r.Body == nil {
+		return nil
+	}
+	if err := decodeJSON(r, v); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// queryOrBodyParam takes name from the query string or fromBody, the value
+// decodeOptionalJSON read for it. Exactly one source must supply it;
+// otherwise problem describes why not.
+func queryOrBodyParam(r *http.Request, name, fromBody string) (value, problem string) {
+	fromQuery := r.URL.Query().Get(name)
+	switch {
+	case fromQuery != "" && fromBody != "":
+		return "", name + " must be given in either the query string or the JSON body, not both"
+	case fromQuery != "":
+		return fromQuery, ""
+	case fromBody != "":
+		return fromBody, ""
+	}
+	return "", name + " is required"
+}
+
+// formatTimestamp normalizes a timestamp read from the database to RFC3339 in UTC.
+func formatTimestamp(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, *value)
+	if err != nil {
+		return value
+	}
+	formatted := parsed.UTC().Format(time.RFC3339)
+	return &formatted
 }
 
 func getReviewerIDs(reviewers []entity.User) []string {
-    ids := make([]string, len(reviewers))
-    for i, reviewer := range reviewers {
-        ids[i] = reviewer.ID
-    }
-    return ids
+	ids := make([]string, len(reviewers))
+	for i, reviewer := range reviewers {
+		ids[i] = reviewer.ID
+	}
+	return ids
+}
+
+// parsePagination reads limit and offset, writing a 400 and returning false
+// when either is malformed.
+func (h *Handlers) parsePagination(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	limit := defaultStatsLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "limit must be a positive integer")
+			return 0, 0, false
+		}
+		limit = parsed
+	}
+	offset := defaultStatsOffset
+	if value := r.URL.Query().Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "offset must be a non-negative integer")
+			return 0, 0, false
+		}
+		offset = parsed
+	}
+	return limit, offset, true
 }
 
 func (h *Handlers) GetStats(w http.ResponseWriter, r *http.Request) {
-    stats, err := h.service.GetStats()
-    if err != nil {
-        h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
-        return
-    }
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(map[string]interface{}{
-        "stats": stats,
-    })
-}
\ No newline at end of file
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	limit, offset, ok := h.parsePagination(w, r)
+	if !ok {
+		return
+	}
+	var filter entity.StatsFilter
+	for _, param := range []struct {
+		name  string
+		value **time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	} {
+		value := r.URL.Query().Get(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", param.name+" must be an RFC3339 timestamp")
+			return
+		}
+		*param.value = &parsed
+	}
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "from must not be after to")
+		return
+	}
+	switch sort := entity.StatsSort(r.URL.Query().Get("sort")); sort {
+	case "", entity.StatsSortCountDesc, entity.StatsSortCountAsc, entity.StatsSortName:
+		filter.Sort = sort
+	default:
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "sort must be one of count_desc, count_asc, name")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "format must be one of json, csv")
+		return
+	}
+	target := r.URL.Query().Get("target")
+	if target != "" && target != "users" && target != "prs" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "target must be one of users, prs")
+		return
+	}
+	stats, err := h.service.GetStats(r.Context(), limit, offset, filter)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	var body []byte
+	if format == "csv" {
+		body, err = statsCSV(stats, target)
+	} else {
+		body, err = json.Marshal(map[string]interface{}{
+			"stats": stats,
+		})
+	}
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if format == "csv" {
+		filename := "stats-users.csv"
+		if target == "prs" {
+			filename = "stats-prs.csv"
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Write(body)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
+// statsCSV renders the per-user counts, or the per-PR counts when target is
+// "prs", as CSV with a header row.
+func statsCSV(stats *entity.Stats, target string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if target == "prs" {
+		writer.Write([]string{"pull_request_id", "pull_request_name", "count"})
+		for _, pr := range stats.PRAssignmentCounts {
+			writer.Write([]string{pr.PRID, pr.Title, strconv.Itoa(pr.Count)})
+		}
+	} else {
+		writer.Write([]string{"user_id", "username", "count"})
+		for _, user := range stats.UserAssignmentCounts {
+			writer.Write([]string{user.UserID, user.Username, strconv.Itoa(user.Count)})
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// etagMatches reports whether an If-None-Match header lists etag or "*". Weak
+// comparison is used, so W/ prefixes are ignored on both sides.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handlers) GetTeamStats(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "team_name is required")
+		return
+	}
+	stats, err := h.service.GetTeamStats(r.Context(), teamName)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "team not found")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"team_name": teamName,
+		"stats":     stats,
+	})
+}
+
+func (h *Handlers) GetUserStats(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "user_id is required")
+		return
+	}
+	stats, err := h.service.GetUserStats(r.Context(), userID)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "user not found")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+func (h *Handlers) GetConcentration(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	concentration, err := h.service.GetConcentration(r.Context())
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(concentration)
+}
+
+func (h *Handlers) RequiredTeamSize(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	policy := entity.ReviewPolicy{DesiredReviewers: service.DefaultReviewersCount}
+	params := []struct {
+		name  string
+		value *int
+	}{
+		{"desired", &policy.DesiredReviewers},
+		{"reserve", &policy.Reserve},
+		{"cap", &policy.Cap},
+	}
+	for _, param := range params {
+		value := r.URL.Query().Get(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", param.name+" must be an integer")
+			return
+		}
+		*param.value = parsed
+	}
+	size, err := h.service.RequiredTeamSize(policy)
+	if err != nil {
+		if err == entity.ErrInvalidPolicy {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "desired must be positive, reserve and cap must not be negative")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy":             policy,
+		"required_team_size": size,
+	})
+}
+
+func (h *Handlers) GetReviewerWeeklySummary(w http.ResponseWriter, r *http.Request) {
+	if !h.requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "user_id is required")
+		return
+	}
+	weeks := defaultSummaryWeeks
+	if value := r.URL.Query().Get("weeks"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxSummaryWeeks {
+			h.writeError(w, r, http.StatusBadRequest, "INVALID_REQUEST", "weeks must be between 1 and 52")
+			return
+		}
+		weeks = parsed
+	}
+	summary, err := h.service.GetReviewerWeeklySummary(r.Context(), userID, weeks)
+	if err != nil {
+		if err == entity.ErrNotFound {
+			h.writeError(w, r, http.StatusNotFound, "NOT_FOUND", "user not found")
+		} else {
+			h.writeError(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id": userID,
+		"weeks":   summary,
+	})
+}
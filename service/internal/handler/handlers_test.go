@@ -2,1136 +2,4297 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
-    "fmt"
+	"time"
 
-    "service/internal/entity"
+	"service/internal/entity"
 )
 
 type mockService struct {
-    createTeamFunc        func(teamName string, members []entity.User) (*entity.Team, error)
-    getTeamFunc           func(teamName string) (*entity.Team, []entity.User, error)
-    setUserActiveFunc     func(userID string, isActive bool) (*entity.User, error)
-    getUserReviewPRsFunc  func(userID string) ([]entity.PullRequest, error)
-    createPRFunc          func(prID, title, authorID string) (*entity.PullRequest, error)
-    mergePRFunc           func(prID string) (*entity.PullRequest, error)
-    reassignReviewerFunc  func(prID, oldUserID string) (*entity.PullRequest, string, error)
-    getPRFunc             func(prID string) (*entity.PullRequest, error)
-    getStatsFunc          func() (*entity.Stats, error)
+	createTeamFunc               func(teamName string, members []entity.User, defaultReviewers int) (*entity.Team, error)
+	setTeamReviewerCountFunc     func(teamName string, count int) error
+	importTeamsFunc              func(teams []entity.TeamWithMembers) ([]entity.ImportResult, error)
+	getTeamFunc                  func(teamName string) (*entity.Team, []entity.User, error)
+	renameTeamFunc               func(oldName, newName string) (*entity.Team, error)
+	setUserActiveFunc            func(userID string, isActive bool) (*entity.User, []string, error)
+	retireUserFunc               func(userID string) ([]entity.Reassignment, error)
+	getUserFunc                  func(userID string) (*entity.User, error)
+	getUserLoadFunc              func(userID string) (int, int, error)
+	getUserTeamsFunc             func(userID string) ([]entity.Team, error)
+	reassignAllFunc              func(userID string, bestEffort bool) ([]entity.ReassignResult, error)
+	setTeamActiveFunc            func(teamName string, isActive bool) ([]entity.User, []entity.Reassignment, error)
+	getUserReviewPRsFunc         func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error)
+	getReviewersForPRsFunc       func(prIDs []string) (map[string][]entity.User, error)
+	createPRFunc                 func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error)
+	getIdempotentResponseFunc    func(key, prID string) ([]byte, error)
+	saveIdempotentResponseFunc   func(key, prID string, response []byte) error
+	mergePRFunc                  func(prID string) (*entity.PullRequest, error)
+	closePRFunc                  func(prID string) (*entity.PullRequest, error)
+	reopenPRFunc                 func(prID string) (*entity.PullRequest, error)
+	reassignReviewerFunc         func(prID, oldUserID string) (*entity.PullRequest, string, error)
+	previewReassignFunc          func(prID, oldUserID string) (string, error)
+	previewReviewersFunc         func(authorID string, count int) ([]entity.CandidateReviewer, error)
+	getReassignmentHistoryFunc   func(prID string) ([]entity.Reassignment, error)
+	getPRTimelineFunc            func(prID string) ([]entity.TimelineEvent, error)
+	addReviewerFunc              func(prID, userID string) (*entity.PullRequest, error)
+	removeReviewerFunc           func(prID, userID string) (*entity.PullRequest, error)
+	respondReviewFunc            func(prID, userID string, accept bool) (string, error)
+	getPRFunc                    func(prID string) (*entity.PullRequest, error)
+	listPRsFunc                  func(status, authorID string, limit, offset int) ([]entity.PullRequest, error)
+	searchPRsFunc                func(query string, limit int) ([]entity.PullRequest, error)
+	getStatsFunc                 func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error)
+	getTeamStatsFunc             func(teamName string) (*entity.Stats, error)
+	getUserStatsFunc             func(userID string) (*entity.UserAssignmentCount, error)
+	getConcentrationFunc         func() (*entity.Concentration, error)
+	requiredTeamSizeFunc         func(policy entity.ReviewPolicy) (int, error)
+	getReviewerWeeklySummaryFunc func(userID string, weeks int) ([]entity.WeekCount, error)
 }
 
-func (m *mockService) CreateTeam(teamName string, members []entity.User) (*entity.Team, error) {
-    return m.createTeamFunc(teamName, members)
+func (m *mockService) CreateTeam(ctx context.Context, teamName string, members []entity.User, defaultReviewers int) (*entity.Team, error) {
+	return m.createTeamFunc(teamName, members, defaultReviewers)
 }
 
-func (m *mockService) GetTeam(teamName string) (*entity.Team, []entity.User, error) {
-    return m.getTeamFunc(teamName)
+func (m *mockService) SetTeamReviewerCount(ctx context.Context, teamName string, count int) error {
+	return m.setTeamReviewerCountFunc(teamName, count)
 }
 
-func (m *mockService) SetUserActive(userID string, isActive bool) (*entity.User, error) {
-    return m.setUserActiveFunc(userID, isActive)
+func (m *mockService) ImportTeams(ctx context.Context, teams []entity.TeamWithMembers) ([]entity.ImportResult, error) {
+	return m.importTeamsFunc(teams)
 }
 
-func (m *mockService) GetUserReviewPRs(userID string) ([]entity.PullRequest, error) {
-    return []entity.PullRequest{}, nil
+func (m *mockService) GetTeam(ctx context.Context, teamName string) (*entity.Team, []entity.User, error) {
+	return m.getTeamFunc(teamName)
 }
 
-func (m *mockService) CreatePR(prID, title, authorID string) (*entity.PullRequest, error) {
-    return m.createPRFunc(prID, title, authorID)
+func (m *mockService) RenameTeam(ctx context.Context, oldName, newName string) (*entity.Team, error) {
+	return m.renameTeamFunc(oldName, newName)
 }
 
-func (m *mockService) MergePR(prID string) (*entity.PullRequest, error) {
-    return m.mergePRFunc(prID)
+func (m *mockService) SetUserActive(ctx context.Context, userID string, isActive bool) (*entity.User, []string, error) {
+	return m.setUserActiveFunc(userID, isActive)
 }
 
-func (m *mockService) ReassignReviewer(prID, oldUserID string) (*entity.PullRequest, string, error) {
-    return m.reassignReviewerFunc(prID, oldUserID)
+func (m *mockService) SetTeamActive(ctx context.Context, teamName string, isActive bool) ([]entity.User, []entity.Reassignment, error) {
+	return m.setTeamActiveFunc(teamName, isActive)
 }
 
-func (m *mockService) GetPR(prID string) (*entity.PullRequest, error) {
-    return &entity.PullRequest{}, nil
+func (m *mockService) GetUser(ctx context.Context, userID string) (*entity.User, error) {
+	return m.getUserFunc(userID)
 }
 
-func (m *mockService) GetStats() (*entity.Stats, error) {
-    if m.getStatsFunc != nil {
-        return m.getStatsFunc()
-    }
-    return &entity.Stats{}, nil
+func (m *mockService) ReassignAllForUser(ctx context.Context, userID string, bestEffort bool) ([]entity.ReassignResult, error) {
+	return m.reassignAllFunc(userID, bestEffort)
+}
+
+func (m *mockService) GetUserLoad(ctx context.Context, userID string) (open, total int, err error) {
+	return m.getUserLoadFunc(userID)
+}
+
+func (m *mockService) GetUserTeams(ctx context.Context, userID string) ([]entity.Team, error) {
+	return m.getUserTeamsFunc(userID)
+}
+
+func (m *mockService) RetireUser(ctx context.Context, userID string) ([]entity.Reassignment, error) {
+	return m.retireUserFunc(userID)
+}
+
+func (m *mockService) GetUserReviewPRs(ctx context.Context, userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+	if m.getUserReviewPRsFunc != nil {
+		return m.getUserReviewPRsFunc(userID, status, page)
+	}
+	return []entity.PullRequest{}, nil, nil
+}
+
+func (m *mockService) GetReviewersForPRs(ctx context.Context, prIDs []string) (map[string][]entity.User, error) {
+	return m.getReviewersForPRsFunc(prIDs)
+}
+
+func (m *mockService) CreatePR(ctx context.Context, prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+	return m.createPRFunc(prID, title, authorID, opts)
+}
+
+func (m *mockService) GetIdempotentResponse(ctx context.Context, key, prID string) ([]byte, error) {
+	return m.getIdempotentResponseFunc(key, prID)
+}
+
+func (m *mockService) SaveIdempotentResponse(ctx context.Context, key, prID string, response []byte) error {
+	return m.saveIdempotentResponseFunc(key, prID, response)
+}
+
+func (m *mockService) MergePR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	return m.mergePRFunc(prID)
+}
+
+func (m *mockService) ClosePR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	return m.closePRFunc(prID)
+}
+
+func (m *mockService) ReopenPR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	return m.reopenPRFunc(prID)
+}
+
+func (m *mockService) ReassignReviewer(ctx context.Context, prID, oldUserID string) (*entity.PullRequest, string, error) {
+	return m.reassignReviewerFunc(prID, oldUserID)
+}
+
+func (m *mockService) PreviewReassign(ctx context.Context, prID, oldUserID string) (string, error) {
+	return m.previewReassignFunc(prID, oldUserID)
+}
+
+func (m *mockService) PreviewReviewers(ctx context.Context, authorID string, count int) ([]entity.CandidateReviewer, error) {
+	return m.previewReviewersFunc(authorID, count)
+}
+
+func (m *mockService) GetReassignmentHistory(ctx context.Context, prID string) ([]entity.Reassignment, error) {
+	return m.getReassignmentHistoryFunc(prID)
+}
+
+func (m *mockService) GetPRTimeline(ctx context.Context, prID string) ([]entity.TimelineEvent, error) {
+	return m.getPRTimelineFunc(prID)
+}
+
+func (m *mockService) AddReviewer(ctx context.Context, prID, userID string) (*entity.PullRequest, error) {
+	return m.addReviewerFunc(prID, userID)
+}
+
+func (m *mockService) RemoveReviewer(ctx context.Context, prID, userID string) (*entity.PullRequest, error) {
+	return m.removeReviewerFunc(prID, userID)
+}
+
+func (m *mockService) RespondReview(ctx context.Context, prID, userID string, accept bool) (string, error) {
+	return m.respondReviewFunc(prID, userID, accept)
+}
+
+func (m *mockService) ListPRs(ctx context.Context, status, authorID string, limit, offset int) ([]entity.PullRequest, error) {
+	return m.listPRsFunc(status, authorID, limit, offset)
+}
+
+func (m *mockService) SearchPRs(ctx context.Context, query string, limit int) ([]entity.PullRequest, error) {
+	return m.searchPRsFunc(query, limit)
+}
+
+func (m *mockService) GetPR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	if m.getPRFunc != nil {
+		return m.getPRFunc(prID)
+	}
+	return &entity.PullRequest{}, nil
+}
+
+func (m *mockService) GetStats(ctx context.Context, limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+	if m.getStatsFunc != nil {
+		return m.getStatsFunc(limit, offset, filter)
+	}
+	return &entity.Stats{}, nil
+}
+
+func (m *mockService) GetTeamStats(ctx context.Context, teamName string) (*entity.Stats, error) {
+	return m.getTeamStatsFunc(teamName)
+}
+
+func (m *mockService) GetUserStats(ctx context.Context, userID string) (*entity.UserAssignmentCount, error) {
+	return m.getUserStatsFunc(userID)
+}
+
+func (m *mockService) GetConcentration(ctx context.Context) (*entity.Concentration, error) {
+	return m.getConcentrationFunc()
+}
+
+func (m *mockService) GetOperationalCounts(ctx context.Context) (*entity.OperationalCounts, error) {
+	return &entity.OperationalCounts{}, nil
+}
+
+func (m *mockService) RequiredTeamSize(policy entity.ReviewPolicy) (int, error) {
+	return m.requiredTeamSizeFunc(policy)
+}
+
+func (m *mockService) GetReviewerWeeklySummary(ctx context.Context, userID string, weeks int) ([]entity.WeekCount, error) {
+	return m.getReviewerWeeklySummaryFunc(userID, weeks)
+}
+
+func TestHandlers_ContentType(t *testing.T) {
+	mock := &mockService{
+		createTeamFunc: func(teamName string, members []entity.User, defaultReviewers int) (*entity.Team, error) {
+			if teamName == "taken" {
+				return nil, entity.ErrTeamExists
+			}
+			return &entity.Team{Name: teamName}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"Created", `{"team_name":"payments","members":[]}`, http.StatusCreated},
+		{"Service error", `{"team_name":"taken","members":[]}`, http.StatusBadRequest},
+		{"Validation error", `{"members":[]}`, http.StatusBadRequest},
+		{"Malformed body", `{`, http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.AddTeam(w, httptest.NewRequest("POST", "/team/add", strings.NewReader(tc.body)))
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Expected Content-Type application/json, got %q", got)
+			}
+		})
+	}
+}
+
+func TestHandlers_ErrorLogging_RequestID(t *testing.T) {
+	mock := &mockService{
+		getTeamFunc: func(teamName string) (*entity.Team, []entity.User, error) {
+			return nil, nil, fmt.Errorf("connection refused")
+		},
+	}
+	var logs bytes.Buffer
+	handler := NewHandlersWithConfig(mock, Config{Logger: slog.New(slog.NewJSONHandler(&logs, nil))})
+	w := httptest.NewRecorder()
+	handler.GetTeam(w, httptest.NewRequest("GET", "/team/get?team_name=payments", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", w.Code)
+	}
+	var response ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error.RequestID == "" {
+		t.Fatal("Expected a request_id in the response")
+	}
+	if got := w.Header().Get("X-Request-Id"); got != response.Error.RequestID {
+		t.Errorf("Expected X-Request-Id %q, got %q", response.Error.RequestID, got)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to decode log entry %q: %v", logs.String(), err)
+	}
+	expected := map[string]interface{}{
+		"level":      "ERROR",
+		"endpoint":   "/team/get",
+		"code":       "INTERNAL_ERROR",
+		"message":    "connection refused",
+		"request_id": response.Error.RequestID,
+	}
+	for key, want := range expected {
+		if entry[key] != want {
+			t.Errorf("Expected log %s %v, got %v", key, want, entry[key])
+		}
+	}
+}
+
+func TestHandlers_ErrorLogging_ClientErrorAtDebug(t *testing.T) {
+	var logs bytes.Buffer
+	handler := NewHandlersWithConfig(&mockService{}, Config{Logger: slog.New(slog.NewJSONHandler(&logs, nil))})
+	w := httptest.NewRecorder()
+	handler.GetTeam(w, httptest.NewRequest("GET", "/team/get", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	if w.Header().Get("X-Request-Id") != "" {
+		t.Error("Expected no X-Request-Id on a 4xx response")
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected 4xx to be logged only at debug level, got %q", logs.String())
+	}
 }
 
 func TestHandlers_AddTeam_Success_WithMembers(t *testing.T) {
-    var capturedMembers []entity.User
-    mock := &mockService{
-        createTeamFunc: func(teamName string, members []entity.User) (*entity.Team, error) {
-            capturedMembers = members 
-            return &entity.Team{Name: teamName}, nil
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "team_name": "payments",
-        "members": []map[string]interface{}{
-            {"user_id": "u1", "username": "Alice", "is_active": true},
-            {"user_id": "u2", "username": "Bob", "is_active": true},
-        },
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/teams", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.AddTeam(w, req)
-    if w.Code != http.StatusCreated {
-        t.Errorf("Expected status 201, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-    team, exists := response["team"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Response must contain 'team' field")
-    }
-    if team["team_name"] != "payments" {
-        t.Errorf("Expected team_name 'payments', got %v", team["team_name"])
-    }
-    membersData, exists := team["members"].([]interface{})
-    if !exists {
-        t.Fatal("Team must contain 'members' field")
-    }
-    if len(membersData) != 2 {
-        t.Errorf("Expected 2 members, got %d", len(membersData))
-        return
-    }
-    if len(capturedMembers) != 2 {
-        t.Errorf("Mock should have received 2 members, got %d", len(capturedMembers))
-    }
-    if len(capturedMembers) >= 2 {
-        if capturedMembers[0].ID != "u1" {
-            t.Errorf("Expected first captured member ID 'u1', got %s", capturedMembers[0].ID)
-        }
-        if capturedMembers[0].Username != "Alice" {
-            t.Errorf("Expected first captured member Username 'Alice', got %s", capturedMembers[0].Username)
-        }
-        if capturedMembers[0].IsActive != true {
-            t.Errorf("Expected first captured member IsActive true, got %t", capturedMembers[0].IsActive)
-        }
-
-        if capturedMembers[1].ID != "u2" {
-            t.Errorf("Expected second captured member ID 'u2', got %s", capturedMembers[1].ID)
-        }
-        if capturedMembers[1].Username != "Bob" {
-            t.Errorf("Expected second captured member Username 'Bob', got %s", capturedMembers[1].Username)
-        }
-        if capturedMembers[1].IsActive != true {
-            t.Errorf("Expected second captured member IsActive true, got %t", capturedMembers[1].IsActive)
-        }
-    }
-    t.Logf("Team created successfully with %d members", len(membersData))
-    t.Logf("Response: %s", w.Body.String())
+	var capturedMembers []entity.User
+	mock := &mockService{
+		createTeamFunc: func(teamName string, members []entity.User, defaultReviewers int) (*entity.Team, error) {
+			capturedMembers = members
+			return &entity.Team{Name: teamName}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"team_name": "payments",
+		"members": []map[string]interface{}{
+			{"user_id": "u1", "username": "Alice", "is_active": true},
+			{"user_id": "u2", "username": "Bob", "is_active": true},
+		},
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/teams", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.AddTeam(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	team, exists := response["team"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Response must contain 'team' field")
+	}
+	if team["team_name"] != "payments" {
+		t.Errorf("Expected team_name 'payments', got %v", team["team_name"])
+	}
+	membersData, exists := team["members"].([]interface{})
+	if !exists {
+		t.Fatal("Team must contain 'members' field")
+	}
+	if len(membersData) != 2 {
+		t.Errorf("Expected 2 members, got %d", len(membersData))
+		return
+	}
+	if len(capturedMembers) != 2 {
+		t.Errorf("Mock should have received 2 members, got %d", len(capturedMembers))
+	}
+	if len(capturedMembers) >= 2 {
+		if capturedMembers[0].ID != "u1" {
+			t.Errorf("Expected first captured member ID 'u1', got %s", capturedMembers[0].ID)
+		}
+		if capturedMembers[0].Username != "Alice" {
+			t.Errorf("Expected first captured member Username 'Alice', got %s", capturedMembers[0].Username)
+		}
+		if capturedMembers[0].IsActive != true {
+			t.Errorf("Expected first captured member IsActive true, got %t", capturedMembers[0].IsActive)
+		}
+
+		if capturedMembers[1].ID != "u2" {
+			t.Errorf("Expected second captured member ID 'u2', got %s", capturedMembers[1].ID)
+		}
+		if capturedMembers[1].Username != "Bob" {
+			t.Errorf("Expected second captured member Username 'Bob', got %s", capturedMembers[1].Username)
+		}
+		if capturedMembers[1].IsActive != true {
+			t.Errorf("Expected second captured member IsActive true, got %t", capturedMembers[1].IsActive)
+		}
+	}
+	t.Logf("Team created successfully with %d members", len(membersData))
+	t.Logf("Response: %s", w.Body.String())
 }
 
+func TestHandlers_AddTeam_BodyTooLarge(t *testing.T) {
+	called := false
+	mock := &mockService{
+		createTeamFunc: func(teamName string, members []entity.User, defaultReviewers int) (*entity.Team, error) {
+			called = true
+			return &entity.Team{Name: teamName}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	members := strings.Repeat(`{"user_id":"u","username":"U","is_active":true},`, 100)
+	body := `{"team_name":"huge","members":[` + strings.TrimSuffix(members, ",") + `]}`
+	limited := handler.LimitBody(1024, handler.AddTeam)
+	for _, declared := range []bool{true, false} {
+		req := httptest.NewRequest("POST", "/team/add", strings.NewReader(body))
+		if !declared {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		limited(w, req)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("Expected status 413 (declared length %v), got %d", declared, w.Code)
+		}
+		var response ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Error.Code != "PAYLOAD_TOO_LARGE" {
+			t.Errorf("Expected error code PAYLOAD_TOO_LARGE, got %q", response.Error.Code)
+		}
+	}
+	if called {
+		t.Error("Expected an oversized body to never reach the service")
+	}
+}
 
 func TestHandlers_AddTeam_TeamAlreadyExists(t *testing.T) {
-    mock := &mockService{
-        createTeamFunc: func(teamName string, members []entity.User) (*entity.Team, error) {
-            return nil, entity.ErrTeamExists
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "team_name": "payments",
-        "members": []map[string]interface{}{
-            {"user_id": "u1", "username": "Alice", "is_active": true},
-            {"user_id": "u2", "username": "Bob", "is_active": true},
-        },
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/teams", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.AddTeam(w, req)
-    if w.Code != http.StatusBadRequest {
-        t.Errorf("Expected status 400, got %d", w.Code)
-        t.Logf("Response: %s", w.Body.String())
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse error response: %v", err)
-    }
-    errorData, exists := response["error"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Error response must contain 'error' field")
-    }
-    errorCode, exists := errorData["code"].(string)
-    if !exists {
-        t.Fatal("Error must contain 'code' field")
-    }
-    if errorCode != "TEAM_EXISTS" {
-        t.Errorf("Expected error code 'TEAM_EXISTS', got %v", errorCode)
-    }
-    errorMessage, exists := errorData["message"].(string)
-    if !exists {
-        t.Fatal("Error must contain 'message' field")
-    }
-    if errorMessage != "team already exists" {
-        t.Errorf("Expected error message 'team already exists', got %v", errorMessage)
-    }
+	mock := &mockService{
+		createTeamFunc: func(teamName string, members []entity.User, defaultReviewers int) (*entity.Team, error) {
+			return nil, entity.ErrTeamExists
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"team_name": "payments",
+		"members": []map[string]interface{}{
+			{"user_id": "u1", "username": "Alice", "is_active": true},
+			{"user_id": "u2", "username": "Bob", "is_active": true},
+		},
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/teams", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.AddTeam(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+		t.Logf("Response: %s", w.Body.String())
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	errorData, exists := response["error"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Error response must contain 'error' field")
+	}
+	errorCode, exists := errorData["code"].(string)
+	if !exists {
+		t.Fatal("Error must contain 'code' field")
+	}
+	if errorCode != "TEAM_EXISTS" {
+		t.Errorf("Expected error code 'TEAM_EXISTS', got %v", errorCode)
+	}
+	errorMessage, exists := errorData["message"].(string)
+	if !exists {
+		t.Fatal("Error must contain 'message' field")
+	}
+	if errorMessage != "team already exists" {
+		t.Errorf("Expected error message 'team already exists', got %v", errorMessage)
+	}
+}
+
+func TestHandlers_AddTeam_DuplicateUsername(t *testing.T) {
+	mock := &mockService{
+		createTeamFunc: func(teamName string, members []entity.User, defaultReviewers int) (*entity.Team, error) {
+			return nil, entity.ErrDuplicateUsername
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{
+		"team_name": "payments",
+		"members": []map[string]interface{}{
+			{"user_id": "u1", "username": "Alice", "is_active": true},
+			{"user_id": "u2", "username": "Alice", "is_active": true},
+		},
+	})
+	req := httptest.NewRequest("POST", "/team/add", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.AddTeam(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	var response map[string]map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["error"]["code"] != "DUPLICATE_USERNAME" {
+		t.Errorf("Expected error code 'DUPLICATE_USERNAME', got %v", response["error"]["code"])
+	}
+}
+
+func TestHandlers_AddTeam_BlankTeamName(t *testing.T) {
+	mock := &mockService{
+		createTeamFunc: func(teamName string, members []entity.User, defaultReviewers int) (*entity.Team, error) {
+			return nil, entity.ErrEmptyTeamName
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{"team_name": "   ", "members": []interface{}{}})
+	req := httptest.NewRequest("POST", "/team/add", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.AddTeam(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	var response map[string]map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["error"]["code"] != "INVALID_REQUEST" {
+		t.Errorf("Expected error code 'INVALID_REQUEST', got %v", response["error"]["code"])
+	}
+}
+
+func TestHandlers_BlankUserID(t *testing.T) {
+	mock := &mockService{
+		setUserActiveFunc: func(userID string, isActive bool) (*entity.User, []string, error) {
+			return nil, nil, entity.ErrBlankUserID
+		},
+		reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
+			return nil, "", entity.ErrBlankUserID
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		path    string
+		body    string
+		handler http.HandlerFunc
+	}{
+		{"/users/setIsActive", `{"user_id":"  ","is_active":false}`, handler.SetUserActive},
+		{"/pullRequest/reassign", `{"pull_request_id":"pr-1","old_user_id":" "}`, handler.ReassignReviewer},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			tc.handler(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", w.Code)
+			}
+			var response ErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if response.Error.Code != "INVALID_REQUEST" {
+				t.Errorf("Expected INVALID_REQUEST, got %q", response.Error.Code)
+			}
+		})
+	}
+}
+
+func TestHandlers_AddTeam_FieldErrors(t *testing.T) {
+	handler := NewHandlers(&mockService{})
+	body, _ := json.Marshal(map[string]interface{}{
+		"team_name": "",
+		"members": []map[string]interface{}{
+			{"user_id": "u1", "username": "Alice", "is_active": true},
+			{"user_id": "", "username": "Bob", "is_active": true},
+		},
+	})
+	req := httptest.NewRequest("POST", "/team/add", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.AddTeam(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	var response ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	if response.Error.Code != "INVALID_REQUEST" || response.Error.Message != "team_name is required" {
+		t.Errorf("Expected INVALID_REQUEST with the first problem as message, got %+v", response.Error)
+	}
+	expected := map[string]string{
+		"team_name":          "team_name is required",
+		"members[1].user_id": "user_id is required",
+	}
+	if len(response.Error.Fields) != len(expected) {
+		t.Fatalf("Expected fields %v, got %v", expected, response.Error.Fields)
+	}
+	for field, message := range expected {
+		if response.Error.Fields[field] != message {
+			t.Errorf("Expected fields[%q] = %q, got %q", field, message, response.Error.Fields[field])
+		}
+	}
+}
+
+func TestHandlers_WriteError_OmitsFields(t *testing.T) {
+	handler := NewHandlers(&mockService{})
+	req := httptest.NewRequest("GET", "/pullRequest/get", nil)
+	w := httptest.NewRecorder()
+	handler.GetPR(w, req)
+	if strings.Contains(w.Body.String(), "fields") {
+		t.Errorf("Expected no fields for a single-message error, got %s", w.Body.String())
+	}
 }
 
 func TestHandlers_AddTeam_InvalidJSON(t *testing.T) {
-    mock := &mockService{}
-    handler := NewHandlers(mock)
-    req := httptest.NewRequest("POST", "/teams", bytes.NewReader([]byte("invalid json")))
-    w := httptest.NewRecorder()
-    handler.AddTeam(w, req)
-    if w.Code != http.StatusBadRequest {
-        t.Errorf("Expected status 400, got %d", w.Code)
-    }
+	mock := &mockService{}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("POST", "/teams", bytes.NewReader([]byte("invalid json")))
+	w := httptest.NewRecorder()
+	handler.AddTeam(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
 }
 
 func TestHandlers_GetTeam_Success(t *testing.T) {
-    mock := &mockService{
-        getTeamFunc: func(teamName string) (*entity.Team, []entity.User, error) {
-            team := &entity.Team{Name: teamName}
-            members := []entity.User{
-                {ID: "u1", Username: "Alice", IsActive: true},
-                {ID: "u2", Username: "Bob", IsActive: false},
-            }
-            return team, members, nil
-        },
-    }
-    handler := NewHandlers(mock)
-    req := httptest.NewRequest("GET", "/team/get?team_name=backend", nil)
-    w := httptest.NewRecorder()
-    handler.GetTeam(w, req)
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-    if response["team_name"] != "backend" {
-        t.Errorf("Expected team_name 'backend', got %v", response["team_name"])
-    }
-    membersData, exists := response["members"].([]interface{})
-    if !exists {
-        t.Fatal("Response must contain 'members' field")
-    }
-    if len(membersData) != 2 {
-        t.Errorf("Expected 2 members, got %d", len(membersData))
-    }
-    member1, ok := membersData[0].(map[string]interface{})
-    if !ok {
-        t.Fatal("First member should be an object")
-    }
-    if member1["user_id"] != "u1" {
-        t.Errorf("Expected first member user_id 'u1', got %v", member1["user_id"])
-    }
-    if member1["username"] != "Alice" {
-        t.Errorf("Expected first member username 'Alice', got %v", member1["username"])
-    }
-    if member1["is_active"] != true {
-        t.Errorf("Expected first member is_active true, got %v", member1["is_active"])
-    }
-    t.Logf("Team retrieved successfully: %s", w.Body.String())
+	mock := &mockService{
+		getTeamFunc: func(teamName string) (*entity.Team, []entity.User, error) {
+			team := &entity.Team{Name: teamName}
+			members := []entity.User{
+				{ID: "u1", Username: "Alice", IsActive: true},
+				{ID: "u2", Username: "Bob", IsActive: false},
+			}
+			return team, members, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/team/get?team_name=backend", nil)
+	w := httptest.NewRecorder()
+	handler.GetTeam(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response["team_name"] != "backend" {
+		t.Errorf("Expected team_name 'backend', got %v", response["team_name"])
+	}
+	membersData, exists := response["members"].([]interface{})
+	if !exists {
+		t.Fatal("Response must contain 'members' field")
+	}
+	if len(membersData) != 2 {
+		t.Errorf("Expected 2 members, got %d", len(membersData))
+	}
+	member1, ok := membersData[0].(map[string]interface{})
+	if !ok {
+		t.Fatal("First member should be an object")
+	}
+	if member1["user_id"] != "u1" {
+		t.Errorf("Expected first member user_id 'u1', got %v", member1["user_id"])
+	}
+	if member1["username"] != "Alice" {
+		t.Errorf("Expected first member username 'Alice', got %v", member1["username"])
+	}
+	if member1["is_active"] != true {
+		t.Errorf("Expected first member is_active true, got %v", member1["is_active"])
+	}
+	t.Logf("Team retrieved successfully: %s", w.Body.String())
 }
 
-func TestHandlers_GetTeam_NotFound(t *testing.T) {
-    mock := &mockService{
-        getTeamFunc: func(teamName string) (*entity.Team, []entity.User, error) {
-            return nil, nil, entity.ErrNotFound
-        },
-    }
-    handler := NewHandlers(mock)
-    req := httptest.NewRequest("GET", "/team/get?team_name=nonexistent", nil)
-    w := httptest.NewRecorder()
-    handler.GetTeam(w, req)
-    if w.Code != http.StatusNotFound {
-        t.Errorf("Expected status 404, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse error response: %v", err)
-    }
-    errorData, exists := response["error"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Error response must contain 'error' field")
-    }
-    errorCode, exists := errorData["code"].(string)
-    if !exists {
-        t.Fatal("Error must contain 'code' field")
-    }
-    if errorCode != "NOT_FOUND" {
-        t.Errorf("Expected error code 'NOT_FOUND', got %v", errorCode)
-    }
-    t.Logf("Team not found error handled correctly: %s", w.Body.String())
+func TestHandlers_Envelope_GetTeam(t *testing.T) {
+	mock := &mockService{
+		getTeamFunc: func(teamName string) (*entity.Team, []entity.User, error) {
+			if teamName != "backend" {
+				return nil, nil, entity.ErrNotFound
+			}
+			return &entity.Team{Name: teamName}, []entity.User{{ID: "u1", Username: "Alice", IsActive: true}}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	getTeam := handler.Envelope(handler.GetTeam)
+
+	w := httptest.NewRecorder()
+	getTeam(w, httptest.NewRequest("GET", "/team/get?team_name=backend", nil))
+	var bare map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &bare); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if w.Code != http.StatusOK || bare["team_name"] != "backend" || bare["data"] != nil {
+		t.Errorf("Expected the bare team shape, got %d %s", w.Code, w.Body.String())
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/team/get?team_name=backend&envelope=true", nil),
+		func() *http.Request {
+			req := httptest.NewRequest("GET", "/team/get?team_name=backend", nil)
+			req.Header.Set("Accept", "application/vnd.api+json")
+			return req
+		}(),
+	} {
+		w := httptest.NewRecorder()
+		getTeam(w, req)
+		var response struct {
+			Data struct {
+				TeamName string        `json:"team_name"`
+				Members  []interface{} `json:"members"`
+			} `json:"data"`
+			Meta struct {
+				Timestamp string `json:"timestamp"`
+			} `json:"meta"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if w.Code != http.StatusOK || response.Data.TeamName != "backend" || len(response.Data.Members) != 1 {
+			t.Errorf("Expected the team under data, got %d %s", w.Code, w.Body.String())
+		}
+		if _, err := time.Parse(time.RFC3339, response.Meta.Timestamp); err != nil {
+			t.Errorf("Expected an RFC 3339 meta.timestamp, got %q", response.Meta.Timestamp)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	getTeam(w, httptest.NewRequest("GET", "/team/get?team_name=missing&envelope=true", nil))
+	want := `{"errors":[{"code":"NOT_FOUND","message":"team not found"}]}`
+	if w.Code != http.StatusNotFound || strings.TrimSpace(w.Body.String()) != want {
+		t.Errorf("Expected 404 %s, got %d %s", want, w.Code, w.Body.String())
+	}
 }
 
+func TestHandlers_GetTeam_NameSources(t *testing.T) {
+	var requested string
+	mock := &mockService{
+		getTeamFunc: func(teamName string) (*entity.Team, []entity.User, error) {
+			requested = teamName
+			return &entity.Team{Name: teamName}, []entity.User{}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		wantCode int
+		wantTeam string
+	}{
+		{"query", "GET", "/team/get?team_name=backend", "", http.StatusOK, "backend"},
+		{"json body", "POST", "/team/get", `{"team_name":"payments"}`, http.StatusOK, "payments"},
+		{"both", "POST", "/team/get?team_name=backend", `{"team_name":"payments"}`, http.StatusBadRequest, ""},
+		{"neither", "POST", "/team/get", `{}`, http.StatusBadRequest, ""},
+		{"empty body", "POST", "/team/get?team_name=backend", ` `, http.StatusOK, "backend"},
+		{"unknown field", "POST", "/team/get", `{"teamname":"payments"}`, http.StatusBadRequest, ""},
+		{"not a string", "POST", "/team/get", `{"team_name":7}`, http.StatusBadRequest, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requested = ""
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			handler.GetTeam(w, req)
+			if w.Code != tc.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+			if requested != tc.wantTeam {
+				t.Errorf("Expected team %q to be requested, got %q", tc.wantTeam, requested)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetTeam_NotFound(t *testing.T) {
+	mock := &mockService{
+		getTeamFunc: func(teamName string) (*entity.Team, []entity.User, error) {
+			return nil, nil, entity.ErrNotFound
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/team/get?team_name=nonexistent", nil)
+	w := httptest.NewRecorder()
+	handler.GetTeam(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	errorData, exists := response["error"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Error response must contain 'error' field")
+	}
+	errorCode, exists := errorData["code"].(string)
+	if !exists {
+		t.Fatal("Error must contain 'code' field")
+	}
+	if errorCode != "NOT_FOUND" {
+		t.Errorf("Expected error code 'NOT_FOUND', got %v", errorCode)
+	}
+	t.Logf("Team not found error handled correctly: %s", w.Body.String())
+}
 
 func TestHandlers_SetUserActive_Success(t *testing.T) {
-    mock := &mockService{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return &entity.User{
-                ID:       userID,
-                Username: "Bob",
-                TeamName: "backend",
-                IsActive: isActive,
-            }, nil
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "user_id":   "u2",
-        "is_active": false,
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/users/setIsActive", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.SetUserActive(w, req)
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-    userData, exists := response["user"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Response must contain 'user' field")
-    }
-    if userData["user_id"] != "u2" {
-        t.Errorf("Expected user_id 'u2', got %v", userData["user_id"])
-    }
-    if userData["username"] != "Bob" {
-        t.Errorf("Expected username 'Bob', got %v", userData["username"])
-    }
-    if userData["team_name"] != "backend" {
-        t.Errorf("Expected team_name 'backend', got %v", userData["team_name"])
-    }
-    if userData["is_active"] != false {
-        t.Errorf("Expected is_active false, got %v", userData["is_active"])
-    }
-    t.Logf("User active status updated successfully: %s", w.Body.String())
+	mock := &mockService{
+		setUserActiveFunc: func(userID string, isActive bool) (*entity.User, []string, error) {
+			return &entity.User{
+				ID:       userID,
+				Username: "Bob",
+				TeamName: "backend",
+				IsActive: isActive,
+			}, []string{"pr-1", "pr-2"}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"user_id":   "u2",
+		"is_active": false,
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/users/setIsActive", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.SetUserActive(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	userData, exists := response["user"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Response must contain 'user' field")
+	}
+	if userData["user_id"] != "u2" {
+		t.Errorf("Expected user_id 'u2', got %v", userData["user_id"])
+	}
+	if userData["username"] != "Bob" {
+		t.Errorf("Expected username 'Bob', got %v", userData["username"])
+	}
+	if userData["team_name"] != "backend" {
+		t.Errorf("Expected team_name 'backend', got %v", userData["team_name"])
+	}
+	if userData["is_active"] != false {
+		t.Errorf("Expected is_active false, got %v", userData["is_active"])
+	}
+	affected, _ := response["affected_prs"].([]interface{})
+	if len(affected) != 2 || affected[0] != "pr-1" || affected[1] != "pr-2" {
+		t.Errorf("Expected affected_prs [pr-1 pr-2], got %v", response["affected_prs"])
+	}
+	t.Logf("User active status updated successfully: %s", w.Body.String())
 }
 
 func TestHandlers_SetUserActive_UserNotFound(t *testing.T) {
-    mock := &mockService{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return nil, entity.ErrNotFound
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "user_id":   "nonexistent",
-        "is_active": true,
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/users/setIsActive", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.SetUserActive(w, req)
-    if w.Code != http.StatusNotFound {
-        t.Errorf("Expected status 404, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse error response: %v", err)
-    }
-    errorData, exists := response["error"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Error response must contain 'error' field")
-    }
-    errorCode, exists := errorData["code"].(string)
-    if !exists {
-        t.Fatal("Error must contain 'code' field")
-    }
-    if errorCode != "NOT_FOUND" {
-        t.Errorf("Expected error code 'NOT_FOUND', got %v", errorCode)
-    }
-    t.Logf("User not found error handled correctly")
+	mock := &mockService{
+		setUserActiveFunc: func(userID string, isActive bool) (*entity.User, []string, error) {
+			return nil, nil, entity.ErrNotFound
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"user_id":   "nonexistent",
+		"is_active": true,
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/users/setIsActive", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.SetUserActive(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	errorData, exists := response["error"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Error response must contain 'error' field")
+	}
+	errorCode, exists := errorData["code"].(string)
+	if !exists {
+		t.Fatal("Error must contain 'code' field")
+	}
+	if errorCode != "NOT_FOUND" {
+		t.Errorf("Expected error code 'NOT_FOUND', got %v", errorCode)
+	}
+	t.Logf("User not found error handled correctly")
 }
 
 func TestHandlers_SetUserActive_InvalidJSON(t *testing.T) {
-    mock := &mockService{}
-    handler := NewHandlers(mock)
-    req := httptest.NewRequest("POST", "/users/setIsActive", bytes.NewReader([]byte("invalid json")))
-    w := httptest.NewRecorder()
-    handler.SetUserActive(w, req)
-    if w.Code != http.StatusBadRequest {
-        t.Errorf("Expected status 400, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    json.Unmarshal(w.Body.Bytes(), &response)
-    errorData := response["error"].(map[string]interface{})
-    if errorData["code"] != "INVALID_REQUEST" {
-        t.Errorf("Expected error code 'INVALID_REQUEST', got %v", errorData["code"])
-    }
-    t.Logf("Invalid JSON handled correctly")
+	mock := &mockService{}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("POST", "/users/setIsActive", bytes.NewReader([]byte("invalid json")))
+	w := httptest.NewRecorder()
+	handler.SetUserActive(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	errorData := response["error"].(map[string]interface{})
+	if errorData["code"] != "INVALID_REQUEST" {
+		t.Errorf("Expected error code 'INVALID_REQUEST', got %v", errorData["code"])
+	}
+	t.Logf("Invalid JSON handled correctly")
+}
+
+func TestHandlers_SetUserActive_MissingIsActive(t *testing.T) {
+	mock := &mockService{}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("POST", "/users/setIsActive", strings.NewReader(`{"user_id":"u1"}`))
+	w := httptest.NewRecorder()
+	handler.SetUserActive(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	var response ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Error.Code != "INVALID_REQUEST" || response.Error.Message != "is_active is required" {
+		t.Errorf("Unexpected error: %+v", response.Error)
+	}
 }
 
 func TestHandlers_CreatePR_Success(t *testing.T) {
-    mock := &mockService{
-        createPRFunc: func(prID, title, authorID string) (*entity.PullRequest, error) {
-            return &entity.PullRequest{
-                ID:       prID,
-                Title:    title,
-                AuthorID: authorID,
-                Status:   "OPEN",
-                AssignedReviewers: []entity.User{
-                    {ID: "u2", Username: "Bob", IsActive: true},
-                    {ID: "u3", Username: "Charlie", IsActive: true},
-                },
-            }, nil
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "pull_request_id":   "pr-1001",
-        "pull_request_name": "Add search",
-        "author_id":         "u1",
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.CreatePR(w, req)
-    if w.Code != http.StatusCreated {
-        t.Errorf("Expected status 201, got %d", w.Code)
-        t.Logf("Response: %s", w.Body.String())
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-    prData, exists := response["pr"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Response must contain 'pr' field")
-    }
-    if prData["pull_request_id"] != "pr-1001" {
-        t.Errorf("Expected pull_request_id 'pr-1001', got %v", prData["pull_request_id"])
-    }
-    if prData["pull_request_name"] != "Add search" {
-        t.Errorf("Expected pull_request_name 'Add search', got %v", prData["pull_request_name"])
-    }
-    if prData["author_id"] != "u1" {
-        t.Errorf("Expected author_id 'u1', got %v", prData["author_id"])
-    }
-    if prData["status"] != "OPEN" {
-        t.Errorf("Expected status 'OPEN', got %v", prData["status"])
-    }
-    reviewers, exists := prData["assigned_reviewers"].([]interface{})
-    if !exists {
-        t.Fatal("PR must contain 'assigned_reviewers' field")
-    }
-    if len(reviewers) != 2 {
-        t.Errorf("Expected 2 assigned reviewers, got %d", len(reviewers))
-    }
-    t.Logf("PR created successfully: %s", w.Body.String())
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:       prID,
+				Title:    title,
+				AuthorID: authorID,
+				Status:   "OPEN",
+				AssignedReviewers: []entity.User{
+					{ID: "u2", Username: "Bob", IsActive: true},
+					{ID: "u3", Username: "Charlie", IsActive: true},
+				},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": "Add search",
+		"author_id":         "u1",
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Code)
+		t.Logf("Response: %s", w.Body.String())
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	prData, exists := response["pr"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Response must contain 'pr' field")
+	}
+	if prData["pull_request_id"] != "pr-1001" {
+		t.Errorf("Expected pull_request_id 'pr-1001', got %v", prData["pull_request_id"])
+	}
+	if prData["pull_request_name"] != "Add search" {
+		t.Errorf("Expected pull_request_name 'Add search', got %v", prData["pull_request_name"])
+	}
+	if prData["author_id"] != "u1" {
+		t.Errorf("Expected author_id 'u1', got %v", prData["author_id"])
+	}
+	if prData["status"] != "OPEN" {
+		t.Errorf("Expected status 'OPEN', got %v", prData["status"])
+	}
+	reviewers, exists := prData["assigned_reviewers"].([]interface{})
+	if !exists {
+		t.Fatal("PR must contain 'assigned_reviewers' field")
+	}
+	if len(reviewers) != 2 {
+		t.Errorf("Expected 2 assigned reviewers, got %d", len(reviewers))
+	}
+	t.Logf("PR created successfully: %s", w.Body.String())
+}
+
+func TestHandlers_VerboseAssignedReviewers(t *testing.T) {
+	assignedAt := "2025-10-24T12:34:56Z"
+	pr := &entity.PullRequest{
+		ID:       "pr-1001",
+		Title:    "Add search",
+		AuthorID: "u1",
+		Status:   "OPEN",
+		AssignedReviewers: []entity.User{
+			{ID: "u2", Username: "Bob", IsActive: true, AssignedAt: &assignedAt},
+			{ID: "u3", Username: "Charlie", IsActive: true, AssignedAt: &assignedAt},
+		},
+	}
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return pr, nil
+		},
+		mergePRFunc: func(prID string) (*entity.PullRequest, error) {
+			return pr, nil
+		},
+		reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
+			return pr, "u3", nil
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		name   string
+		path   string
+		body   map[string]interface{}
+		handle func(w http.ResponseWriter, r *http.Request)
+	}{
+		{"create", "/pullRequest/create", map[string]interface{}{"pull_request_id": "pr-1001", "pull_request_name": "Add search", "author_id": "u1"}, handler.CreatePR},
+		{"merge", "/pullRequest/merge", map[string]interface{}{"pull_request_id": "pr-1001"}, handler.MergePR},
+		{"reassign", "/pullRequest/reassign", map[string]interface{}{"pull_request_id": "pr-1001", "old_user_id": "u4"}, handler.ReassignReviewer},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.body)
+			w := httptest.NewRecorder()
+			tc.handle(w, httptest.NewRequest("POST", tc.path, bytes.NewReader(body)))
+			var ids struct {
+				PR struct {
+					AssignedReviewers []string `json:"assigned_reviewers"`
+				} `json:"pr"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &ids); err != nil {
+				t.Fatalf("Expected reviewer ids by default, got %s: %v", w.Body.String(), err)
+			}
+			if len(ids.PR.AssignedReviewers) != 2 || ids.PR.AssignedReviewers[0] != "u2" {
+				t.Errorf("Unexpected default reviewers: %v", ids.PR.AssignedReviewers)
+			}
+			w = httptest.NewRecorder()
+			tc.handle(w, httptest.NewRequest("POST", tc.path+"?verbose=true", bytes.NewReader(body)))
+			var users struct {
+				PR struct {
+					AssignedReviewers []entity.User `json:"assigned_reviewers"`
+				} `json:"pr"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &users); err != nil {
+				t.Fatalf("Expected reviewer objects with verbose=true, got %s: %v", w.Body.String(), err)
+			}
+			if len(users.PR.AssignedReviewers) != 2 || users.PR.AssignedReviewers[1].Username != "Charlie" || !users.PR.AssignedReviewers[1].IsActive {
+				t.Errorf("Unexpected verbose reviewers: %+v", users.PR.AssignedReviewers)
+			}
+			if at := users.PR.AssignedReviewers[0].AssignedAt; at == nil || *at != assignedAt {
+				t.Errorf("Expected assigned_at %s, got %v", assignedAt, at)
+			}
+		})
+	}
+}
+
+func TestHandlers_CreatePR_InvalidInput(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    map[string]interface{}
+		message string
+	}{
+		{"missing pull_request_id", map[string]interface{}{"pull_request_name": "Add search", "author_id": "u1"}, "pull_request_id is required"},
+		{"missing pull_request_name", map[string]interface{}{"pull_request_id": "pr-1001", "author_id": "u1"}, "pull_request_name is required"},
+		{"missing author_id", map[string]interface{}{"pull_request_id": "pr-1001", "pull_request_name": "Add search"}, "author_id is required"},
+		{"title too long", map[string]interface{}{"pull_request_id": "pr-1001", "pull_request_name": strings.Repeat("a", 201), "author_id": "u1"}, "pull_request_name must be at most 200 characters"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &mockService{
+				createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+					t.Fatal("CreatePR must not be called for invalid input")
+					return nil, nil
+				},
+			}
+			handler := NewHandlers(mock)
+			body, _ := json.Marshal(tc.body)
+			req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.CreatePR(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+			var response ErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if response.Error.Code != "INVALID_REQUEST" {
+				t.Errorf("Expected INVALID_REQUEST, got %s", response.Error.Code)
+			}
+			if response.Error.Message != tc.message {
+				t.Errorf("Expected message %q, got %q", tc.message, response.Error.Message)
+			}
+		})
+	}
+}
+
+func TestHandlers_CreatePR_MaxLengthTitle(t *testing.T) {
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return &entity.PullRequest{ID: prID, Title: title, AuthorID: authorID, Status: "OPEN"}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": strings.Repeat("я", 200),
+		"author_id":         "u1",
+	})
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected a 200-character title to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
 }
 
 func TestHandlers_CreatePR_AlreadyExists(t *testing.T) {
-    mock := &mockService{
-        createPRFunc: func(prID, title, authorID string) (*entity.PullRequest, error) {
-            return nil, entity.ErrPRExists
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "pull_request_id":   "pr-1001",
-        "pull_request_name": "Add search",
-        "author_id":         "u1",
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.CreatePR(w, req)
-    if w.Code != http.StatusConflict {
-        t.Errorf("Expected status 409, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse error response: %v", err)
-    }
-    errorData, exists := response["error"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Error response must contain 'error' field")
-    }
-    if errorData["code"] != "PR_EXISTS" {
-        t.Errorf("Expected error code 'PR_EXISTS', got %v", errorData["code"])
-    }
-    t.Logf("PR already exists error handled correctly")
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return nil, entity.ErrPRExists
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": "Add search",
+		"author_id":         "u1",
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	errorData, exists := response["error"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Error response must contain 'error' field")
+	}
+	if errorData["code"] != "PR_EXISTS" {
+		t.Errorf("Expected error code 'PR_EXISTS', got %v", errorData["code"])
+	}
+	t.Logf("PR already exists error handled correctly")
 }
 
 func TestHandlers_CreatePR_AuthorNotFound(t *testing.T) {
-    mock := &mockService{
-        createPRFunc: func(prID, title, authorID string) (*entity.PullRequest, error) {
-            return nil, entity.ErrNotFound
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "pull_request_id":   "pr-1001",
-        "pull_request_name": "Add search",
-        "author_id":         "nonexistent",
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.CreatePR(w, req)
-    if w.Code != http.StatusNotFound {
-        t.Errorf("Expected status 404, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    json.Unmarshal(w.Body.Bytes(), &response)
-    errorData := response["error"].(map[string]interface{})
-    if errorData["code"] != "NOT_FOUND" {
-        t.Errorf("Expected error code 'NOT_FOUND', got %v", errorData["code"])
-    }
-    t.Logf("Author not found error handled correctly")
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return nil, entity.ErrAuthorNotFound
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": "Add search",
+		"author_id":         "nonexistent",
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	errorData := response["error"].(map[string]interface{})
+	if errorData["code"] != "AUTHOR_NOT_FOUND" {
+		t.Errorf("Expected error code 'AUTHOR_NOT_FOUND', got %v", errorData["code"])
+	}
+	t.Logf("Author not found error handled correctly")
+}
+
+func TestHandlers_CreatePR_GenericNotFound(t *testing.T) {
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return nil, entity.ErrNotFound
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": "Add search",
+		"author_id":         "u1",
+	})
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", w.Code)
+	}
+	var response map[string]map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["error"]["code"] != "NOT_FOUND" {
+		t.Errorf("Expected error code 'NOT_FOUND', got %v", response["error"]["code"])
+	}
 }
 
 func TestHandlers_CreatePR_NoCandidateReviewers(t *testing.T) {
-    mock := &mockService{
-        createPRFunc: func(prID, title, authorID string) (*entity.PullRequest, error) {
-            return nil, entity.ErrNoCandidate
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "pull_request_id":   "pr-1001",
-        "pull_request_name": "Add search",
-        "author_id":         "u1",
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.CreatePR(w, req)
-    if w.Code != http.StatusNotFound {
-        t.Errorf("Expected status 404, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    json.Unmarshal(w.Body.Bytes(), &response)
-    errorData := response["error"].(map[string]interface{})
-    if errorData["code"] != "NO_CANDIDATE" {
-        t.Errorf("Expected error code 'NO_CANDIDATE', got %v", errorData["code"])
-    }
-    t.Logf("No candidate reviewers error handled correctly")
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return nil, entity.ErrNoCandidate
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": "Add search",
+		"author_id":         "u1",
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	errorData := response["error"].(map[string]interface{})
+	if errorData["code"] != "NO_CANDIDATE" {
+		t.Errorf("Expected error code 'NO_CANDIDATE', got %v", errorData["code"])
+	}
+	t.Logf("No candidate reviewers error handled correctly")
+}
+
+func TestHandlers_CreatePR_SoloAuthor(t *testing.T) {
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return nil, entity.ErrSoloAuthor
+		},
+	}
+	handler := NewHandlers(mock)
+	body := `{"pull_request_id":"pr-1001","pull_request_name":"Add search","author_id":"u1"}`
+	req := httptest.NewRequest("POST", "/pullRequest/create", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d", w.Code)
+	}
+	var response ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Error.Code != "SOLO_AUTHOR" || response.Error.Message != "author has no eligible teammates to review" {
+		t.Errorf("Unexpected error: %+v", response.Error)
+	}
+}
+
+func TestHandlers_CreatePR_UnknownField(t *testing.T) {
+	called := false
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			called = true
+			return &entity.PullRequest{ID: prID, Title: title, AuthorID: authorID, Status: "OPEN"}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	body := `{"pull_request_id":"pr-1001","title":"Add search","author_id":"u1"}`
+	req := httptest.NewRequest("POST", "/pullRequest/create", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	var response ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Error.Code != "INVALID_REQUEST" || response.Error.Fields["title"] == "" {
+		t.Errorf("Expected INVALID_REQUEST naming title, got %+v", response.Error)
+	}
+	if called {
+		t.Error("Expected the request to be rejected before reaching the service")
+	}
+	body = `{"pull_request_id":"pr-1001","pull_request_name":"Add search","author_id":"u1","exclude_reviewers":[],"avoid_recent_pairings":0}`
+	req = httptest.NewRequest("POST", "/pullRequest/create", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected a payload with only known fields to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandlers_CreatePR_AllInactive(t *testing.T) {
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return nil, entity.ErrAllInactive
+		},
+	}
+	handler := NewHandlers(mock)
+	body := `{"pull_request_id":"pr-1001","pull_request_name":"Add search","author_id":"u1"}`
+	req := httptest.NewRequest("POST", "/pullRequest/create", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d", w.Code)
+	}
+	var response ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Error.Code != "ALL_INACTIVE" {
+		t.Errorf("Expected error code ALL_INACTIVE, got %q", response.Error.Code)
+	}
+}
+
+func TestHandlers_CreatePR_TeamTooSmall(t *testing.T) {
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return nil, entity.ErrTeamTooSmall
+		},
+	}
+	handler := NewHandlers(mock)
+	body := `{"pull_request_id":"pr-1001","pull_request_name":"Add search","author_id":"u1"}`
+	req := httptest.NewRequest("POST", "/pullRequest/create", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d", w.Code)
+	}
+	var response ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Error.Code != "TEAM_TOO_SMALL" {
+		t.Errorf("Expected error code TEAM_TOO_SMALL, got %q", response.Error.Code)
+	}
+}
+
+func TestHandlers_CreatePR_InvalidReviewerCount(t *testing.T) {
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return nil, entity.ErrInvalidReviewerCount
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": "Add search",
+		"author_id":         "u1",
+	})
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	errorData := response["error"].(map[string]interface{})
+	if errorData["code"] != "INVALID_REQUEST" {
+		t.Errorf("Expected error code 'INVALID_REQUEST', got %v", errorData["code"])
+	}
+}
+
+func TestHandlers_CreatePR_ExcludeReviewers(t *testing.T) {
+	var captured entity.CreatePROptions
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			captured = opts
+			return &entity.PullRequest{ID: prID, Title: title, AuthorID: authorID, Status: "OPEN"}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": "Add search",
+		"author_id":         "u1",
+		"exclude_reviewers": []string{"u2"},
+	})
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+	if len(captured.ExcludeReviewers) != 1 || captured.ExcludeReviewers[0] != "u2" {
+		t.Errorf("Expected exclude_reviewers [u2] to reach the service, got %v", captured.ExcludeReviewers)
+	}
+}
+
+func TestHandlers_CreatePR_AvoidRecentPairings(t *testing.T) {
+	var captured entity.CreatePROptions
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			captured = opts
+			return &entity.PullRequest{ID: prID, Title: title, AuthorID: authorID, Status: "OPEN"}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{
+		"pull_request_id":       "pr-1001",
+		"pull_request_name":     "Add search",
+		"author_id":             "u1",
+		"avoid_recent_pairings": 2,
+	})
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+	if captured.AvoidRecentPairings != 2 {
+		t.Errorf("Expected avoid_recent_pairings 2 to reach the service, got %d", captured.AvoidRecentPairings)
+	}
+
+	body, _ = json.Marshal(map[string]interface{}{
+		"pull_request_id":       "pr-1002",
+		"pull_request_name":     "Add search",
+		"author_id":             "u1",
+		"avoid_recent_pairings": -1,
+	})
+	req = httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a negative value, got %d", w.Code)
+	}
+}
+
+func TestHandlers_CreatePR_ReviewerAssignments(t *testing.T) {
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:                prID,
+				Title:             title,
+				AuthorID:          authorID,
+				Status:            "OPEN",
+				AssignedReviewers: []entity.User{{ID: "u2"}},
+				ReviewerLoads:     []entity.CandidateReviewer{{UserID: "u2", Load: 4}},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": "Add search",
+		"author_id":         "u1",
+	})
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+	var response struct {
+		PR struct {
+			ReviewerAssignments []entity.CandidateReviewer `json:"reviewer_assignments"`
+		} `json:"pr"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	assignments := response.PR.ReviewerAssignments
+	if len(assignments) != 1 || assignments[0].UserID != "u2" || assignments[0].Load != 4 {
+		t.Errorf("Expected reviewer_assignments [{u2 4}], got %v", assignments)
+	}
+}
+
+func TestHandlers_CreatePR_Debug(t *testing.T) {
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			pr := &entity.PullRequest{ID: prID, Title: title, AuthorID: authorID, Status: "OPEN", AssignedReviewers: []entity.User{{ID: "u2"}}}
+			if opts.Debug {
+				pr.CandidateEvaluations = []entity.CandidateEvaluation{
+					{UserID: "u1", SkipReason: entity.SkipAuthor},
+					{UserID: "u2", Load: 1, Selected: true},
+					{UserID: "u3", Load: 5, SkipReason: entity.SkipOverCap},
+				}
+			}
+			return pr, nil
+		},
+	}
+	body := `{"pull_request_id":"pr-1","pull_request_name":"Add search","author_id":"u1"}`
+	testCases := []struct {
+		name      string
+		enabled   bool
+		query     string
+		wantDebug bool
+	}{
+		{"Enabled and requested", true, "?debug=true", true},
+		{"Enabled but not requested", true, "", false},
+		{"Requested but disabled", false, "?debug=true", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := NewHandlersWithConfig(mock, Config{Debug: tc.enabled})
+			w := httptest.NewRecorder()
+			handler.CreatePR(w, httptest.NewRequest("POST", "/pullRequest/create"+tc.query, strings.NewReader(body)))
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+			}
+			var response struct {
+				Debug *struct {
+					Candidates []map[string]interface{} `json:"candidates"`
+				} `json:"debug"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if !tc.wantDebug {
+				if response.Debug != nil {
+					t.Errorf("Expected no debug object, got %s", w.Body.String())
+				}
+				return
+			}
+			if response.Debug == nil || len(response.Debug.Candidates) != 3 {
+				t.Fatalf("Expected 3 evaluated candidates, got %s", w.Body.String())
+			}
+			want := []map[string]interface{}{
+				{"user_id": "u1", "load": float64(0), "selected": false, "skip_reason": "author"},
+				{"user_id": "u2", "load": float64(1), "selected": true},
+				{"user_id": "u3", "load": float64(5), "selected": false, "skip_reason": "over_cap"},
+			}
+			if !reflect.DeepEqual(response.Debug.Candidates, want) {
+				t.Errorf("Expected %v, got %v", want, response.Debug.Candidates)
+			}
+		})
+	}
+}
+
+func TestHandlers_CreatePR_ReviewersNotSatisfied(t *testing.T) {
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			// A team of two: the author's only teammate is the sole reviewer.
+			return &entity.PullRequest{
+				ID:                 prID,
+				Title:              title,
+				AuthorID:           authorID,
+				Status:             "OPEN",
+				AssignedReviewers:  []entity.User{{ID: "u2"}},
+				RequestedReviewers: 2,
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": "Add search",
+		"author_id":         "u1",
+	})
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+	var response struct {
+		PR struct {
+			RequestedReviewers int  `json:"requested_reviewers"`
+			ReviewersSatisfied bool `json:"reviewers_satisfied"`
+		} `json:"pr"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if response.PR.RequestedReviewers != 2 {
+		t.Errorf("Expected requested_reviewers 2, got %d", response.PR.RequestedReviewers)
+	}
+	if response.PR.ReviewersSatisfied {
+		t.Error("Expected reviewers_satisfied false")
+	}
+}
+
+func TestHandlers_CreatePR_ExcludeUnknownReviewer(t *testing.T) {
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return nil, entity.ErrUnknownUser
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": "Add search",
+		"author_id":         "u1",
+		"exclude_reviewers": []string{"ghost"},
+	})
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func newIdempotentMock() (*mockService, *int) {
+	type record struct {
+		prID     string
+		response []byte
+	}
+	records := map[string]record{}
+	created := map[string]bool{}
+	calls := 0
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			calls++
+			if created[prID] {
+				return nil, entity.ErrPRExists
+			}
+			created[prID] = true
+			return &entity.PullRequest{
+				ID:                prID,
+				Title:             title,
+				AuthorID:          authorID,
+				Status:            "OPEN",
+				AssignedReviewers: []entity.User{{ID: "u2"}, {ID: "u3"}},
+			}, nil
+		},
+		getIdempotentResponseFunc: func(key, prID string) ([]byte, error) {
+			rec, ok := records[key]
+			if !ok {
+				return nil, entity.ErrNotFound
+			}
+			if rec.prID != prID {
+				return nil, entity.ErrIdempotencyKeyReused
+			}
+			return rec.response, nil
+		},
+		saveIdempotentResponseFunc: func(key, prID string, response []byte) error {
+			records[key] = record{prID: prID, response: append([]byte(nil), response...)}
+			return nil
+		},
+	}
+	return mock, &calls
+}
+
+func createPRWithKey(handler *Handlers, key, prID string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]interface{}{
+		"pull_request_id":   prID,
+		"pull_request_name": "Add search",
+		"author_id":         "u1",
+	})
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	return w
+}
+
+func TestHandlers_CreatePR_IdempotencyKeyReplay(t *testing.T) {
+	mock, calls := newIdempotentMock()
+	handler := NewHandlers(mock)
+	first := createPRWithKey(handler, "delivery-1", "pr-1001")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", first.Code, first.Body.String())
+	}
+	second := createPRWithKey(handler, "delivery-1", "pr-1001")
+	if second.Code != http.StatusCreated {
+		t.Fatalf("Expected replayed status 201, got %d: %s", second.Code, second.Body.String())
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("Expected replayed body %s, got %s", first.Body.String(), second.Body.String())
+	}
+	if *calls != 1 {
+		t.Errorf("Expected CreatePR to run once, got %d", *calls)
+	}
+}
+
+func TestHandlers_CreatePR_IdempotencySaveFailureLogged(t *testing.T) {
+	mock, _ := newIdempotentMock()
+	mock.saveIdempotentResponseFunc = func(key, prID string, response []byte) error {
+		return fmt.Errorf("connection reset")
+	}
+	var logs bytes.Buffer
+	handler := NewHandlersWithConfig(mock, Config{Logger: slog.New(slog.NewJSONHandler(&logs, nil))})
+	w := createPRWithKey(handler, "delivery-1", "pr-1001")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 despite the save failure, got %d: %s", w.Code, w.Body.String())
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to decode log entry %q: %v", logs.String(), err)
+	}
+	expected := map[string]interface{}{
+		"level":           "ERROR",
+		"idempotency_key": "delivery-1",
+		"pull_request_id": "pr-1001",
+		"error":           "connection reset",
+	}
+	for key, want := range expected {
+		if entry[key] != want {
+			t.Errorf("Expected log %s %v, got %v", key, want, entry[key])
+		}
+	}
+}
+
+func TestHandlers_CreatePR_IdempotencyKeyMismatch(t *testing.T) {
+	mock, _ := newIdempotentMock()
+	handler := NewHandlers(mock)
+	if w := createPRWithKey(handler, "delivery-1", "pr-1001"); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	w := createPRWithKey(handler, "delivery-2", "pr-1001")
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected a different key for the same PR to conflict with 409, got %d", w.Code)
+	}
+	w = createPRWithKey(handler, "delivery-1", "pr-1002")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected reusing a key for another PR to return 422, got %d", w.Code)
+	}
+	var response ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Error.Code != "IDEMPOTENCY_KEY_REUSED" {
+		t.Errorf("Expected IDEMPOTENCY_KEY_REUSED, got %s", response.Error.Code)
+	}
+}
+
+func TestHandlers_CreatedAtInPRResponses(t *testing.T) {
+	createdAt := "2025-10-24T15:34:56.123456+03:00"
+	pr := &entity.PullRequest{
+		ID:                "pr-1001",
+		Title:             "Add search",
+		AuthorID:          "u1",
+		Status:            "OPEN",
+		AssignedReviewers: []entity.User{{ID: "u2"}},
+		CreatedAt:         &createdAt,
+	}
+	mock := &mockService{
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return pr, nil
+		},
+		mergePRFunc: func(prID string) (*entity.PullRequest, error) {
+			return pr, nil
+		},
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return pr, nil
+		},
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			return []entity.PullRequest{*pr}, nil, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	createBody, _ := json.Marshal(map[string]interface{}{"pull_request_id": "pr-1001", "pull_request_name": "Add search", "author_id": "u1"})
+	mergeBody, _ := json.Marshal(map[string]interface{}{"pull_request_id": "pr-1001"})
+	testCases := []struct {
+		name   string
+		req    *http.Request
+		handle func(w http.ResponseWriter, r *http.Request)
+	}{
+		{"create", httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(createBody)), handler.CreatePR},
+		{"merge", httptest.NewRequest("POST", "/pullRequest/merge", bytes.NewReader(mergeBody)), handler.MergePR},
+		{"get", httptest.NewRequest("GET", "/pullRequest/get?pull_request_id=pr-1001", nil), handler.GetPR},
+		{"getReview", httptest.NewRequest("GET", "/users/getReview?user_id=u2", nil), handler.GetUserReviewPRs},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tc.handle(w, tc.req)
+			var response struct {
+				PR struct {
+					CreatedAt *string `json:"created_at"`
+				} `json:"pr"`
+				PullRequests []struct {
+					CreatedAt *string `json:"created_at"`
+				} `json:"pull_requests"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			value := response.PR.CreatedAt
+			if len(response.PullRequests) > 0 {
+				value = response.PullRequests[0].CreatedAt
+			}
+			if value == nil {
+				t.Fatalf("Expected created_at in %s", w.Body.String())
+			}
+			if *value != "2025-10-24T12:34:56Z" {
+				t.Errorf("Expected created_at normalized to RFC3339 UTC, got %s", *value)
+			}
+			if _, err := time.Parse(time.RFC3339, *value); err != nil {
+				t.Errorf("created_at %q is not RFC3339: %v", *value, err)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetPR_NotFound(t *testing.T) {
+	mock := &mockService{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return nil, entity.ErrNotFound
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/pullRequest/get?pull_request_id=missing", nil)
+	w := httptest.NewRecorder()
+	handler.GetPR(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
 }
 
 func TestHandlers_MergePR_Success(t *testing.T) {
-    mock := &mockService{
-        mergePRFunc: func(prID string) (*entity.PullRequest, error) {
-            mergedAt := "2025-10-24T12:34:56Z"
-            return &entity.PullRequest{
-                ID:       prID,
-                Title:    "Add search",
-                AuthorID: "u1",
-                Status:   "MERGED",
-                AssignedReviewers: []entity.User{
-                    {ID: "u2", Username: "Bob", IsActive: true},
-                    {ID: "u3", Username: "Charlie", IsActive: true},
-                },
-                MergedAt: &mergedAt,
-            }, nil
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "pull_request_id": "pr-1001",
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/pullRequest/merge", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.MergePR(w, req)
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-        t.Logf("Response: %s", w.Body.String())
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-    prData, exists := response["pr"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Response must contain 'pr' field")
-    }
-    if prData["pull_request_id"] != "pr-1001" {
-        t.Errorf("Expected pull_request_id 'pr-1001', got %v", prData["pull_request_id"])
-    }
-    if prData["status"] != "MERGED" {
-        t.Errorf("Expected status 'MERGED', got %v", prData["status"])
-    }
-    if prData["mergedAt"] == nil {
-        t.Error("Merged PR should have 'mergedAt' field")
-    }
-    t.Logf("PR merged successfully: %s", w.Body.String())
+	mock := &mockService{
+		mergePRFunc: func(prID string) (*entity.PullRequest, error) {
+			mergedAt := "2025-10-24T12:34:56Z"
+			return &entity.PullRequest{
+				ID:       prID,
+				Title:    "Add search",
+				AuthorID: "u1",
+				Status:   "MERGED",
+				AssignedReviewers: []entity.User{
+					{ID: "u2", Username: "Bob", IsActive: true},
+					{ID: "u3", Username: "Charlie", IsActive: true},
+				},
+				MergedAt: &mergedAt,
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"pull_request_id": "pr-1001",
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pullRequest/merge", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.MergePR(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+		t.Logf("Response: %s", w.Body.String())
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	prData, exists := response["pr"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Response must contain 'pr' field")
+	}
+	if prData["pull_request_id"] != "pr-1001" {
+		t.Errorf("Expected pull_request_id 'pr-1001', got %v", prData["pull_request_id"])
+	}
+	if prData["status"] != "MERGED" {
+		t.Errorf("Expected status 'MERGED', got %v", prData["status"])
+	}
+	if prData["mergedAt"] == nil {
+		t.Error("Merged PR should have 'mergedAt' field")
+	}
+	reviewers, _ := prData["assigned_reviewers"].([]interface{})
+	if len(reviewers) != 2 || reviewers[0] != "u2" || reviewers[1] != "u3" {
+		t.Errorf("Expected assigned_reviewers [u2 u3], got %v", prData["assigned_reviewers"])
+	}
+	t.Logf("PR merged successfully: %s", w.Body.String())
 }
 
 func TestHandlers_MergePR_NotFound(t *testing.T) {
-    mock := &mockService{
-        mergePRFunc: func(prID string) (*entity.PullRequest, error) {
-            return nil, entity.ErrNotFound
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "pull_request_id": "nonexistent-pr",
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/pullRequest/merge", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.MergePR(w, req)
-    if w.Code != http.StatusNotFound {
-        t.Errorf("Expected status 404, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    json.Unmarshal(w.Body.Bytes(), &response)
-    errorData := response["error"].(map[string]interface{})
-    if errorData["code"] != "NOT_FOUND" {
-        t.Errorf("Expected error code 'NOT_FOUND', got %v", errorData["code"])
-    }
-    t.Logf("PR not found error handled correctly")
+	mock := &mockService{
+		mergePRFunc: func(prID string) (*entity.PullRequest, error) {
+			return nil, entity.ErrNotFound
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"pull_request_id": "nonexistent-pr",
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pullRequest/merge", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.MergePR(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	errorData := response["error"].(map[string]interface{})
+	if errorData["code"] != "NOT_FOUND" {
+		t.Errorf("Expected error code 'NOT_FOUND', got %v", errorData["code"])
+	}
+	t.Logf("PR not found error handled correctly")
+}
+
+func TestHandlers_MergePR_InvalidPRID(t *testing.T) {
+	mock := &mockService{
+		mergePRFunc: func(prID string) (*entity.PullRequest, error) {
+			return nil, entity.ErrInvalidPRID
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("POST", "/pullRequest/merge", strings.NewReader(`{"pull_request_id":"pr 1"}`))
+	w := httptest.NewRecorder()
+	handler.MergePR(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	var response ErrorResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	if response.Error.Code != "INVALID_REQUEST" || response.Error.Message != "pull_request_id has an invalid format" {
+		t.Errorf("Unexpected error: %+v", response.Error)
+	}
+}
+
+func TestHandlers_ClosePR_Success(t *testing.T) {
+	mock := &mockService{
+		closePRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:       prID,
+				Title:    "Abandoned idea",
+				AuthorID: "u1",
+				Status:   "CLOSED",
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{"pull_request_id": "pr-1001"})
+	req := httptest.NewRequest("POST", "/pullRequest/close", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ClosePR(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	prData, exists := response["pr"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Response must contain 'pr' field")
+	}
+	if prData["status"] != "CLOSED" {
+		t.Errorf("Expected status 'CLOSED', got %v", prData["status"])
+	}
+}
+
+func TestHandlers_ClosePR_Errors(t *testing.T) {
+	testCases := []struct {
+		err          error
+		expectedCode int
+		errorCode    string
+	}{
+		{entity.ErrNotFound, http.StatusNotFound, "NOT_FOUND"},
+		{entity.ErrPRMerged, http.StatusConflict, "PR_MERGED"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.errorCode, func(t *testing.T) {
+			mock := &mockService{
+				closePRFunc: func(prID string) (*entity.PullRequest, error) {
+					return nil, tc.err
+				},
+			}
+			handler := NewHandlers(mock)
+			body, _ := json.Marshal(map[string]interface{}{"pull_request_id": "pr-1001"})
+			req := httptest.NewRequest("POST", "/pullRequest/close", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.ClosePR(w, req)
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedCode, w.Code)
+			}
+			var response map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			errorData := response["error"].(map[string]interface{})
+			if errorData["code"] != tc.errorCode {
+				t.Errorf("Expected error code '%s', got %v", tc.errorCode, errorData["code"])
+			}
+		})
+	}
+}
+
+func TestHandlers_ReopenPR_Success(t *testing.T) {
+	mock := &mockService{
+		reopenPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:                prID,
+				Title:             "Reverted feature",
+				AuthorID:          "u1",
+				Status:            "OPEN",
+				AssignedReviewers: []entity.User{{ID: "u2"}, {ID: "u3"}},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{"pull_request_id": "pr-1001"})
+	req := httptest.NewRequest("POST", "/pullRequest/reopen", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ReopenPR(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	prData := response["pr"].(map[string]interface{})
+	if prData["status"] != "OPEN" {
+		t.Errorf("Expected status 'OPEN', got %v", prData["status"])
+	}
+	if reviewers := prData["assigned_reviewers"].([]interface{}); len(reviewers) != 2 {
+		t.Errorf("Expected 2 reviewers, got %d", len(reviewers))
+	}
+}
+
+func TestHandlers_ReopenPR_Errors(t *testing.T) {
+	testCases := []struct {
+		err          error
+		expectedCode int
+		errorCode    string
+	}{
+		{entity.ErrNotFound, http.StatusNotFound, "NOT_FOUND"},
+		{entity.ErrPRClosed, http.StatusConflict, "PR_CLOSED"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.errorCode, func(t *testing.T) {
+			mock := &mockService{
+				reopenPRFunc: func(prID string) (*entity.PullRequest, error) {
+					return nil, tc.err
+				},
+			}
+			handler := NewHandlers(mock)
+			body, _ := json.Marshal(map[string]interface{}{"pull_request_id": "pr-1001"})
+			req := httptest.NewRequest("POST", "/pullRequest/reopen", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.ReopenPR(w, req)
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedCode, w.Code)
+			}
+			var response map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			errorData := response["error"].(map[string]interface{})
+			if errorData["code"] != tc.errorCode {
+				t.Errorf("Expected error code '%s', got %v", tc.errorCode, errorData["code"])
+			}
+		})
+	}
+}
+
+func TestHandlers_ReassignReviewer_PRClosed(t *testing.T) {
+	mock := &mockService{
+		reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
+			return nil, "", entity.ErrPRClosed
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{"pull_request_id": "pr-1001", "old_user_id": "u2"})
+	req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ReassignReviewer(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+	}
 }
 
 func TestHandlers_ReassignReviewer_Success(t *testing.T) {
-    mock := &mockService{
-        reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
-            return &entity.PullRequest{
-                ID:       prID,
-                Title:    "Add search",
-                AuthorID: "u1",
-                Status:   "OPEN",
-                AssignedReviewers: []entity.User{
-                    {ID: "u3", Username: "Charlie", IsActive: true},
-                    {ID: "u5", Username: "Eve", IsActive: true},
-                },
-            }, "u5", nil
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "pull_request_id": "pr-1001",
-        "old_user_id":     "u2",
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.ReassignReviewer(w, req)
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-        t.Logf("Response: %s", w.Body.String())
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-    if response["replaced_by"] != "u5" {
-        t.Errorf("Expected replaced_by 'u5', got %v", response["replaced_by"])
-    }
-    prData, exists := response["pr"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Response must contain 'pr' field")
-    }
-    if prData["pull_request_id"] != "pr-1001" {
-        t.Errorf("Expected pull_request_id 'pr-1001', got %v", prData["pull_request_id"])
-    }
-    if prData["status"] != "OPEN" {
-        t.Errorf("Expected status 'OPEN', got %v", prData["status"])
-    }
-    t.Logf("Reviewer reassigned successfully: %s", w.Body.String())
+	mock := &mockService{
+		reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
+			return &entity.PullRequest{
+				ID:       prID,
+				Title:    "Add search",
+				AuthorID: "u1",
+				Status:   "OPEN",
+				AssignedReviewers: []entity.User{
+					{ID: "u3", Username: "Charlie", IsActive: true},
+					{ID: "u5", Username: "Eve", IsActive: true},
+				},
+			}, "u5", nil
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"pull_request_id": "pr-1001",
+		"old_user_id":     "u2",
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ReassignReviewer(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+		t.Logf("Response: %s", w.Body.String())
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response["replaced_by"] != "u5" {
+		t.Errorf("Expected replaced_by 'u5', got %v", response["replaced_by"])
+	}
+	prData, exists := response["pr"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Response must contain 'pr' field")
+	}
+	if prData["pull_request_id"] != "pr-1001" {
+		t.Errorf("Expected pull_request_id 'pr-1001', got %v", prData["pull_request_id"])
+	}
+	if prData["status"] != "OPEN" {
+		t.Errorf("Expected status 'OPEN', got %v", prData["status"])
+	}
+	t.Logf("Reviewer reassigned successfully: %s", w.Body.String())
+}
+
+func TestHandlers_ReassignReviewer_EchoesReplacedReviewer(t *testing.T) {
+	mock := &mockService{
+		reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
+			return &entity.PullRequest{
+				ID:                prID,
+				Status:            "OPEN",
+				AssignedReviewers: []entity.User{{ID: "u5", Username: "Eve", IsActive: true}},
+				Reassignment: &entity.Reassignment{
+					PRID:        prID,
+					OldUserID:   oldUserID,
+					NewUserID:   "u5",
+					OldUsername: "Bob",
+					NewUsername: "Eve",
+				},
+			}, "u5", nil
+		},
+	}
+	handler := NewHandlers(mock)
+	body := `{"pull_request_id":"pr-1001","old_user_id":"u2"}`
+	req := httptest.NewRequest("POST", "/pullRequest/reassign", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ReassignReviewer(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		Replaced           string `json:"replaced"`
+		ReplacedUsername   string `json:"replaced_username"`
+		ReplacedBy         string `json:"replaced_by"`
+		ReplacedByUsername string `json:"replaced_by_username"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Replaced != "u2" || response.ReplacedBy != "u5" {
+		t.Errorf("Expected replaced u2 and replaced_by u5, got %s", w.Body.String())
+	}
+	if response.ReplacedUsername != "Bob" || response.ReplacedByUsername != "Eve" {
+		t.Errorf("Expected usernames Bob and Eve, got %s", w.Body.String())
+	}
 }
 
 func TestHandlers_ReassignReviewer_PRNotFound(t *testing.T) {
-    mock := &mockService{
-        reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
-            return nil, "", entity.ErrNotFound
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "pull_request_id": "nonexistent-pr",
-        "old_user_id":     "u2",
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.ReassignReviewer(w, req)
-    if w.Code != http.StatusNotFound {
-        t.Errorf("Expected status 404, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    json.Unmarshal(w.Body.Bytes(), &response)
-    errorData := response["error"].(map[string]interface{})
-    if errorData["code"] != "NOT_FOUND" {
-        t.Errorf("Expected error code 'NOT_FOUND', got %v", errorData["code"])
-    }
-    t.Logf("PR not found error handled correctly")
+	mock := &mockService{
+		reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
+			return nil, "", entity.ErrNotFound
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"pull_request_id": "nonexistent-pr",
+		"old_user_id":     "u2",
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ReassignReviewer(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	errorData := response["error"].(map[string]interface{})
+	if errorData["code"] != "NOT_FOUND" {
+		t.Errorf("Expected error code 'NOT_FOUND', got %v", errorData["code"])
+	}
+	t.Logf("PR not found error handled correctly")
 }
 
 func TestHandlers_ReassignReviewer_PRAlreadyMerged(t *testing.T) {
-    mock := &mockService{
-        reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
-            return nil, "", entity.ErrPRMerged
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "pull_request_id": "pr-1001",
-        "old_user_id":     "u2",
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.ReassignReviewer(w, req)
-    if w.Code != http.StatusConflict {
-        t.Errorf("Expected status 409, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    json.Unmarshal(w.Body.Bytes(), &response)
-    errorData := response["error"].(map[string]interface{})
-    if errorData["code"] != "PR_MERGED" {
-        t.Errorf("Expected error code 'PR_MERGED', got %v", errorData["code"])
-    }
-    t.Logf("PR merged error handled correctly")
+	mock := &mockService{
+		reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
+			return nil, "", entity.ErrPRMerged
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"pull_request_id": "pr-1001",
+		"old_user_id":     "u2",
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ReassignReviewer(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	errorData := response["error"].(map[string]interface{})
+	if errorData["code"] != "PR_MERGED" {
+		t.Errorf("Expected error code 'PR_MERGED', got %v", errorData["code"])
+	}
+	t.Logf("PR merged error handled correctly")
 }
 
 func TestHandlers_ReassignReviewer_ReviewerNotAssigned(t *testing.T) {
-    mock := &mockService{
-        reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
-            return nil, "", entity.ErrNotAssigned
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "pull_request_id": "pr-1001",
-        "old_user_id":     "u9",
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.ReassignReviewer(w, req)
-    if w.Code != http.StatusConflict {
-        t.Errorf("Expected status 409, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    json.Unmarshal(w.Body.Bytes(), &response)
-    errorData := response["error"].(map[string]interface{})
-    if errorData["code"] != "NOT_ASSIGNED" {
-        t.Errorf("Expected error code 'NOT_ASSIGNED', got %v", errorData["code"])
-    }
-    t.Logf("Reviewer not assigned error handled correctly")
+	mock := &mockService{
+		reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
+			return nil, "", entity.ErrNotAssigned
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"pull_request_id": "pr-1001",
+		"old_user_id":     "u9",
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ReassignReviewer(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	errorData := response["error"].(map[string]interface{})
+	if errorData["code"] != "NOT_ASSIGNED" {
+		t.Errorf("Expected error code 'NOT_ASSIGNED', got %v", errorData["code"])
+	}
+	t.Logf("Reviewer not assigned error handled correctly")
 }
 
 func TestHandlers_ReassignReviewer_NoCandidate(t *testing.T) {
-    mock := &mockService{
-        reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
-            return nil, "", entity.ErrNoCandidate
-        },
-    }
-    handler := NewHandlers(mock)
-    requestBody := map[string]interface{}{
-        "pull_request_id": "pr-1001",
-        "old_user_id":     "u2",
-    }
-    body, _ := json.Marshal(requestBody)
-    req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
-    w := httptest.NewRecorder()
-    handler.ReassignReviewer(w, req)
-    if w.Code != http.StatusConflict {
-        t.Errorf("Expected status 409, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    json.Unmarshal(w.Body.Bytes(), &response)
-    errorData := response["error"].(map[string]interface{})
-    if errorData["code"] != "NO_CANDIDATE" {
-        t.Errorf("Expected error code 'NO_CANDIDATE', got %v", errorData["code"])
-    }
-    t.Logf("No candidate error handled correctly")
+	mock := &mockService{
+		reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
+			return nil, "", entity.ErrNoCandidate
+		},
+	}
+	handler := NewHandlers(mock)
+	requestBody := map[string]interface{}{
+		"pull_request_id": "pr-1001",
+		"old_user_id":     "u2",
+	}
+	body, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ReassignReviewer(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	errorData := response["error"].(map[string]interface{})
+	if errorData["code"] != "NO_CANDIDATE" {
+		t.Errorf("Expected error code 'NO_CANDIDATE', got %v", errorData["code"])
+	}
+	t.Logf("No candidate error handled correctly")
+}
+
+func TestHandlers_ReassignReviewer_AuthorNoTeam(t *testing.T) {
+	mock := &mockService{
+		reassignReviewerFunc: func(prID, oldUserID string) (*entity.PullRequest, string, error) {
+			return nil, "", entity.ErrAuthorNoTeam
+		},
+	}
+	handler := NewHandlers(mock)
+	body, _ := json.Marshal(map[string]interface{}{"pull_request_id": "pr-1001", "old_user_id": "u2"})
+	req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ReassignReviewer(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	errorData := response["error"].(map[string]interface{})
+	if errorData["code"] != "AUTHOR_NO_TEAM" {
+		t.Errorf("Expected error code 'AUTHOR_NO_TEAM', got %v", errorData["code"])
+	}
+}
+
+func TestHandlers_PreviewReassign_Success(t *testing.T) {
+	mock := &mockService{
+		previewReassignFunc: func(prID, oldUserID string) (string, error) {
+			return "u5", nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/pullRequest/previewReassign?pull_request_id=pr-1001&old_user_id=u2", nil)
+	w := httptest.NewRecorder()
+	handler.PreviewReassign(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response["replaced_by"] != "u5" {
+		t.Errorf("Expected replaced_by 'u5', got %v", response["replaced_by"])
+	}
+}
+
+func TestHandlers_PreviewReassign_NoCandidate(t *testing.T) {
+	mock := &mockService{
+		previewReassignFunc: func(prID, oldUserID string) (string, error) {
+			return "", entity.ErrNoCandidate
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/pullRequest/previewReassign?pull_request_id=pr-1001&old_user_id=u2", nil)
+	w := httptest.NewRecorder()
+	handler.PreviewReassign(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+	}
+}
+
+func TestHandlers_PreviewReviewers_MatchesCreatePR(t *testing.T) {
+	pick := []entity.CandidateReviewer{{UserID: "u2", Load: 0}, {UserID: "u3", Load: 2}}
+	mock := &mockService{
+		previewReviewersFunc: func(authorID string, count int) ([]entity.CandidateReviewer, error) {
+			if authorID != "u1" || count != 2 {
+				t.Errorf("Unexpected preview call: %s, %d", authorID, count)
+			}
+			return pick, nil
+		},
+		createPRFunc: func(prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:                prID,
+				Title:             title,
+				AuthorID:          authorID,
+				Status:            "OPEN",
+				AssignedReviewers: []entity.User{{ID: "u2"}, {ID: "u3"}},
+				ReviewerLoads:     pick,
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/pullRequest/previewReviewers?author_id=u1&count=2", nil)
+	w := httptest.NewRecorder()
+	handler.PreviewReviewers(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var preview struct {
+		ReviewerAssignments []entity.CandidateReviewer `json:"reviewer_assignments"`
+	}
+	json.NewDecoder(w.Body).Decode(&preview)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"pull_request_id":   "pr-1001",
+		"pull_request_name": "Add search",
+		"author_id":         "u1",
+	})
+	req = httptest.NewRequest("POST", "/pullRequest/create", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	handler.CreatePR(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+	var created struct {
+		PR struct {
+			ReviewerAssignments []entity.CandidateReviewer `json:"reviewer_assignments"`
+		} `json:"pr"`
+	}
+	json.NewDecoder(w.Body).Decode(&created)
+	if !reflect.DeepEqual(preview.ReviewerAssignments, created.PR.ReviewerAssignments) {
+		t.Errorf("Preview %v does not match CreatePR %v", preview.ReviewerAssignments, created.PR.ReviewerAssignments)
+	}
+}
+
+func TestHandlers_PreviewReviewers_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		err      error
+		wantCode int
+		wantErr  string
+	}{
+		{"MissingAuthor", "", nil, http.StatusBadRequest, "INVALID_REQUEST"},
+		{"InvalidCount", "author_id=u1&count=0", nil, http.StatusBadRequest, "INVALID_REQUEST"},
+		{"UnknownAuthor", "author_id=ghost", entity.ErrNotFound, http.StatusNotFound, "NOT_FOUND"},
+		{"NoCandidate", "author_id=u1", entity.ErrNoCandidate, http.StatusNotFound, "NO_CANDIDATE"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &mockService{
+				previewReviewersFunc: func(authorID string, count int) ([]entity.CandidateReviewer, error) {
+					return nil, tc.err
+				},
+			}
+			handler := NewHandlers(mock)
+			req := httptest.NewRequest("GET", "/pullRequest/previewReviewers?"+tc.query, nil)
+			w := httptest.NewRecorder()
+			handler.PreviewReviewers(w, req)
+			if w.Code != tc.wantCode {
+				t.Fatalf("Expected status %d, got %d", tc.wantCode, w.Code)
+			}
+			var response ErrorResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if response.Error.Code != tc.wantErr {
+				t.Errorf("Expected code %s, got %s", tc.wantErr, response.Error.Code)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetPRTimeline(t *testing.T) {
+	createdAt, reassignedAt, mergedAt := "2024-01-01T10:00:00.5Z", "2024-01-01T11:00:00Z", "2024-01-02T09:00:00Z"
+	mock := &mockService{
+		getPRTimelineFunc: func(prID string) ([]entity.TimelineEvent, error) {
+			if prID != "pr-1001" {
+				return nil, entity.ErrNotFound
+			}
+			return []entity.TimelineEvent{
+				{Type: entity.TimelineCreated, At: &createdAt},
+				{Type: entity.TimelineReassigned, At: &reassignedAt, OldUserID: "u2", NewUserID: "u5"},
+				{Type: entity.TimelineMerged, At: &mergedAt},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	w := httptest.NewRecorder()
+	handler.GetPRTimeline(w, httptest.NewRequest("GET", "/pullRequest/timeline?pull_request_id=pr-1001", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	want := `{"pull_request_id":"pr-1001","timeline":[` +
+		`{"type":"created","at":"2024-01-01T10:00:00Z"},` +
+		`{"type":"reassigned","at":"2024-01-01T11:00:00Z","old_user_id":"u2","replaced_by":"u5"},` +
+		`{"type":"merged","at":"2024-01-02T09:00:00Z"}]}`
+	if got := strings.TrimSpace(w.Body.String()); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+	w = httptest.NewRecorder()
+	handler.GetPRTimeline(w, httptest.NewRequest("GET", "/pullRequest/timeline?pull_request_id=pr-missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestHandlers_GetReassignmentHistory(t *testing.T) {
+	reassignedAt := "2024-01-01T10:00:00.5Z"
+	mock := &mockService{
+		getReassignmentHistoryFunc: func(prID string) ([]entity.Reassignment, error) {
+			if prID != "pr-1001" {
+				return nil, entity.ErrNotFound
+			}
+			return []entity.Reassignment{{PRID: prID, OldUserID: "u2", NewUserID: "u5", ReassignedAt: &reassignedAt}}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/pullRequest/history?pull_request_id=pr-1001", nil)
+	w := httptest.NewRecorder()
+	handler.GetReassignmentHistory(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		PullRequestID string `json:"pull_request_id"`
+		History       []struct {
+			OldUserID    string `json:"old_user_id"`
+			NewUserID    string `json:"replaced_by"`
+			ReassignedAt string `json:"reassigned_at"`
+		} `json:"history"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.PullRequestID != "pr-1001" || len(response.History) != 1 {
+		t.Fatalf("Unexpected response: %s", w.Body.String())
+	}
+	entry := response.History[0]
+	if entry.OldUserID != "u2" || entry.NewUserID != "u5" || entry.ReassignedAt != "2024-01-01T10:00:00Z" {
+		t.Errorf("Unexpected history entry: %+v", entry)
+	}
+
+	req = httptest.NewRequest("GET", "/pullRequest/history?pull_request_id=pr-missing", nil)
+	w = httptest.NewRecorder()
+	handler.GetReassignmentHistory(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+	req = httptest.NewRequest("GET", "/pullRequest/history", nil)
+	w = httptest.NewRecorder()
+	handler.GetReassignmentHistory(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
 }
 
 func TestHandlers_GetUserReviewPRs_Success(t *testing.T) {
-    mock := &mockService{
-        getUserReviewPRsFunc: func(userID string) ([]entity.PullRequest, error) {
-            return []entity.PullRequest{}, nil
-        },
-    }
-    handler := NewHandlers(mock)
-    req := httptest.NewRequest("GET", "/users/getReview?user_id=u2", nil)
-    w := httptest.NewRecorder()
-    handler.GetUserReviewPRs(w, req)
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-        t.Logf("Response: %s", w.Body.String())
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-    if response["user_id"] != "u2" {
-        t.Errorf("Expected user_id 'u2', got %v", response["user_id"])
-    }
-    prsData, exists := response["pull_requests"].([]interface{})
-    if !exists {
-        t.Fatal("Response must contain 'pull_requests' field")
-    }
-    if len(prsData) != 0 {
-        t.Errorf("Expected 0 pull requests for new user, got %d", len(prsData))
-    }
-    t.Logf("User u2 has no PRs for review - correct behavior")
-    t.Logf("Response: %s", w.Body.String())
+	mock := &mockService{
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			return []entity.PullRequest{}, nil, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/users/getReview?user_id=u2", nil)
+	w := httptest.NewRecorder()
+	handler.GetUserReviewPRs(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+		t.Logf("Response: %s", w.Body.String())
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response["user_id"] != "u2" {
+		t.Errorf("Expected user_id 'u2', got %v", response["user_id"])
+	}
+	prsData, exists := response["pull_requests"].([]interface{})
+	if !exists {
+		t.Fatal("Response must contain 'pull_requests' field")
+	}
+	if len(prsData) != 0 {
+		t.Errorf("Expected 0 pull requests for new user, got %d", len(prsData))
+	}
+	t.Logf("User u2 has no PRs for review - correct behavior")
+	t.Logf("Response: %s", w.Body.String())
+}
+
+func TestHandlers_GetUserReviewPRs_JSONBody(t *testing.T) {
+	var requested string
+	mock := &mockService{
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			requested = userID
+			return []entity.PullRequest{}, nil, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("POST", "/users/getReview", strings.NewReader(`{"user_id":"u2"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+	handler.GetUserReviewPRs(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if requested != "u2" {
+		t.Errorf("Expected user_id u2 from the body, got %q", requested)
+	}
+	req = httptest.NewRequest("POST", "/users/getReview?user_id=u3", strings.NewReader(`{"user_id":"u2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	handler.GetUserReviewPRs(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 when both sources are set, got %d", w.Code)
+	}
+	req = httptest.NewRequest("POST", "/users/getReview", strings.NewReader(`{"userid":"u2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	handler.GetUserReviewPRs(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"fields":{"userid"`) {
+		t.Errorf("Expected a misspelled field to be rejected by name, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandlers_GetUserReviewPRs_StatusFilter(t *testing.T) {
+	prs := []entity.PullRequest{
+		{ID: "pr-1", Title: "Feature A", AuthorID: "u1", Status: "OPEN"},
+		{ID: "pr-2", Title: "Feature B", AuthorID: "u1", Status: "MERGED"},
+		{ID: "pr-3", Title: "Feature C", AuthorID: "u3", Status: "OPEN"},
+	}
+	var gotStatus string
+	mock := &mockService{
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			gotStatus = status
+			var filtered []entity.PullRequest
+			for _, pr := range prs {
+				if status == "" || pr.Status == status {
+					filtered = append(filtered, pr)
+				}
+			}
+			return filtered, nil, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/users/getReview?user_id=u2&status=OPEN", nil)
+	w := httptest.NewRecorder()
+	handler.GetUserReviewPRs(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if gotStatus != "OPEN" {
+		t.Errorf("Expected status filter OPEN to reach the service, got %q", gotStatus)
+	}
+	var response struct {
+		PullRequests []struct {
+			PullRequestID string `json:"pull_request_id"`
+			Status        string `json:"status"`
+		} `json:"pull_requests"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.PullRequests) != 2 {
+		t.Fatalf("Expected 2 open PRs, got %d", len(response.PullRequests))
+	}
+	for _, pr := range response.PullRequests {
+		if pr.Status != "OPEN" {
+			t.Errorf("Expected only OPEN PRs, got %s with status %s", pr.PullRequestID, pr.Status)
+		}
+	}
+}
+
+func TestHandlers_GetUserReviewPRs_Cursor(t *testing.T) {
+	var pages []entity.ReviewPage
+	createdAt := "2024-01-01T10:00:00.123456Z"
+	mock := &mockService{
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			pages = append(pages, page)
+			if page.After == nil {
+				return []entity.PullRequest{{ID: "pr|2", Status: "OPEN", CreatedAt: &createdAt}}, &entity.ReviewCursor{CreatedAt: createdAt, PRID: "pr|2"}, nil
+			}
+			return []entity.PullRequest{{ID: "pr-1", Status: "OPEN"}}, nil, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/users/getReview?user_id=u2&limit=1", nil)
+	w := httptest.NewRecorder()
+	handler.GetUserReviewPRs(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.NextCursor == "" {
+		t.Fatal("Expected next_cursor on a full page")
+	}
+	req = httptest.NewRequest("GET", "/users/getReview?user_id=u2&limit=1&cursor="+response.NextCursor, nil)
+	w = httptest.NewRecorder()
+	handler.GetUserReviewPRs(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "next_cursor") {
+		t.Errorf("Expected no next_cursor on the last page, got %s", w.Body.String())
+	}
+	if len(pages) != 2 || pages[0].Limit != 1 || pages[1].After == nil {
+		t.Fatalf("Expected two paged calls, got %v", pages)
+	}
+	if *pages[1].After != (entity.ReviewCursor{CreatedAt: createdAt, PRID: "pr|2"}) {
+		t.Errorf("Expected the cursor to round-trip, got %v", *pages[1].After)
+	}
+}
+
+func TestHandlers_GetUserReviewPRs_InvalidPagination(t *testing.T) {
+	handler := NewHandlers(&mockService{})
+	for _, query := range []string{"limit=0", "limit=abc", "cursor=not-a-cursor", "cursor=" + base64.RawURLEncoding.EncodeToString([]byte("yesterday|pr-1"))} {
+		req := httptest.NewRequest("GET", "/users/getReview?user_id=u2&"+query, nil)
+		w := httptest.NewRecorder()
+		handler.GetUserReviewPRs(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestHandlers_GetUserReviewPRs_InvalidStatus(t *testing.T) {
+	handler := NewHandlers(&mockService{})
+	req := httptest.NewRequest("GET", "/users/getReview?user_id=u2&status=open", nil)
+	w := httptest.NewRecorder()
+	handler.GetUserReviewPRs(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHandlers_GetUserReviewPRs_IncludeReviewers(t *testing.T) {
+	calls := 0
+	mock := &mockService{
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			return []entity.PullRequest{
+				{ID: "pr-1", Title: "Feature A", AuthorID: "u1", Status: "OPEN"},
+				{ID: "pr-2", Title: "Feature B", AuthorID: "u3", Status: "OPEN"},
+			}, nil, nil
+		},
+		getReviewersForPRsFunc: func(prIDs []string) (map[string][]entity.User, error) {
+			calls++
+			if len(prIDs) != 2 || prIDs[0] != "pr-1" || prIDs[1] != "pr-2" {
+				t.Errorf("Expected lookup for [pr-1 pr-2], got %v", prIDs)
+			}
+			return map[string][]entity.User{
+				"pr-1": {
+					{ID: "u2", Username: "Bob", IsActive: true},
+					{ID: "u4", Username: "Dave", IsActive: true},
+				},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/users/getReview?user_id=u2&include_reviewers=true", nil)
+	w := httptest.NewRecorder()
+	handler.GetUserReviewPRs(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single batched reviewer lookup, got %d", calls)
+	}
+	var response struct {
+		PullRequests []struct {
+			PullRequestID     string        `json:"pull_request_id"`
+			AssignedReviewers []entity.User `json:"assigned_reviewers"`
+		} `json:"pull_requests"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.PullRequests) != 2 {
+		t.Fatalf("Expected 2 pull requests, got %d", len(response.PullRequests))
+	}
+	if len(response.PullRequests[0].AssignedReviewers) != 2 {
+		t.Errorf("Expected 2 reviewers on pr-1, got %d", len(response.PullRequests[0].AssignedReviewers))
+	}
+	if response.PullRequests[1].AssignedReviewers == nil || len(response.PullRequests[1].AssignedReviewers) != 0 {
+		t.Errorf("Expected empty reviewers list on pr-2, got %v", response.PullRequests[1].AssignedReviewers)
+	}
+}
+
+func TestHandlers_GetUserReviewPRs_WithoutReviewersByDefault(t *testing.T) {
+	mock := &mockService{
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			return []entity.PullRequest{{ID: "pr-1", Title: "Feature A", AuthorID: "u1", Status: "OPEN"}}, nil, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/users/getReview?user_id=u2", nil)
+	w := httptest.NewRecorder()
+	handler.GetUserReviewPRs(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "assigned_reviewers") {
+		t.Errorf("Expected no assigned_reviewers without include_reviewers, got %s", w.Body.String())
+	}
 }
 
 func TestHandlers_GetStats_Success(t *testing.T) {
-    mockStats := &entity.Stats{
-        TotalAssignments: 150,
-        UserAssignmentCounts: []entity.UserAssignmentCount{
-            {
-                UserID:   "u123",
-                Username: "alice",
-                Count:    45,
-            },
-            {
-                UserID:   "u456",
-                Username: "bob",
-                Count:    38,
-            },
-            {
-                UserID:   "u789",
-                Username: "charlie",
-                Count:    27,
-            },
-        },
-        PRAssignmentCounts: []entity.PRAssignmentCount{
-            {
-                PRID:  "pr-1001",
-                Title: "Add payment feature",
-                Count: 8,
-            },
-            {
-                PRID:  "pr-1002",
-                Title: "Fix authentication bug",
-                Count: 6,
-            },
-            {
-                PRID:  "pr-1003",
-                Title: "Update database schema",
-                Count: 5,
-            },
-        },
-    }
-    mock := &mockService{
-        getStatsFunc: func() (*entity.Stats, error) {
-            return mockStats, nil
-        },
-    }
-    handler := NewHandlers(mock)
-    req := httptest.NewRequest("GET", "/stats", nil)
-    w := httptest.NewRecorder()
-    handler.GetStats(w, req)
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-        t.Logf("Response: %s", w.Body.String())
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-    statsData, exists := response["stats"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Response must contain 'stats' field")
-    }
-    if statsData["total_assignments"] != float64(150) {
-        t.Errorf("Expected total_assignments 150, got %v", statsData["total_assignments"])
-    }
-    usersData, exists := statsData["user_assignment_counts"].([]interface{})
-    if !exists {
-        t.Fatal("Stats must contain 'user_assignment_counts' field")
-    }
-    if len(usersData) != 3 {
-        t.Errorf("Expected 3 user assignment counts, got %d", len(usersData))
-    }
-    if len(usersData) > 0 {
-        user1 := usersData[0].(map[string]interface{})
-        if user1["user_id"] != "u123" {
-            t.Errorf("Expected first user_id 'u123', got %v", user1["user_id"])
-        }
-        if user1["username"] != "alice" {
-            t.Errorf("Expected first username 'alice', got %v", user1["username"])
-        }
-        if user1["count"] != float64(45) {
-            t.Errorf("Expected first user count 45, got %v", user1["count"])
-        }
-    }
-    prsData, exists := statsData["pr_assignment_counts"].([]interface{})
-    if !exists {
-        t.Fatal("Stats must contain 'pr_assignment_counts' field")
-    }
-
-    if len(prsData) != 3 {
-        t.Errorf("Expected 3 PR assignment counts, got %d", len(prsData))
-    }
-    if len(prsData) > 0 {
-        pr1 := prsData[0].(map[string]interface{})
-        if pr1["pull_request_id"] != "pr-1001" {
-            t.Errorf("Expected first PR ID 'pr-1001', got %v", pr1["pull_request_id"])
-        }
-        if pr1["pull_request_name"] != "Add payment feature" {
-            t.Errorf("Expected first PR title 'Add payment feature', got %v", pr1["pull_request_name"])
-        }
-        if pr1["count"] != float64(8) {
-            t.Errorf("Expected first PR count 8, got %v", pr1["count"])
-        }
-    }
-    t.Logf("Stats retrieved successfully: %s", w.Body.String())
+	mockStats := &entity.Stats{
+		TotalAssignments:          150,
+		AverageTimeToMergeSeconds: 5400.5,
+		UserAssignmentCounts: []entity.UserAssignmentCount{
+			{
+				UserID:   "u123",
+				Username: "alice",
+				Count:    45,
+			},
+			{
+				UserID:   "u456",
+				Username: "bob",
+				Count:    38,
+			},
+			{
+				UserID:   "u789",
+				Username: "charlie",
+				Count:    27,
+			},
+		},
+		PRAssignmentCounts: []entity.PRAssignmentCount{
+			{
+				PRID:  "pr-1001",
+				Title: "Add payment feature",
+				Count: 8,
+			},
+			{
+				PRID:  "pr-1002",
+				Title: "Fix authentication bug",
+				Count: 6,
+			},
+			{
+				PRID:  "pr-1003",
+				Title: "Update database schema",
+				Count: 5,
+			},
+		},
+	}
+	mock := &mockService{
+		getStatsFunc: func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+			return mockStats, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats", nil)
+	w := httptest.NewRecorder()
+	handler.GetStats(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+		t.Logf("Response: %s", w.Body.String())
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	statsData, exists := response["stats"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Response must contain 'stats' field")
+	}
+	if statsData["total_assignments"] != float64(150) {
+		t.Errorf("Expected total_assignments 150, got %v", statsData["total_assignments"])
+	}
+	if statsData["average_time_to_merge_seconds"] != 5400.5 {
+		t.Errorf("Expected average_time_to_merge_seconds 5400.5, got %v", statsData["average_time_to_merge_seconds"])
+	}
+	usersData, exists := statsData["user_assignment_counts"].([]interface{})
+	if !exists {
+		t.Fatal("Stats must contain 'user_assignment_counts' field")
+	}
+	if len(usersData) != 3 {
+		t.Errorf("Expected 3 user assignment counts, got %d", len(usersData))
+	}
+	if len(usersData) > 0 {
+		user1 := usersData[0].(map[string]interface{})
+		if user1["user_id"] != "u123" {
+			t.Errorf("Expected first user_id 'u123', got %v", user1["user_id"])
+		}
+		if user1["username"] != "alice" {
+			t.Errorf("Expected first username 'alice', got %v", user1["username"])
+		}
+		if user1["count"] != float64(45) {
+			t.Errorf("Expected first user count 45, got %v", user1["count"])
+		}
+	}
+	prsData, exists := statsData["pr_assignment_counts"].([]interface{})
+	if !exists {
+		t.Fatal("Stats must contain 'pr_assignment_counts' field")
+	}
+
+	if len(prsData) != 3 {
+		t.Errorf("Expected 3 PR assignment counts, got %d", len(prsData))
+	}
+	if len(prsData) > 0 {
+		pr1 := prsData[0].(map[string]interface{})
+		if pr1["pull_request_id"] != "pr-1001" {
+			t.Errorf("Expected first PR ID 'pr-1001', got %v", pr1["pull_request_id"])
+		}
+		if pr1["pull_request_name"] != "Add payment feature" {
+			t.Errorf("Expected first PR title 'Add payment feature', got %v", pr1["pull_request_name"])
+		}
+		if pr1["count"] != float64(8) {
+			t.Errorf("Expected first PR count 8, got %v", pr1["count"])
+		}
+	}
+	t.Logf("Stats retrieved successfully: %s", w.Body.String())
 }
 
 func TestHandlers_GetStats_EmptyData(t *testing.T) {
-    mockStats := &entity.Stats{
-        TotalAssignments:     0,
-        UserAssignmentCounts: []entity.UserAssignmentCount{},
-        PRAssignmentCounts:   []entity.PRAssignmentCount{},
-    }
-    mock := &mockService{
-        getStatsFunc: func() (*entity.Stats, error) {
-            return mockStats, nil
-        },
-    }
-    handler := NewHandlers(mock)
-    req := httptest.NewRequest("GET", "/stats", nil)
-    w := httptest.NewRecorder()
-    handler.GetStats(w, req)
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-    statsData, exists := response["stats"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Response must contain 'stats' field")
-    }
-    if statsData["total_assignments"] != float64(0) {
-        t.Errorf("Expected total_assignments 0, got %v", statsData["total_assignments"])
-    }
-    usersData, exists := statsData["user_assignment_counts"].([]interface{})
-    if !exists {
-        t.Fatal("Stats must contain 'user_assignment_counts' field")
-    }
-    if len(usersData) != 0 {
-        t.Errorf("Expected 0 user assignment counts, got %d", len(usersData))
-    }
-    prsData, exists := statsData["pr_assignment_counts"].([]interface{})
-    if !exists {
-        t.Fatal("Stats must contain 'pr_assignment_counts' field")
-    }
-    if len(prsData) != 0 {
-        t.Errorf("Expected 0 PR assignment counts, got %d", len(prsData))
-    }
-    t.Logf("Empty stats handled correctly: %s", w.Body.String())
+	mockStats := &entity.Stats{
+		TotalAssignments:     0,
+		UserAssignmentCounts: []entity.UserAssignmentCount{},
+		PRAssignmentCounts:   []entity.PRAssignmentCount{},
+	}
+	mock := &mockService{
+		getStatsFunc: func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+			return mockStats, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats", nil)
+	w := httptest.NewRecorder()
+	handler.GetStats(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	statsData, exists := response["stats"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Response must contain 'stats' field")
+	}
+	if statsData["total_assignments"] != float64(0) {
+		t.Errorf("Expected total_assignments 0, got %v", statsData["total_assignments"])
+	}
+	usersData, exists := statsData["user_assignment_counts"].([]interface{})
+	if !exists {
+		t.Fatal("Stats must contain 'user_assignment_counts' field")
+	}
+	if len(usersData) != 0 {
+		t.Errorf("Expected 0 user assignment counts, got %d", len(usersData))
+	}
+	prsData, exists := statsData["pr_assignment_counts"].([]interface{})
+	if !exists {
+		t.Fatal("Stats must contain 'pr_assignment_counts' field")
+	}
+	if len(prsData) != 0 {
+		t.Errorf("Expected 0 PR assignment counts, got %d", len(prsData))
+	}
+	t.Logf("Empty stats handled correctly: %s", w.Body.String())
+}
+
+func TestHandlers_GetStats_ETag(t *testing.T) {
+	stats := &entity.Stats{
+		TotalAssignments:     2,
+		UserAssignmentCounts: []entity.UserAssignmentCount{{UserID: "u1", Username: "Alice", Count: 2}},
+		PRAssignmentCounts:   []entity.PRAssignmentCount{},
+	}
+	mock := &mockService{
+		getStatsFunc: func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+			return stats, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	w := httptest.NewRecorder()
+	handler.GetStats(w, httptest.NewRequest("GET", "/stats", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("Expected 200 with a weak ETag, got %d and %q", w.Code, etag)
+	}
+	req := httptest.NewRequest("GET", "/stats", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	handler.GetStats(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304 for unchanged stats, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected an empty body on 304, got %q", w.Body.String())
+	}
+	stats.TotalAssignments = 3
+	w = httptest.NewRecorder()
+	handler.GetStats(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("Expected 200 with a new ETag after the stats changed, got %d and %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestHandlers_GetStats_CSV(t *testing.T) {
+	mock := &mockService{
+		getStatsFunc: func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+			return &entity.Stats{
+				UserAssignmentCounts: []entity.UserAssignmentCount{{UserID: "u1", Username: "Smith, Alice", Count: 2}},
+				PRAssignmentCounts:   []entity.PRAssignmentCount{{PRID: "pr-1", Title: "Fix login", Count: 1}},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		name     string
+		query    string
+		expected string
+		filename string
+	}{
+		{"Users", "?format=csv", "user_id,username,count\nu1,\"Smith, Alice\",2\n", "stats-users.csv"},
+		{"PRs", "?format=csv&target=prs", "pull_request_id,pull_request_name,count\npr-1,Fix login,1\n", "stats-prs.csv"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.GetStats(w, httptest.NewRequest("GET", "/stats"+tc.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/csv") {
+				t.Errorf("Expected text/csv, got %q", got)
+			}
+			if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="`+tc.filename+`"` {
+				t.Errorf("Unexpected Content-Disposition %q", got)
+			}
+			if w.Body.String() != tc.expected {
+				t.Errorf("Expected body %q, got %q", tc.expected, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_GetStats_InvalidFormat(t *testing.T) {
+	handler := NewHandlers(&mockService{})
+	for _, query := range []string{"?format=xml", "?format=csv&target=teams"} {
+		w := httptest.NewRecorder()
+		handler.GetStats(w, httptest.NewRequest("GET", "/stats"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+		var response ErrorResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		if response.Error.Code != "INVALID_REQUEST" {
+			t.Errorf("%s: expected INVALID_REQUEST, got %q", query, response.Error.Code)
+		}
+	}
 }
 
 func TestHandlers_GetStats_ServiceError(t *testing.T) {
-    mock := &mockService{
-        getStatsFunc: func() (*entity.Stats, error) {
-            return nil, entity.ErrNotFound
-        },
-    }
-    handler := NewHandlers(mock)
-    req := httptest.NewRequest("GET", "/stats", nil)
-    w := httptest.NewRecorder()
-    handler.GetStats(w, req)
-    if w.Code != http.StatusInternalServerError {
-        t.Errorf("Expected status 500, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse error response: %v", err)
-    }
-    errorData, exists := response["error"].(map[string]interface{})
-    if !exists {
-        t.Fatal("Error response must contain 'error' field")
-    }
-    errorCode, exists := errorData["code"].(string)
-    if !exists {
-        t.Fatal("Error must contain 'code' field")
-    }
-    if errorCode != "INTERNAL_ERROR" {
-        t.Errorf("Expected error code 'INTERNAL_ERROR', got %v", errorCode)
-    }
-    t.Logf("Service error handled correctly: %s", w.Body.String())
+	mock := &mockService{
+		getStatsFunc: func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+			return nil, entity.ErrNotFound
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats", nil)
+	w := httptest.NewRecorder()
+	handler.GetStats(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	errorData, exists := response["error"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Error response must contain 'error' field")
+	}
+	errorCode, exists := errorData["code"].(string)
+	if !exists {
+		t.Fatal("Error must contain 'code' field")
+	}
+	if errorCode != "INTERNAL_ERROR" {
+		t.Errorf("Expected error code 'INTERNAL_ERROR', got %v", errorCode)
+	}
+	t.Logf("Service error handled correctly: %s", w.Body.String())
 }
 
 func TestHandlers_GetStats_SingleUserAndPR(t *testing.T) {
-    mockStats := &entity.Stats{
-        TotalAssignments: 15,
-        UserAssignmentCounts: []entity.UserAssignmentCount{
-            {
-                UserID:   "u999",
-                Username: "sole_reviewer",
-                Count:    15,
-            },
-        },
-        PRAssignmentCounts: []entity.PRAssignmentCount{
-            {
-                PRID:  "pr-5001",
-                Title: "Initial commit",
-                Count: 3,
-            },
-        },
-    }
-    mock := &mockService{
-        getStatsFunc: func() (*entity.Stats, error) {
-            return mockStats, nil
-        },
-    }
-    handler := NewHandlers(mock)
-    req := httptest.NewRequest("GET", "/stats", nil)
-    w := httptest.NewRecorder()
-    handler.GetStats(w, req)
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-    statsData := response["stats"].(map[string]interface{})
-    usersData := statsData["user_assignment_counts"].([]interface{})
-    if len(usersData) != 1 {
-        t.Errorf("Expected 1 user assignment count, got %d", len(usersData))
-    }
-    user := usersData[0].(map[string]interface{})
-    if user["count"] != float64(15) {
-        t.Errorf("Expected user count 15, got %v", user["count"])
-    }
-    prsData := statsData["pr_assignment_counts"].([]interface{})
-    if len(prsData) != 1 {
-        t.Errorf("Expected 1 PR assignment count, got %d", len(prsData))
-    }
-    t.Logf("Single user/PR stats retrieved successfully: %s", w.Body.String())
+	mockStats := &entity.Stats{
+		TotalAssignments: 15,
+		UserAssignmentCounts: []entity.UserAssignmentCount{
+			{
+				UserID:   "u999",
+				Username: "sole_reviewer",
+				Count:    15,
+			},
+		},
+		PRAssignmentCounts: []entity.PRAssignmentCount{
+			{
+				PRID:  "pr-5001",
+				Title: "Initial commit",
+				Count: 3,
+			},
+		},
+	}
+	mock := &mockService{
+		getStatsFunc: func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+			return mockStats, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats", nil)
+	w := httptest.NewRecorder()
+	handler.GetStats(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	statsData := response["stats"].(map[string]interface{})
+	usersData := statsData["user_assignment_counts"].([]interface{})
+	if len(usersData) != 1 {
+		t.Errorf("Expected 1 user assignment count, got %d", len(usersData))
+	}
+	user := usersData[0].(map[string]interface{})
+	if user["count"] != float64(15) {
+		t.Errorf("Expected user count 15, got %v", user["count"])
+	}
+	prsData := statsData["pr_assignment_counts"].([]interface{})
+	if len(prsData) != 1 {
+		t.Errorf("Expected 1 PR assignment count, got %d", len(prsData))
+	}
+	t.Logf("Single user/PR stats retrieved successfully: %s", w.Body.String())
 }
 
 func TestHandlers_GetStats_LargeDataset(t *testing.T) {
-    userCounts := make([]entity.UserAssignmentCount, 50)
-    prCounts := make([]entity.PRAssignmentCount, 100)
-    for i := 0; i < 50; i++ {
-        userCounts[i] = entity.UserAssignmentCount{
-            UserID:   fmt.Sprintf("u%d", i+1),
-            Username: fmt.Sprintf("user%d", i+1),
-            Count:    i + 1,
-        }
-    }
-    for i := 0; i < 100; i++ {
-        prCounts[i] = entity.PRAssignmentCount{
-            PRID:  fmt.Sprintf("pr-%d", i+1),
-            Title: fmt.Sprintf("Feature %d", i+1),
-            Count: (i % 10) + 1,
-        }
-    }
-    mockStats := &entity.Stats{
-        TotalAssignments:     1275,
-        UserAssignmentCounts: userCounts,
-        PRAssignmentCounts:   prCounts,
-    }
-    mock := &mockService{
-        getStatsFunc: func() (*entity.Stats, error) {
-            return mockStats, nil
-        },
-    }
-    handler := NewHandlers(mock)
-    req := httptest.NewRequest("GET", "/stats", nil)
-    w := httptest.NewRecorder()
-    handler.GetStats(w, req)
-    if w.Code != http.StatusOK {
-        t.Errorf("Expected status 200, got %d", w.Code)
-        return
-    }
-    var response map[string]interface{}
-    err := json.Unmarshal(w.Body.Bytes(), &response)
-    if err != nil {
-        t.Fatalf("Failed to parse response: %v", err)
-    }
-    statsData := response["stats"].(map[string]interface{})
-    usersData := statsData["user_assignment_counts"].([]interface{})
-    if len(usersData) != 50 {
-        t.Errorf("Expected 50 user assignment counts, got %d", len(usersData))
-    }
-    prsData := statsData["pr_assignment_counts"].([]interface{})
-    if len(prsData) != 100 {
-        t.Errorf("Expected 100 PR assignment counts, got %d", len(prsData))
-    }
-    t.Logf("Large dataset handled successfully: %d users, %d PRs", len(usersData), len(prsData))
+	userCounts := make([]entity.UserAssignmentCount, 50)
+	prCounts := make([]entity.PRAssignmentCount, 100)
+	for i := 0; i < 50; i++ {
+		userCounts[i] = entity.UserAssignmentCount{
+			UserID:   fmt.Sprintf("u%d", i+1),
+			Username: fmt.Sprintf("user%d", i+1),
+			Count:    i + 1,
+		}
+	}
+	for i := 0; i < 100; i++ {
+		prCounts[i] = entity.PRAssignmentCount{
+			PRID:  fmt.Sprintf("pr-%d", i+1),
+			Title: fmt.Sprintf("Feature %d", i+1),
+			Count: (i % 10) + 1,
+		}
+	}
+	mockStats := &entity.Stats{
+		TotalAssignments:     1275,
+		UserAssignmentCounts: userCounts,
+		PRAssignmentCounts:   prCounts,
+	}
+	mock := &mockService{
+		getStatsFunc: func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+			return mockStats, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats", nil)
+	w := httptest.NewRecorder()
+	handler.GetStats(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+		return
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	statsData := response["stats"].(map[string]interface{})
+	usersData := statsData["user_assignment_counts"].([]interface{})
+	if len(usersData) != 50 {
+		t.Errorf("Expected 50 user assignment counts, got %d", len(usersData))
+	}
+	prsData := statsData["pr_assignment_counts"].([]interface{})
+	if len(prsData) != 100 {
+		t.Errorf("Expected 100 PR assignment counts, got %d", len(prsData))
+	}
+	t.Logf("Large dataset handled successfully: %d users, %d PRs", len(usersData), len(prsData))
+}
+
+func TestHandlers_GetStats_Pagination(t *testing.T) {
+	var gotLimit, gotOffset int
+	mock := &mockService{
+		getStatsFunc: func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+			gotLimit, gotOffset = limit, offset
+			return &entity.Stats{}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		query          string
+		expectedLimit  int
+		expectedOffset int
+	}{
+		{"/stats", 50, 0},
+		{"/stats?limit=10", 10, 0},
+		{"/stats?limit=10&offset=30", 10, 30},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.query, nil)
+			w := httptest.NewRecorder()
+			handler.GetStats(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+			if gotLimit != tc.expectedLimit || gotOffset != tc.expectedOffset {
+				t.Errorf("Expected limit %d and offset %d, got %d and %d",
+					tc.expectedLimit, tc.expectedOffset, gotLimit, gotOffset)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetStats_InvalidPagination(t *testing.T) {
+	mock := &mockService{}
+	handler := NewHandlers(mock)
+	queries := []string{
+		"/stats?offset=-1",
+		"/stats?limit=0",
+		"/stats?limit=abc",
+		"/stats?offset=abc",
+	}
+	for _, query := range queries {
+		t.Run(query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", query, nil)
+			w := httptest.NewRecorder()
+			handler.GetStats(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+			var response map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			errorData, _ := response["error"].(map[string]interface{})
+			if errorData["code"] != "INVALID_REQUEST" {
+				t.Errorf("Expected error code 'INVALID_REQUEST', got %v", errorData["code"])
+			}
+		})
+	}
+}
+
+func TestHandlers_GetTeamStats_Success(t *testing.T) {
+	mock := &mockService{
+		getTeamStatsFunc: func(teamName string) (*entity.Stats, error) {
+			if teamName != "backend" {
+				t.Errorf("Expected team_name 'backend', got %s", teamName)
+			}
+			return &entity.Stats{
+				TotalAssignments: 2,
+				UserAssignmentCounts: []entity.UserAssignmentCount{
+					{UserID: "u1", Username: "Alice", Count: 2},
+				},
+				PRAssignmentCounts: []entity.PRAssignmentCount{
+					{PRID: "pr-1", Title: "Feature", Count: 2},
+				},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats/team?team_name=backend", nil)
+	w := httptest.NewRecorder()
+	handler.GetTeamStats(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response["team_name"] != "backend" {
+		t.Errorf("Expected team_name 'backend', got %v", response["team_name"])
+	}
+	statsData, exists := response["stats"].(map[string]interface{})
+	if !exists {
+		t.Fatal("Response must contain 'stats' field")
+	}
+	if statsData["total_assignments"] != float64(2) {
+		t.Errorf("Expected total_assignments 2, got %v", statsData["total_assignments"])
+	}
+}
+
+func TestHandlers_GetTeamStats_NotFound(t *testing.T) {
+	mock := &mockService{
+		getTeamStatsFunc: func(teamName string) (*entity.Stats, error) {
+			return nil, entity.ErrNotFound
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats/team?team_name=ghost", nil)
+	w := httptest.NewRecorder()
+	handler.GetTeamStats(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestHandlers_GetTeamStats_MissingTeamName(t *testing.T) {
+	handler := NewHandlers(&mockService{})
+	req := httptest.NewRequest("GET", "/stats/team", nil)
+	w := httptest.NewRecorder()
+	handler.GetTeamStats(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHandlers_GetUserStats(t *testing.T) {
+	mock := &mockService{
+		getUserStatsFunc: func(userID string) (*entity.UserAssignmentCount, error) {
+			if userID == "ghost" {
+				return nil, entity.ErrNotFound
+			}
+			return &entity.UserAssignmentCount{UserID: userID, Username: "Reviewer1", Count: 3, OpenCount: 2, MergedCount: 1}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.GetUserStats(w, httptest.NewRequest("GET", "/stats/user?user_id=reviewer1", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		var response entity.UserAssignmentCount
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response.UserID != "reviewer1" || response.Count != 3 || response.OpenCount != 2 || response.MergedCount != 1 {
+			t.Errorf("Unexpected stats: %+v", response)
+		}
+	})
+	t.Run("Unknown user", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.GetUserStats(w, httptest.NewRequest("GET", "/stats/user?user_id=ghost", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+	t.Run("Missing user_id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.GetUserStats(w, httptest.NewRequest("GET", "/stats/user", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandlers_GetConcentration_Success(t *testing.T) {
+	mock := &mockService{
+		getConcentrationFunc: func() (*entity.Concentration, error) {
+			return &entity.Concentration{
+				GiniCoefficient: 0.5,
+				UserLoads: []entity.UserAssignmentCount{
+					{UserID: "u1", Username: "Alice", Count: 3},
+					{UserID: "u2", Username: "Bob", Count: 1},
+				},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats/concentration", nil)
+	w := httptest.NewRecorder()
+	handler.GetConcentration(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response entity.Concentration
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.GiniCoefficient != 0.5 {
+		t.Errorf("Expected gini_coefficient 0.5, got %v", response.GiniCoefficient)
+	}
+	if len(response.UserLoads) != 2 {
+		t.Errorf("Expected 2 user loads, got %d", len(response.UserLoads))
+	}
+}
+
+func TestHandlers_GetConcentration_ServiceError(t *testing.T) {
+	mock := &mockService{
+		getConcentrationFunc: func() (*entity.Concentration, error) {
+			return nil, fmt.Errorf("database error")
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats/concentration", nil)
+	w := httptest.NewRecorder()
+	handler.GetConcentration(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+}
+
+func TestHandlers_RequiredTeamSize_Success(t *testing.T) {
+	var captured entity.ReviewPolicy
+	mock := &mockService{
+		requiredTeamSizeFunc: func(policy entity.ReviewPolicy) (int, error) {
+			captured = policy
+			return 5, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/team/requiredSize?desired=3&reserve=1", nil)
+	w := httptest.NewRecorder()
+	handler.RequiredTeamSize(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if captured.DesiredReviewers != 3 || captured.Reserve != 1 || captured.Cap != 0 {
+		t.Errorf("Unexpected policy passed to service: %+v", captured)
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response["required_team_size"] != float64(5) {
+		t.Errorf("Expected required_team_size 5, got %v", response["required_team_size"])
+	}
+}
+
+func TestHandlers_RequiredTeamSize_InvalidPolicy(t *testing.T) {
+	mock := &mockService{
+		requiredTeamSizeFunc: func(policy entity.ReviewPolicy) (int, error) {
+			return 0, entity.ErrInvalidPolicy
+		},
+	}
+	handler := NewHandlers(mock)
+	for _, query := range []string{"/team/requiredSize?desired=abc", "/team/requiredSize?reserve=-1"} {
+		req := httptest.NewRequest("GET", query, nil)
+		w := httptest.NewRecorder()
+		handler.RequiredTeamSize(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestHandlers_GetStats_DateRange(t *testing.T) {
+	var captured entity.StatsFilter
+	mock := &mockService{
+		getStatsFunc: func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+			captured = filter
+			return &entity.Stats{}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats?from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+	handler.GetStats(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if captured.From == nil || captured.From.Format("2006-01-02") != "2025-01-01" {
+		t.Errorf("Expected from 2025-01-01, got %v", captured.From)
+	}
+	if captured.To == nil || captured.To.Format("2006-01-02") != "2025-02-01" {
+		t.Errorf("Expected to 2025-02-01, got %v", captured.To)
+	}
+	req = httptest.NewRequest("GET", "/stats", nil)
+	w = httptest.NewRecorder()
+	handler.GetStats(w, req)
+	if captured.From != nil || captured.To != nil {
+		t.Errorf("Expected empty filter when range is omitted, got %+v", captured)
+	}
+}
+
+func TestHandlers_GetStats_InvalidDateRange(t *testing.T) {
+	handler := NewHandlers(&mockService{})
+	queries := []string{
+		"/stats?from=yesterday",
+		"/stats?to=2025-13-01",
+		"/stats?from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z",
+	}
+	for _, query := range queries {
+		t.Run(query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", query, nil)
+			w := httptest.NewRecorder()
+			handler.GetStats(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetReviewerWeeklySummary_Success(t *testing.T) {
+	var capturedWeeks int
+	mock := &mockService{
+		getReviewerWeeklySummaryFunc: func(userID string, weeks int) ([]entity.WeekCount, error) {
+			capturedWeeks = weeks
+			return []entity.WeekCount{
+				{ISOYear: 2025, ISOWeek: 10, WeekStart: "2025-03-03", Count: 0},
+				{ISOYear: 2025, ISOWeek: 11, WeekStart: "2025-03-10", Count: 2},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats/reviewerWeekly?user_id=u1&weeks=2", nil)
+	w := httptest.NewRecorder()
+	handler.GetReviewerWeeklySummary(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if capturedWeeks != 2 {
+		t.Errorf("Expected weeks 2, got %d", capturedWeeks)
+	}
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	weeksData, exists := response["weeks"].([]interface{})
+	if !exists || len(weeksData) != 2 {
+		t.Fatalf("Expected 2 weeks in response, got %v", response["weeks"])
+	}
+	lastWeek := weeksData[1].(map[string]interface{})
+	if lastWeek["iso_week"] != float64(11) || lastWeek["count"] != float64(2) {
+		t.Errorf("Unexpected last week: %v", lastWeek)
+	}
+}
+
+func TestHandlers_GetReviewerWeeklySummary_DefaultAndInvalidWeeks(t *testing.T) {
+	var capturedWeeks int
+	mock := &mockService{
+		getReviewerWeeklySummaryFunc: func(userID string, weeks int) ([]entity.WeekCount, error) {
+			capturedWeeks = weeks
+			return []entity.WeekCount{}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/stats/reviewerWeekly?user_id=u1", nil)
+	w := httptest.NewRecorder()
+	handler.GetReviewerWeeklySummary(w, req)
+	if capturedWeeks != 8 {
+		t.Errorf("Expected default of 8 weeks, got %d", capturedWeeks)
+	}
+	for _, query := range []string{"/stats/reviewerWeekly?user_id=u1&weeks=0", "/stats/reviewerWeekly?weeks=4"} {
+		req := httptest.NewRequest("GET", query, nil)
+		w := httptest.NewRecorder()
+		handler.GetReviewerWeeklySummary(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
 }
 
 func TestHandlers_MethodNotAllowed(t *testing.T) {
-    mock := &mockService{}
-    handler := NewHandlers(mock)
-    testCases := []struct {
-        method string
-        path   string
-    }{
-        {"PUT", "/teams"},
-        {"DELETE", "/teams"},
-        {"PATCH", "/teams"},
-        {"PUT", "/users/setIsActive"},
-        {"GET", "/users/setIsActive"},
-        {"PUT", "/pullRequest/create"},
-        {"GET", "/pullRequest/create"},
-    }
-    for _, tc := range testCases {
-        t.Run(tc.method+tc.path, func(t *testing.T) {
-            req := httptest.NewRequest(tc.method, tc.path, nil)
-            w := httptest.NewRecorder()
-            switch tc.path {
-            case "/teams":
-                handler.AddTeam(w, req)
-            case "/users/setIsActive":
-                handler.SetUserActive(w, req)
-            case "/pullRequest/create":
-                handler.CreatePR(w, req)
-            }
-            if w.Code >= 200 && w.Code < 300 {
-                t.Errorf("Expected error status for %s %s, got %d", tc.method, tc.path, w.Code)
-            }
-        })
-    }
-}
\ No newline at end of file
+	mock := &mockService{}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		method string
+		path   string
+		allow  string
+	}{
+		{"PUT", "/teams", "POST"},
+		{"DELETE", "/teams", "POST"},
+		{"PATCH", "/teams", "POST"},
+		{"GET", "/teams", "POST"},
+		{"PUT", "/users/setIsActive", "POST"},
+		{"GET", "/users/setIsActive", "POST"},
+		{"PUT", "/pullRequest/create", "POST"},
+		{"GET", "/pullRequest/create", "POST"},
+		{"PUT", "/team/get", "GET, POST"},
+		{"POST", "/stats", "GET"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.method+tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			w := httptest.NewRecorder()
+			switch tc.path {
+			case "/teams":
+				handler.AddTeam(w, req)
+			case "/users/setIsActive":
+				handler.SetUserActive(w, req)
+			case "/pullRequest/create":
+				handler.CreatePR(w, req)
+			case "/team/get":
+				handler.GetTeam(w, req)
+			case "/stats":
+				handler.GetStats(w, req)
+			}
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Errorf("Expected status 405 for %s %s, got %d", tc.method, tc.path, w.Code)
+			}
+			if allow := w.Header().Get("Allow"); allow != tc.allow {
+				t.Errorf("Expected Allow header %q, got %q", tc.allow, allow)
+			}
+			var response ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.Error.Code != "METHOD_NOT_ALLOWED" {
+				t.Errorf("Expected error code METHOD_NOT_ALLOWED, got %s", response.Error.Code)
+			}
+		})
+	}
+}
+
+func TestHandlers_RenameTeam(t *testing.T) {
+	mock := &mockService{
+		renameTeamFunc: func(oldName, newName string) (*entity.Team, error) {
+			switch {
+			case oldName == "missing":
+				return nil, entity.ErrNotFound
+			case newName == "platform":
+				return nil, entity.ErrTeamExists
+			case strings.TrimSpace(newName) == "":
+				return nil, entity.ErrEmptyTeamName
+			}
+			return &entity.Team{Name: strings.TrimSpace(newName)}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		body   map[string]string
+		status int
+		code   string
+	}{
+		{map[string]string{"old_name": "payments", "new_name": "billing"}, http.StatusOK, ""},
+		{map[string]string{"old_name": "missing", "new_name": "billing"}, http.StatusNotFound, "NOT_FOUND"},
+		{map[string]string{"old_name": "payments", "new_name": "platform"}, http.StatusBadRequest, "TEAM_EXISTS"},
+		{map[string]string{"old_name": "payments"}, http.StatusBadRequest, "INVALID_REQUEST"},
+		{map[string]string{"old_name": "payments", "new_name": "   "}, http.StatusBadRequest, "INVALID_REQUEST"},
+		{map[string]string{"old_name": "payments", "new_name": " billing "}, http.StatusOK, ""},
+	}
+	for _, tc := range testCases {
+		body, _ := json.Marshal(tc.body)
+		req := httptest.NewRequest("POST", "/team/rename", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.RenameTeam(w, req)
+		if w.Code != tc.status {
+			t.Errorf("%v: expected status %d, got %d", tc.body, tc.status, w.Code)
+			continue
+		}
+		var response map[string]map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if tc.code == "" {
+			if response["team"]["team_name"] != "billing" {
+				t.Errorf("Expected renamed team in response, got %s", w.Body.String())
+			}
+		} else if response["error"]["code"] != tc.code {
+			t.Errorf("%v: expected error code %s, got %v", tc.body, tc.code, response["error"]["code"])
+		}
+	}
+}
+
+func TestHandlers_SetTeamReviewerCount(t *testing.T) {
+	var saved int
+	mock := &mockService{
+		setTeamReviewerCountFunc: func(teamName string, count int) error {
+			if teamName == "missing" {
+				return entity.ErrNotFound
+			}
+			saved = count
+			return nil
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"team_name":"infra","default_reviewers":3}`, http.StatusOK, ""},
+		{`{"team_name":"missing","default_reviewers":3}`, http.StatusNotFound, "NOT_FOUND"},
+		{`{"team_name":"infra","default_reviewers":0}`, http.StatusBadRequest, "INVALID_REQUEST"},
+		{`{"team_name":"infra","default_reviewers":11}`, http.StatusBadRequest, "INVALID_REQUEST"},
+		{`{"default_reviewers":3}`, http.StatusBadRequest, "INVALID_REQUEST"},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		handler.SetTeamReviewerCount(w, httptest.NewRequest("POST", "/team/setReviewerCount", strings.NewReader(tc.body)))
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.body, tc.status, w.Code)
+			continue
+		}
+		var response map[string]map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if tc.code == "" {
+			if response["team"]["default_reviewers"] != float64(3) || saved != 3 {
+				t.Errorf("Expected default_reviewers 3 to be saved, got %s", w.Body.String())
+			}
+		} else if response["error"]["code"] != tc.code {
+			t.Errorf("%s: expected error code %s, got %v", tc.body, tc.code, response["error"]["code"])
+		}
+	}
+}
+
+func TestHandlers_ImportTeams_PartialSuccess(t *testing.T) {
+	var captured []entity.TeamWithMembers
+	mock := &mockService{
+		importTeamsFunc: func(teams []entity.TeamWithMembers) ([]entity.ImportResult, error) {
+			captured = teams
+			return []entity.ImportResult{
+				{TeamName: "frontend", TeamID: "1"},
+				{TeamName: "backend", Err: entity.ErrTeamExists},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	body := `{"teams":[{"team_name":"frontend","members":[{"user_id":"u1","username":"Alice","is_active":true}]},{"team_name":"backend","members":[]}]}`
+	req := httptest.NewRequest("POST", "/teams/import", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ImportTeams(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(captured) != 2 || len(captured[0].Members) != 1 || captured[0].Members[0].ID != "u1" {
+		t.Errorf("Expected teams to be passed through, got %+v", captured)
+	}
+	var response struct {
+		Results []struct {
+			TeamName string `json:"team_name"`
+			Success  bool   `json:"success"`
+			Error    *struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(response.Results))
+	}
+	if !response.Results[0].Success || response.Results[0].Error != nil {
+		t.Errorf("Expected frontend to succeed, got %+v", response.Results[0])
+	}
+	if response.Results[1].Success || response.Results[1].Error == nil || response.Results[1].Error.Code != "TEAM_EXISTS" {
+		t.Errorf("Expected backend to fail with TEAM_EXISTS, got %+v", response.Results[1])
+	}
+}
+
+func TestHandlers_ImportTeams_EmptyBatch(t *testing.T) {
+	handler := NewHandlers(&mockService{})
+	req := httptest.NewRequest("POST", "/teams/import", strings.NewReader(`{"teams":[]}`))
+	w := httptest.NewRecorder()
+	handler.ImportTeams(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHandlers_SetTeamActive(t *testing.T) {
+	mock := &mockService{
+		setTeamActiveFunc: func(teamName string, isActive bool) ([]entity.User, []entity.Reassignment, error) {
+			if teamName == "ghost" {
+				return nil, nil, entity.ErrNotFound
+			}
+			return []entity.User{{ID: "u1", Username: "Alice", IsActive: isActive, TeamName: teamName}},
+				[]entity.Reassignment{{PRID: "pr-1", OldUserID: "u1"}}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("POST", "/team/setActive", strings.NewReader(`{"team_name":"backend","is_active":false}`))
+	w := httptest.NewRecorder()
+	handler.SetTeamActive(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		TeamName   string                `json:"team_name"`
+		Members    []entity.User         `json:"members"`
+		Reassigned []entity.Reassignment `json:"reassigned_prs"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.TeamName != "backend" || len(response.Members) != 1 || response.Members[0].IsActive || len(response.Reassigned) != 1 {
+		t.Errorf("Unexpected response: %s", w.Body.String())
+	}
+	req = httptest.NewRequest("POST", "/team/setActive", strings.NewReader(`{"team_name":"ghost","is_active":false}`))
+	w = httptest.NewRecorder()
+	handler.SetTeamActive(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown team, got %d", w.Code)
+	}
+	req = httptest.NewRequest("POST", "/team/setActive", strings.NewReader(`{"team_name":"backend"}`))
+	w = httptest.NewRecorder()
+	handler.SetTeamActive(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without is_active, got %d", w.Code)
+	}
+}
+
+func TestHandlers_GetUser(t *testing.T) {
+	mock := &mockService{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			switch userID {
+			case "u1":
+				return &entity.User{ID: "u1", Username: "Alice", TeamName: "backend", IsActive: true}, nil
+			case "loner":
+				return &entity.User{ID: "loner", Username: "Loner", IsActive: false}, nil
+			}
+			return nil, entity.ErrNotFound
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		userID   string
+		wantCode int
+		wantBody string
+	}{
+		{"u1", http.StatusOK, `{"user_id":"u1","username":"Alice","team_name":"backend","is_active":true}`},
+		{"loner", http.StatusOK, `{"user_id":"loner","username":"Loner","team_name":"","is_active":false}`},
+		{"ghost", http.StatusNotFound, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.userID, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.GetUser(w, httptest.NewRequest("GET", "/users/get?user_id="+tc.userID, nil))
+			if w.Code != tc.wantCode {
+				t.Fatalf("Expected status %d, got %d", tc.wantCode, w.Code)
+			}
+			if tc.wantBody != "" && strings.TrimSpace(w.Body.String()) != tc.wantBody {
+				t.Errorf("Expected %s, got %s", tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_GetUserLoad(t *testing.T) {
+	mock := &mockService{
+		getUserLoadFunc: func(userID string) (int, int, error) {
+			if userID != "u2" {
+				return 0, 0, entity.ErrNotFound
+			}
+			return 1, 2, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{"user_id=u2", http.StatusOK, `{"user_id":"u2","open_assignments":1,"total_assignments":2}`},
+		{"user_id=ghost", http.StatusNotFound, ""},
+		{"", http.StatusBadRequest, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.GetUserLoad(w, httptest.NewRequest("GET", "/users/load?"+tc.query, nil))
+			if w.Code != tc.wantCode {
+				t.Fatalf("Expected status %d, got %d", tc.wantCode, w.Code)
+			}
+			if tc.wantBody != "" && strings.TrimSpace(w.Body.String()) != tc.wantBody {
+				t.Errorf("Expected %s, got %s", tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_GetUserTeams(t *testing.T) {
+	mock := &mockService{
+		getUserTeamsFunc: func(userID string) ([]entity.Team, error) {
+			switch userID {
+			case "loner":
+				return []entity.Team{}, nil
+			case "u1":
+				return []entity.Team{{Name: "backend"}}, nil
+			case "u2":
+				return []entity.Team{{Name: "backend"}, {Name: "infra", DefaultReviewers: 3}}, nil
+			}
+			return nil, entity.ErrNotFound
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{"user_id=loner", http.StatusOK, `{"user_id":"loner","teams":[]}`},
+		{"user_id=u1", http.StatusOK, `{"user_id":"u1","teams":[{"team_name":"backend"}]}`},
+		{"user_id=u2", http.StatusOK, `{"user_id":"u2","teams":[{"team_name":"backend"},{"team_name":"infra","default_reviewers":3}]}`},
+		{"user_id=ghost", http.StatusNotFound, ""},
+		{"", http.StatusBadRequest, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.GetUserTeams(w, httptest.NewRequest("GET", "/users/teams?"+tc.query, nil))
+			if w.Code != tc.wantCode {
+				t.Fatalf("Expected status %d, got %d", tc.wantCode, w.Code)
+			}
+			if tc.wantBody != "" && strings.TrimSpace(w.Body.String()) != tc.wantBody {
+				t.Errorf("Expected %s, got %s", tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_ReassignAllForUser(t *testing.T) {
+	mock := &mockService{
+		reassignAllFunc: func(userID string, bestEffort bool) ([]entity.ReassignResult, error) {
+			results := []entity.ReassignResult{{PRID: "pr-a", NewUserID: "u3"}, {PRID: "pr-b", Error: "NO_CANDIDATE"}}
+			if !bestEffort {
+				return results, entity.ErrNoCandidate
+			}
+			return append(results, entity.ReassignResult{PRID: "pr-c", NewUserID: "u3"}), nil
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			`{"user_id":"u2","best_effort":true}`,
+			http.StatusOK,
+			`{"user_id":"u2","results":[{"pull_request_id":"pr-a","replaced_by":"u3"},{"pull_request_id":"pr-b","error":"NO_CANDIDATE"},{"pull_request_id":"pr-c","replaced_by":"u3"}]}`,
+		},
+		{
+			`{"user_id":"u2"}`,
+			http.StatusConflict,
+			`{"error":{"code":"NO_CANDIDATE","message":"no active replacement candidate for pr-b; nothing was reassigned"}}`,
+		},
+		{`{}`, http.StatusBadRequest, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.body, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/users/reassignAll", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			handler.ReassignAllForUser(w, req)
+			if w.Code != tc.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+			if tc.wantBody != "" && strings.TrimSpace(w.Body.String()) != tc.wantBody {
+				t.Errorf("Expected %s, got %s", tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_RetireUser(t *testing.T) {
+	mock := &mockService{
+		retireUserFunc: func(userID string) ([]entity.Reassignment, error) {
+			if userID != "u1" {
+				return nil, entity.ErrNotFound
+			}
+			return []entity.Reassignment{{PRID: "pr-1", NewUserID: "u2"}, {PRID: "pr-2"}}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("POST", "/users/retire", strings.NewReader(`{"user_id":"u1"}`))
+	w := httptest.NewRecorder()
+	handler.RetireUser(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		UserID     string                `json:"user_id"`
+		Reassigned []entity.Reassignment `json:"reassigned_prs"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.UserID != "u1" || len(response.Reassigned) != 2 {
+		t.Fatalf("Unexpected response: %s", w.Body.String())
+	}
+	if response.Reassigned[0].PRID != "pr-1" || response.Reassigned[0].NewUserID != "u2" {
+		t.Errorf("Expected pr-1 to be handed to u2, got %+v", response.Reassigned[0])
+	}
+
+	req = httptest.NewRequest("POST", "/users/retire", strings.NewReader(`{"user_id":"ghost"}`))
+	w = httptest.NewRecorder()
+	handler.RetireUser(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown user, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/users/retire", strings.NewReader(`{}`))
+	w = httptest.NewRecorder()
+	handler.RetireUser(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without user_id, got %d", w.Code)
+	}
+}
+
+func TestHandlers_AddReviewer(t *testing.T) {
+	mock := &mockService{
+		addReviewerFunc: func(prID, userID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:                prID,
+				Title:             "Test PR",
+				AuthorID:          "author1",
+				Status:            "OPEN",
+				AssignedReviewers: []entity.User{{ID: "reviewer1"}, {ID: userID}},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("POST", "/pullRequest/addReviewer", strings.NewReader(`{"pull_request_id":"pr-1","user_id":"reviewer2"}`))
+	w := httptest.NewRecorder()
+	handler.AddReviewer(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		PR struct {
+			ID                string   `json:"pull_request_id"`
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.PR.ID != "pr-1" || len(response.PR.AssignedReviewers) != 2 || response.PR.AssignedReviewers[1] != "reviewer2" {
+		t.Errorf("Unexpected response: %s", w.Body.String())
+	}
+}
+
+func TestHandlers_AddReviewer_Errors(t *testing.T) {
+	testCases := []struct {
+		err          error
+		expectedCode int
+		errorCode    string
+	}{
+		{entity.ErrNotFound, http.StatusNotFound, "NOT_FOUND"},
+		{entity.ErrSelfReview, http.StatusConflict, "SELF_REVIEW"},
+		{entity.ErrAlreadyAssigned, http.StatusConflict, "ALREADY_ASSIGNED"},
+		{entity.ErrPRMerged, http.StatusConflict, "PR_MERGED"},
+		{entity.ErrPRClosed, http.StatusConflict, "PR_CLOSED"},
+		{entity.ErrNoCandidate, http.StatusConflict, "NO_CANDIDATE"},
+		{entity.ErrReviewerLimit, http.StatusConflict, "REVIEWER_LIMIT"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.errorCode, func(t *testing.T) {
+			mock := &mockService{
+				addReviewerFunc: func(prID, userID string) (*entity.PullRequest, error) {
+					return nil, tc.err
+				},
+			}
+			handler := NewHandlers(mock)
+			req := httptest.NewRequest("POST", "/pullRequest/addReviewer", strings.NewReader(`{"pull_request_id":"pr-1","user_id":"u1"}`))
+			w := httptest.NewRecorder()
+			handler.AddReviewer(w, req)
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedCode, w.Code)
+			}
+			var response ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.Error.Code != tc.errorCode {
+				t.Errorf("Expected error code %s, got %s", tc.errorCode, response.Error.Code)
+			}
+		})
+	}
+	handler := NewHandlers(&mockService{})
+	req := httptest.NewRequest("POST", "/pullRequest/addReviewer", strings.NewReader(`{"pull_request_id":"pr-1"}`))
+	w := httptest.NewRecorder()
+	handler.AddReviewer(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without user_id, got %d", w.Code)
+	}
+}
+
+func TestHandlers_RespondReview(t *testing.T) {
+	mock := &mockService{
+		respondReviewFunc: func(prID, userID string, accept bool) (string, error) {
+			switch {
+			case userID == "stranger":
+				return "", entity.ErrNotAssigned
+			case accept || userID == "reviewer3":
+				return "", nil
+			}
+			return "reviewer3", nil
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "accept",
+			body:       `{"pull_request_id":"pr-1","user_id":"reviewer1","accept":true}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"pull_request_id":"pr-1","user_id":"reviewer1","state":"ACCEPTED"}`,
+		},
+		{
+			name:       "decline with replacement",
+			body:       `{"pull_request_id":"pr-1","user_id":"reviewer2","accept":false}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"pull_request_id":"pr-1","user_id":"reviewer2","state":"DECLINED","replaced_by":"reviewer3"}`,
+		},
+		{
+			name:       "decline with no candidate",
+			body:       `{"pull_request_id":"pr-1","user_id":"reviewer3","accept":false}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"pull_request_id":"pr-1","user_id":"reviewer3","state":"DECLINED"}`,
+		},
+		{
+			name:       "not assigned",
+			body:       `{"pull_request_id":"pr-1","user_id":"stranger","accept":true}`,
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"error":{"code":"NOT_ASSIGNED","message":"reviewer is not assigned to this PR"}}`,
+		},
+		{
+			name:       "missing accept",
+			body:       `{"pull_request_id":"pr-1","user_id":"reviewer1"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":{"code":"INVALID_REQUEST","message":"pull_request_id, user_id and accept are required"}}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.RespondReview(w, httptest.NewRequest("POST", "/pullRequest/respondReview", strings.NewReader(tc.body)))
+			if w.Code != tc.wantStatus {
+				t.Errorf("Expected status %d, got %d", tc.wantStatus, w.Code)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tc.wantBody {
+				t.Errorf("Expected %s, got %s", tc.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestHandlers_RemoveReviewer(t *testing.T) {
+	mock := &mockService{
+		removeReviewerFunc: func(prID, userID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:                prID,
+				Title:             "Test PR",
+				AuthorID:          "author1",
+				Status:            "OPEN",
+				AssignedReviewers: []entity.User{{ID: "reviewer2"}},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("POST", "/pullRequest/removeReviewer", strings.NewReader(`{"pull_request_id":"pr-1","user_id":"reviewer1"}`))
+	w := httptest.NewRecorder()
+	handler.RemoveReviewer(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.PR.AssignedReviewers) != 1 || response.PR.AssignedReviewers[0] != "reviewer2" {
+		t.Errorf("Expected only reviewer2 to remain, got %v", response.PR.AssignedReviewers)
+	}
+}
+
+func TestHandlers_RemoveReviewer_Errors(t *testing.T) {
+	testCases := []struct {
+		err          error
+		expectedCode int
+		errorCode    string
+	}{
+		{entity.ErrNotFound, http.StatusNotFound, "NOT_FOUND"},
+		{entity.ErrLastReviewer, http.StatusConflict, "LAST_REVIEWER"},
+		{entity.ErrNotAssigned, http.StatusConflict, "NOT_ASSIGNED"},
+		{entity.ErrPRMerged, http.StatusConflict, "PR_MERGED"},
+		{entity.ErrPRClosed, http.StatusConflict, "PR_CLOSED"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.errorCode, func(t *testing.T) {
+			mock := &mockService{
+				removeReviewerFunc: func(prID, userID string) (*entity.PullRequest, error) {
+					return nil, tc.err
+				},
+			}
+			handler := NewHandlers(mock)
+			req := httptest.NewRequest("POST", "/pullRequest/removeReviewer", strings.NewReader(`{"pull_request_id":"pr-1","user_id":"u1"}`))
+			w := httptest.NewRecorder()
+			handler.RemoveReviewer(w, req)
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedCode, w.Code)
+			}
+			var response ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.Error.Code != tc.errorCode {
+				t.Errorf("Expected error code %s, got %s", tc.errorCode, response.Error.Code)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetStats_Sort(t *testing.T) {
+	ordered := map[entity.StatsSort]*entity.Stats{
+		entity.StatsSortCountDesc: {
+			UserAssignmentCounts: []entity.UserAssignmentCount{{UserID: "u2", Username: "bob", Count: 5}, {UserID: "u1", Username: "alice", Count: 1}},
+			PRAssignmentCounts:   []entity.PRAssignmentCount{{PRID: "pr-2", Title: "Beta", Count: 2}, {PRID: "pr-1", Title: "Alpha", Count: 1}},
+		},
+		entity.StatsSortCountAsc: {
+			UserAssignmentCounts: []entity.UserAssignmentCount{{UserID: "u1", Username: "alice", Count: 1}, {UserID: "u2", Username: "bob", Count: 5}},
+			PRAssignmentCounts:   []entity.PRAssignmentCount{{PRID: "pr-1", Title: "Alpha", Count: 1}, {PRID: "pr-2", Title: "Beta", Count: 2}},
+		},
+		entity.StatsSortName: {
+			UserAssignmentCounts: []entity.UserAssignmentCount{{UserID: "u1", Username: "alice", Count: 1}, {UserID: "u2", Username: "bob", Count: 5}},
+			PRAssignmentCounts:   []entity.PRAssignmentCount{{PRID: "pr-1", Title: "Alpha", Count: 1}, {PRID: "pr-2", Title: "Beta", Count: 2}},
+		},
+	}
+	var captured entity.StatsSort
+	mock := &mockService{
+		getStatsFunc: func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+			captured = filter.Sort
+			if filter.Sort == "" {
+				return ordered[entity.StatsSortCountDesc], nil
+			}
+			return ordered[filter.Sort], nil
+		},
+	}
+	handler := NewHandlers(mock)
+	testCases := []struct {
+		query         string
+		expectedSort  entity.StatsSort
+		expectedUsers []string
+		expectedPRs   []string
+	}{
+		{"", "", []string{"u2", "u1"}, []string{"pr-2", "pr-1"}},
+		{"count_desc", entity.StatsSortCountDesc, []string{"u2", "u1"}, []string{"pr-2", "pr-1"}},
+		{"count_asc", entity.StatsSortCountAsc, []string{"u1", "u2"}, []string{"pr-1", "pr-2"}},
+		{"name", entity.StatsSortName, []string{"u1", "u2"}, []string{"pr-1", "pr-2"}},
+	}
+	for _, tc := range testCases {
+		t.Run("sort="+tc.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/stats?sort="+tc.query, nil)
+			w := httptest.NewRecorder()
+			handler.GetStats(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+			if captured != tc.expectedSort {
+				t.Errorf("Expected sort %q to reach the service, got %q", tc.expectedSort, captured)
+			}
+			var response struct {
+				Stats entity.Stats `json:"stats"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			for i, id := range tc.expectedUsers {
+				if response.Stats.UserAssignmentCounts[i].UserID != id {
+					t.Errorf("Expected user %d to be %s, got %s", i, id, response.Stats.UserAssignmentCounts[i].UserID)
+				}
+			}
+			for i, id := range tc.expectedPRs {
+				if response.Stats.PRAssignmentCounts[i].PRID != id {
+					t.Errorf("Expected PR %d to be %s, got %s", i, id, response.Stats.PRAssignmentCounts[i].PRID)
+				}
+			}
+		})
+	}
+	req := httptest.NewRequest("GET", "/stats?sort=popularity", nil)
+	w := httptest.NewRecorder()
+	handler.GetStats(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown sort, got %d", w.Code)
+	}
+}
+
+func TestHandlers_ListPRs(t *testing.T) {
+	type call struct {
+		status, authorID string
+		limit, offset    int
+	}
+	var captured call
+	mock := &mockService{
+		listPRsFunc: func(status, authorID string, limit, offset int) ([]entity.PullRequest, error) {
+			captured = call{status, authorID, limit, offset}
+			if status == "CLOSED" {
+				return []entity.PullRequest{}, nil
+			}
+			createdAt := "2025-10-24T12:34:56Z"
+			return []entity.PullRequest{
+				{ID: "pr-2", Title: "Second", AuthorID: "u1", Status: "OPEN", CreatedAt: &createdAt, ReviewerCount: 2},
+			}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/pullRequests?status=OPEN&author_id=u1&limit=10&offset=20", nil)
+	w := httptest.NewRecorder()
+	handler.ListPRs(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if captured != (call{"OPEN", "u1", 10, 20}) {
+		t.Errorf("Unexpected service call: %+v", captured)
+	}
+	var response struct {
+		PullRequests []map[string]interface{} `json:"pull_requests"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.PullRequests) != 1 {
+		t.Fatalf("Expected 1 PR, got %d", len(response.PullRequests))
+	}
+	pr := response.PullRequests[0]
+	if pr["pull_request_id"] != "pr-2" || pr["reviewer_count"] != float64(2) || pr["created_at"] != "2025-10-24T12:34:56Z" {
+		t.Errorf("Unexpected PR item: %v", pr)
+	}
+
+	req = httptest.NewRequest("GET", "/pullRequests?status=CLOSED", nil)
+	w = httptest.NewRecorder()
+	handler.ListPRs(w, req)
+	if !strings.Contains(w.Body.String(), `"pull_requests":[]`) {
+		t.Errorf("Expected an empty array, got %s", w.Body.String())
+	}
+	if captured != (call{"CLOSED", "", defaultStatsLimit, defaultStatsOffset}) {
+		t.Errorf("Expected default pagination, got %+v", captured)
+	}
+}
+
+func TestHandlers_SearchPRs(t *testing.T) {
+	var gotQuery string
+	var gotLimit int
+	mock := &mockService{
+		searchPRsFunc: func(query string, limit int) ([]entity.PullRequest, error) {
+			gotQuery, gotLimit = query, limit
+			return []entity.PullRequest{{ID: "pr-1", Title: "Add search", AuthorID: "u1", Status: "OPEN"}}, nil
+		},
+	}
+	handler := NewHandlers(mock)
+	req := httptest.NewRequest("GET", "/pullRequests/search?q=sea", nil)
+	w := httptest.NewRecorder()
+	handler.SearchPRs(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if gotQuery != "sea" || gotLimit != 100 {
+		t.Errorf("Expected search for %q with limit 100, got %q with limit %d", "sea", gotQuery, gotLimit)
+	}
+	var response struct {
+		PullRequests []map[string]interface{} `json:"pull_requests"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if len(response.PullRequests) != 1 || response.PullRequests[0]["pull_request_id"] != "pr-1" {
+		t.Errorf("Unexpected response: %v", response.PullRequests)
+	}
+}
+
+func TestHandlers_SearchPRs_ShortQuery(t *testing.T) {
+	handler := NewHandlers(&mockService{})
+	for _, query := range []string{"", "q=", "q=a", "q=%20a%20"} {
+		t.Run(query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/pullRequests/search?"+query, nil)
+			w := httptest.NewRecorder()
+			handler.SearchPRs(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestHandlers_ListPRs_InvalidParams(t *testing.T) {
+	handler := NewHandlers(&mockService{})
+	for _, query := range []string{"status=DRAFT", "limit=0", "limit=-1", "limit=abc", "offset=-1"} {
+		t.Run(query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/pullRequests?"+query, nil)
+			w := httptest.NewRecorder()
+			handler.ListPRs(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+			}
+		})
+	}
+}
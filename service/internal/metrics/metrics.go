@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	endpoint string
+	status   int
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	total  uint64
+}
+
+type gauge struct {
+	name  string
+	help  string
+	value func(ctx context.Context) (float64, error)
+}
+
+// Registry collects request metrics and renders them in the Prometheus text
+// exposition format.
+type Registry struct {
+	mu        sync.Mutex
+	buckets   []float64
+	requests  map[requestKey]uint64
+	durations map[string]*histogram
+	gauges    []gauge
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		buckets:   DefaultBuckets,
+		requests:  map[requestKey]uint64{},
+		durations: map[string]*histogram{},
+	}
+}
+
+func (reg *Registry) ObserveRequest(endpoint string, status int, duration time.Duration) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.requests[requestKey{endpoint: endpoint, status: status}]++
+	h, ok := reg.durations[endpoint]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(reg.buckets))}
+		reg.durations[endpoint] = h
+	}
+	seconds := duration.Seconds()
+	for i, bound := range reg.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.total++
+}
+
+// RegisterGauge adds a gauge whose value is read on every scrape.
+func (reg *Registry) RegisterGauge(name, help string, value func(ctx context.Context) (float64, error)) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.gauges = append(reg.gauges, gauge{name: name, help: help, value: value})
+}
+
+type dbTimeKey struct{}
+
+// dbTime accumulates the time a request spent in database calls.
+type dbTime struct {
+	nanos atomic.Int64
+	used  atomic.Bool
+}
+
+// ObserveDB adds the time elapsed since start to the request's db timing, so
+// it is reported in the Server-Timing header. Typical use is
+// defer metrics.ObserveDB(ctx, time.Now()). It is a no-op outside Instrument.
+func ObserveDB(ctx context.Context, start time.Time) {
+	if t, ok := ctx.Value(dbTimeKey{}).(*dbTime); ok {
+		t.nanos.Add(int64(time.Since(start)))
+		t.used.Store(true)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	start       time.Time
+	db          *dbTime
+	wroteHeader bool
+}
+
+// WriteHeader adds the Server-Timing header just before the headers go out;
+// total is the handler time up to that point.
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+	rec.Header().Set("Server-Timing", rec.serverTiming())
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *statusRecorder) serverTiming() string {
+	total := fmt.Sprintf("total;dur=%s", formatMillis(time.Since(rec.start)))
+	if !rec.db.used.Load() {
+		return total
+	}
+	return fmt.Sprintf("db;dur=%s, %s", formatMillis(time.Duration(rec.db.nanos.Load())), total)
+}
+
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
+
+// Instrument wraps next so every request is counted by endpoint and response
+// status and its duration is recorded. Responses carry a Server-Timing header
+// with the total duration and, when the handler reached ObserveDB, a db metric.
+func (reg *Registry) Instrument(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		db := &dbTime{}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK, start: start, db: db}
+		next(rec, r.WithContext(context.WithValue(r.Context(), dbTimeKey{}, db)))
+		if !rec.wroteHeader {
+			rec.WriteHeader(http.StatusOK)
+		}
+		reg.ObserveRequest(endpoint, rec.status, time.Since(start))
+	}
+}
+
+func (reg *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	reg.write(r.Context(), w)
+}
+
+func (reg *Registry) write(ctx context.Context, w io.Writer) {
+	reg.mu.Lock()
+	keys := make([]requestKey, 0, len(reg.requests))
+	for key := range reg.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].endpoint != keys[j].endpoint {
+			return keys[i].endpoint < keys[j].endpoint
+		}
+		return keys[i].status < keys[j].status
+	})
+	fmt.Fprintln(w, "# HELP http_requests_total Total HTTP requests by endpoint and status code.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "http_requests_total{endpoint=%q,status=\"%d\"} %d\n", key.endpoint, key.status, reg.requests[key])
+	}
+	endpoints := make([]string, 0, len(reg.durations))
+	for endpoint := range reg.durations {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds HTTP request duration in seconds by endpoint.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+	for _, endpoint := range endpoints {
+		h := reg.durations[endpoint]
+		for i, bound := range reg.buckets {
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{endpoint=%q,le=%q} %d\n", endpoint, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{endpoint=%q,le=\"+Inf\"} %d\n", endpoint, h.total)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{endpoint=%q} %s\n", endpoint, formatFloat(h.sum))
+		fmt.Fprintf(w, "http_request_duration_seconds_count{endpoint=%q} %d\n", endpoint, h.total)
+	}
+	gauges := append([]gauge(nil), reg.gauges...)
+	reg.mu.Unlock()
+
+	for _, g := range gauges {
+		value, err := g.value(ctx)
+		if err != nil {
+			log.Printf("metrics: failed to read gauge %s: %v", g.name, err)
+			continue
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n", g.name, g.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", g.name)
+		fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(value))
+	}
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
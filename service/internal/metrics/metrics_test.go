@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry_InstrumentCountsByEndpointAndStatus(t *testing.T) {
+	reg := NewRegistry()
+	ok := reg.Instrument("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	missing := reg.Instrument("/team/get", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	ok(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	ok(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	missing(httptest.NewRecorder(), httptest.NewRequest("GET", "/team/get", nil))
+
+	w := httptest.NewRecorder()
+	reg.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, line := range []string{
+		`http_requests_total{endpoint="/health",status="200"} 2`,
+		`http_requests_total{endpoint="/team/get",status="404"} 1`,
+		`http_request_duration_seconds_bucket{endpoint="/health",le="+Inf"} 2`,
+		`http_request_duration_seconds_count{endpoint="/team/get"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %q in output:\n%s", line, body)
+		}
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestRegistry_InstrumentServerTiming(t *testing.T) {
+	reg := NewRegistry()
+	stats := reg.Instrument("/stats", func(w http.ResponseWriter, r *http.Request) {
+		ObserveDB(r.Context(), time.Now().Add(-5*time.Millisecond))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"total_assignments":0}`))
+	})
+	health := reg.Instrument("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	parse := func(header string) map[string]float64 {
+		metrics := map[string]float64{}
+		for _, entry := range strings.Split(header, ",") {
+			name, params, ok := strings.Cut(strings.TrimSpace(entry), ";")
+			if !ok {
+				t.Fatalf("Unparseable Server-Timing entry %q in %q", entry, header)
+			}
+			value, ok := strings.CutPrefix(params, "dur=")
+			if !ok {
+				t.Fatalf("Missing dur in %q", entry)
+			}
+			dur, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatalf("Invalid dur in %q: %v", entry, err)
+			}
+			metrics[name] = dur
+		}
+		return metrics
+	}
+
+	w := httptest.NewRecorder()
+	stats(w, httptest.NewRequest("GET", "/stats", nil))
+	timing := parse(w.Header().Get("Server-Timing"))
+	if timing["db"] < 5 {
+		t.Errorf("Expected db of at least 5ms, got %v", timing)
+	}
+	if _, ok := timing["total"]; !ok {
+		t.Errorf("Expected a total metric, got %v", timing)
+	}
+
+	w = httptest.NewRecorder()
+	health(w, httptest.NewRequest("GET", "/health", nil))
+	timing = parse(w.Header().Get("Server-Timing"))
+	if _, ok := timing["db"]; ok {
+		t.Errorf("Expected no db metric without database calls, got %v", timing)
+	}
+	if _, ok := timing["total"]; !ok {
+		t.Errorf("Expected a total metric, got %v", timing)
+	}
+}
+
+func TestRegistry_HistogramBuckets(t *testing.T) {
+	reg := NewRegistry()
+	reg.ObserveRequest("/stats", http.StatusOK, 20*time.Millisecond)
+	reg.ObserveRequest("/stats", http.StatusOK, 3*time.Second)
+	w := httptest.NewRecorder()
+	reg.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, line := range []string{
+		`http_request_duration_seconds_bucket{endpoint="/stats",le="0.01"} 0`,
+		`http_request_duration_seconds_bucket{endpoint="/stats",le="0.025"} 1`,
+		`http_request_duration_seconds_bucket{endpoint="/stats",le="2.5"} 1`,
+		`http_request_duration_seconds_bucket{endpoint="/stats",le="5"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %q in output:\n%s", line, body)
+		}
+	}
+}
+
+func TestRegistry_Gauges(t *testing.T) {
+	reg := NewRegistry()
+	reg.RegisterGauge("pull_requests_open", "Open pull requests.", func(ctx context.Context) (float64, error) {
+		return 7, nil
+	})
+	reg.RegisterGauge("broken_gauge", "Always fails.", func(ctx context.Context) (float64, error) {
+		return 0, errors.New("database error")
+	})
+	w := httptest.NewRecorder()
+	reg.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, "# TYPE pull_requests_open gauge\npull_requests_open 7\n") {
+		t.Errorf("Expected open PR gauge in output:\n%s", body)
+	}
+	if strings.Contains(body, "broken_gauge") {
+		t.Errorf("Expected failing gauge to be skipped:\n%s", body)
+	}
+}
@@ -0,0 +1,88 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+type Notifier interface {
+	ReviewerAssigned(prID, userID string)
+	// MembershipChanged reports the user ids added to and removed from
+	// teamName once the change is committed.
+	MembershipChanged(teamName string, added, removed []string)
+}
+
+type NopNotifier struct{}
+
+func (NopNotifier) ReviewerAssigned(prID, userID string) {}
+
+func (NopNotifier) MembershipChanged(teamName string, added, removed []string) {}
+
+type HTTPNotifier struct {
+	url    string
+	client *http.Client
+}
+
+type reviewerAssignedEvent struct {
+	Event         string `json:"event"`
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+}
+
+type membershipChangedEvent struct {
+	Event    string   `json:"event"`
+	TeamName string   `json:"team_name"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+}
+
+func NewHTTPNotifier(url string) *HTTPNotifier {
+	return &HTTPNotifier{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// ReviewerAssigned posts the event in the background so a slow or failing
+// webhook never delays or fails the request that triggered it.
+func (n *HTTPNotifier) ReviewerAssigned(prID, userID string) {
+	go n.post("reviewer_assigned", reviewerAssignedEvent{
+		Event:         "reviewer_assigned",
+		PullRequestID: prID,
+		UserID:        userID,
+	})
+}
+
+// MembershipChanged posts the event in the background, like ReviewerAssigned.
+// Nil lists are sent as empty arrays.
+func (n *HTTPNotifier) MembershipChanged(teamName string, added, removed []string) {
+	if added == nil {
+		added = []string{}
+	}
+	if removed == nil {
+		removed = []string{}
+	}
+	go n.post("team_membership_changed", membershipChangedEvent{
+		Event:    "team_membership_changed",
+		TeamName: teamName,
+		Added:    added,
+		Removed:  removed,
+	})
+}
+
+func (n *HTTPNotifier) post(name string, event interface{}) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("notifier: failed to encode %s event: %v", name, err)
+		return
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("notifier: failed to deliver %s event: %v", name, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		log.Printf("notifier: webhook returned %d for %s event", resp.StatusCode, name)
+	}
+}
@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHTTPNotifier_ReviewerAssigned(t *testing.T) {
+	received := make(chan reviewerAssignedEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event reviewerAssignedEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		received <- event
+	}))
+	defer server.Close()
+	NewHTTPNotifier(server.URL).ReviewerAssigned("pr-1", "u2")
+	select {
+	case event := <-received:
+		if event.Event != "reviewer_assigned" || event.PullRequestID != "pr-1" || event.UserID != "u2" {
+			t.Errorf("Unexpected payload: %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Webhook was not called")
+	}
+}
+
+func TestHTTPNotifier_MembershipChanged(t *testing.T) {
+	received := make(chan membershipChangedEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event membershipChangedEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		received <- event
+	}))
+	defer server.Close()
+	NewHTTPNotifier(server.URL).MembershipChanged("backend", []string{"u1", "u2"}, nil)
+	select {
+	case event := <-received:
+		if event.Event != "team_membership_changed" || event.TeamName != "backend" {
+			t.Errorf("Unexpected payload: %+v", event)
+		}
+		if !reflect.DeepEqual(event.Added, []string{"u1", "u2"}) || event.Removed == nil || len(event.Removed) != 0 {
+			t.Errorf("Expected added [u1 u2] and empty removed, got %v and %v", event.Added, event.Removed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Webhook was not called")
+	}
+}
+
+func TestHTTPNotifier_MembershipChanged_EndpointDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+	done := make(chan struct{})
+	go func() {
+		NewHTTPNotifier(url).MembershipChanged("backend", nil, []string{"u1"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("MembershipChanged blocked on an unreachable webhook")
+	}
+}
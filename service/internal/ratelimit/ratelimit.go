@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is an in-memory token bucket per client key. Each bucket holds up to
+// perMinute tokens and refills continuously at perMinute tokens per minute.
+type Limiter struct {
+	mu      sync.Mutex
+	burst   float64
+	rate    float64
+	now     func() time.Time
+	buckets map[string]*bucket
+}
+
+func New(perMinute int) *Limiter {
+	return NewWithClock(perMinute, time.Now)
+}
+
+func NewWithClock(perMinute int, now func() time.Time) *Limiter {
+	return &Limiter{
+		burst:   float64(perMinute),
+		rate:    float64(perMinute) / time.Minute.Seconds(),
+		now:     now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it returns
+// false and how long until the next token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Sweep evicts buckets that have been idle long enough to refill completely;
+// dropping them is indistinguishable from keeping them.
+func (l *Limiter) Sweep() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// StartSweeper runs Sweep every interval until ctx is cancelled.
+func (l *Limiter) StartSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				l.Sweep()
+			}
+		}
+	}()
+}
+
+// ClientKey identifies the caller by its X-Client-Id header, falling back to
+// the remote IP.
+func ClientKey(r *http.Request) string {
+	if id := r.Header.Get("X-Client-Id"); id != "" {
+		return id
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type errorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Middleware answers 429 RATE_LIMITED with a Retry-After header once the
+// client's bucket is empty. A nil Limiter disables limiting.
+func (l *Limiter) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := l.Allow(ClientKey(r))
+		if ok {
+			next(w, r)
+			return
+		}
+		seconds := int(math.Ceil(wait.Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+		var response errorResponse
+		response.Error.Code = "RATE_LIMITED"
+		response.Error.Message = "too many requests"
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(response)
+	}
+}
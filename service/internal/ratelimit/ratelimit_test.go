@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestMiddleware_BurstThenRecover(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewWithClock(3, clock.Now)
+	handler := limiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/pullRequest/create", nil)
+		req.Header.Set("X-Client-Id", "ci-bot")
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+	for i := 0; i < 3; i++ {
+		if w := send(); w.Code != http.StatusCreated {
+			t.Fatalf("Request %d: expected status 201, got %d", i+1, w.Code)
+		}
+	}
+	w := send()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429 past the limit, got %d", w.Code)
+	}
+	if retry := w.Header().Get("Retry-After"); retry != "20" {
+		t.Errorf("Expected Retry-After 20, got %q", retry)
+	}
+	var response errorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Error.Code != "RATE_LIMITED" {
+		t.Errorf("Expected error code RATE_LIMITED, got %q", response.Error.Code)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		if w := send(); w.Code != http.StatusCreated {
+			t.Fatalf("Request %d after the window: expected status 201, got %d", i+1, w.Code)
+		}
+	}
+}
+
+func TestAllow_KeysAreIndependent(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewWithClock(1, clock.Now)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Fatal("Expected the first request from a to pass")
+	}
+	if ok, _ := limiter.Allow("a"); ok {
+		t.Error("Expected the second request from a to be limited")
+	}
+	if ok, _ := limiter.Allow("b"); !ok {
+		t.Error("Expected b to have its own bucket")
+	}
+}
+
+func TestSweep_EvictsOnlyRefilledBuckets(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewWithClock(60, clock.Now)
+	limiter.Allow("idle")
+	clock.now = clock.now.Add(30 * time.Second)
+	limiter.Allow("busy")
+	limiter.Allow("busy")
+	clock.now = clock.now.Add(time.Second)
+	limiter.Sweep()
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Error("Expected the refilled idle bucket to be evicted")
+	}
+	if _, ok := limiter.buckets["busy"]; !ok {
+		t.Error("Expected the partially drained bucket to be kept")
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.RemoteAddr = "10.0.0.7:51234"
+	if key := ClientKey(req); key != "10.0.0.7" {
+		t.Errorf("Expected remote IP, got %q", key)
+	}
+	req.Header.Set("X-Client-Id", "ci-bot")
+	if key := ClientKey(req); key != "ci-bot" {
+		t.Errorf("Expected X-Client-Id, got %q", key)
+	}
+}
+
+func TestMiddleware_NilLimiterPassesThrough(t *testing.T) {
+	var limiter *Limiter
+	called := false
+	limiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	if !called {
+		t.Error("Expected a nil limiter to call the handler")
+	}
+}
@@ -1,55 +1,255 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 
 	"service/internal/entity"
+	"service/internal/metrics"
 )
 
+// HistoricalLoadWeight is how much each past assignment (any PR status) adds to
+// a candidate's load relative to one assignment on a currently open PR.
+const HistoricalLoadWeight = 0.1
+
 type Repository interface {
-	CreateTeam(team *entity.Team, members []entity.User) error
-	GetTeam(teamName string) (*entity.Team, []entity.User, error)
-	SetUserActive(userID string, isActive bool) (*entity.User, error)
-	GetUserReviewPRs(userID string) ([]entity.PullRequest, error)
-	CreatePR(pr *entity.PullRequest, reviewerIDs []string) error
-	MergePR(prID string) (*entity.PullRequest, error)
-	GetPR(prID string) (*entity.PullRequest, error)
-	GetPRReviewers(prID string) ([]entity.User, error)
-	ReassignReviewer(prID, oldUserID string) (string, error)
-	GetCandidateReviewers(authorID string, limit int) ([]string, error)
-	GetStats() (*entity.Stats, error)
+	CreateTeam(ctx context.Context, team *entity.Team, members []entity.User) error
+	CreateTeamsBulk(ctx context.Context, teams []entity.TeamWithMembers) ([]entity.ImportResult, error)
+	GetTeam(ctx context.Context, teamName string) (*entity.Team, []entity.User, error)
+	RenameTeam(ctx context.Context, oldName, newName string) error
+	GetUser(ctx context.Context, userID string) (*entity.User, error)
+	GetUserLoad(ctx context.Context, userID string) (open, total int, err error)
+	SetUserActive(ctx context.Context, userID string, isActive bool) (*entity.User, error)
+	DeactivateAndRetire(ctx context.Context, userID string) ([]entity.Reassignment, error)
+	ReassignAllForUser(ctx context.Context, userID string, bestEffort bool) ([]entity.ReassignResult, error)
+	SetTeamMembersActive(ctx context.Context, teamName string, isActive bool) ([]entity.User, []entity.Reassignment, error)
+	GetUserReviewPRs(ctx context.Context, userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error)
+	CreatePR(ctx context.Context, pr *entity.PullRequest, reviewerIDs []string) error
+	MergePR(ctx context.Context, prID string) (*entity.PullRequest, error)
+	ClosePR(ctx context.Context, prID string) (*entity.PullRequest, error)
+	ReopenPR(ctx context.Context, prID string) (*entity.PullRequest, error)
+	GetPR(ctx context.Context, prID string) (*entity.PullRequest, error)
+	ListPRs(ctx context.Context, status, authorID string, limit, offset int) ([]entity.PullRequest, error)
+	SearchPRs(ctx context.Context, query string, limit int) ([]entity.PullRequest, error)
+	GetPRReviewers(ctx context.Context, prID string) ([]entity.User, error)
+	GetReviewersForPRs(ctx context.Context, prIDs []string) (map[string][]entity.User, error)
+	ReassignReviewer(ctx context.Context, prID, oldUserID string) (string, error)
+	GetReassignmentHistory(ctx context.Context, prID string) ([]entity.Reassignment, error)
+	GetPRTimeline(ctx context.Context, prID string) ([]entity.TimelineEvent, error)
+	FetchUnpublishedEvents(ctx context.Context, limit int) ([]entity.Event, error)
+	MarkEventPublished(ctx context.Context, id int64) error
+	PreviewReassign(ctx context.Context, prID, oldUserID string) (string, error)
+	AddReviewer(ctx context.Context, prID, userID string) error
+	RemoveReviewer(ctx context.Context, prID, userID string) error
+	RespondReview(ctx context.Context, prID, userID string, accept bool) (string, error)
+	GetCandidateReviewers(ctx context.Context, authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error)
+	GetUserTeams(ctx context.Context, userID string) ([]entity.Team, error)
+	EvaluateCandidates(ctx context.Context, authorID string, excludeIDs []string, teamName string) ([]entity.CandidateEvaluation, error)
+	GetMissingUserIDs(ctx context.Context, userIDs []string) ([]string, error)
+	CountActiveTeammates(ctx context.Context, userID string) (int, error)
+	CountTeammates(ctx context.Context, userID string) (int, error)
+	CountActiveMembers(ctx context.Context, teamName string) (int, error)
+	GetTeamReviewerCount(ctx context.Context, teamName string) (int, error)
+	SetTeamReviewerCount(ctx context.Context, teamName string, count int) error
+	GetStatsPaged(ctx context.Context, limit, offset int, filter entity.StatsFilter) (*entity.Stats, error)
+	GetTeamStats(ctx context.Context, teamName string) (*entity.Stats, error)
+	GetUserStats(ctx context.Context, userID string) (*entity.UserAssignmentCount, error)
+	GetConcentration(ctx context.Context) (*entity.Concentration, error)
+	GetOperationalCounts(ctx context.Context) (*entity.OperationalCounts, error)
+	GetIdempotencyRecord(ctx context.Context, key string) (string, []byte, error)
+	SaveIdempotencyRecord(ctx context.Context, key, prID string, response []byte) error
+	GetReviewerWeeklySummary(ctx context.Context, userID string, weeks int) ([]entity.WeekCount, error)
 }
 
+// DefaultMaxReviewersPerPR is how many active reviewers AddReviewer allows on
+// one PR when Config leaves it unset.
+const DefaultMaxReviewersPerPR = 5
+
 type RepositoryImpl struct {
-	db *sql.DB
+	db                *sql.DB
+	now               func() time.Time
+	maxReviewerLoad   int
+	strategy          AssignmentStrategy
+	uniqueUsernames   bool
+	maxReviewersPerPR int
+}
+
+type Config struct {
+	Now func() time.Time
+	// MaxReviewerLoad caps concurrent OPEN-PR assignments per candidate; 0 means unlimited.
+	MaxReviewerLoad int
+	// Strategy orders eligible candidates; LeastLoaded when nil.
+	Strategy AssignmentStrategy
+	// UniqueUsernames rejects team batches in which two members share a username.
+	UniqueUsernames bool
+	// MaxReviewersPerPR caps active reviewers on a PR for AddReviewer;
+	// DefaultMaxReviewersPerPR when 0.
+	MaxReviewersPerPR int
+}
+
+// AssignmentStrategy decides the order in which eligible team members are
+// offered as reviewers by GetCandidateReviewers.
+type AssignmentStrategy interface {
+	candidateOrder() string
+}
+
+// LeastLoaded prefers members with the fewest open assignments, weighted by
+// their history, breaking ties by user_id.
+type LeastLoaded struct{}
+
+func (LeastLoaded) candidateOrder() string {
+	return "COUNT(pr.pull_request_id) + $3::float8 * COUNT(r.user_id) ASC, u.user_id"
+}
+
+// RoundRobin prefers members whose most recent assignment is the oldest, so
+// reviews rotate through the team instead of always favouring low user_ids.
+type RoundRobin struct{}
+
+func (RoundRobin) candidateOrder() string {
+	return `(SELECT MAX(ra.assigned_at) FROM reviewers ra WHERE ra.user_id = u.user_id) ASC NULLS FIRST,
+            COUNT(pr.pull_request_id) + $3::float8 * COUNT(r.user_id) ASC, u.user_id`
+}
+
+// authorTeamFallback selects the team recorded for a PR whose author named
+// none: the author's first team by name, as GetUser reports it. authorExpr is
+// the SQL expression holding the author's user_id.
+func authorTeamFallback(authorExpr string) string {
+	return `(SELECT ftm.team_id FROM team_members ftm
+			JOIN teams ft ON ft.team_id = ftm.team_id
+			WHERE ftm.user_id = ` + authorExpr + `
+			ORDER BY ft.team_name
+			LIMIT 1)`
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 }
 
 func NewRepository(db *sql.DB) Repository {
-	return &RepositoryImpl{db: db}
+	return NewRepositoryWithConfig(db, Config{})
+}
+
+func NewRepositoryWithClock(db *sql.DB, now func() time.Time) Repository {
+	return NewRepositoryWithConfig(db, Config{Now: now})
+}
+
+func NewRepositoryWithConfig(db *sql.DB, cfg Config) Repository {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Strategy == nil {
+		cfg.Strategy = LeastLoaded{}
+	}
+	if cfg.MaxReviewersPerPR == 0 {
+		cfg.MaxReviewersPerPR = DefaultMaxReviewersPerPR
+	}
+	return &RepositoryImpl{
+		db:                db,
+		now:               cfg.Now,
+		maxReviewerLoad:   cfg.MaxReviewerLoad,
+		strategy:          cfg.Strategy,
+		uniqueUsernames:   cfg.UniqueUsernames,
+		maxReviewersPerPR: cfg.MaxReviewersPerPR,
+	}
 }
 
-func (r *RepositoryImpl) CreateTeam(team *entity.Team, members []entity.User) error {
-	tx, err := r.db.Begin()
+func (r *RepositoryImpl) CreateTeam(ctx context.Context, team *entity.Team, members []entity.User) error {
+	if err := r.checkUniqueUsernames(members); err != nil {
+		return err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
+	if err := createTeamTx(ctx, tx, team, members); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateTeamsBulk creates every team in a single transaction, isolating each
+// one in a savepoint so that a failing team is reported in its ImportResult
+// without aborting the rest of the batch.
+func (r *RepositoryImpl) CreateTeamsBulk(ctx context.Context, teams []entity.TeamWithMembers) ([]entity.ImportResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	results := make([]entity.ImportResult, 0, len(teams))
+	for _, t := range teams {
+		if err := r.checkUniqueUsernames(t.Members); err != nil {
+			results = append(results, entity.ImportResult{TeamName: t.TeamName, Err: err})
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT team_import"); err != nil {
+			return nil, err
+		}
+		team := &entity.Team{Name: t.TeamName}
+		if err := createTeamTx(ctx, tx, team, t.Members); err != nil {
+			if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT team_import"); rbErr != nil {
+				return nil, rbErr
+			}
+			results = append(results, entity.ImportResult{TeamName: t.TeamName, Err: err})
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT team_import"); err != nil {
+			return nil, err
+		}
+		results = append(results, entity.ImportResult{TeamName: t.TeamName, TeamID: team.ID})
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// checkUniqueUsernames returns ErrDuplicateUsername when UniqueUsernames is
+// enabled and two different user_ids share a username, compared case-insensitively.
+func (r *RepositoryImpl) checkUniqueUsernames(members []entity.User) error {
+	if !r.uniqueUsernames {
+		return nil
+	}
+	owners := make(map[string]string, len(members))
+	for _, member := range members {
+		name := strings.ToLower(member.Username)
+		if owner, ok := owners[name]; ok && owner != member.ID {
+			return entity.ErrDuplicateUsername
+		}
+		owners[name] = member.ID
+	}
+	return nil
+}
+
+func createTeamTx(ctx context.Context, tx *sql.Tx, team *entity.Team, members []entity.User) error {
 	var existingTeamID string
-	err = tx.QueryRow("SELECT team_id FROM teams WHERE LOWER(team_name) = LOWER($1)", team.Name).Scan(&existingTeamID)
+	err := tx.QueryRowContext(ctx, "SELECT team_id FROM teams WHERE LOWER(team_name) = LOWER($1)", team.Name).Scan(&existingTeamID)
 	if err == nil {
 		return entity.ErrTeamExists
 	} else if err != sql.ErrNoRows {
 		return err
 	}
-	err = tx.QueryRow(
-		"INSERT INTO teams (team_name) VALUES ($1) RETURNING team_id",
-		team.Name,
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO teams (team_name, default_reviewers) VALUES ($1, NULLIF($2, 0)) RETURNING team_id",
+		team.Name, team.DefaultReviewers,
 	).Scan(&team.ID)
 	if err != nil {
 		return err
 	}
 	for _, member := range members {
-		_, err = tx.Exec(`
+		_, err = tx.ExecContext(ctx, `
 			INSERT INTO users (user_id, username, is_active) 
 			VALUES ($1, $2, $3)
 			ON CONFLICT (user_id) DO UPDATE SET 
@@ -59,7 +259,7 @@ func (r *RepositoryImpl) CreateTeam(team *entity.Team, members []entity.User) er
 		if err != nil {
 			return err
 		}
-		_, err = tx.Exec(
+		_, err = tx.ExecContext(ctx,
 			"INSERT INTO team_members (team_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
 			team.ID, member.ID,
 		)
@@ -67,12 +267,49 @@ func (r *RepositoryImpl) CreateTeam(team *entity.Team, members []entity.User) er
 			return err
 		}
 	}
+	return nil
+}
+
+// RenameTeam changes a team's name. Names are compared case-insensitively, so
+// a team may be renamed to a different casing of its own name.
+func (r *RepositoryImpl) RenameTeam(ctx context.Context, oldName, newName string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var teamID string
+	err = tx.QueryRowContext(ctx,
+		"SELECT team_id FROM teams WHERE LOWER(team_name) = LOWER($1) FOR UPDATE",
+		oldName,
+	).Scan(&teamID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.ErrNotFound
+		}
+		return err
+	}
+	var taken bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM teams WHERE LOWER(team_name) = LOWER($1) AND team_id <> $2)",
+		newName, teamID,
+	).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return entity.ErrTeamExists
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE teams SET team_name = $1 WHERE team_id = $2", newName, teamID)
+	if err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
-func (r *RepositoryImpl) GetTeam(teamName string) (*entity.Team, []entity.User, error) {
+func (r *RepositoryImpl) GetTeam(ctx context.Context, teamName string) (*entity.Team, []entity.User, error) {
 	var team entity.Team
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(ctx,
 		"SELECT team_id, team_name FROM teams WHERE LOWER(team_name) = LOWER($1)",
 		teamName,
 	).Scan(&team.ID, &team.Name)
@@ -82,7 +319,7 @@ func (r *RepositoryImpl) GetTeam(teamName string) (*entity.Team, []entity.User,
 		}
 		return nil, nil, err
 	}
-	rows, err := r.db.Query(`
+	rows, err := r.db.QueryContext(ctx, `
 		SELECT u.user_id, u.username, u.is_active 
 		FROM users u
 		JOIN team_members tm ON u.user_id = tm.user_id
@@ -104,9 +341,48 @@ func (r *RepositoryImpl) GetTeam(teamName string) (*entity.Team, []entity.User,
 	return &team, members, nil
 }
 
-func (r *RepositoryImpl) SetUserActive(userID string, isActive bool) (*entity.User, error) {
+// GetUser loads a user and their team name without modifying anything. A user
+// in several teams gets the first by name, as GetUserTeams lists them.
+func (r *RepositoryImpl) GetUser(ctx context.Context, userID string) (*entity.User, error) {
+	var user entity.User
+	err := r.db.QueryRowContext(ctx, `
+		SELECT u.user_id, u.username, u.is_active, COALESCE(t.team_name, '')
+		FROM users u
+		LEFT JOIN team_members tm ON u.user_id = tm.user_id
+		LEFT JOIN teams t ON tm.team_id = t.team_id
+		WHERE u.user_id = $1
+		ORDER BY t.team_name
+		LIMIT 1
+	`, userID).Scan(&user.ID, &user.Username, &user.IsActive, &user.TeamName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserLoad counts userID's active review assignments, on OPEN PRs and
+// overall, the same way GetCandidateReviewers does.
+func (r *RepositoryImpl) GetUserLoad(ctx context.Context, userID string) (open, total int, err error) {
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COUNT(pr.pull_request_id), COUNT(r.user_id)
+		FROM users u
+		LEFT JOIN reviewers r ON u.user_id = r.user_id AND r.is_active = true
+		LEFT JOIN pull_requests pr ON r.pull_request_id = pr.pull_request_id AND pr.status = 'OPEN'
+		WHERE u.user_id = $1
+		GROUP BY u.user_id
+	`, userID).Scan(&open, &total)
+	if err == sql.ErrNoRows {
+		return 0, 0, entity.ErrNotFound
+	}
+	return open, total, err
+}
+
+func (r *RepositoryImpl) SetUserActive(ctx context.Context, userID string, isActive bool) (*entity.User, error) {
 	var user entity.User
-	err := r.db.QueryRow(`
+	err := r.db.QueryRowContext(ctx, `
 		UPDATE users SET is_active = $1 
 		WHERE user_id = $2 
 		RETURNING user_id, username, is_active
@@ -117,7 +393,7 @@ func (r *RepositoryImpl) SetUserActive(userID string, isActive bool) (*entity.Us
 		}
 		return nil, err
 	}
-	err = r.db.QueryRow(`
+	err = r.db.QueryRowContext(ctx, `
 		SELECT t.team_name 
 		FROM teams t
 		JOIN team_members tm ON t.team_id = tm.team_id
@@ -129,151 +405,1032 @@ func (r *RepositoryImpl) SetUserActive(userID string, isActive bool) (*entity.Us
 	return &user, nil
 }
 
-func (r *RepositoryImpl) GetUserReviewPRs(userID string) ([]entity.PullRequest, error) {
-	rows, err := r.db.Query(`
-		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status
+// DeactivateAndRetire marks the user inactive, removes them from every team and
+// moves them off the OPEN PRs they review. Reviewer rows are deactivated rather
+// than deleted so merged-PR history stays intact for stats. PRs without an
+// eligible replacement are reported with an empty NewUserID.
+func (r *RepositoryImpl) DeactivateAndRetire(ctx context.Context, userID string) ([]entity.Reassignment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var lockedID string
+	err = tx.QueryRowContext(ctx, "SELECT user_id FROM users WHERE user_id = $1 FOR UPDATE", userID).Scan(&lockedID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
+	}
+	reassignments, err := r.moveOffOpenPRs(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM team_members WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET is_active = false WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return reassignments, nil
+}
+
+// moveOffOpenPRs deactivates userID's reviewer rows on OPEN PRs and assigns a
+// replacement where one is available. PRs without an eligible replacement are
+// reported with an empty NewUserID.
+func (r *RepositoryImpl) moveOffOpenPRs(ctx context.Context, tx *sql.Tx, userID string) ([]entity.Reassignment, error) {
+	prIDs, err := openReviewPRIDs(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	reassignments := make([]entity.Reassignment, 0, len(prIDs))
+	for _, prID := range prIDs {
+		if err := lockPR(ctx, tx, prID); err != nil {
+			return nil, err
+		}
+		newUserID, err := r.findReplacement(ctx, tx, prID, userID)
+		if err != nil && err != entity.ErrNoCandidate && err != entity.ErrAuthorNoTeam {
+			return nil, err
+		}
+		if newUserID != "" {
+			if err := swapReviewer(ctx, tx, prID, userID, newUserID); err != nil {
+				return nil, err
+			}
+		} else {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE reviewers SET is_active = false 
+				WHERE pull_request_id = $1 AND user_id = $2
+			`, prID, userID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		reassignments = append(reassignments, entity.Reassignment{PRID: prID, OldUserID: userID, NewUserID: newUserID})
+	}
+	return reassignments, nil
+}
+
+// openReviewPRIDs lists the OPEN PRs on which userID is an active reviewer.
+func openReviewPRIDs(ctx context.Context, tx *sql.Tx, userID string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT r.pull_request_id
+		FROM reviewers r
+		JOIN pull_requests pr ON r.pull_request_id = pr.pull_request_id
+		WHERE r.user_id = $1 AND r.is_active = true AND pr.status = 'OPEN'
+		ORDER BY r.pull_request_id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var prIDs []string
+	for rows.Next() {
+		var prID string
+		if err := rows.Scan(&prID); err != nil {
+			return nil, err
+		}
+		prIDs = append(prIDs, prID)
+	}
+	return prIDs, rows.Err()
+}
+
+// ReassignAllForUser reassigns userID off every OPEN PR they review in one
+// transaction. When a PR has no replacement, bestEffort leaves the user on it
+// and records the failure in its result; otherwise nothing is committed and
+// ErrNoCandidate is returned with the results up to and including that PR.
+func (r *RepositoryImpl) ReassignAllForUser(ctx context.Context, userID string, bestEffort bool) ([]entity.ReassignResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)", userID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, entity.ErrNotFound
+	}
+	prIDs, err := openReviewPRIDs(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]entity.ReassignResult, 0, len(prIDs))
+	for _, prID := range prIDs {
+		if err := lockPR(ctx, tx, prID); err != nil {
+			return nil, err
+		}
+		newUserID, err := r.findReplacement(ctx, tx, prID, userID)
+		switch err {
+		case nil:
+		case entity.ErrNoCandidate, entity.ErrAuthorNoTeam:
+			code := "NO_CANDIDATE"
+			if err == entity.ErrAuthorNoTeam {
+				code = "AUTHOR_NO_TEAM"
+			}
+			results = append(results, entity.ReassignResult{PRID: prID, Error: code})
+			if !bestEffort {
+				return results, entity.ErrNoCandidate
+			}
+			continue
+		default:
+			return nil, err
+		}
+		if err := swapReviewer(ctx, tx, prID, userID, newUserID); err != nil {
+			return nil, err
+		}
+		results = append(results, entity.ReassignResult{PRID: prID, NewUserID: newUserID})
+	}
+	return results, tx.Commit()
+}
+
+// SetTeamMembersActive sets is_active for every member of teamName and returns
+// the updated users. Deactivated members are moved off the OPEN PRs they
+// review, as in DeactivateAndRetire, but stay in the team.
+func (r *RepositoryImpl) SetTeamMembersActive(ctx context.Context, teamName string, isActive bool) ([]entity.User, []entity.Reassignment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	var teamID int
+	err = tx.QueryRowContext(ctx, "SELECT team_id FROM teams WHERE LOWER(team_name) = LOWER($1) FOR UPDATE", teamName).Scan(&teamID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, entity.ErrNotFound
+		}
+		return nil, nil, err
+	}
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE users SET is_active = $2
+		WHERE user_id IN (SELECT user_id FROM team_members WHERE team_id = $1)
+		RETURNING user_id, username, is_active
+	`, teamID, isActive)
+	if err != nil {
+		return nil, nil, err
+	}
+	users := []entity.User{}
+	for rows.Next() {
+		user := entity.User{TeamName: teamName}
+		if err := rows.Scan(&user.ID, &user.Username, &user.IsActive); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		users = append(users, user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	reassignments := []entity.Reassignment{}
+	if !isActive {
+		for _, user := range users {
+			moved, err := r.moveOffOpenPRs(ctx, tx, user.ID)
+			if err != nil {
+				return nil, nil, err
+			}
+			reassignments = append(reassignments, moved...)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return users, reassignments, nil
+}
+
+// GetUserReviewPRs returns the PRs userID actively reviews, newest first; an
+// empty status matches every status. With page.Limit > 0 it returns one page
+// starting after page.After, plus a cursor for the next page when more rows
+// remain.
+func (r *RepositoryImpl) GetUserReviewPRs(ctx context.Context, userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+	var afterCreatedAt, afterID, limit interface{}
+	if page.After != nil {
+		afterCreatedAt, afterID = page.After.CreatedAt, page.After.PRID
+	}
+	if page.Limit > 0 {
+		// One extra row tells us whether another page follows.
+		limit = page.Limit + 1
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status, pr.created_at
 		FROM pull_requests pr
 		JOIN reviewers r ON pr.pull_request_id = r.pull_request_id
 		WHERE r.user_id = $1 AND r.is_active = true
-	`, userID)
+			AND ($2::text = '' OR pr.status = $2)
+			AND ($3::timestamptz IS NULL OR (pr.created_at, pr.pull_request_id) < ($3::timestamptz, $4::text))
+		ORDER BY pr.created_at DESC, pr.pull_request_id DESC
+		LIMIT $5::int
+	`, userID, status, afterCreatedAt, afterID, limit)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 	var prs []entity.PullRequest
 	for rows.Next() {
 		var pr entity.PullRequest
-		err := rows.Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status)
+		err := rows.Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status, &pr.CreatedAt)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		prs = append(prs, pr)
 	}
-	return prs, nil
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if page.Limit == 0 || len(prs) <= page.Limit {
+		return prs, nil, nil
+	}
+	prs = prs[:page.Limit]
+	last := prs[len(prs)-1]
+	next := &entity.ReviewCursor{PRID: last.ID}
+	if last.CreatedAt != nil {
+		next.CreatedAt = *last.CreatedAt
+	}
+	return prs, next, nil
 }
 
-func (r *RepositoryImpl) CreatePR(pr *entity.PullRequest, reviewerIDs []string) error {
-	tx, err := r.db.Begin()
+func (r *RepositoryImpl) CreatePR(ctx context.Context, pr *entity.PullRequest, reviewerIDs []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 	var existingPRID string
-	err = tx.QueryRow("SELECT pull_request_id FROM pull_requests WHERE pull_request_id = $1", pr.ID).Scan(&existingPRID)
+	err = tx.QueryRowContext(ctx, "SELECT pull_request_id FROM pull_requests WHERE pull_request_id = $1", pr.ID).Scan(&existingPRID)
 	if err == nil {
 		return entity.ErrPRExists
 	} else if err != sql.ErrNoRows {
 		return err
 	}
-	_, err = tx.Exec(`
-		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status)
-		VALUES ($1, $2, $3, $4)
-	`, pr.ID, pr.Title, pr.AuthorID, "OPEN")
+	var authorExists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)", pr.AuthorID).Scan(&authorExists)
 	if err != nil {
 		return err
 	}
-	for _, reviewerID := range reviewerIDs {
-		_, err = tx.Exec(`
-			INSERT INTO reviewers (pull_request_id, user_id, is_active)
-			VALUES ($1, $2, true)
-		`, pr.ID, reviewerID)
-		if err != nil {
+	if !authorExists {
+		return entity.ErrNotFound
+	}
+	var teamID sql.NullInt64
+	if pr.TeamName != "" {
+		err = tx.QueryRowContext(ctx, "SELECT team_id FROM teams WHERE LOWER(team_name) = LOWER($1)", pr.TeamName).Scan(&teamID)
+		if err == sql.ErrNoRows {
+			return entity.ErrNotFound
+		} else if err != nil {
 			return err
 		}
 	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, team_id)
+		VALUES ($1, $2, $3, $4, COALESCE(
+			$5::int,
+			`+authorTeamFallback("$3")+`
+		))
+	`, pr.ID, pr.Title, pr.AuthorID, "OPEN", teamID)
+	if err != nil {
+		return err
+	}
+	err = recordEvent(ctx, tx, entity.EventReviewersAssigned, map[string]interface{}{
+		"pull_request_id": pr.ID,
+		"author_id":       pr.AuthorID,
+		"reviewers":       reviewerIDs,
+	})
+	if err != nil {
+		return err
+	}
+	if err := insertReviewers(ctx, tx, pr.ID, reviewerIDs); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
-func (r *RepositoryImpl) MergePR(prID string) (*entity.PullRequest, error) {
-    var pr entity.PullRequest
-    err := r.db.QueryRow(`
+// insertReviewers assigns all reviewerIDs to prID with a single multi-row
+// INSERT, so one unknown user id fails the whole statement.
+func insertReviewers(ctx context.Context, tx *sql.Tx, prID string, reviewerIDs []string) error {
+	if len(reviewerIDs) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(reviewerIDs))
+	args := make([]interface{}, 0, len(reviewerIDs)+1)
+	args = append(args, prID)
+	for i, reviewerID := range reviewerIDs {
+		placeholders[i] = "($1, $" + strconv.Itoa(i+2) + ", true)"
+		args = append(args, reviewerID)
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO reviewers (pull_request_id, user_id, is_active)
+		VALUES `+strings.Join(placeholders, ", "), args...)
+	return err
+}
+
+func (r *RepositoryImpl) GetIdempotencyRecord(ctx context.Context, key string) (string, []byte, error) {
+	var prID string
+	var response []byte
+	err := r.db.QueryRowContext(ctx,
+		"SELECT pull_request_id, response FROM idempotency_keys WHERE idempotency_key = $1",
+		key,
+	).Scan(&prID, &response)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil, entity.ErrNotFound
+		}
+		return "", nil, err
+	}
+	return prID, response, nil
+}
+
+func (r *RepositoryImpl) SaveIdempotencyRecord(ctx context.Context, key, prID string, response []byte) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (idempotency_key, pull_request_id, response)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (idempotency_key) DO NOTHING
+	`, key, prID, response)
+	return err
+}
+
+// MergePR marks an OPEN PR as merged and returns it with the reviewers that
+// were active at merge time, read in the same transaction as the update.
+func (r *RepositoryImpl) MergePR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var pr entity.PullRequest
+	err = tx.QueryRowContext(ctx, `
         UPDATE pull_requests 
         SET status = 'MERGED', merged_at = CURRENT_TIMESTAMP
-        WHERE pull_request_id = $1 AND status != 'MERGED'
+        WHERE pull_request_id = $1 AND status = 'OPEN'
         RETURNING pull_request_id, pull_request_name, author_id, status, created_at, merged_at
     `, prID).Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt)
-    if err != nil {
-        if err == sql.ErrNoRows {
-            var status string
-            err = r.db.QueryRow("SELECT status FROM pull_requests WHERE pull_request_id = $1", prID).Scan(&status)
-            if err == nil && status == "MERGED" {
-                return r.GetPR(prID)
-            }
-            return nil, entity.ErrNotFound
-        }
-        return nil, err
-    }
-    reviewers, err := r.GetPRReviewers(prID)
-    if err != nil {
-        return nil, err
-    }
-    pr.AssignedReviewers = reviewers
-    return &pr, nil
-}
-
-func (r *RepositoryImpl) GetPR(prID string) (*entity.PullRequest, error) {
-	var pr entity.PullRequest
-	err := r.db.QueryRow(`
-		SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
-		FROM pull_requests 
-		WHERE pull_request_id = $1
-	`, prID).Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
+			tx.Rollback()
+			var status string
+			err = r.db.QueryRowContext(ctx, "SELECT status FROM pull_requests WHERE pull_request_id = $1", prID).Scan(&status)
+			if err == nil && status == "MERGED" {
+				return r.GetPR(ctx, prID)
+			}
+			if err == nil && status == "CLOSED" {
+				return nil, entity.ErrPRClosed
+			}
 			return nil, entity.ErrNotFound
 		}
 		return nil, err
 	}
-	reviewers, err := r.GetPRReviewers(prID)
+	reviewers, err := prReviewers(ctx, tx, prID)
 	if err != nil {
 		return nil, err
 	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	pr.AssignedReviewers = reviewers
 	return &pr, nil
 }
 
-func (r *RepositoryImpl) GetPRReviewers(prID string) ([]entity.User, error) {
-	rows, err := r.db.Query(`
-		SELECT u.user_id, u.username, u.is_active
-		FROM users u
-		JOIN reviewers r ON u.user_id = r.user_id
-		WHERE r.pull_request_id = $1 AND r.is_active = true
-	`, prID)
+func (r *RepositoryImpl) ClosePR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var reviewers []entity.User
-	for rows.Next() {
-		var user entity.User
-		err := rows.Scan(&user.ID, &user.Username, &user.IsActive)
+	defer tx.Rollback()
+	var status string
+	err = tx.QueryRowContext(ctx,
+		"SELECT status FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE",
+		prID,
+	).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
+	}
+	switch status {
+	case "MERGED":
+		return nil, entity.ErrPRMerged
+	case "OPEN":
+		_, err = tx.ExecContext(ctx, "UPDATE pull_requests SET status = 'CLOSED' WHERE pull_request_id = $1", prID)
 		if err != nil {
 			return nil, err
 		}
-		reviewers = append(reviewers, user)
+		_, err = tx.ExecContext(ctx, "UPDATE reviewers SET is_active = false WHERE pull_request_id = $1", prID)
+		if err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
 	}
-	return reviewers, nil
+	return r.GetPR(ctx, prID)
 }
 
-func (r *RepositoryImpl) ReassignReviewer(prID, oldUserID string) (string, error) {
-	tx, err := r.db.Begin()
+// ReopenPR moves a merged PR back to OPEN. Merging leaves reviewer rows active,
+// so the reviewers assigned at merge time are the ones assigned after reopening.
+func (r *RepositoryImpl) ReopenPR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer tx.Rollback()
 	var status string
-	err = tx.QueryRow("SELECT status FROM pull_requests WHERE pull_request_id = $1", prID).Scan(&status)
+	err = tx.QueryRowContext(ctx,
+		"SELECT status FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE",
+		prID,
+	).Scan(&status)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", entity.ErrNotFound
+			return nil, entity.ErrNotFound
 		}
-		return "", err
+		return nil, err
+	}
+	switch status {
+	case "CLOSED":
+		return nil, entity.ErrPRClosed
+	case "MERGED":
+		_, err = tx.ExecContext(ctx, "UPDATE pull_requests SET status = 'OPEN', merged_at = NULL WHERE pull_request_id = $1", prID)
+		if err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return r.GetPR(ctx, prID)
+}
+
+func (r *RepositoryImpl) GetPR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	var pr entity.PullRequest
+	err := r.db.QueryRowContext(ctx, `
+		SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
+		FROM pull_requests 
+		WHERE pull_request_id = $1
+	`, prID).Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.MergedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
+	}
+	reviewers, err := r.GetPRReviewers(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	pr.AssignedReviewers = reviewers
+	return &pr, nil
+}
+
+// ListPRs returns PRs newest first, optionally filtered by status and author;
+// empty filter values match everything.
+func (r *RepositoryImpl) ListPRs(ctx context.Context, status, authorID string, limit, offset int) ([]entity.PullRequest, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status, pr.created_at,
+			COUNT(r.user_id) as reviewer_count
+		FROM pull_requests pr
+		LEFT JOIN reviewers r ON pr.pull_request_id = r.pull_request_id AND r.is_active = true
+		WHERE ($1::text = '' OR pr.status = $1)
+			AND ($2::text = '' OR pr.author_id = $2)
+		GROUP BY pr.pull_request_id
+		ORDER BY pr.created_at DESC, pr.pull_request_id
+		LIMIT $3 OFFSET $4
+	`, status, authorID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	prs := []entity.PullRequest{}
+	for rows.Next() {
+		var pr entity.PullRequest
+		err := rows.Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.ReviewerCount)
+		if err != nil {
+			return nil, err
+		}
+		prs = append(prs, pr)
+	}
+	return prs, rows.Err()
+}
+
+// SearchPRs returns PRs whose title contains query, case-insensitively, newest
+// first. LIKE wildcards in query match literally.
+func (r *RepositoryImpl) SearchPRs(ctx context.Context, query string, limit int) ([]entity.PullRequest, error) {
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status, pr.created_at,
+			COUNT(r.user_id) as reviewer_count
+		FROM pull_requests pr
+		LEFT JOIN reviewers r ON pr.pull_request_id = r.pull_request_id AND r.is_active = true
+		WHERE pr.pull_request_name ILIKE $1
+		GROUP BY pr.pull_request_id
+		ORDER BY pr.created_at DESC, pr.pull_request_id
+		LIMIT $2
+	`, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	prs := []entity.PullRequest{}
+	for rows.Next() {
+		var pr entity.PullRequest
+		err := rows.Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.ReviewerCount)
+		if err != nil {
+			return nil, err
+		}
+		prs = append(prs, pr)
+	}
+	return prs, rows.Err()
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *RepositoryImpl) GetPRReviewers(ctx context.Context, prID string) ([]entity.User, error) {
+	return prReviewers(ctx, r.db, prID)
+}
+
+func prReviewers(ctx context.Context, q querier, prID string) ([]entity.User, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT u.user_id, u.username, u.is_active, r.assigned_at
+		FROM users u
+		JOIN reviewers r ON u.user_id = r.user_id
+		WHERE r.pull_request_id = $1 AND r.is_active = true
+	`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var reviewers []entity.User
+	for rows.Next() {
+		var user entity.User
+		err := rows.Scan(&user.ID, &user.Username, &user.IsActive, &user.AssignedAt)
+		if err != nil {
+			return nil, err
+		}
+		reviewers = append(reviewers, user)
+	}
+	return reviewers, nil
+}
+
+func (r *RepositoryImpl) GetReviewersForPRs(ctx context.Context, prIDs []string) (map[string][]entity.User, error) {
+	reviewers := make(map[string][]entity.User, len(prIDs))
+	if len(prIDs) == 0 {
+		return reviewers, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT r.pull_request_id, u.user_id, u.username, u.is_active, r.assigned_at
+		FROM users u
+		JOIN reviewers r ON u.user_id = r.user_id
+		WHERE r.pull_request_id = ANY($1) AND r.is_active = true
+		ORDER BY r.pull_request_id, u.user_id
+	`, pq.Array(prIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var prID string
+		var user entity.User
+		err := rows.Scan(&prID, &user.ID, &user.Username, &user.IsActive, &user.AssignedAt)
+		if err != nil {
+			return nil, err
+		}
+		reviewers[prID] = append(reviewers[prID], user)
+	}
+	return reviewers, nil
+}
+
+func (r *RepositoryImpl) ReassignReviewer(ctx context.Context, prID, oldUserID string) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	if err := lockPR(ctx, tx, prID); err != nil {
+		return "", err
+	}
+	newUserID, err := r.findReplacement(ctx, tx, prID, oldUserID)
+	if err != nil {
+		return "", err
+	}
+	if err := swapReviewer(ctx, tx, prID, oldUserID, newUserID); err != nil {
+		return "", err
+	}
+	return newUserID, tx.Commit()
+}
+
+// swapReviewer replaces oldUserID with newUserID on prID and records the swap
+// in the log and the outbox.
+func swapReviewer(ctx context.Context, tx *sql.Tx, prID, oldUserID, newUserID string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE reviewers SET is_active = false 
+		WHERE pull_request_id = $1 AND user_id = $2
+	`, prID, oldUserID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO reviewers (pull_request_id, user_id, is_active)
+		VALUES ($1, $2, true)
+		ON CONFLICT (pull_request_id, user_id) DO UPDATE SET
+			is_active = true,
+			state = 'PENDING',
+			assigned_at = CURRENT_TIMESTAMP
+	`, prID, newUserID)
+	if err != nil {
+		return err
+	}
+	if err := logReassignment(ctx, tx, prID, oldUserID, newUserID); err != nil {
+		return err
+	}
+	return recordReassignedEvent(ctx, tx, prID, oldUserID, newUserID)
+}
+
+// logReassignment records a reviewer swap in reassignment_log. It runs inside
+// the caller's transaction so the log never disagrees with the reviewers table.
+func logReassignment(ctx context.Context, tx *sql.Tx, prID, oldUserID, newUserID string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO reassignment_log (pull_request_id, old_user_id, new_user_id)
+		VALUES ($1, $2, $3)
+	`, prID, oldUserID, newUserID)
+	return err
+}
+
+// recordEvent appends an outbox row inside the caller's transaction, so the
+// event exists exactly when the change it describes is committed.
+func recordEvent(ctx context.Context, tx *sql.Tx, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO events (event_type, payload) VALUES ($1, $2)", eventType, data)
+	return err
+}
+
+func recordReassignedEvent(ctx context.Context, tx *sql.Tx, prID, oldUserID, newUserID string) error {
+	return recordEvent(ctx, tx, entity.EventReviewerReassigned, map[string]string{
+		"pull_request_id": prID,
+		"old_user_id":     oldUserID,
+		"new_user_id":     newUserID,
+	})
+}
+
+// FetchUnpublishedEvents returns up to limit unpublished events, oldest first.
+func (r *RepositoryImpl) FetchUnpublishedEvents(ctx context.Context, limit int) ([]entity.Event, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, event_type, payload, created_at
+		FROM events
+		WHERE published = false
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []entity.Event{}
+	for rows.Next() {
+		var event entity.Event
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.Type, &payload, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (r *RepositoryImpl) MarkEventPublished(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE events SET published = true WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+// GetReassignmentHistory returns every logged reassignment of prID, oldest first.
+func (r *RepositoryImpl) GetReassignmentHistory(ctx context.Context, prID string) ([]entity.Reassignment, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $1)", prID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, entity.ErrNotFound
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT pull_request_id, old_user_id, new_user_id, reassigned_at
+		FROM reassignment_log
+		WHERE pull_request_id = $1
+		ORDER BY reassigned_at, id
+	`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	history := []entity.Reassignment{}
+	for rows.Next() {
+		var entry entity.Reassignment
+		if err := rows.Scan(&entry.PRID, &entry.OldUserID, &entry.NewUserID, &entry.ReassignedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+// GetPRTimeline merges prID's creation, logged reassignments and merge into
+// one list, oldest first. Events with the same timestamp keep that order.
+func (r *RepositoryImpl) GetPRTimeline(ctx context.Context, prID string) ([]entity.TimelineEvent, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $1)", prID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, entity.ErrNotFound
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT event_type, at, old_user_id, new_user_id FROM (
+			SELECT $2::text AS event_type, created_at AS at, '' AS old_user_id, '' AS new_user_id, 0 AS rank, 0 AS seq
+			FROM pull_requests
+			WHERE pull_request_id = $1 AND created_at IS NOT NULL
+			UNION ALL
+			SELECT $3::text, reassigned_at, old_user_id, new_user_id, 1, id
+			FROM reassignment_log
+			WHERE pull_request_id = $1
+			UNION ALL
+			SELECT $4::text, merged_at, '', '', 2, 0
+			FROM pull_requests
+			WHERE pull_request_id = $1 AND merged_at IS NOT NULL
+		) events
+		ORDER BY at, rank, seq
+	`, prID, entity.TimelineCreated, entity.TimelineReassigned, entity.TimelineMerged)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	timeline := []entity.TimelineEvent{}
+	for rows.Next() {
+		var event entity.TimelineEvent
+		if err := rows.Scan(&event.Type, &event.At, &event.OldUserID, &event.NewUserID); err != nil {
+			return nil, err
+		}
+		timeline = append(timeline, event)
+	}
+	return timeline, rows.Err()
+}
+
+// AddReviewer manually assigns userID to an OPEN PR. The user must be an active
+// member of the author's team; a previously unassigned reviewer is reactivated.
+func (r *RepositoryImpl) AddReviewer(ctx context.Context, prID, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var status, authorID string
+	var teamID sql.NullInt64
+	err = tx.QueryRowContext(ctx,
+		"SELECT status, author_id, team_id FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE",
+		prID,
+	).Scan(&status, &authorID, &teamID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.ErrNotFound
+		}
+		return err
+	}
+	if status == "MERGED" {
+		return entity.ErrPRMerged
+	}
+	if status == "CLOSED" {
+		return entity.ErrPRClosed
+	}
+	var isActive bool
+	err = tx.QueryRowContext(ctx, "SELECT is_active FROM users WHERE user_id = $1", userID).Scan(&isActive)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.ErrNotFound
+		}
+		return err
+	}
+	if userID == authorID {
+		return entity.ErrSelfReview
+	}
+	var isAssigned bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM reviewers 
+			WHERE pull_request_id = $1 AND user_id = $2 AND is_active = true
+		)
+	`, prID, userID).Scan(&isAssigned)
+	if err != nil {
+		return err
+	}
+	if isAssigned {
+		return entity.ErrAlreadyAssigned
+	}
+	// Check against the team recorded on the PR, like findReplacement, so
+	// the pool does not drift when the author changes teams.
+	var inAuthorTeam bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM team_members tm
+			WHERE tm.team_id = COALESCE($3::int, `+authorTeamFallback("$1")+`)
+				AND tm.user_id = $2
+		)
+	`, authorID, userID, teamID).Scan(&inAuthorTeam)
+	if err != nil {
+		return err
+	}
+	if !inAuthorTeam || !isActive {
+		return entity.ErrNoCandidate
+	}
+	var activeCount int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM reviewers WHERE pull_request_id = $1 AND is_active = true",
+		prID,
+	).Scan(&activeCount)
+	if err != nil {
+		return err
+	}
+	if activeCount >= r.maxReviewersPerPR {
+		return entity.ErrReviewerLimit
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO reviewers (pull_request_id, user_id, is_active)
+		VALUES ($1, $2, true)
+		ON CONFLICT (pull_request_id, user_id) DO UPDATE SET
+			is_active = true,
+			state = 'PENDING',
+			assigned_at = CURRENT_TIMESTAMP
+	`, prID, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveReviewer deactivates userID's reviewer row without assigning a
+// replacement, refusing to leave the PR with no active reviewers.
+func (r *RepositoryImpl) RemoveReviewer(ctx context.Context, prID, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var status string
+	err = tx.QueryRowContext(ctx,
+		"SELECT status FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE",
+		prID,
+	).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entity.ErrNotFound
+		}
+		return err
+	}
+	if status == "MERGED" {
+		return entity.ErrPRMerged
+	}
+	if status == "CLOSED" {
+		return entity.ErrPRClosed
+	}
+	var isAssigned bool
+	var activeCount int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(BOOL_OR(user_id = $2), false), COUNT(*)
+		FROM reviewers
+		WHERE pull_request_id = $1 AND is_active = true
+	`, prID, userID).Scan(&isAssigned, &activeCount)
+	if err != nil {
+		return err
+	}
+	if !isAssigned {
+		return entity.ErrNotAssigned
+	}
+	if activeCount <= 1 {
+		return entity.ErrLastReviewer
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE reviewers SET is_active = false 
+		WHERE pull_request_id = $1 AND user_id = $2
+	`, prID, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RespondReview records userID's answer to their assignment on an OPEN PR. A
+// decline deactivates the row and hands the slot to a teammate the way
+// ReassignReviewer would; when nobody is left the decline still stands and the
+// returned replacement is "".
+func (r *RepositoryImpl) RespondReview(ctx context.Context, prID, userID string, accept bool) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	var status string
+	err = tx.QueryRowContext(ctx,
+		"SELECT status FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE",
+		prID,
+	).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", entity.ErrNotFound
+		}
+		return "", err
+	}
+	if status == "MERGED" {
+		return "", entity.ErrPRMerged
+	}
+	if status == "CLOSED" {
+		return "", entity.ErrPRClosed
+	}
+	if accept {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE reviewers SET state = 'ACCEPTED'
+			WHERE pull_request_id = $1 AND user_id = $2 AND is_active = true
+		`, prID, userID)
+		if err != nil {
+			return "", err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return "", err
+		}
+		if affected == 0 {
+			return "", entity.ErrNotAssigned
+		}
+		return "", tx.Commit()
+	}
+	newUserID, err := r.findReplacement(ctx, tx, prID, userID)
+	if err != nil && err != entity.ErrNoCandidate && err != entity.ErrAuthorNoTeam {
+		return "", err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE reviewers SET state = 'DECLINED', is_active = false
+		WHERE pull_request_id = $1 AND user_id = $2
+	`, prID, userID)
+	if err != nil {
+		return "", err
+	}
+	if newUserID != "" {
+		if err := swapReviewer(ctx, tx, prID, userID, newUserID); err != nil {
+			return "", err
+		}
+	}
+	return newUserID, tx.Commit()
+}
+
+// lockPR takes a row lock on the PR so that concurrent reviewer changes to the
+// same PR serialize for the rest of tx.
+func lockPR(ctx context.Context, tx *sql.Tx, prID string) error {
+	var lockedID string
+	err := tx.QueryRowContext(ctx,
+		"SELECT pull_request_id FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE",
+		prID,
+	).Scan(&lockedID)
+	if err == sql.ErrNoRows {
+		return entity.ErrNotFound
+	}
+	return err
+}
+
+func (r *RepositoryImpl) PreviewReassign(ctx context.Context, prID, oldUserID string) (string, error) {
+	return r.findReplacement(ctx, r.db, prID, oldUserID)
+}
+
+func (r *RepositoryImpl) findReplacement(ctx context.Context, q queryRower, prID, oldUserID string) (string, error) {
+	var status string
+	err := q.QueryRowContext(ctx, "SELECT status FROM pull_requests WHERE pull_request_id = $1", prID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", entity.ErrNotFound
+		}
+		return "", err
 	}
 	if status == "MERGED" {
 		return "", entity.ErrPRMerged
 	}
+	if status == "CLOSED" {
+		return "", entity.ErrPRClosed
+	}
 	var isAssigned bool
-	err = tx.QueryRow(`
+	err = q.QueryRowContext(ctx, `
 		SELECT EXISTS(
 			SELECT 1 FROM reviewers 
 			WHERE pull_request_id = $1 AND user_id = $2 AND is_active = true
@@ -285,131 +1442,661 @@ func (r *RepositoryImpl) ReassignReviewer(prID, oldUserID string) (string, error
 	if !isAssigned {
 		return "", entity.ErrNotAssigned
 	}
+	// The pool is the team recorded when the PR was created, so it stays
+	// stable if the author later moves. Rows without one fall back to the
+	// author's first current team by name.
 	var authorID string
-	var teamID string
-	err = tx.QueryRow(`
-		SELECT pr.author_id, t.team_id
+	var teamID sql.NullInt64
+	err = q.QueryRowContext(ctx, `
+		SELECT pr.author_id, COALESCE(pr.team_id, `+authorTeamFallback("pr.author_id")+`)
 		FROM pull_requests pr
-		JOIN team_members tm ON pr.author_id = tm.user_id
-		JOIN teams t ON tm.team_id = t.team_id
 		WHERE pr.pull_request_id = $1
 	`, prID).Scan(&authorID, &teamID)
 	if err != nil {
 		return "", err
 	}
+	if !teamID.Valid {
+		return "", entity.ErrAuthorNoTeam
+	}
+	// Rank and cap teammates the same way GetCandidateReviewers does, so a
+	// reassignment cannot hand work to someone CreatePR would pass over.
 	var newUserID string
-	err = tx.QueryRow(`
+	err = q.QueryRowContext(ctx, `
 		SELECT u.user_id 
 		FROM users u
 		JOIN team_members tm ON u.user_id = tm.user_id
+		LEFT JOIN reviewers r ON u.user_id = r.user_id AND r.is_active = true
+		LEFT JOIN pull_requests pr ON r.pull_request_id = pr.pull_request_id AND pr.status = 'OPEN'
 		WHERE tm.team_id = $1 
 		AND u.user_id != $2 
-		AND u.user_id != $3
+		AND u.user_id != $4
 		AND u.is_active = true
 		AND u.user_id NOT IN (
 			SELECT user_id FROM reviewers 
-			WHERE pull_request_id = $4 AND is_active = true
+			WHERE pull_request_id = $5 AND (is_active = true OR state = 'DECLINED')
 		)
+		GROUP BY u.user_id
+		HAVING $6::int = 0 OR COUNT(pr.pull_request_id) < $6::int
+		ORDER BY `+r.strategy.candidateOrder()+`
 		LIMIT 1
-	`, teamID, authorID, oldUserID, prID).Scan(&newUserID)
+	`, teamID.Int64, authorID, HistoricalLoadWeight, oldUserID, prID, r.maxReviewerLoad).Scan(&newUserID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", entity.ErrNoCandidate
 		}
 		return "", err
 	}
-	_, err = tx.Exec(`
-		UPDATE reviewers SET is_active = false 
-		WHERE pull_request_id = $1 AND user_id = $2
-	`, prID, oldUserID)
-	if err != nil {
-		return "", err
-	}
-	_, err = tx.Exec(`
-		INSERT INTO reviewers (pull_request_id, user_id, is_active)
-		VALUES ($1, $2, true)
-	`, prID, newUserID)
-	if err != nil {
-		return "", err
-	}
-	return newUserID, tx.Commit()
+	return newUserID, nil
 }
-func (r *RepositoryImpl) GetCandidateReviewers(authorID string, limit int) ([]string, error) {
-    rows, err := r.db.Query(`
+
+// GetCandidateReviewers returns up to limit eligible teammates of authorID in
+// strategy order, drawn from teamName only when it is set. When avoidRecent > 0, anyone who reviewed one of the author's
+// last avoidRecent PRs is ranked after all other candidates. Each candidate
+// carries its current open review count.
+func (r *RepositoryImpl) GetCandidateReviewers(ctx context.Context, authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+	if excludeIDs == nil {
+		excludeIDs = []string{}
+	}
+	rows, err := r.db.QueryContext(ctx, `
         SELECT 
             u.user_id,
-            COUNT(r.user_id) as current_assignments
+            COUNT(pr.pull_request_id) as current_assignments,
+            COUNT(r.user_id) as total_assignments
         FROM users u
         JOIN team_members tm ON u.user_id = tm.user_id
         JOIN team_members tm_author ON tm.team_id = tm_author.team_id
+        JOIN teams t ON t.team_id = tm_author.team_id
         LEFT JOIN reviewers r ON u.user_id = r.user_id AND r.is_active = true
         LEFT JOIN pull_requests pr ON r.pull_request_id = pr.pull_request_id AND pr.status = 'OPEN'
         WHERE tm_author.user_id = $1 
+            AND ($7::text = '' OR LOWER(t.team_name) = LOWER($7))
             AND u.user_id != $1
+            AND u.user_id != ALL($4)
             AND u.is_active = true
         GROUP BY u.user_id
-        ORDER BY current_assignments ASC, u.user_id
+        HAVING $5::int = 0 OR COUNT(pr.pull_request_id) < $5::int
+        ORDER BY
+            ($6::int > 0 AND u.user_id IN (
+                SELECT rr.user_id FROM reviewers rr
+                WHERE rr.pull_request_id IN (
+                    SELECT ap.pull_request_id FROM pull_requests ap
+                    WHERE ap.author_id = $1
+                    ORDER BY ap.created_at DESC
+                    LIMIT $6
+                )
+            )) ASC,
+            `+r.strategy.candidateOrder()+`
         LIMIT $2
-    `, authorID, limit)
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-    
-    var userIDs []string
-    for rows.Next() {
-        var userID string
-        var currentAssignments int
-        err := rows.Scan(&userID, &currentAssignments)
-        if err != nil {
-            return nil, err
-        }
-        userIDs = append(userIDs, userID)
-    }
-    return userIDs, nil
-}
-
-func (r *RepositoryImpl) GetStats() (*entity.Stats, error) {
-    stats := &entity.Stats{}
-    userRows, err := r.db.Query(`
-        SELECT u.user_id, u.username, COUNT(r.user_id) as assignment_count
-        FROM users u
-        LEFT JOIN reviewers r ON u.user_id = r.user_id AND r.is_active = true
-        GROUP BY u.user_id, u.username
-        ORDER BY assignment_count DESC
-    `)
-    if err != nil {
-        return nil, err
-    }
-    defer userRows.Close()
-    for userRows.Next() {
-        var userStat entity.UserAssignmentCount
-        err := userRows.Scan(&userStat.UserID, &userStat.Username, &userStat.Count)
-        if err != nil {
-            return nil, err
-        }
-        stats.UserAssignmentCounts = append(stats.UserAssignmentCounts, userStat)
-        stats.TotalAssignments += userStat.Count
-    }
-    prRows, err := r.db.Query(`
-        SELECT pr.pull_request_id, pr.pull_request_name, COUNT(r.user_id) as assignment_count
-        FROM pull_requests pr
-        LEFT JOIN reviewers r ON pr.pull_request_id = r.pull_request_id AND r.is_active = true
-        GROUP BY pr.pull_request_id, pr.pull_request_name
-        ORDER BY assignment_count DESC
-    `)
-    if err != nil {
-        return nil, err
-    }
-    defer prRows.Close()
-    for prRows.Next() {
-        var prStat entity.PRAssignmentCount
-        err := prRows.Scan(&prStat.PRID, &prStat.Title, &prStat.Count)
-        if err != nil {
-            return nil, err
-        }
-        stats.PRAssignmentCounts = append(stats.PRAssignmentCounts, prStat)
-    }
-    return stats, nil
+    `, authorID, limit, HistoricalLoadWeight, pq.Array(excludeIDs), r.maxReviewerLoad, avoidRecent, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []entity.CandidateReviewer
+	for rows.Next() {
+		var candidate entity.CandidateReviewer
+		var totalAssignments int
+		err := rows.Scan(&candidate.UserID, &candidate.Load, &totalAssignments)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, rows.Err()
+}
+
+// EvaluateCandidates lists everyone in the pool GetCandidateReviewers draws
+// from, author included, with their open review count and the reason
+// GetCandidateReviewers would pass them over. Eligible members get no reason;
+// Selected is left for the caller.
+func (r *RepositoryImpl) EvaluateCandidates(ctx context.Context, authorID string, excludeIDs []string, teamName string) ([]entity.CandidateEvaluation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT u.user_id, u.is_active, COUNT(DISTINCT pr.pull_request_id)
+		FROM users u
+		JOIN team_members tm ON u.user_id = tm.user_id
+		JOIN team_members tm_author ON tm.team_id = tm_author.team_id
+		JOIN teams t ON t.team_id = tm_author.team_id
+		LEFT JOIN reviewers r ON u.user_id = r.user_id AND r.is_active = true
+		LEFT JOIN pull_requests pr ON r.pull_request_id = pr.pull_request_id AND pr.status = 'OPEN'
+		WHERE tm_author.user_id = $1
+			AND ($2::text = '' OR LOWER(t.team_name) = LOWER($2))
+		GROUP BY u.user_id, u.is_active
+		ORDER BY u.user_id
+	`, authorID, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	excluded := make(map[string]bool, len(excludeIDs))
+	for _, id := range excludeIDs {
+		excluded[id] = true
+	}
+	evaluations := []entity.CandidateEvaluation{}
+	for rows.Next() {
+		var evaluation entity.CandidateEvaluation
+		var isActive bool
+		if err := rows.Scan(&evaluation.UserID, &isActive, &evaluation.Load); err != nil {
+			return nil, err
+		}
+		switch {
+		case evaluation.UserID == authorID:
+			evaluation.SkipReason = entity.SkipAuthor
+		case excluded[evaluation.UserID]:
+			evaluation.SkipReason = entity.SkipExcluded
+		case !isActive:
+			evaluation.SkipReason = entity.SkipInactive
+		case r.maxReviewerLoad > 0 && evaluation.Load >= r.maxReviewerLoad:
+			evaluation.SkipReason = entity.SkipOverCap
+		}
+		evaluations = append(evaluations, evaluation)
+	}
+	return evaluations, rows.Err()
+}
+
+// GetUserTeams returns every team userID belongs to, sorted by name, and
+// ErrNotFound when the user does not exist.
+func (r *RepositoryImpl) GetUserTeams(ctx context.Context, userID string) ([]entity.Team, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)", userID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, entity.ErrNotFound
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.team_id, t.team_name, COALESCE(t.default_reviewers, 0)
+		FROM teams t
+		JOIN team_members tm ON t.team_id = tm.team_id
+		WHERE tm.user_id = $1
+		ORDER BY t.team_name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	teams := []entity.Team{}
+	for rows.Next() {
+		var team entity.Team
+		if err := rows.Scan(&team.ID, &team.Name, &team.DefaultReviewers); err != nil {
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+	return teams, rows.Err()
+}
+
+// CountActiveTeammates returns how many active users share a team with userID,
+// not counting userID itself.
+func (r *RepositoryImpl) CountActiveTeammates(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT u.user_id)
+		FROM team_members author_tm
+		JOIN team_members tm ON tm.team_id = author_tm.team_id
+		JOIN users u ON u.user_id = tm.user_id
+		WHERE author_tm.user_id = $1 AND u.user_id != $1 AND u.is_active = true
+	`, userID).Scan(&count)
+	return count, err
+}
+
+// CountTeammates is CountActiveTeammates without the is_active filter.
+func (r *RepositoryImpl) CountTeammates(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT tm.user_id)
+		FROM team_members author_tm
+		JOIN team_members tm ON tm.team_id = author_tm.team_id
+		WHERE author_tm.user_id = $1 AND tm.user_id != $1
+	`, userID).Scan(&count)
+	return count, err
+}
+
+// CountActiveMembers returns how many active users belong to teamName.
+func (r *RepositoryImpl) CountActiveMembers(ctx context.Context, teamName string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM team_members tm
+		JOIN teams t ON t.team_id = tm.team_id
+		JOIN users u ON u.user_id = tm.user_id
+		WHERE LOWER(t.team_name) = LOWER($1) AND u.is_active = true
+	`, teamName).Scan(&count)
+	return count, err
+}
+
+// GetTeamReviewerCount returns teamName's default_reviewers, or 0 when the
+// team has none.
+func (r *RepositoryImpl) GetTeamReviewerCount(ctx context.Context, teamName string) (int, error) {
+	var count sql.NullInt64
+	err := r.db.QueryRowContext(ctx, "SELECT default_reviewers FROM teams WHERE LOWER(team_name) = LOWER($1)", teamName).Scan(&count)
+	if err == sql.ErrNoRows {
+		return 0, entity.ErrNotFound
+	}
+	return int(count.Int64), err
+}
+
+func (r *RepositoryImpl) SetTeamReviewerCount(ctx context.Context, teamName string, count int) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE teams SET default_reviewers = $2 WHERE LOWER(team_name) = LOWER($1)", teamName, count)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (r *RepositoryImpl) GetMissingUserIDs(ctx context.Context, userIDs []string) ([]string, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id FROM UNNEST($1::text[]) AS id
+        WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.user_id = id)
+    `, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var missing []string
+	for rows.Next() {
+		var userID string
+		err := rows.Scan(&userID)
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, userID)
+	}
+	return missing, nil
+}
+
+// statsOrder returns the ORDER BY clauses for the user and PR stats lists.
+func statsOrder(sort entity.StatsSort) (userOrder, prOrder string) {
+	switch sort {
+	case entity.StatsSortCountAsc:
+		return "assignment_count ASC, u.user_id", "assignment_count ASC, pr.pull_request_id"
+	case entity.StatsSortName:
+		return "u.username, u.user_id", "pr.pull_request_name, pr.pull_request_id"
+	default:
+		return "assignment_count DESC, u.user_id", "assignment_count DESC, pr.pull_request_id"
+	}
+}
+
+// statsTxOptions gives the stats queries a single snapshot so the total and the
+// per-user and per-PR counts agree with each other.
+var statsTxOptions = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+func (r *RepositoryImpl) GetStatsPaged(ctx context.Context, limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+	defer metrics.ObserveDB(ctx, time.Now())
+	userOrder, prOrder := statsOrder(filter.Sort)
+	stats := &entity.Stats{
+		UserAssignmentCounts: []entity.UserAssignmentCount{},
+		PRAssignmentCounts:   []entity.PRAssignmentCount{},
+	}
+	tx, err := r.db.BeginTx(ctx, statsTxOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	stats.TotalAssignments, err = totalAssignments(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+	userRows, err := tx.QueryContext(ctx, `
+		SELECT u.user_id, u.username, u.is_active, COUNT(r.user_id) as assignment_count,
+		    COUNT(r.user_id) FILTER (WHERE rpr.status = 'OPEN') as open_count,
+		    COUNT(r.user_id) FILTER (WHERE rpr.status = 'MERGED') as merged_count
+		FROM users u
+		LEFT JOIN (
+			reviewers r
+			JOIN pull_requests rpr ON r.pull_request_id = rpr.pull_request_id
+				AND ($3::timestamptz IS NULL OR rpr.created_at >= $3)
+				AND ($4::timestamptz IS NULL OR rpr.created_at <= $4)
+		) ON u.user_id = r.user_id AND r.is_active = true
+		GROUP BY u.user_id, u.username, u.is_active
+		ORDER BY `+userOrder+`
+		LIMIT $1 OFFSET $2
+	`, limit, offset, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+	defer userRows.Close()
+	for userRows.Next() {
+		var userStat entity.UserAssignmentCount
+		err := userRows.Scan(&userStat.UserID, &userStat.Username, &userStat.IsActive, &userStat.Count, &userStat.OpenCount, &userStat.MergedCount)
+		if err != nil {
+			return nil, err
+		}
+		stats.UserAssignmentCounts = append(stats.UserAssignmentCounts, userStat)
+	}
+	if err := userRows.Err(); err != nil {
+		return nil, err
+	}
+	prRows, err := tx.QueryContext(ctx, `
+		SELECT pr.pull_request_id, pr.pull_request_name, COUNT(r.user_id) as assignment_count
+		FROM pull_requests pr
+		LEFT JOIN reviewers r ON pr.pull_request_id = r.pull_request_id AND r.is_active = true
+		WHERE ($3::timestamptz IS NULL OR pr.created_at >= $3)
+			AND ($4::timestamptz IS NULL OR pr.created_at <= $4)
+		GROUP BY pr.pull_request_id, pr.pull_request_name
+		ORDER BY `+prOrder+`
+		LIMIT $1 OFFSET $2
+	`, limit, offset, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+	defer prRows.Close()
+	for prRows.Next() {
+		var prStat entity.PRAssignmentCount
+		err := prRows.Scan(&prStat.PRID, &prStat.Title, &prStat.Count)
+		if err != nil {
+			return nil, err
+		}
+		stats.PRAssignmentCounts = append(stats.PRAssignmentCounts, prStat)
+	}
+	if err := prRows.Err(); err != nil {
+		return nil, err
+	}
+	stats.AverageTimeToMergeSeconds, err = averageTimeToMerge(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+	loads, err := activeUserLoads(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+	stats.FairnessGini = giniCoefficient(loads)
+	return stats, tx.Commit()
+}
+
+// activeUserLoads returns every active user's count of active reviews on PRs
+// created within filter, so the fairness score does not depend on paging.
+func activeUserLoads(ctx context.Context, q querier, filter entity.StatsFilter) ([]int, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT COUNT(r.user_id)
+		FROM users u
+		LEFT JOIN (
+			reviewers r
+			JOIN pull_requests rpr ON r.pull_request_id = rpr.pull_request_id
+				AND ($1::timestamptz IS NULL OR rpr.created_at >= $1)
+				AND ($2::timestamptz IS NULL OR rpr.created_at <= $2)
+		) ON u.user_id = r.user_id AND r.is_active = true
+		WHERE u.is_active = true
+		GROUP BY u.user_id
+	`, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	loads := []int{}
+	for rows.Next() {
+		var load int
+		if err := rows.Scan(&load); err != nil {
+			return nil, err
+		}
+		loads = append(loads, load)
+	}
+	return loads, rows.Err()
+}
+
+// totalAssignments counts active reviewer rows on PRs created within filter.
+func totalAssignments(ctx context.Context, q queryRower, filter entity.StatsFilter) (int, error) {
+	var total int
+	err := q.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM reviewers r
+		JOIN pull_requests pr ON r.pull_request_id = pr.pull_request_id
+		WHERE r.is_active = true
+			AND ($1::timestamptz IS NULL OR pr.created_at >= $1)
+			AND ($2::timestamptz IS NULL OR pr.created_at <= $2)
+	`, filter.From, filter.To).Scan(&total)
+	return total, err
+}
+
+// averageTimeToMerge returns the mean merged_at - created_at in seconds over
+// MERGED PRs created within filter, or 0 when there are none.
+func averageTimeToMerge(ctx context.Context, q queryRower, filter entity.StatsFilter) (float64, error) {
+	var avg float64
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(AVG(EXTRACT(EPOCH FROM merged_at - created_at)), 0)::float8
+		FROM pull_requests
+		WHERE status = 'MERGED' AND merged_at IS NOT NULL
+			AND ($1::timestamptz IS NULL OR created_at >= $1)
+			AND ($2::timestamptz IS NULL OR created_at <= $2)
+	`, filter.From, filter.To).Scan(&avg)
+	return avg, err
+}
+
+// GetTeamStats reports the team members' review counts and the assignments on
+// PRs authored by the team. TotalAssignments is summed over those PRs, so it
+// matches pr_assignment_counts rather than the members' reviews elsewhere.
+func (r *RepositoryImpl) GetTeamStats(ctx context.Context, teamName string) (*entity.Stats, error) {
+	defer metrics.ObserveDB(ctx, time.Now())
+	tx, err := r.db.BeginTx(ctx, statsTxOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var teamID string
+	err = tx.QueryRowContext(ctx,
+		"SELECT team_id FROM teams WHERE LOWER(team_name) = LOWER($1)",
+		teamName,
+	).Scan(&teamID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
+	}
+	stats := &entity.Stats{
+		UserAssignmentCounts: []entity.UserAssignmentCount{},
+		PRAssignmentCounts:   []entity.PRAssignmentCount{},
+	}
+	userRows, err := tx.QueryContext(ctx, `
+		SELECT u.user_id, u.username, u.is_active, COUNT(r.user_id) as assignment_count,
+			COUNT(r.user_id) FILTER (WHERE rpr.status = 'OPEN') as open_count,
+			COUNT(r.user_id) FILTER (WHERE rpr.status = 'MERGED') as merged_count
+		FROM users u
+		JOIN team_members tm ON u.user_id = tm.user_id
+		LEFT JOIN reviewers r ON u.user_id = r.user_id AND r.is_active = true
+		LEFT JOIN pull_requests rpr ON r.pull_request_id = rpr.pull_request_id
+		WHERE tm.team_id = $1
+		GROUP BY u.user_id, u.username, u.is_active
+		ORDER BY assignment_count DESC, u.user_id
+	`, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer userRows.Close()
+	for userRows.Next() {
+		var userStat entity.UserAssignmentCount
+		err := userRows.Scan(&userStat.UserID, &userStat.Username, &userStat.IsActive, &userStat.Count, &userStat.OpenCount, &userStat.MergedCount)
+		if err != nil {
+			return nil, err
+		}
+		stats.UserAssignmentCounts = append(stats.UserAssignmentCounts, userStat)
+	}
+	if err := userRows.Err(); err != nil {
+		return nil, err
+	}
+	prRows, err := tx.QueryContext(ctx, `
+		SELECT pr.pull_request_id, pr.pull_request_name, COUNT(r.user_id) as assignment_count
+		FROM pull_requests pr
+		JOIN team_members tm ON pr.author_id = tm.user_id
+		LEFT JOIN reviewers r ON pr.pull_request_id = r.pull_request_id AND r.is_active = true
+		WHERE tm.team_id = $1
+		GROUP BY pr.pull_request_id, pr.pull_request_name
+		ORDER BY assignment_count DESC, pr.pull_request_id
+	`, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer prRows.Close()
+	for prRows.Next() {
+		var prStat entity.PRAssignmentCount
+		err := prRows.Scan(&prStat.PRID, &prStat.Title, &prStat.Count)
+		if err != nil {
+			return nil, err
+		}
+		stats.PRAssignmentCounts = append(stats.PRAssignmentCounts, prStat)
+		stats.TotalAssignments += prStat.Count
+	}
+	if err := prRows.Err(); err != nil {
+		return nil, err
+	}
+	stats.FairnessGini = fairnessGini(stats.UserAssignmentCounts)
+	return stats, tx.Commit()
+}
+
+// GetUserStats returns a single user's active review assignments with the same
+// open/merged breakdown as GetStatsPaged.
+func (r *RepositoryImpl) GetUserStats(ctx context.Context, userID string) (*entity.UserAssignmentCount, error) {
+	defer metrics.ObserveDB(ctx, time.Now())
+	var userStat entity.UserAssignmentCount
+	err := r.db.QueryRowContext(ctx, `
+		SELECT u.user_id, u.username, u.is_active, COUNT(r.user_id) as assignment_count,
+			COUNT(r.user_id) FILTER (WHERE rpr.status = 'OPEN') as open_count,
+			COUNT(r.user_id) FILTER (WHERE rpr.status = 'MERGED') as merged_count
+		FROM users u
+		LEFT JOIN reviewers r ON u.user_id = r.user_id AND r.is_active = true
+		LEFT JOIN pull_requests rpr ON r.pull_request_id = rpr.pull_request_id
+		WHERE u.user_id = $1
+		GROUP BY u.user_id, u.username, u.is_active
+	`, userID).Scan(&userStat.UserID, &userStat.Username, &userStat.IsActive, &userStat.Count, &userStat.OpenCount, &userStat.MergedCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
+	}
+	return &userStat, nil
+}
+
+func (r *RepositoryImpl) GetConcentration(ctx context.Context) (*entity.Concentration, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT u.user_id, u.username, COUNT(pr.pull_request_id) as assignment_count
+		FROM users u
+		LEFT JOIN reviewers r ON u.user_id = r.user_id AND r.is_active = true
+		LEFT JOIN pull_requests pr ON r.pull_request_id = pr.pull_request_id AND pr.status = 'OPEN'
+		WHERE u.is_active = true
+		GROUP BY u.user_id, u.username
+		ORDER BY assignment_count DESC, u.user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	concentration := &entity.Concentration{UserLoads: []entity.UserAssignmentCount{}}
+	loads := []int{}
+	for rows.Next() {
+		var userStat entity.UserAssignmentCount
+		err := rows.Scan(&userStat.UserID, &userStat.Username, &userStat.Count)
+		if err != nil {
+			return nil, err
+		}
+		userStat.OpenCount = userStat.Count
+		userStat.IsActive = true
+		concentration.UserLoads = append(concentration.UserLoads, userStat)
+		loads = append(loads, userStat.Count)
+	}
+	concentration.GiniCoefficient = giniCoefficient(loads)
+	return concentration, nil
+}
+
+func (r *RepositoryImpl) GetOperationalCounts(ctx context.Context) (*entity.OperationalCounts, error) {
+	var counts entity.OperationalCounts
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM pull_requests WHERE status = 'OPEN'),
+			(SELECT COUNT(*) FROM users WHERE is_active = true)
+	`).Scan(&counts.OpenPRs, &counts.ActiveReviewers)
+	if err != nil {
+		return nil, err
+	}
+	return &counts, nil
+}
+
+// giniCoefficient returns 0 for a perfectly even distribution and approaches 1
+// as the load concentrates on a single user.
+func giniCoefficient(loads []int) float64 {
+	n := len(loads)
+	if n == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), loads...)
+	sort.Ints(sorted)
+	var total, weighted float64
+	for i, load := range sorted {
+		total += float64(load)
+		weighted += float64(i+1) * float64(load)
+	}
+	if total == 0 {
+		return 0
+	}
+	return 2*weighted/(float64(n)*total) - float64(n+1)/float64(n)
+}
+
+// fairnessGini is giniCoefficient over the counts of active users only.
+func fairnessGini(counts []entity.UserAssignmentCount) float64 {
+	loads := []int{}
+	for _, count := range counts {
+		if count.IsActive {
+			loads = append(loads, count.Count)
+		}
+	}
+	return giniCoefficient(loads)
 }
 
+func (r *RepositoryImpl) GetReviewerWeeklySummary(ctx context.Context, userID string, weeks int) ([]entity.WeekCount, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)", userID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, entity.ErrNotFound
+	}
+	now := r.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	currentWeekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	firstWeekStart := currentWeekStart.AddDate(0, 0, -7*(weeks-1))
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			EXTRACT(ISOYEAR FROM assigned_at AT TIME ZONE 'UTC')::int AS iso_year,
+			EXTRACT(WEEK FROM assigned_at AT TIME ZONE 'UTC')::int AS iso_week,
+			COUNT(*) AS assignment_count
+		FROM reviewers
+		WHERE user_id = $1 AND assigned_at >= $2 AND assigned_at < $3 AND state <> 'DECLINED'
+		GROUP BY iso_year, iso_week
+	`, userID, firstWeekStart, currentWeekStart.AddDate(0, 0, 7))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[[2]int]int)
+	for rows.Next() {
+		var year, week, count int
+		err := rows.Scan(&year, &week, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[[2]int{year, week}] = count
+	}
+	summary := make([]entity.WeekCount, 0, weeks)
+	for weekStart := firstWeekStart; !weekStart.After(currentWeekStart); weekStart = weekStart.AddDate(0, 0, 7) {
+		year, week := weekStart.ISOWeek()
+		summary = append(summary, entity.WeekCount{
+			ISOYear:   year,
+			ISOWeek:   week,
+			WeekStart: weekStart.Format("2006-01-02"),
+			Count:     counts[[2]int{year, week}],
+		})
+	}
+	return summary, nil
+}
@@ -1,14 +1,23 @@
 package repository_test
 
 import (
+	"context"
 	"database/sql"
-	"testing"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
 
 	_ "github.com/lib/pq"
 
-	"service/internal/repository"
 	"service/internal/entity"
+	"service/internal/repository"
 )
 
 func setupTestDB(t *testing.T) *sql.DB {
@@ -18,11 +27,12 @@ func setupTestDB(t *testing.T) *sql.DB {
 		t.Skipf("Skipping test - cannot connect to test DB: %v", err)
 	}
 	_, err = db.Exec(`
-		DROP TABLE IF EXISTS reviewers, team_members, pull_requests, users, teams CASCADE;
+		DROP TABLE IF EXISTS events, idempotency_keys, reassignment_log, reviewers, team_members, pull_requests, users, teams CASCADE;
 		
 		CREATE TABLE teams (
 			team_id SERIAL PRIMARY KEY,
-			team_name VARCHAR(100) UNIQUE NOT NULL
+			team_name VARCHAR(100) UNIQUE NOT NULL,
+			default_reviewers INT CHECK (default_reviewers BETWEEN 1 AND 10)
 		);
 
 		CREATE TABLE users (
@@ -42,17 +52,44 @@ func setupTestDB(t *testing.T) *sql.DB {
 			pull_request_id TEXT PRIMARY KEY,
 			pull_request_name VARCHAR(200) NOT NULL,
 			author_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
-			status VARCHAR(20) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'MERGED')),
+			status VARCHAR(20) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'MERGED', 'CLOSED')),
 			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 			merged_at TIMESTAMP WITH TIME ZONE NULL
 		);
 
+		ALTER TABLE pull_requests ADD COLUMN team_id INT REFERENCES teams(team_id) ON DELETE SET NULL;
+
 		CREATE TABLE reviewers (
 			pull_request_id TEXT REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
 			user_id TEXT REFERENCES users(user_id) ON DELETE CASCADE,
 			is_active BOOLEAN NOT NULL DEFAULT true,
+			assigned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			state VARCHAR(20) NOT NULL DEFAULT 'PENDING' CHECK (state IN ('PENDING', 'ACCEPTED', 'DECLINED')),
 			PRIMARY KEY (pull_request_id, user_id)
 		);
+
+		CREATE TABLE reassignment_log (
+			id SERIAL PRIMARY KEY,
+			pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+			old_user_id TEXT NOT NULL,
+			new_user_id TEXT NOT NULL,
+			reassigned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE events (
+			id BIGSERIAL PRIMARY KEY,
+			event_type TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			published BOOLEAN NOT NULL DEFAULT false
+		);
+
+		CREATE TABLE idempotency_keys (
+			idempotency_key TEXT PRIMARY KEY,
+			pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+			response BYTEA NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
 	`)
 	if err != nil {
 		t.Fatalf("Failed to setup test database: %v", err)
@@ -70,7 +107,7 @@ func TestRepository_CreateTeam(t *testing.T) {
 			{ID: "u1", Username: "Alice", IsActive: true},
 			{ID: "u2", Username: "Bob", IsActive: true},
 		}
-		err := repo.CreateTeam(team, members)
+		err := repo.CreateTeam(context.Background(), team, members)
 		if err != nil {
 			t.Errorf("CreateTeam failed: %v", err)
 		}
@@ -81,7 +118,7 @@ func TestRepository_CreateTeam(t *testing.T) {
 	t.Run("create duplicate team", func(t *testing.T) {
 		team := &entity.Team{Name: "backend"}
 		members := []entity.User{{ID: "u3", Username: "Charlie", IsActive: true}}
-		err := repo.CreateTeam(team, members)
+		err := repo.CreateTeam(context.Background(), team, members)
 		if err != entity.ErrTeamExists {
 			t.Errorf("Expected ErrTeamExists, got %v", err)
 		}
@@ -96,9 +133,9 @@ func TestRepository_GetTeam(t *testing.T) {
 	members := []entity.User{
 		{ID: "u1", Username: "Alice", IsActive: true},
 	}
-	repo.CreateTeam(team, members)
+	repo.CreateTeam(context.Background(), team, members)
 	t.Run("get existing team", func(t *testing.T) {
-		team, members, err := repo.GetTeam("frontend")
+		team, members, err := repo.GetTeam(context.Background(), "frontend")
 		if err != nil {
 			t.Errorf("GetTeam failed: %v", err)
 		}
@@ -110,7 +147,7 @@ func TestRepository_GetTeam(t *testing.T) {
 		}
 	})
 	t.Run("get non-existent team", func(t *testing.T) {
-		_, _, err := repo.GetTeam("nonexistent")
+		_, _, err := repo.GetTeam(context.Background(), "nonexistent")
 		if err != entity.ErrNotFound {
 			t.Errorf("Expected ErrNotFound, got %v", err)
 		}
@@ -121,776 +158,3871 @@ func TestRepository_CreateTeam_EmptyTeam(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 	repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "empty_team"}
-    members := []entity.User{} 
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Errorf("Should create team with no members, got error: %v", err)
-    }
-    retrievedTeam, retrievedMembers, err := repo.GetTeam("empty_team")
-    if err != nil {
-        t.Errorf("Should retrieve created team: %v", err)
-    }
-    if retrievedTeam.Name != "empty_team" {
-        t.Errorf("Expected team name 'empty_team', got %s", retrievedTeam.Name)
-    }
-    if len(retrievedMembers) != 0 {
-        t.Errorf("Expected 0 members, got %d", len(retrievedMembers))
-    }
+	team := &entity.Team{Name: "empty_team"}
+	members := []entity.User{}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Errorf("Should create team with no members, got error: %v", err)
+	}
+	retrievedTeam, retrievedMembers, err := repo.GetTeam(context.Background(), "empty_team")
+	if err != nil {
+		t.Errorf("Should retrieve created team: %v", err)
+	}
+	if retrievedTeam.Name != "empty_team" {
+		t.Errorf("Expected team name 'empty_team', got %s", retrievedTeam.Name)
+	}
+	if len(retrievedMembers) != 0 {
+		t.Errorf("Expected 0 members, got %d", len(retrievedMembers))
+	}
 }
 
 func TestRepository_CreateTeam_CaseInsensitive(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 	repo := repository.NewRepository(db)
-    team1 := &entity.Team{Name: "Backend"}
-    err := repo.CreateTeam(team1, []entity.User{})
-    if err != nil {
-        t.Fatalf("Failed to create first team: %v", err)
-    }
-    team2 := &entity.Team{Name: "BACKEND"}
-    err = repo.CreateTeam(team2, []entity.User{})
-    if !errors.Is(err, entity.ErrTeamExists) {
-        t.Errorf("Expected ErrTeamExists for case-insensitive duplicate, got: %v", err)
-    }
+	team1 := &entity.Team{Name: "Backend"}
+	err := repo.CreateTeam(context.Background(), team1, []entity.User{})
+	if err != nil {
+		t.Fatalf("Failed to create first team: %v", err)
+	}
+	team2 := &entity.Team{Name: "BACKEND"}
+	err = repo.CreateTeam(context.Background(), team2, []entity.User{})
+	if !errors.Is(err, entity.ErrTeamExists) {
+		t.Errorf("Expected ErrTeamExists for case-insensitive duplicate, got: %v", err)
+	}
+}
+
+func TestRepository_GetUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	members := []entity.User{{ID: "u1", Username: "Alice", IsActive: false}}
+	if err := repo.CreateTeam(ctx, &entity.Team{Name: "get-user-team"}, members); err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	user, err := repo.GetUser(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.Username != "Alice" || user.IsActive || user.TeamName != "get-user-team" {
+		t.Errorf("Unexpected user %+v", user)
+	}
+	if _, err := db.Exec("INSERT INTO users (user_id, username, is_active) VALUES ('loner', 'Loner', true)"); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	user, err = repo.GetUser(ctx, "loner")
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.TeamName != "" || !user.IsActive {
+		t.Errorf("Expected an active user without a team, got %+v", user)
+	}
+	if _, err := repo.GetUser(ctx, "ghost"); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRepository_GetUser_MultipleTeams(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	for _, name := range []string{"zeta", "beta", "gamma"} {
+		err := repo.CreateTeam(ctx, &entity.Team{Name: name}, []entity.User{{ID: "multi", Username: "Multi", IsActive: true}})
+		if err != nil {
+			t.Fatalf("Failed to create team %s: %v", name, err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		user, err := repo.GetUser(ctx, "multi")
+		if err != nil {
+			t.Fatalf("GetUser failed: %v", err)
+		}
+		if user.TeamName != "beta" {
+			t.Fatalf("Expected the first team by name, got %q", user.TeamName)
+		}
+	}
+}
+
+func TestRepository_GetUserLoad(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "load-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	open, total, err := repo.GetUserLoad(ctx, "reviewer1")
+	if err != nil {
+		t.Fatalf("GetUserLoad failed: %v", err)
+	}
+	if open != 0 || total != 0 {
+		t.Errorf("Expected no load before any PR, got open=%d total=%d", open, total)
+	}
+	for _, id := range []string{"pr-open", "pr-merged"} {
+		if err := repo.CreatePR(ctx, &entity.PullRequest{ID: id, Title: id, AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+	}
+	if _, err := repo.MergePR(ctx, "pr-merged"); err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	open, total, err = repo.GetUserLoad(ctx, "reviewer1")
+	if err != nil {
+		t.Fatalf("GetUserLoad failed: %v", err)
+	}
+	if open != 1 || total != 2 {
+		t.Errorf("Expected open=1 total=2, got open=%d total=%d", open, total)
+	}
+	if _, _, err := repo.GetUserLoad(ctx, "ghost"); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
 }
 
 func TestRepository_SetUserActive_UserNotExists(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 	repo := repository.NewRepository(db)
-    _, err := repo.SetUserActive("nonexistent-user", true)
-    if !errors.Is(err, entity.ErrNotFound) {
-        t.Errorf("Expected ErrNotFound for non-existent user, got: %v", err)
-    }
+	_, err := repo.SetUserActive(context.Background(), "nonexistent-user", true)
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for non-existent user, got: %v", err)
+	}
 }
 
 func TestRepository_SetUserActive_UserWithoutTeam(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 	repo := repository.NewRepository(db)
-    _, err := db.Exec("INSERT INTO users (user_id, username, is_active) VALUES ($1, $2, $3)", 
-        "lonely_user", "Lonely", true)
-    if err != nil {
-        t.Fatalf("Failed to setup test: %v", err)
-    }
-    user, err := repo.SetUserActive("lonely_user", false)
-    if err != nil {
-        t.Errorf("Should deactivate user without team: %v", err)
-    }
-    if user.TeamName != "" {
-        t.Errorf("Expected empty team name for user without team, got: %s", user.TeamName)
-    }
+	_, err := db.Exec("INSERT INTO users (user_id, username, is_active) VALUES ($1, $2, $3)",
+		"lonely_user", "Lonely", true)
+	if err != nil {
+		t.Fatalf("Failed to setup test: %v", err)
+	}
+	user, err := repo.SetUserActive(context.Background(), "lonely_user", false)
+	if err != nil {
+		t.Errorf("Should deactivate user without team: %v", err)
+	}
+	if user.TeamName != "" {
+		t.Errorf("Expected empty team name for user without team, got: %s", user.TeamName)
+	}
 }
 
 func TestRepository_GetPR_NotExists(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 	repo := repository.NewRepository(db)
-    _, err := repo.GetPR("nonexistent-pr")
-    if !errors.Is(err, entity.ErrNotFound) {
-        t.Errorf("Expected ErrNotFound for non-existent PR, got: %v", err)
-    }
+	_, err := repo.GetPR(context.Background(), "nonexistent-pr")
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for non-existent PR, got: %v", err)
+	}
+}
+
+func TestRepository_MergePR_ReturnsReviewers(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "merge-reviewers-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-merge-reviewers", Title: "Merge", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	merged, err := repo.MergePR(ctx, "pr-merge-reviewers")
+	if err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	ids := make([]string, len(merged.AssignedReviewers))
+	for i, reviewer := range merged.AssignedReviewers {
+		ids[i] = reviewer.ID
+	}
+	if len(ids) != 2 || !contains(ids, "reviewer1") || !contains(ids, "reviewer2") {
+		t.Errorf("Expected the merged PR to list reviewer1 and reviewer2, got %v", ids)
+	}
 }
 
 func TestRepository_MergePR_AlreadyMerged(t *testing.T) {
-    db := setupTestDB(t)
-	defer db.Close()
-	repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "merge-test-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    pr := &entity.PullRequest{
-        ID:       "pr-to-merge-twice", 
-        Title:    "Test PR",
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr, []string{"reviewer1"})
-    if err != nil {
-        t.Fatalf("Failed to create PR: %v", err)
-    }
-    mergedPR1, err := repo.MergePR("pr-to-merge-twice")
-    if err != nil {
-        t.Fatalf("Failed first merge: %v", err)
-    }
-    if mergedPR1.Status != "MERGED" {
-        t.Errorf("First merge should set status to MERGED, got: %s", mergedPR1.Status)
-    }
-    mergedPR2, err := repo.MergePR("pr-to-merge-twice")
-    if err != nil {
-        t.Errorf("Second merge should be idempotent, got error: %v", err)
-    }
-    if mergedPR2.Status != "MERGED" {
-        t.Errorf("Second merge should keep status MERGED, got: %s", mergedPR2.Status)
-    }
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "merge-test-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr := &entity.PullRequest{
+		ID:       "pr-to-merge-twice",
+		Title:    "Test PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	mergedPR1, err := repo.MergePR(context.Background(), "pr-to-merge-twice")
+	if err != nil {
+		t.Fatalf("Failed first merge: %v", err)
+	}
+	if mergedPR1.Status != "MERGED" {
+		t.Errorf("First merge should set status to MERGED, got: %s", mergedPR1.Status)
+	}
+	mergedPR2, err := repo.MergePR(context.Background(), "pr-to-merge-twice")
+	if err != nil {
+		t.Errorf("Second merge should be idempotent, got error: %v", err)
+	}
+	if mergedPR2.Status != "MERGED" {
+		t.Errorf("Second merge should keep status MERGED, got: %s", mergedPR2.Status)
+	}
 }
 
 func TestRepository_GetUserReviewPRs_MultipleReviewers(t *testing.T) {
-    db := setupTestDB(t)
-	defer db.Close()
-	repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "review-test-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "author2", Username: "Author2", IsActive: true},
-        {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-        {ID: "reviewer2", Username: "Reviewer2", IsActive: true},
-        {ID: "reviewer3", Username: "Reviewer3", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    pr1 := &entity.PullRequest{
-        ID:       "pr-multi-1",
-        Title:    "PR 1", 
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr1, []string{"reviewer1", "reviewer2"})
-    if err != nil {
-        t.Fatalf("Failed to create PR1: %v", err)
-    }
-    pr2 := &entity.PullRequest{
-        ID:       "pr-multi-2",
-        Title:    "PR 2",
-        AuthorID: "author2", 
-    }
-    err = repo.CreatePR(pr2, []string{"reviewer1", "reviewer3"})
-    if err != nil {
-        t.Fatalf("Failed to create PR2: %v", err)
-    }
-    prs, err := repo.GetUserReviewPRs("reviewer1")
-    if err != nil {
-        t.Errorf("Failed to get user review PRs: %v", err)
-    }
-    if len(prs) != 2 {
-        t.Errorf("Expected 2 PRs for reviewer1, got %d", len(prs))
-    }
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "review-test-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "author2", Username: "Author2", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr1 := &entity.PullRequest{
+		ID:       "pr-multi-1",
+		Title:    "PR 1",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr1, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR1: %v", err)
+	}
+	pr2 := &entity.PullRequest{
+		ID:       "pr-multi-2",
+		Title:    "PR 2",
+		AuthorID: "author2",
+	}
+	err = repo.CreatePR(context.Background(), pr2, []string{"reviewer1", "reviewer3"})
+	if err != nil {
+		t.Fatalf("Failed to create PR2: %v", err)
+	}
+	prs, _, err := repo.GetUserReviewPRs(context.Background(), "reviewer1", "", entity.ReviewPage{})
+	if err != nil {
+		t.Errorf("Failed to get user review PRs: %v", err)
+	}
+	if len(prs) != 2 {
+		t.Errorf("Expected 2 PRs for reviewer1, got %d", len(prs))
+	}
 }
 
 func TestRepository_ReassignReviewer_ComplexScenario(t *testing.T) {
-    db := setupTestDB(t)
-	defer db.Close()
-	repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "dev-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-        {ID: "reviewer2", Username: "Reviewer2", IsActive: true},
-        {ID: "reviewer3", Username: "Reviewer3", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    pr := &entity.PullRequest{
-        ID:       "pr-reassign",
-        Title:    "Test PR",
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr, []string{"reviewer1", "reviewer2"})
-    if err != nil {
-        t.Fatalf("Failed to create PR: %v", err)
-    }
-    newReviewer, err := repo.ReassignReviewer("pr-reassign", "reviewer1")
-    if err != nil {
-        t.Errorf("Failed to reassign reviewer: %v", err)
-    }
-    if newReviewer != "reviewer3" {
-        t.Errorf("Expected new reviewer to be reviewer3, got: %s", newReviewer)
-    }
-    updatedPR, err := repo.GetPR("pr-reassign")
-    if err != nil {
-        t.Errorf("Failed to get updated PR: %v", err)
-    }
-    reviewerIDs := make([]string, len(updatedPR.AssignedReviewers))
-    for i, reviewer := range updatedPR.AssignedReviewers {
-        reviewerIDs[i] = reviewer.ID
-    }
-    if len(reviewerIDs) != 2 {
-        t.Errorf("Expected 2 reviewers, got %d", len(reviewerIDs))
-    }
-    if !contains(reviewerIDs, "reviewer2") || !contains(reviewerIDs, "reviewer3") {
-        t.Errorf("Expected reviewers [reviewer2, reviewer3], got %v", reviewerIDs)
-    }
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "dev-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr := &entity.PullRequest{
+		ID:       "pr-reassign",
+		Title:    "Test PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	newReviewer, err := repo.ReassignReviewer(context.Background(), "pr-reassign", "reviewer1")
+	if err != nil {
+		t.Errorf("Failed to reassign reviewer: %v", err)
+	}
+	if newReviewer != "reviewer3" {
+		t.Errorf("Expected new reviewer to be reviewer3, got: %s", newReviewer)
+	}
+	updatedPR, err := repo.GetPR(context.Background(), "pr-reassign")
+	if err != nil {
+		t.Errorf("Failed to get updated PR: %v", err)
+	}
+	reviewerIDs := make([]string, len(updatedPR.AssignedReviewers))
+	for i, reviewer := range updatedPR.AssignedReviewers {
+		reviewerIDs[i] = reviewer.ID
+	}
+	if len(reviewerIDs) != 2 {
+		t.Errorf("Expected 2 reviewers, got %d", len(reviewerIDs))
+	}
+	if !contains(reviewerIDs, "reviewer2") || !contains(reviewerIDs, "reviewer3") {
+		t.Errorf("Expected reviewers [reviewer2, reviewer3], got %v", reviewerIDs)
+	}
 }
 
 func TestRepository_ReassignReviewer_Errors(t *testing.T) {
-    db := setupTestDB(t)
-	defer db.Close()
-	repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "error-test-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-        {ID: "not-assigned-user", Username: "NotAssigned", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    t.Run("PRNotExists", func(t *testing.T) {
-        _, err := repo.ReassignReviewer("nonexistent-pr", "reviewer1")
-        if !errors.Is(err, entity.ErrNotFound) {
-            t.Errorf("Expected ErrNotFound for non-existent PR, got: %v", err)
-        }
-    })
-    t.Run("ReviewerNotAssigned", func(t *testing.T) {
-        pr := &entity.PullRequest{
-            ID:       "pr-error-test",
-            Title:    "Test PR",
-            AuthorID: "author1",
-        }
-        err := repo.CreatePR(pr, []string{"reviewer1"})
-        if err != nil {
-            t.Fatalf("Failed to create PR: %v", err)
-        }
-        _, err = repo.ReassignReviewer("pr-error-test", "not-assigned-user")
-        if !errors.Is(err, entity.ErrNotAssigned) {
-            t.Errorf("Expected ErrNotAssigned for not assigned reviewer, got: %v", err)
-        }
-    })
-}
-
-
-func contains(slice []string, item string) bool {
-    for _, s := range slice {
-        if s == item {
-            return true
-        }
-    }
-    return false
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "error-test-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "not-assigned-user", Username: "NotAssigned", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	t.Run("PRNotExists", func(t *testing.T) {
+		_, err := repo.ReassignReviewer(context.Background(), "nonexistent-pr", "reviewer1")
+		if !errors.Is(err, entity.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for non-existent PR, got: %v", err)
+		}
+	})
+	t.Run("ReviewerNotAssigned", func(t *testing.T) {
+		pr := &entity.PullRequest{
+			ID:       "pr-error-test",
+			Title:    "Test PR",
+			AuthorID: "author1",
+		}
+		err := repo.CreatePR(context.Background(), pr, []string{"reviewer1"})
+		if err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+		_, err = repo.ReassignReviewer(context.Background(), "pr-error-test", "not-assigned-user")
+		if !errors.Is(err, entity.ErrNotAssigned) {
+			t.Errorf("Expected ErrNotAssigned for not assigned reviewer, got: %v", err)
+		}
+	})
 }
 
-func TestRepository_CreateTeam_DuplicateMembers(t *testing.T) {
-    db := setupTestDB(t)
-    defer db.Close()
-    repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "duplicate-team"}
-    members := []entity.User{
-        {ID: "user1", Username: "User1", IsActive: true},
-        {ID: "user1", Username: "User1", IsActive: true},
-        {ID: "user2", Username: "User2", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Errorf("Should handle duplicate members gracefully, got error: %v", err)
-    }
-    _, retrievedMembers, err := repo.GetTeam("duplicate-team")
-    if err != nil {
-        t.Errorf("Should retrieve team: %v", err)
-    }
-    uniqueUsers := make(map[string]bool)
-    for _, member := range retrievedMembers {
-        if uniqueUsers[member.ID] {
-            t.Errorf("Found duplicate user in team: %s", member.ID)
-        }
-        uniqueUsers[member.ID] = true
-    }
+func candidateIDs(candidates []entity.CandidateReviewer, err error) ([]string, error) {
+	ids := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.UserID
+	}
+	return ids, err
 }
 
-func TestRepository_CreatePR_TransactionRollbackOnInvalidReviewer(t *testing.T) {
-    db := setupTestDB(t)
-    defer db.Close()
-    repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "transaction-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    pr1 := &entity.PullRequest{
-        ID:       "pr-success",
-        Title:    "Success PR",
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr1, []string{"reviewer1"})
-    if err != nil {
-        t.Fatalf("Failed to create first PR: %v", err)
-    }
-    pr2 := &entity.PullRequest{
-        ID:       "pr-fail",
-        Title:    "Fail PR", 
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr2, []string{"nonexistent-reviewer"})
-    if err == nil {
-        t.Error("Should fail when reviewer doesn't exist")
-    }
-    _, err = repo.GetPR("pr-fail")
-    if !errors.Is(err, entity.ErrNotFound) {
-        t.Errorf("Failed PR should not be created, got: %v", err)
-    }
-    existingPR, err := repo.GetPR("pr-success")
-    if err != nil {
-        t.Errorf("First PR should still exist: %v", err)
-    }
-    if existingPR.ID != "pr-success" {
-        t.Errorf("First PR was affected by second PR's failure")
-    }
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
 }
 
-func TestRepository_ReassignReviewer_PRAlreadyMerged(t *testing.T) {
-    db := setupTestDB(t)
-    defer db.Close()
-    repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "merged-pr-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-        {ID: "reviewer2", Username: "Reviewer2", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    pr := &entity.PullRequest{
-        ID:       "pr-merged",
-        Title:    "Test PR",
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr, []string{"reviewer1"})
-    if err != nil {
-        t.Fatalf("Failed to create PR: %v", err)
-    }
-    _, err = repo.MergePR("pr-merged")
-    if err != nil {
-        t.Fatalf("Failed to merge PR: %v", err)
-    }
-    _, err = repo.ReassignReviewer("pr-merged", "reviewer1")
-    if !errors.Is(err, entity.ErrPRMerged) {
-        t.Errorf("Expected ErrPRMerged for merged PR, got: %v", err)
-    }
+func TestRepository_TeamReviewerCount(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	if err := repo.CreateTeam(ctx, &entity.Team{Name: "infra", DefaultReviewers: 3}, nil); err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreateTeam(ctx, &entity.Team{Name: "docs"}, nil); err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	for team, want := range map[string]int{"infra": 3, "docs": 0} {
+		got, err := repo.GetTeamReviewerCount(ctx, team)
+		if err != nil || got != want {
+			t.Errorf("Expected %s to have %d default reviewers, got %d, %v", team, want, got, err)
+		}
+	}
+	if err := repo.SetTeamReviewerCount(ctx, "Docs", 1); err != nil {
+		t.Fatalf("SetTeamReviewerCount failed: %v", err)
+	}
+	if got, err := repo.GetTeamReviewerCount(ctx, "DOCS"); err != nil || got != 1 {
+		t.Errorf("Expected docs to have 1 default reviewer, got %d, %v", got, err)
+	}
+	if err := repo.SetTeamReviewerCount(ctx, "missing", 1); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from SetTeamReviewerCount, got %v", err)
+	}
+	if _, err := repo.GetTeamReviewerCount(ctx, "missing"); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from GetTeamReviewerCount, got %v", err)
+	}
 }
 
-func TestRepository_ReassignReviewer_PRStillOpen(t *testing.T) {
-    db := setupTestDB(t)
-    defer db.Close()
-    repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "open-pr-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-        {ID: "reviewer2", Username: "Reviewer2", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    pr := &entity.PullRequest{
-        ID:       "pr-open",
-        Title:    "Test PR",
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr, []string{"reviewer1"})
-    if err != nil {
-        t.Fatalf("Failed to create PR: %v", err)
-    }
-    currentPR, err := repo.GetPR("pr-open")
-    if err != nil {
-        t.Fatalf("Failed to get PR: %v", err)
-    }
-    if currentPR.Status != "OPEN" {
-        t.Errorf("PR should be OPEN before reassignment, got: %s", currentPR.Status)
-    }
-    newReviewer, err := repo.ReassignReviewer("pr-open", "reviewer1")
-    if errors.Is(err, entity.ErrPRMerged) {
-        t.Error("Should not get ErrPRMerged for open PR")
-    }
-    if err == nil {
-        if newReviewer == "" {
-            t.Error("Should get new reviewer ID")
-        }
-    }
+func TestRepository_CountActiveMembers(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "backend"}, []entity.User{
+		{ID: "u1", Username: "U1", IsActive: true},
+		{ID: "u2", Username: "U2", IsActive: true},
+		{ID: "u3", Username: "U3", IsActive: false},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	for _, name := range []string{"backend", "Backend"} {
+		if got, err := repo.CountActiveMembers(ctx, name); err != nil || got != 2 {
+			t.Errorf("Expected %s to have 2 active members, got %d, %v", name, got, err)
+		}
+	}
 }
 
-func TestRepository_ReassignReviewer_NoCandidatesInTeam(t *testing.T) {
-    db := setupTestDB(t)
-    defer db.Close()
-    repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "no-candidates-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    pr := &entity.PullRequest{
-        ID:       "pr-no-candidates",
-        Title:    "Test PR",
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr, []string{"reviewer1"})
-    if err != nil {
-        t.Fatalf("Failed to create PR: %v", err)
-    }
-    _, err = repo.ReassignReviewer("pr-no-candidates", "reviewer1")
-    if !errors.Is(err, entity.ErrNoCandidate) {
-        t.Errorf("Expected ErrNoCandidate when no candidates available, got: %v", err)
-    }
+func TestRepository_RenameTeam(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	members := []entity.User{{ID: "u1", Username: "Alice", IsActive: true}}
+	if err := repo.CreateTeam(ctx, &entity.Team{Name: "payments"}, members); err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreateTeam(ctx, &entity.Team{Name: "platform"}, nil); err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.RenameTeam(ctx, "payments", "billing"); err != nil {
+		t.Fatalf("RenameTeam failed: %v", err)
+	}
+	team, users, err := repo.GetTeam(ctx, "billing")
+	if err != nil {
+		t.Fatalf("GetTeam(billing) failed: %v", err)
+	}
+	if team.Name != "billing" || len(users) != 1 || users[0].ID != "u1" {
+		t.Errorf("Expected billing with member u1, got %v %v", team, users)
+	}
+	if _, _, err := repo.GetTeam(ctx, "payments"); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for the old name, got %v", err)
+	}
+	if err := repo.RenameTeam(ctx, "billing", "PLATFORM"); !errors.Is(err, entity.ErrTeamExists) {
+		t.Errorf("Expected ErrTeamExists on a case-insensitive collision, got %v", err)
+	}
+	if err := repo.RenameTeam(ctx, "missing", "anything"); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing team, got %v", err)
+	}
+	if err := repo.RenameTeam(ctx, "billing", "Billing"); err != nil {
+		t.Errorf("Expected a team to be renamable to a different casing, got %v", err)
+	}
 }
 
-func TestRepository_ReassignReviewer_AllPotentialCandidatesAlreadyReviewers(t *testing.T) {
-    db := setupTestDB(t)
-    defer db.Close()
-    repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "all-reviewers-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-        {ID: "reviewer2", Username: "Reviewer2", IsActive: true},
-        {ID: "reviewer3", Username: "Reviewer3", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    pr := &entity.PullRequest{
-        ID:       "pr-all-reviewers",
-        Title:    "Test PR",
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr, []string{"reviewer1", "reviewer2", "reviewer3"})
-    if err != nil {
-        t.Fatalf("Failed to create PR: %v", err)
-    }
-    _, err = repo.ReassignReviewer("pr-all-reviewers", "reviewer1")
-    if !errors.Is(err, entity.ErrNoCandidate) {
-        t.Errorf("Expected ErrNoCandidate when all candidates are already reviewers, got: %v", err)
-    }
+func TestRepository_CreateTeam_DuplicateUsernames(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	members := []entity.User{
+		{ID: "alice1", Username: "Alice", IsActive: true},
+		{ID: "alice2", Username: "alice", IsActive: true},
+	}
+	strict := repository.NewRepositoryWithConfig(db, repository.Config{UniqueUsernames: true})
+	err := strict.CreateTeam(ctx, &entity.Team{Name: "strict-team"}, members)
+	if !errors.Is(err, entity.ErrDuplicateUsername) {
+		t.Fatalf("Expected ErrDuplicateUsername, got %v", err)
+	}
+	if _, _, err := strict.GetTeam(ctx, "strict-team"); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected the rejected team not to be stored, got %v", err)
+	}
+	results, err := strict.CreateTeamsBulk(ctx, []entity.TeamWithMembers{{TeamName: "strict-bulk", Members: members}})
+	if err != nil {
+		t.Fatalf("CreateTeamsBulk failed: %v", err)
+	}
+	if len(results) != 1 || !errors.Is(results[0].Err, entity.ErrDuplicateUsername) {
+		t.Errorf("Expected ErrDuplicateUsername in the import result, got %v", results)
+	}
+	lenient := repository.NewRepository(db)
+	err = lenient.CreateTeam(ctx, &entity.Team{Name: "lenient-team"}, members)
+	if err != nil {
+		t.Errorf("Expected duplicate usernames to be allowed by default, got %v", err)
+	}
 }
 
-func TestRepository_GetStats_ComplexScenario(t *testing.T) {
-    db := setupTestDB(t)
-    defer db.Close()
-    repo := repository.NewRepository(db)
-    teams := []struct {
-        name    string
-        members []entity.User
-    }{
-        {
-            name: "team-a",
-            members: []entity.User{
-                {ID: "author-a", Username: "AuthorA", IsActive: true},
-                {ID: "reviewer-a1", Username: "ReviewerA1", IsActive: true},
-                {ID: "reviewer-a2", Username: "ReviewerA2", IsActive: true},
-            },
-        },
-        {
-            name: "team-b", 
-            members: []entity.User{
-                {ID: "author-b", Username: "AuthorB", IsActive: true},
-                {ID: "reviewer-b1", Username: "ReviewerB1", IsActive: true},
-                {ID: "reviewer-b2", Username: "ReviewerB2", IsActive: true},
-            },
-        },
-    }
-    for _, team := range teams {
-        err := repo.CreateTeam(&entity.Team{Name: team.name}, team.members)
-        if err != nil {
-            t.Fatalf("Failed to create team %s: %v", team.name, err)
-        }
-    }
-    testPRs := []struct {
-        id       string
-        title    string
-        author   string
-        reviewers []string
-    }{
-        {"pr-a-1", "Feature A1", "author-a", []string{"reviewer-a1", "reviewer-a2"}},
-        {"pr-a-2", "Feature A2", "author-a", []string{"reviewer-a1"}},
-        {"pr-a-3", "Feature A3", "author-a", []string{"reviewer-a2"}},
-        {"pr-b-1", "Feature B1", "author-b", []string{"reviewer-b1"}},
-        {"pr-b-2", "Feature B2", "author-b", []string{"reviewer-b1", "reviewer-b2"}},
-    }
-    for _, prData := range testPRs {
-        pr := &entity.PullRequest{
-            ID:       prData.id,
-            Title:    prData.title,
-            AuthorID: prData.author,
-        }
-        err := repo.CreatePR(pr, prData.reviewers)
-        if err != nil {
-            t.Fatalf("Failed to create PR %s: %v", prData.id, err)
-        }
-    }
-    stats, err := repo.GetStats()
-    if err != nil {
-        t.Fatalf("GetStats failed: %v", err)
-    }
-    expectedTotal := 2 + 1 + 1 + 1 + 2
-    if stats.TotalAssignments != expectedTotal {
-        t.Errorf("Expected %d total assignments, got %d", expectedTotal, stats.TotalAssignments)
-    }
-    userAssignments := make(map[string]int)
-    for _, uac := range stats.UserAssignmentCounts {
-        userAssignments[uac.UserID] = uac.Count
-    }
-    expectedUserAssignments := map[string]int{
-        "reviewer-a1": 2,
-        "reviewer-a2": 2, 
-        "reviewer-b1": 2,
-        "reviewer-b2": 1, 
-    }
-    for userID, expectedCount := range expectedUserAssignments {
-        if userAssignments[userID] != expectedCount {
-            t.Errorf("User %s should have %d assignments, got %d", userID, expectedCount, userAssignments[userID])
-        }
-    }
-    prAssignments := make(map[string]int)
-    for _, prac := range stats.PRAssignmentCounts {
-        prAssignments[prac.PRID] = prac.Count
-    }
-    expectedPRAssignments := map[string]int{
-        "pr-a-1": 2,
-        "pr-a-2": 1, 
-        "pr-a-3": 1,
-        "pr-b-1": 1,
-        "pr-b-2": 2,
-    }
-    for prID, expectedCount := range expectedPRAssignments {
-        if prAssignments[prID] != expectedCount {
-            t.Errorf("PR %s should have %d assignments, got %d", prID, expectedCount, prAssignments[prID])
-        }
-    }
+func TestRepository_CreateTeam_DuplicateMembers(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "duplicate-team"}
+	members := []entity.User{
+		{ID: "user1", Username: "User1", IsActive: true},
+		{ID: "user1", Username: "User1", IsActive: true},
+		{ID: "user2", Username: "User2", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Errorf("Should handle duplicate members gracefully, got error: %v", err)
+	}
+	_, retrievedMembers, err := repo.GetTeam(context.Background(), "duplicate-team")
+	if err != nil {
+		t.Errorf("Should retrieve team: %v", err)
+	}
+	uniqueUsers := make(map[string]bool)
+	for _, member := range retrievedMembers {
+		if uniqueUsers[member.ID] {
+			t.Errorf("Found duplicate user in team: %s", member.ID)
+		}
+		uniqueUsers[member.ID] = true
+	}
 }
 
-func TestRepository_GetStats_AfterReassignment(t *testing.T) {
-    db := setupTestDB(t)
-    defer db.Close()
-    repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "reassign-stats-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-        {ID: "reviewer2", Username: "Reviewer2", IsActive: true},
-        {ID: "reviewer3", Username: "Reviewer3", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    pr := &entity.PullRequest{
-        ID:       "pr-reassign-stats",
-        Title:    "Test PR",
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr, []string{"reviewer1", "reviewer2"})
-    if err != nil {
-        t.Fatalf("Failed to create PR: %v", err)
-    }
-    statsBefore, err := repo.GetStats()
-    if err != nil {
-        t.Fatalf("GetStats before reassignment failed: %v", err)
-    }
-    _, err = repo.ReassignReviewer("pr-reassign-stats", "reviewer1")
-    if err != nil {
-        t.Fatalf("ReassignReviewer failed: %v", err)
-    }
-    statsAfter, err := repo.GetStats()
-    if err != nil {
-        t.Fatalf("GetStats after reassignment failed: %v", err)
-    }
-    if statsBefore.TotalAssignments != statsAfter.TotalAssignments {
-        t.Errorf("Total assignments should remain the same after reassignment, was %d, now %d", 
-            statsBefore.TotalAssignments, statsAfter.TotalAssignments)
-    }
-    var reviewer1Before, reviewer1After int
-    for _, uac := range statsBefore.UserAssignmentCounts {
-        if uac.UserID == "reviewer1" {
-            reviewer1Before = uac.Count
-        }
-    }
-    for _, uac := range statsAfter.UserAssignmentCounts {
-        if uac.UserID == "reviewer1" {
-            reviewer1After = uac.Count
-        }
-    }
-    if reviewer1After >= reviewer1Before {
-        t.Errorf("Reviewer1 assignments should decrease after reassignment, was %d, now %d", 
-            reviewer1Before, reviewer1After)
-    }
+func TestRepository_CreatePR_TransactionRollbackOnInvalidReviewer(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "transaction-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr1 := &entity.PullRequest{
+		ID:       "pr-success",
+		Title:    "Success PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr1, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create first PR: %v", err)
+	}
+	pr2 := &entity.PullRequest{
+		ID:       "pr-fail",
+		Title:    "Fail PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr2, []string{"nonexistent-reviewer"})
+	if err == nil {
+		t.Error("Should fail when reviewer doesn't exist")
+	}
+	_, err = repo.GetPR(context.Background(), "pr-fail")
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Failed PR should not be created, got: %v", err)
+	}
+	existingPR, err := repo.GetPR(context.Background(), "pr-success")
+	if err != nil {
+		t.Errorf("First PR should still exist: %v", err)
+	}
+	if existingPR.ID != "pr-success" {
+		t.Errorf("First PR was affected by second PR's failure")
+	}
+	pr3 := &entity.PullRequest{
+		ID:       "pr-partial",
+		Title:    "Partial PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr3, []string{"reviewer1", "nonexistent-reviewer"})
+	if err == nil {
+		t.Error("Should fail when any reviewer doesn't exist")
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM reviewers WHERE pull_request_id = $1", "pr-partial").Scan(&count); err != nil {
+		t.Fatalf("Failed to count reviewers: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no reviewer rows for the rolled back PR, got %d", count)
+	}
 }
 
-func TestRepository_GetStats_WithMergedPRs(t *testing.T) {
-    db := setupTestDB(t)
-    defer db.Close()
-    repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "merged-stats-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-        {ID: "reviewer2", Username: "Reviewer2", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    pr1 := &entity.PullRequest{
-        ID:       "pr-merged-1",
-        Title:    "Merged PR",
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr1, []string{"reviewer1", "reviewer2"})
-    if err != nil {
-        t.Fatalf("Failed to create PR1: %v", err)
-    }
-    pr2 := &entity.PullRequest{
-        ID:       "pr-open-1", 
-        Title:    "Open PR",
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr2, []string{"reviewer1"})
-    if err != nil {
-        t.Fatalf("Failed to create PR2: %v", err)
-    }
-    _, err = repo.MergePR("pr-merged-1")
-    if err != nil {
-        t.Fatalf("Failed to merge PR: %v", err)
-    }
-    stats, err := repo.GetStats()
-    if err != nil {
-        t.Fatalf("GetStats failed: %v", err)
-    }
-    if stats.TotalAssignments != 3 { 
-        t.Errorf("Expected 3 total assignments including merged PRs, got %d", stats.TotalAssignments)
-    }
-    var foundMergedPR, foundOpenPR bool
-    for _, prac := range stats.PRAssignmentCounts {
-        if prac.PRID == "pr-merged-1" {
-            foundMergedPR = true
-            if prac.Count != 2 {
-                t.Errorf("Merged PR should have 2 assignments, got %d", prac.Count)
-            }
-        }
-        if prac.PRID == "pr-open-1" {
-            foundOpenPR = true
-            if prac.Count != 1 {
-                t.Errorf("Open PR should have 1 assignment, got %d", prac.Count)
-            }
-        }
-    }
-    if !foundMergedPR {
-        t.Error("Merged PR should be included in stats")
-    }
-    if !foundOpenPR {
-        t.Error("Open PR should be included in stats")
-    }
+func TestRepository_CreatePR_ManyReviewers(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	members := []entity.User{{ID: "author1", Username: "Author1", IsActive: true}}
+	var reviewerIDs []string
+	for i := 1; i <= 6; i++ {
+		id := fmt.Sprintf("reviewer%d", i)
+		members = append(members, entity.User{ID: id, Username: "R" + id, IsActive: true})
+		reviewerIDs = append(reviewerIDs, id)
+	}
+	if err := repo.CreateTeam(context.Background(), &entity.Team{Name: "batch-team"}, members); err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr := &entity.PullRequest{ID: "pr-batch", Title: "Batch PR", AuthorID: "author1"}
+	if err := repo.CreatePR(context.Background(), pr, reviewerIDs); err != nil {
+		t.Fatalf("CreatePR failed: %v", err)
+	}
+	rows, err := db.Query("SELECT user_id FROM reviewers WHERE pull_request_id = $1 AND is_active = true ORDER BY user_id", "pr-batch")
+	if err != nil {
+		t.Fatalf("Failed to query reviewers: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("Failed to scan reviewer: %v", err)
+		}
+		got = append(got, id)
+	}
+	if !reflect.DeepEqual(got, reviewerIDs) {
+		t.Errorf("Expected reviewers %v, got %v", reviewerIDs, got)
+	}
 }
 
-func TestRepository_GetStats_UserWithoutAssignments(t *testing.T) {
-    db := setupTestDB(t)
-    defer db.Close()
-    repo := repository.NewRepository(db)
-    
-    team := &entity.Team{Name: "no-assignments-team"}
-    members := []entity.User{
-        {ID: "author1", Username: "Author1", IsActive: true},
-        {ID: "reviewer-no-assignments", Username: "ReviewerNoAssign", IsActive: true},
-        {ID: "reviewer-with-assignments", Username: "ReviewerWithAssign", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    pr := &entity.PullRequest{
-        ID:       "pr-single-reviewer",
-        Title:    "Test PR", 
-        AuthorID: "author1",
-    }
-    err = repo.CreatePR(pr, []string{"reviewer-with-assignments"})
-    if err != nil {
-        t.Fatalf("Failed to create PR: %v", err)
-    }
-    stats, err := repo.GetStats()
-    if err != nil {
-        t.Fatalf("GetStats failed: %v", err)
-    }
-    var foundUserWithAssignments, foundUserWithoutAssignments bool
-    for _, uac := range stats.UserAssignmentCounts {
-        if uac.UserID == "reviewer-with-assignments" {
-            foundUserWithAssignments = true
-            if uac.Count != 1 {
-                t.Errorf("User with assignments should have count 1, got %d", uac.Count)
-            }
-        }
-        if uac.UserID == "reviewer-no-assignments" {
-            foundUserWithoutAssignments = true
-            if uac.Count != 0 {
-                t.Errorf("User without assignments should have count 0, got %d", uac.Count)
-            }
-        }
-    }
-    if !foundUserWithAssignments {
-        t.Error("User with assignments should be in stats")
-    }
-    if !foundUserWithoutAssignments {
-        t.Error("User without assignments should be in stats with count 0")
-    }
-}
+func TestRepository_ReassignReviewer_PRAlreadyMerged(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "merged-pr-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr := &entity.PullRequest{
+		ID:       "pr-merged",
+		Title:    "Test PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = repo.MergePR(context.Background(), "pr-merged")
+	if err != nil {
+		t.Fatalf("Failed to merge PR: %v", err)
+	}
+	_, err = repo.ReassignReviewer(context.Background(), "pr-merged", "reviewer1")
+	if !errors.Is(err, entity.ErrPRMerged) {
+		t.Errorf("Expected ErrPRMerged for merged PR, got: %v", err)
+	}
+}
+
+func TestRepository_ReassignReviewer_PRStillOpen(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "open-pr-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr := &entity.PullRequest{
+		ID:       "pr-open",
+		Title:    "Test PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	currentPR, err := repo.GetPR(context.Background(), "pr-open")
+	if err != nil {
+		t.Fatalf("Failed to get PR: %v", err)
+	}
+	if currentPR.Status != "OPEN" {
+		t.Errorf("PR should be OPEN before reassignment, got: %s", currentPR.Status)
+	}
+	newReviewer, err := repo.ReassignReviewer(context.Background(), "pr-open", "reviewer1")
+	if errors.Is(err, entity.ErrPRMerged) {
+		t.Error("Should not get ErrPRMerged for open PR")
+	}
+	if err == nil {
+		if newReviewer == "" {
+			t.Error("Should get new reviewer ID")
+		}
+	}
+}
+
+func TestRepository_ReassignReviewer_WritesHistory(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "history-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-history", Title: "History", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	history, err := repo.GetReassignmentHistory(ctx, "pr-history")
+	if err != nil {
+		t.Fatalf("GetReassignmentHistory failed: %v", err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("Expected empty history before reassigning, got %v", history)
+	}
+	newUserID, err := repo.ReassignReviewer(ctx, "pr-history", "reviewer1")
+	if err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	history, err = repo.GetReassignmentHistory(ctx, "pr-history")
+	if err != nil {
+		t.Fatalf("GetReassignmentHistory failed: %v", err)
+	}
+	if len(history) != 1 || history[0].OldUserID != "reviewer1" || history[0].NewUserID != newUserID || history[0].ReassignedAt == nil {
+		t.Errorf("Expected one entry reviewer1 -> %s, got %v", newUserID, history)
+	}
+	_, err = repo.GetReassignmentHistory(ctx, "pr-missing")
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown PR, got %v", err)
+	}
+}
+
+func TestRepository_ReassignReviewer_UsesTeamAtCreation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "old-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreateTeam(ctx, &entity.Team{Name: "new-team"}, []entity.User{
+		{ID: "outsider", Username: "Outsider", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-moved", Title: "Moved", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = db.Exec(`
+		UPDATE team_members SET team_id = (SELECT team_id FROM teams WHERE team_name = 'new-team')
+		WHERE user_id = 'author1'
+	`)
+	if err != nil {
+		t.Fatalf("Failed to move author: %v", err)
+	}
+	newUserID, err := repo.ReassignReviewer(ctx, "pr-moved", "reviewer1")
+	if err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	if newUserID != "reviewer2" {
+		t.Errorf("Expected reviewer2 from the PR's original team, got %s", newUserID)
+	}
+}
+
+func TestRepository_GetPRTimeline(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "timeline-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-timeline", Title: "Timeline", AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	if _, err := repo.ReassignReviewer(ctx, "pr-timeline", "reviewer1"); err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	if _, err := repo.MergePR(ctx, "pr-timeline"); err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	timeline, err := repo.GetPRTimeline(ctx, "pr-timeline")
+	if err != nil {
+		t.Fatalf("GetPRTimeline failed: %v", err)
+	}
+	if len(timeline) != 3 {
+		t.Fatalf("Expected 3 events, got %+v", timeline)
+	}
+	for i, want := range []string{entity.TimelineCreated, entity.TimelineReassigned, entity.TimelineMerged} {
+		if timeline[i].Type != want || timeline[i].At == nil {
+			t.Errorf("Expected event %d to be a timestamped %s, got %+v", i, want, timeline[i])
+		}
+	}
+	if timeline[1].OldUserID != "reviewer1" || timeline[1].NewUserID != "reviewer2" {
+		t.Errorf("Expected reviewer1 -> reviewer2, got %+v", timeline[1])
+	}
+	if _, err := repo.GetPRTimeline(ctx, "pr-missing"); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown PR, got %v", err)
+	}
+}
+
+func TestRepository_ReassignReviewer_PrefersLeastLoaded(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "load-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "a-busy", Username: "Busy", IsActive: true},
+		{ID: "z-idle", Username: "Idle", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	for _, id := range []string{"pr-load-1", "pr-load-2", "pr-load-3"} {
+		if err := repo.CreatePR(ctx, &entity.PullRequest{ID: id, Title: id, AuthorID: "author1"}, []string{"a-busy"}); err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+	}
+	// Merged reviews are not current load.
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-done", Title: "Done", AuthorID: "author1"}, []string{"z-idle"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	if _, err := repo.MergePR(ctx, "pr-done"); err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-target", Title: "Target", AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	newUserID, err := repo.ReassignReviewer(ctx, "pr-target", "reviewer1")
+	if err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	if newUserID != "z-idle" {
+		t.Errorf("Expected the idle z-idle as replacement, got %s", newUserID)
+	}
+}
+
+func TestRepository_RespondReview(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "respond-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-respond", Title: "Respond", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	state := func(userID string) string {
+		var state string
+		if err := db.QueryRow("SELECT state FROM reviewers WHERE pull_request_id = 'pr-respond' AND user_id = $1", userID).Scan(&state); err != nil {
+			t.Fatalf("Failed to read state of %s: %v", userID, err)
+		}
+		return state
+	}
+	activeReviewers := func() []string {
+		reviewers, err := repo.GetPRReviewers(ctx, "pr-respond")
+		if err != nil {
+			t.Fatalf("GetPRReviewers failed: %v", err)
+		}
+		ids := []string{}
+		for _, reviewer := range reviewers {
+			ids = append(ids, reviewer.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	t.Run("accept", func(t *testing.T) {
+		newUserID, err := repo.RespondReview(ctx, "pr-respond", "reviewer1", true)
+		if err != nil || newUserID != "" {
+			t.Fatalf("Expected a plain accept, got %q, %v", newUserID, err)
+		}
+		if got := state("reviewer1"); got != entity.ReviewAccepted {
+			t.Errorf("Expected ACCEPTED, got %s", got)
+		}
+	})
+
+	t.Run("decline with replacement", func(t *testing.T) {
+		newUserID, err := repo.RespondReview(ctx, "pr-respond", "reviewer2", false)
+		if err != nil {
+			t.Fatalf("RespondReview failed: %v", err)
+		}
+		if newUserID != "reviewer3" {
+			t.Errorf("Expected reviewer3 to take over, got %q", newUserID)
+		}
+		if got := state("reviewer2"); got != entity.ReviewDeclined {
+			t.Errorf("Expected DECLINED, got %s", got)
+		}
+		if got := activeReviewers(); !reflect.DeepEqual(got, []string{"reviewer1", "reviewer3"}) {
+			t.Errorf("Expected reviewer1 and reviewer3 active, got %v", got)
+		}
+	})
+
+	t.Run("decline with no candidate", func(t *testing.T) {
+		newUserID, err := repo.RespondReview(ctx, "pr-respond", "reviewer3", false)
+		if err != nil {
+			t.Fatalf("RespondReview failed: %v", err)
+		}
+		if newUserID != "" {
+			t.Errorf("Expected no replacement since reviewer2 already declined, got %q", newUserID)
+		}
+		if got := state("reviewer3"); got != entity.ReviewDeclined {
+			t.Errorf("Expected DECLINED, got %s", got)
+		}
+		if got := activeReviewers(); !reflect.DeepEqual(got, []string{"reviewer1"}) {
+			t.Errorf("Expected only reviewer1 active, got %v", got)
+		}
+	})
+
+	t.Run("not assigned", func(t *testing.T) {
+		if _, err := repo.RespondReview(ctx, "pr-respond", "reviewer2", true); !errors.Is(err, entity.ErrNotAssigned) {
+			t.Errorf("Expected ErrNotAssigned for a declined reviewer, got %v", err)
+		}
+		if _, err := repo.RespondReview(ctx, "pr-respond", "author1", false); !errors.Is(err, entity.ErrNotAssigned) {
+			t.Errorf("Expected ErrNotAssigned for a non-reviewer, got %v", err)
+		}
+		if _, err := repo.RespondReview(ctx, "pr-missing", "reviewer1", true); !errors.Is(err, entity.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestRepository_ReassignAllForUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "stay-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "leaver", Username: "Leaver", IsActive: true},
+		{ID: "backup", Username: "Backup", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreateTeam(ctx, &entity.Team{Name: "small-team"}, []entity.User{
+		{ID: "author2", Username: "Author2", IsActive: true},
+		{ID: "leaver", Username: "Leaver", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	// pr-b belongs to small-team, where nobody but the leaver can review.
+	for _, pr := range []struct{ id, author string }{{"pr-a", "author1"}, {"pr-b", "author2"}, {"pr-c", "author1"}} {
+		if err := repo.CreatePR(ctx, &entity.PullRequest{ID: pr.id, Title: pr.id, AuthorID: pr.author}, []string{"leaver"}); err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+	}
+	reviewsOf := func(prID string) string {
+		reviewers, err := repo.GetPRReviewers(ctx, prID)
+		if err != nil {
+			t.Fatalf("GetPRReviewers failed: %v", err)
+		}
+		var ids []string
+		for _, reviewer := range reviewers {
+			ids = append(ids, reviewer.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	results, err := repo.ReassignAllForUser(ctx, "leaver", false)
+	if !errors.Is(err, entity.ErrNoCandidate) {
+		t.Fatalf("Expected ErrNoCandidate in strict mode, got %v", err)
+	}
+	if len(results) != 2 || results[1].PRID != "pr-b" || results[1].Error != "NO_CANDIDATE" {
+		t.Errorf("Expected results to stop at pr-b, got %+v", results)
+	}
+	for _, prID := range []string{"pr-a", "pr-b", "pr-c"} {
+		if got := reviewsOf(prID); got != "leaver" {
+			t.Errorf("Strict mode should roll back; %s reviewers are %q", prID, got)
+		}
+	}
+
+	results, err = repo.ReassignAllForUser(ctx, "leaver", true)
+	if err != nil {
+		t.Fatalf("ReassignAllForUser failed: %v", err)
+	}
+	want := []entity.ReassignResult{
+		{PRID: "pr-a", NewUserID: "backup"},
+		{PRID: "pr-b", Error: "NO_CANDIDATE"},
+		{PRID: "pr-c", NewUserID: "backup"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Expected %+v, got %+v", want, results)
+	}
+	for prID, wantReviewers := range map[string]string{"pr-a": "backup", "pr-b": "leaver", "pr-c": "backup"} {
+		if got := reviewsOf(prID); got != wantReviewers {
+			t.Errorf("Expected %s reviewers %q, got %q", prID, wantReviewers, got)
+		}
+	}
+
+	if _, err := repo.ReassignAllForUser(ctx, "ghost", true); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRepository_AddReviewer_UsesTeamAtCreation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "old-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreateTeam(ctx, &entity.Team{Name: "new-team"}, []entity.User{
+		{ID: "outsider", Username: "Outsider", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-moved", Title: "Moved", AuthorID: "author1"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = db.Exec(`
+		UPDATE team_members SET team_id = (SELECT team_id FROM teams WHERE team_name = 'new-team')
+		WHERE user_id = 'author1'
+	`)
+	if err != nil {
+		t.Fatalf("Failed to move author: %v", err)
+	}
+	if err := repo.AddReviewer(ctx, "pr-moved", "outsider"); !errors.Is(err, entity.ErrNoCandidate) {
+		t.Errorf("Expected ErrNoCandidate for the author's new teammate, got %v", err)
+	}
+	if err := repo.AddReviewer(ctx, "pr-moved", "reviewer1"); err != nil {
+		t.Errorf("Expected reviewer1 from the PR's original team to be added, got %v", err)
+	}
+}
+
+func TestRepository_CreatePR_MultiTeamAuthorTeam(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	for _, name := range []string{"zeta", "alpha"} {
+		err := repo.CreateTeam(ctx, &entity.Team{Name: name}, []entity.User{{ID: "multi", Username: "Multi", IsActive: true}})
+		if err != nil {
+			t.Fatalf("Failed to create team %s: %v", name, err)
+		}
+	}
+	err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-multi", Title: "Multi", AuthorID: "multi"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	var teamName string
+	err = db.QueryRow(`
+		SELECT t.team_name FROM pull_requests pr JOIN teams t ON t.team_id = pr.team_id
+		WHERE pr.pull_request_id = 'pr-multi'
+	`).Scan(&teamName)
+	if err != nil {
+		t.Fatalf("Failed to read the PR's team: %v", err)
+	}
+	if teamName != "alpha" {
+		t.Errorf("Expected the author's first team by name, got %s", teamName)
+	}
+}
+
+func TestRepository_ReassignReviewer_HistoryRollsBackWithReassign(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "history-rollback-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-rollback", Title: "Rollback", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = db.Exec("ALTER TABLE reassignment_log ADD CONSTRAINT reject_all CHECK (false) NOT VALID")
+	if err != nil {
+		t.Fatalf("Failed to break reassignment_log: %v", err)
+	}
+	_, err = repo.ReassignReviewer(ctx, "pr-rollback", "reviewer1")
+	if err == nil {
+		t.Fatal("Expected ReassignReviewer to fail when the log write fails")
+	}
+	reviewers, err := repo.GetPRReviewers(ctx, "pr-rollback")
+	if err != nil {
+		t.Fatalf("GetPRReviewers failed: %v", err)
+	}
+	if len(reviewers) != 1 || reviewers[0].ID != "reviewer1" {
+		t.Errorf("Expected the reviewer change to be rolled back, got %v", reviewers)
+	}
+	history, err := repo.GetReassignmentHistory(ctx, "pr-rollback")
+	if err != nil {
+		t.Fatalf("GetReassignmentHistory failed: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("Expected no history entries, got %v", history)
+	}
+}
+
+func TestRepository_CreatePR_WritesOneEvent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "outbox-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-outbox", Title: "Outbox", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	events, err := repo.FetchUnpublishedEvents(ctx, 10)
+	if err != nil {
+		t.Fatalf("FetchUnpublishedEvents failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != entity.EventReviewersAssigned {
+		t.Fatalf("Expected one reviewers_assigned event, got %+v", events)
+	}
+	var payload struct {
+		PRID      string   `json:"pull_request_id"`
+		Reviewers []string `json:"reviewers"`
+	}
+	if err := json.Unmarshal(events[0].Payload, &payload); err != nil {
+		t.Fatalf("Failed to parse payload: %v", err)
+	}
+	if payload.PRID != "pr-outbox" || len(payload.Reviewers) != 1 || payload.Reviewers[0] != "reviewer1" {
+		t.Errorf("Unexpected payload %s", events[0].Payload)
+	}
+	if err := repo.MarkEventPublished(ctx, events[0].ID); err != nil {
+		t.Fatalf("MarkEventPublished failed: %v", err)
+	}
+	events, err = repo.FetchUnpublishedEvents(ctx, 10)
+	if err != nil {
+		t.Fatalf("FetchUnpublishedEvents failed: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no unpublished events, got %+v", events)
+	}
+	if err := repo.MarkEventPublished(ctx, 9999); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown event, got %v", err)
+	}
+}
+
+func TestRepository_CreatePR_RollbackWritesNoEvent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "outbox-rollback-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-broken", Title: "Broken", AuthorID: "author1"}, []string{"ghost"})
+	if err == nil {
+		t.Fatal("Expected CreatePR to fail for an unknown reviewer")
+	}
+	events, err := repo.FetchUnpublishedEvents(ctx, 10)
+	if err != nil {
+		t.Fatalf("FetchUnpublishedEvents failed: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected the rolled-back CreatePR to leave no events, got %+v", events)
+	}
+}
+
+func TestRepository_ReassignReviewer_NoCandidatesInTeam(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "no-candidates-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr := &entity.PullRequest{
+		ID:       "pr-no-candidates",
+		Title:    "Test PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = repo.ReassignReviewer(context.Background(), "pr-no-candidates", "reviewer1")
+	if !errors.Is(err, entity.ErrNoCandidate) {
+		t.Errorf("Expected ErrNoCandidate when no candidates available, got: %v", err)
+	}
+}
+
+func TestRepository_ReassignReviewer_AllPotentialCandidatesAlreadyReviewers(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "all-reviewers-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr := &entity.PullRequest{
+		ID:       "pr-all-reviewers",
+		Title:    "Test PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr, []string{"reviewer1", "reviewer2", "reviewer3"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = repo.ReassignReviewer(context.Background(), "pr-all-reviewers", "reviewer1")
+	if !errors.Is(err, entity.ErrNoCandidate) {
+		t.Errorf("Expected ErrNoCandidate when all candidates are already reviewers, got: %v", err)
+	}
+}
+
+func TestRepository_GetStats_ComplexScenario(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	teams := []struct {
+		name    string
+		members []entity.User
+	}{
+		{
+			name: "team-a",
+			members: []entity.User{
+				{ID: "author-a", Username: "AuthorA", IsActive: true},
+				{ID: "reviewer-a1", Username: "ReviewerA1", IsActive: true},
+				{ID: "reviewer-a2", Username: "ReviewerA2", IsActive: true},
+			},
+		},
+		{
+			name: "team-b",
+			members: []entity.User{
+				{ID: "author-b", Username: "AuthorB", IsActive: true},
+				{ID: "reviewer-b1", Username: "ReviewerB1", IsActive: true},
+				{ID: "reviewer-b2", Username: "ReviewerB2", IsActive: true},
+			},
+		},
+	}
+	for _, team := range teams {
+		err := repo.CreateTeam(context.Background(), &entity.Team{Name: team.name}, team.members)
+		if err != nil {
+			t.Fatalf("Failed to create team %s: %v", team.name, err)
+		}
+	}
+	testPRs := []struct {
+		id        string
+		title     string
+		author    string
+		reviewers []string
+	}{
+		{"pr-a-1", "Feature A1", "author-a", []string{"reviewer-a1", "reviewer-a2"}},
+		{"pr-a-2", "Feature A2", "author-a", []string{"reviewer-a1"}},
+		{"pr-a-3", "Feature A3", "author-a", []string{"reviewer-a2"}},
+		{"pr-b-1", "Feature B1", "author-b", []string{"reviewer-b1"}},
+		{"pr-b-2", "Feature B2", "author-b", []string{"reviewer-b1", "reviewer-b2"}},
+	}
+	for _, prData := range testPRs {
+		pr := &entity.PullRequest{
+			ID:       prData.id,
+			Title:    prData.title,
+			AuthorID: prData.author,
+		}
+		err := repo.CreatePR(context.Background(), pr, prData.reviewers)
+		if err != nil {
+			t.Fatalf("Failed to create PR %s: %v", prData.id, err)
+		}
+	}
+	stats, err := repo.GetStatsPaged(context.Background(), 100, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	expectedTotal := 2 + 1 + 1 + 1 + 2
+	if stats.TotalAssignments != expectedTotal {
+		t.Errorf("Expected %d total assignments, got %d", expectedTotal, stats.TotalAssignments)
+	}
+	userAssignments := make(map[string]int)
+	for _, uac := range stats.UserAssignmentCounts {
+		userAssignments[uac.UserID] = uac.Count
+	}
+	expectedUserAssignments := map[string]int{
+		"reviewer-a1": 2,
+		"reviewer-a2": 2,
+		"reviewer-b1": 2,
+		"reviewer-b2": 1,
+	}
+	for userID, expectedCount := range expectedUserAssignments {
+		if userAssignments[userID] != expectedCount {
+			t.Errorf("User %s should have %d assignments, got %d", userID, expectedCount, userAssignments[userID])
+		}
+	}
+	prAssignments := make(map[string]int)
+	for _, prac := range stats.PRAssignmentCounts {
+		prAssignments[prac.PRID] = prac.Count
+	}
+	expectedPRAssignments := map[string]int{
+		"pr-a-1": 2,
+		"pr-a-2": 1,
+		"pr-a-3": 1,
+		"pr-b-1": 1,
+		"pr-b-2": 2,
+	}
+	for prID, expectedCount := range expectedPRAssignments {
+		if prAssignments[prID] != expectedCount {
+			t.Errorf("PR %s should have %d assignments, got %d", prID, expectedCount, prAssignments[prID])
+		}
+	}
+}
+
+func TestRepository_GetStats_AfterReassignment(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "reassign-stats-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr := &entity.PullRequest{
+		ID:       "pr-reassign-stats",
+		Title:    "Test PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	statsBefore, err := repo.GetStatsPaged(context.Background(), 100, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged before reassignment failed: %v", err)
+	}
+	_, err = repo.ReassignReviewer(context.Background(), "pr-reassign-stats", "reviewer1")
+	if err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	statsAfter, err := repo.GetStatsPaged(context.Background(), 100, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged after reassignment failed: %v", err)
+	}
+	if statsBefore.TotalAssignments != statsAfter.TotalAssignments {
+		t.Errorf("Total assignments should remain the same after reassignment, was %d, now %d",
+			statsBefore.TotalAssignments, statsAfter.TotalAssignments)
+	}
+	var reviewer1Before, reviewer1After int
+	for _, uac := range statsBefore.UserAssignmentCounts {
+		if uac.UserID == "reviewer1" {
+			reviewer1Before = uac.Count
+		}
+	}
+	for _, uac := range statsAfter.UserAssignmentCounts {
+		if uac.UserID == "reviewer1" {
+			reviewer1After = uac.Count
+		}
+	}
+	if reviewer1After >= reviewer1Before {
+		t.Errorf("Reviewer1 assignments should decrease after reassignment, was %d, now %d",
+			reviewer1Before, reviewer1After)
+	}
+}
+
+func TestRepository_GetStats_WithMergedPRs(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "merged-stats-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr1 := &entity.PullRequest{
+		ID:       "pr-merged-1",
+		Title:    "Merged PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr1, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR1: %v", err)
+	}
+	pr2 := &entity.PullRequest{
+		ID:       "pr-open-1",
+		Title:    "Open PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr2, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR2: %v", err)
+	}
+	_, err = repo.MergePR(context.Background(), "pr-merged-1")
+	if err != nil {
+		t.Fatalf("Failed to merge PR: %v", err)
+	}
+	stats, err := repo.GetStatsPaged(context.Background(), 100, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	if stats.TotalAssignments != 3 {
+		t.Errorf("Expected 3 total assignments including merged PRs, got %d", stats.TotalAssignments)
+	}
+	var foundMergedPR, foundOpenPR bool
+	for _, prac := range stats.PRAssignmentCounts {
+		if prac.PRID == "pr-merged-1" {
+			foundMergedPR = true
+			if prac.Count != 2 {
+				t.Errorf("Merged PR should have 2 assignments, got %d", prac.Count)
+			}
+		}
+		if prac.PRID == "pr-open-1" {
+			foundOpenPR = true
+			if prac.Count != 1 {
+				t.Errorf("Open PR should have 1 assignment, got %d", prac.Count)
+			}
+		}
+	}
+	if !foundMergedPR {
+		t.Error("Merged PR should be included in stats")
+	}
+	if !foundOpenPR {
+		t.Error("Open PR should be included in stats")
+	}
+}
+
+func TestRepository_GetStats_UserWithoutAssignments(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+
+	team := &entity.Team{Name: "no-assignments-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer-no-assignments", Username: "ReviewerNoAssign", IsActive: true},
+		{ID: "reviewer-with-assignments", Username: "ReviewerWithAssign", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	pr := &entity.PullRequest{
+		ID:       "pr-single-reviewer",
+		Title:    "Test PR",
+		AuthorID: "author1",
+	}
+	err = repo.CreatePR(context.Background(), pr, []string{"reviewer-with-assignments"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	stats, err := repo.GetStatsPaged(context.Background(), 100, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	var foundUserWithAssignments, foundUserWithoutAssignments bool
+	for _, uac := range stats.UserAssignmentCounts {
+		if uac.UserID == "reviewer-with-assignments" {
+			foundUserWithAssignments = true
+			if uac.Count != 1 {
+				t.Errorf("User with assignments should have count 1, got %d", uac.Count)
+			}
+		}
+		if uac.UserID == "reviewer-no-assignments" {
+			foundUserWithoutAssignments = true
+			if uac.Count != 0 {
+				t.Errorf("User without assignments should have count 0, got %d", uac.Count)
+			}
+		}
+	}
+	if !foundUserWithAssignments {
+		t.Error("User with assignments should be in stats")
+	}
+	if !foundUserWithoutAssignments {
+		t.Error("User without assignments should be in stats with count 0")
+	}
+}
+
+func TestRepository_GetCandidateReviewers_Simple(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "simple-team"}
+	members := []entity.User{
+		{ID: "s1", Username: "Simple1", IsActive: true},
+		{ID: "s2", Username: "Simple2", IsActive: true},
+		{ID: "s3", Username: "Simple3", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	t.Run("basic assignment", func(t *testing.T) {
+		candidates, err := candidateIDs(repo.GetCandidateReviewers(context.Background(), "s1", 2, nil, 0, ""))
+		if err != nil {
+			t.Fatalf("GetCandidateReviewers failed: %v", err)
+		}
+		if len(candidates) != 2 {
+			t.Errorf("Expected 2 candidates, got %d", len(candidates))
+		}
+		expected := []string{"s2", "s3"}
+		for _, candidate := range candidates {
+			if !contains(expected, candidate) {
+				t.Errorf("Unexpected candidate: %s, expected one of %v", candidate, expected)
+			}
+		}
+		t.Logf("Basic assignment result: %v", candidates)
+	})
+
+	t.Run("after creating PR", func(t *testing.T) {
+		pr := &entity.PullRequest{ID: "pr-simple-1", Title: "Simple PR", AuthorID: "s2"}
+		err := repo.CreatePR(context.Background(), pr, []string{"s1", "s3"})
+		if err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+		candidates, err := candidateIDs(repo.GetCandidateReviewers(context.Background(), "s1", 2, nil, 0, ""))
+		if err != nil {
+			t.Fatalf("GetCandidateReviewers failed: %v", err)
+		}
+		t.Logf("Assignment after PR creation: %v", candidates)
+		foundS2 := false
+		for _, candidate := range candidates {
+			if candidate == "s2" {
+				foundS2 = true
+				break
+			}
+		}
+		if !foundS2 {
+			t.Error("s2 should be selected due to zero load")
+		}
+	})
+}
+func TestRepository_GetStatsPaged(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "paged-stats-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-paged-1", Title: "Paged 1", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-paged-2", Title: "Paged 2", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	t.Run("first page", func(t *testing.T) {
+		stats, err := repo.GetStatsPaged(context.Background(), 1, 0, entity.StatsFilter{})
+		if err != nil {
+			t.Fatalf("GetStatsPaged failed: %v", err)
+		}
+		if stats.TotalAssignments != 3 {
+			t.Errorf("Expected 3 total assignments regardless of page, got %d", stats.TotalAssignments)
+		}
+		if len(stats.UserAssignmentCounts) != 1 || stats.UserAssignmentCounts[0].UserID != "reviewer1" {
+			t.Errorf("Expected only reviewer1 on first page, got %v", stats.UserAssignmentCounts)
+		}
+		if len(stats.PRAssignmentCounts) != 1 || stats.PRAssignmentCounts[0].PRID != "pr-paged-1" {
+			t.Errorf("Expected only pr-paged-1 on first page, got %v", stats.PRAssignmentCounts)
+		}
+	})
+	t.Run("offset past the end", func(t *testing.T) {
+		stats, err := repo.GetStatsPaged(context.Background(), 50, 100, entity.StatsFilter{})
+		if err != nil {
+			t.Fatalf("GetStatsPaged failed: %v", err)
+		}
+		if stats.TotalAssignments != 3 {
+			t.Errorf("Expected 3 total assignments regardless of page, got %d", stats.TotalAssignments)
+		}
+		if len(stats.UserAssignmentCounts) != 0 || len(stats.PRAssignmentCounts) != 0 {
+			t.Errorf("Expected empty pages, got %d users and %d PRs",
+				len(stats.UserAssignmentCounts), len(stats.PRAssignmentCounts))
+		}
+	})
+}
+
+func TestRepository_GetTeamStats_FairnessGini(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "even-team"}, []entity.User{
+		{ID: "a", Username: "A", IsActive: true},
+		{ID: "b", Username: "B", IsActive: true},
+		{ID: "c", Username: "C", IsActive: true},
+		{ID: "idle", Username: "Idle", IsActive: false},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreateTeam(ctx, &entity.Team{Name: "skewed-team"}, []entity.User{
+		{ID: "x", Username: "X", IsActive: true},
+		{ID: "y", Username: "Y", IsActive: true},
+		{ID: "z", Username: "Z", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	prs := []struct {
+		id, author, reviewer string
+	}{
+		{"pr-a", "a", "b"}, {"pr-b", "b", "c"}, {"pr-c", "c", "a"},
+		{"pr-x1", "x", "y"}, {"pr-x2", "x", "y"}, {"pr-x3", "x", "y"},
+	}
+	for _, pr := range prs {
+		err := repo.CreatePR(ctx, &entity.PullRequest{ID: pr.id, Title: pr.id, AuthorID: pr.author}, []string{pr.reviewer})
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", pr.id, err)
+		}
+	}
+	even, err := repo.GetTeamStats(ctx, "even-team")
+	if err != nil {
+		t.Fatalf("GetTeamStats failed: %v", err)
+	}
+	skewed, err := repo.GetTeamStats(ctx, "skewed-team")
+	if err != nil {
+		t.Fatalf("GetTeamStats failed: %v", err)
+	}
+	if even.FairnessGini != 0 {
+		t.Errorf("Expected 0 for an even distribution ignoring inactive users, got %f", even.FairnessGini)
+	}
+	if skewed.FairnessGini <= even.FairnessGini {
+		t.Errorf("Expected the skewed team to score above the even one, got %f <= %f", skewed.FairnessGini, even.FairnessGini)
+	}
+}
+
+func TestRepository_GetStatsPaged_FairnessGiniIgnoresPaging(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "gini-team"}, []entity.User{
+		{ID: "x", Username: "X", IsActive: true},
+		{ID: "y", Username: "Y", IsActive: true},
+		{ID: "z", Username: "Z", IsActive: true},
+		{ID: "idle", Username: "Idle", IsActive: false},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	for _, id := range []string{"pr-1", "pr-2", "pr-3"} {
+		err := repo.CreatePR(ctx, &entity.PullRequest{ID: id, Title: id, AuthorID: "x"}, []string{"y"})
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", id, err)
+		}
+	}
+	all, err := repo.GetStatsPaged(ctx, 100, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	if all.FairnessGini == 0 {
+		t.Fatal("Expected a skewed distribution to score above 0")
+	}
+	pages := []struct {
+		limit, offset int
+		sort          entity.StatsSort
+	}{
+		{1, 0, ""}, {1, 1, ""}, {2, 2, ""}, {1, 0, entity.StatsSortCountAsc}, {1, 0, entity.StatsSortName},
+	}
+	for _, page := range pages {
+		stats, err := repo.GetStatsPaged(ctx, page.limit, page.offset, entity.StatsFilter{Sort: page.sort})
+		if err != nil {
+			t.Fatalf("GetStatsPaged failed: %v", err)
+		}
+		if math.Abs(stats.FairnessGini-all.FairnessGini) > 1e-9 {
+			t.Errorf("limit=%d offset=%d sort=%q: expected fairness_gini %f, got %f",
+				page.limit, page.offset, page.sort, all.FairnessGini, stats.FairnessGini)
+		}
+	}
+}
+
+func TestRepository_GetTeamStats(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	err := repo.CreateTeam(context.Background(), &entity.Team{Name: "stats-team-a"}, []entity.User{
+		{ID: "a-author", Username: "AAuthor", IsActive: true},
+		{ID: "a-reviewer", Username: "AReviewer", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreateTeam(context.Background(), &entity.Team{Name: "stats-team-b"}, []entity.User{
+		{ID: "b-author", Username: "BAuthor", IsActive: true},
+		{ID: "b-reviewer", Username: "BReviewer", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-a", Title: "A", AuthorID: "a-author"}, []string{"a-reviewer"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-b", Title: "B", AuthorID: "b-author"}, []string{"b-reviewer"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-cross", Title: "Cross", AuthorID: "b-author"}, []string{"a-reviewer"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	t.Run("scoped to team", func(t *testing.T) {
+		stats, err := repo.GetTeamStats(context.Background(), "stats-team-a")
+		if err != nil {
+			t.Fatalf("GetTeamStats failed: %v", err)
+		}
+		if stats.TotalAssignments != 1 {
+			t.Errorf("Expected 1 total assignment on the team's PRs, got %d", stats.TotalAssignments)
+		}
+		for _, uac := range stats.UserAssignmentCounts {
+			if uac.UserID == "a-reviewer" && uac.Count != 2 {
+				t.Errorf("Expected a-reviewer's own count to include the cross-team review, got %d", uac.Count)
+			}
+		}
+		if len(stats.UserAssignmentCounts) != 2 {
+			t.Errorf("Expected 2 team members in stats, got %d", len(stats.UserAssignmentCounts))
+		}
+		for _, uac := range stats.UserAssignmentCounts {
+			if uac.UserID == "b-author" || uac.UserID == "b-reviewer" {
+				t.Errorf("User %s from another team should not be in stats", uac.UserID)
+			}
+		}
+		if len(stats.PRAssignmentCounts) != 1 || stats.PRAssignmentCounts[0].PRID != "pr-a" {
+			t.Errorf("Expected only pr-a in stats, got %v", stats.PRAssignmentCounts)
+		}
+	})
+	t.Run("unknown team", func(t *testing.T) {
+		_, err := repo.GetTeamStats(context.Background(), "nonexistent")
+		if !errors.Is(err, entity.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestRepository_GetUserStats(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "user-stats-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "idle", Username: "Idle", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	for _, id := range []string{"pr-open-1", "pr-open-2", "pr-merged"} {
+		err := repo.CreatePR(ctx, &entity.PullRequest{ID: id, Title: id, AuthorID: "author1"}, []string{"reviewer1"})
+		if err != nil {
+			t.Fatalf("Failed to create PR %s: %v", id, err)
+		}
+	}
+	if _, err := repo.MergePR(ctx, "pr-merged"); err != nil {
+		t.Fatalf("Failed to merge PR: %v", err)
+	}
+	t.Run("mixed assignments", func(t *testing.T) {
+		stats, err := repo.GetUserStats(ctx, "reviewer1")
+		if err != nil {
+			t.Fatalf("GetUserStats failed: %v", err)
+		}
+		if stats.UserID != "reviewer1" || stats.Username != "Reviewer1" {
+			t.Errorf("Unexpected user in stats: %+v", stats)
+		}
+		if stats.Count != 3 || stats.OpenCount != 2 || stats.MergedCount != 1 {
+			t.Errorf("Expected 3 assignments (2 open, 1 merged), got %+v", stats)
+		}
+	})
+	t.Run("no assignments", func(t *testing.T) {
+		stats, err := repo.GetUserStats(ctx, "idle")
+		if err != nil {
+			t.Fatalf("GetUserStats failed: %v", err)
+		}
+		if stats.Count != 0 || stats.OpenCount != 0 || stats.MergedCount != 0 {
+			t.Errorf("Expected zero counts, got %+v", stats)
+		}
+	})
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := repo.GetUserStats(ctx, "ghost")
+		if !errors.Is(err, entity.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestRepository_GetStats_DateRange(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "date-range-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-january", Title: "January", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-march", Title: "March", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = db.Exec("UPDATE pull_requests SET created_at = '2025-01-15T00:00:00Z' WHERE pull_request_id = 'pr-january'")
+	if err != nil {
+		t.Fatalf("Failed to set created_at: %v", err)
+	}
+	_, err = db.Exec("UPDATE pull_requests SET created_at = '2025-03-15T00:00:00Z' WHERE pull_request_id = 'pr-march'")
+	if err != nil {
+		t.Fatalf("Failed to set created_at: %v", err)
+	}
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	stats, err := repo.GetStatsPaged(context.Background(), 100, 0, entity.StatsFilter{From: &from, To: &to})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	if stats.TotalAssignments != 2 {
+		t.Errorf("Expected 2 assignments in January, got %d", stats.TotalAssignments)
+	}
+	for _, uac := range stats.UserAssignmentCounts {
+		if uac.UserID == "reviewer1" && uac.Count != 1 {
+			t.Errorf("Expected reviewer1 to have 1 assignment in range, got %d", uac.Count)
+		}
+	}
+	if len(stats.PRAssignmentCounts) != 1 || stats.PRAssignmentCounts[0].PRID != "pr-january" {
+		t.Errorf("Expected only pr-january in range, got %v", stats.PRAssignmentCounts)
+	}
+	paged, err := repo.GetStatsPaged(context.Background(), 50, 0, entity.StatsFilter{From: &from, To: &to})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	if paged.TotalAssignments != 2 {
+		t.Errorf("Expected 2 paged assignments in January, got %d", paged.TotalAssignments)
+	}
+	unfiltered, err := repo.GetStatsPaged(context.Background(), 100, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	if unfiltered.TotalAssignments != 3 {
+		t.Errorf("Expected 3 assignments without a range, got %d", unfiltered.TotalAssignments)
+	}
+}
+
+func TestRepository_ClosePR(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "close-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-to-close", Title: "Abandoned", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	t.Run("close open PR", func(t *testing.T) {
+		pr, err := repo.ClosePR(context.Background(), "pr-to-close")
+		if err != nil {
+			t.Fatalf("ClosePR failed: %v", err)
+		}
+		if pr.Status != "CLOSED" {
+			t.Errorf("Expected status CLOSED, got %s", pr.Status)
+		}
+		if len(pr.AssignedReviewers) != 0 {
+			t.Errorf("Expected reviewers to be deactivated, got %d", len(pr.AssignedReviewers))
+		}
+	})
+	t.Run("close is idempotent", func(t *testing.T) {
+		pr, err := repo.ClosePR(context.Background(), "pr-to-close")
+		if err != nil {
+			t.Fatalf("Second ClosePR should be idempotent, got error: %v", err)
+		}
+		if pr.Status != "CLOSED" {
+			t.Errorf("Expected status CLOSED, got %s", pr.Status)
+		}
+	})
+	t.Run("reassign on closed PR", func(t *testing.T) {
+		_, err := repo.ReassignReviewer(context.Background(), "pr-to-close", "reviewer1")
+		if !errors.Is(err, entity.ErrPRClosed) {
+			t.Errorf("Expected ErrPRClosed, got %v", err)
+		}
+	})
+	t.Run("merge closed PR", func(t *testing.T) {
+		_, err := repo.MergePR(context.Background(), "pr-to-close")
+		if !errors.Is(err, entity.ErrPRClosed) {
+			t.Errorf("Expected ErrPRClosed, got %v", err)
+		}
+	})
+	t.Run("closed PR does not count toward load", func(t *testing.T) {
+		err := repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-open", Title: "Open", AuthorID: "author1"}, []string{"reviewer3"})
+		if err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+		candidates, err := candidateIDs(repo.GetCandidateReviewers(context.Background(), "author1", 2, nil, 0, ""))
+		if err != nil {
+			t.Fatalf("GetCandidateReviewers failed: %v", err)
+		}
+		if len(candidates) != 2 || contains(candidates, "reviewer3") {
+			t.Errorf("Expected reviewers from closed PR to be preferred over reviewer3, got %v", candidates)
+		}
+	})
+	t.Run("close unknown PR", func(t *testing.T) {
+		_, err := repo.ClosePR(context.Background(), "nonexistent-pr")
+		if !errors.Is(err, entity.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestRepository_GetReviewerWeeklySummary(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	now := time.Date(2025, 3, 12, 15, 0, 0, 0, time.UTC)
+	repo := repository.NewRepositoryWithClock(db, func() time.Time { return now })
+	team := &entity.Team{Name: "weekly-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	assignments := map[string]string{
+		"pr-week-8":    "2025-02-18T10:00:00Z",
+		"pr-week-10-a": "2025-03-03T10:00:00Z",
+		"pr-week-10-b": "2025-03-09T23:00:00Z",
+		"pr-week-11":   "2025-03-11T10:00:00Z",
+		"pr-too-old":   "2025-01-01T10:00:00Z",
+	}
+	for prID, assignedAt := range assignments {
+		err := repo.CreatePR(context.Background(), &entity.PullRequest{ID: prID, Title: prID, AuthorID: "author1"}, []string{"reviewer1"})
+		if err != nil {
+			t.Fatalf("Failed to create PR %s: %v", prID, err)
+		}
+		_, err = db.Exec("UPDATE reviewers SET assigned_at = $1 WHERE pull_request_id = $2", assignedAt, prID)
+		if err != nil {
+			t.Fatalf("Failed to set assignment time: %v", err)
+		}
+	}
+	summary, err := repo.GetReviewerWeeklySummary(context.Background(), "reviewer1", 4)
+	if err != nil {
+		t.Fatalf("GetReviewerWeeklySummary failed: %v", err)
+	}
+	expected := []entity.WeekCount{
+		{ISOYear: 2025, ISOWeek: 8, WeekStart: "2025-02-17", Count: 1},
+		{ISOYear: 2025, ISOWeek: 9, WeekStart: "2025-02-24", Count: 0},
+		{ISOYear: 2025, ISOWeek: 10, WeekStart: "2025-03-03", Count: 2},
+		{ISOYear: 2025, ISOWeek: 11, WeekStart: "2025-03-10", Count: 1},
+	}
+	if len(summary) != len(expected) {
+		t.Fatalf("Expected %d weeks, got %d: %v", len(expected), len(summary), summary)
+	}
+	for i := range expected {
+		if summary[i] != expected[i] {
+			t.Errorf("Week %d: expected %+v, got %+v", i, expected[i], summary[i])
+		}
+	}
+	_, err = repo.GetReviewerWeeklySummary(context.Background(), "nonexistent-user", 4)
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for unknown user, got %v", err)
+	}
+}
+
+func TestRepository_GetCandidateReviewers_PrefersLessHistory(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "history-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "a-veteran", Username: "Veteran", IsActive: true},
+		{ID: "b-newcomer", Username: "Newcomer", IsActive: true},
+		{ID: "c-busy", Username: "Busy", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	for _, prID := range []string{"pr-history-1", "pr-history-2"} {
+		err := repo.CreatePR(context.Background(), &entity.PullRequest{ID: prID, Title: prID, AuthorID: "author1"}, []string{"a-veteran"})
+		if err != nil {
+			t.Fatalf("Failed to create PR %s: %v", prID, err)
+		}
+		_, err = repo.MergePR(context.Background(), prID)
+		if err != nil {
+			t.Fatalf("Failed to merge PR %s: %v", prID, err)
+		}
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-open", Title: "Open", AuthorID: "author1"}, []string{"c-busy"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	candidates, err := candidateIDs(repo.GetCandidateReviewers(context.Background(), "author1", 3, nil, 0, ""))
+	if err != nil {
+		t.Fatalf("GetCandidateReviewers failed: %v", err)
+	}
+	expected := []string{"b-newcomer", "a-veteran", "c-busy"}
+	if len(candidates) != len(expected) {
+		t.Fatalf("Expected %d candidates, got %v", len(expected), candidates)
+	}
+	for i := range expected {
+		if candidates[i] != expected[i] {
+			t.Errorf("Expected ordering %v, got %v", expected, candidates)
+			break
+		}
+	}
+}
+
+func TestRepository_ReassignReviewer_AuthorWithoutTeam(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "left-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-orphan", Title: "Orphan", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = db.Exec("UPDATE pull_requests SET team_id = NULL WHERE pull_request_id = $1", "pr-orphan")
+	if err != nil {
+		t.Fatalf("Failed to clear the stored team: %v", err)
+	}
+	_, err = db.Exec("DELETE FROM team_members WHERE user_id = $1", "author1")
+	if err != nil {
+		t.Fatalf("Failed to remove author from team: %v", err)
+	}
+	_, err = repo.ReassignReviewer(context.Background(), "pr-orphan", "reviewer1")
+	if !errors.Is(err, entity.ErrAuthorNoTeam) {
+		t.Errorf("Expected ErrAuthorNoTeam, got %v", err)
+	}
+}
+
+func TestRepository_EvaluateCandidates(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepositoryWithConfig(db, repository.Config{MaxReviewerLoad: 1})
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "eval-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "busy", Username: "Busy", IsActive: true},
+		{ID: "free", Username: "Free", IsActive: true},
+		{ID: "off", Username: "Off", IsActive: false},
+		{ID: "skipped", Username: "Skipped", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-busy", Title: "Busy", AuthorID: "author1"}, []string{"busy"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	evaluations, err := repo.EvaluateCandidates(ctx, "author1", []string{"skipped"}, "")
+	if err != nil {
+		t.Fatalf("EvaluateCandidates failed: %v", err)
+	}
+	want := []entity.CandidateEvaluation{
+		{UserID: "author1", SkipReason: entity.SkipAuthor},
+		{UserID: "busy", Load: 1, SkipReason: entity.SkipOverCap},
+		{UserID: "free"},
+		{UserID: "off", SkipReason: entity.SkipInactive},
+		{UserID: "skipped", SkipReason: entity.SkipExcluded},
+	}
+	if !reflect.DeepEqual(evaluations, want) {
+		t.Errorf("Expected %+v, got %+v", want, evaluations)
+	}
+	pinned, err := repo.EvaluateCandidates(ctx, "author1", []string{"skipped"}, "Eval-Team")
+	if err != nil {
+		t.Fatalf("EvaluateCandidates failed: %v", err)
+	}
+	if !reflect.DeepEqual(pinned, want) {
+		t.Errorf("Expected a differently-cased team name to match, got %+v", pinned)
+	}
+}
+
+func TestRepository_GetUserTeams(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "beta", DefaultReviewers: 3}, []entity.User{
+		{ID: "multi", Username: "Multi", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreateTeam(ctx, &entity.Team{Name: "alpha"}, []entity.User{
+		{ID: "multi", Username: "Multi", IsActive: true},
+		{ID: "single", Username: "Single", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO users (user_id, username, is_active) VALUES ('loner', 'Loner', true)"); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	testCases := []struct {
+		userID string
+		want   []string
+	}{
+		{"loner", []string{}},
+		{"single", []string{"alpha"}},
+		{"multi", []string{"alpha", "beta"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.userID, func(t *testing.T) {
+			teams, err := repo.GetUserTeams(ctx, tc.userID)
+			if err != nil {
+				t.Fatalf("GetUserTeams failed: %v", err)
+			}
+			if teams == nil {
+				t.Fatal("Expected an empty slice, not nil")
+			}
+			names := []string{}
+			for _, team := range teams {
+				names = append(names, team.Name)
+			}
+			if !reflect.DeepEqual(names, tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, names)
+			}
+		})
+	}
+	teams, _ := repo.GetUserTeams(ctx, "multi")
+	if len(teams) == 2 && teams[1].DefaultReviewers != 3 {
+		t.Errorf("Expected beta to carry its default_reviewers, got %+v", teams[1])
+	}
+	if _, err := repo.GetUserTeams(ctx, "ghost"); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown user, got %v", err)
+	}
+}
+
+func TestRepository_GetCandidateReviewers_PinnedTeam(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "alpha"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "alpha1", Username: "Alpha1", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreateTeam(ctx, &entity.Team{Name: "beta"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "beta1", Username: "Beta1", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	candidates, err := candidateIDs(repo.GetCandidateReviewers(ctx, "author1", 2, nil, 0, "BETA"))
+	if err != nil {
+		t.Fatalf("GetCandidateReviewers failed: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0] != "beta1" {
+		t.Errorf("Expected only beta1 from the pinned team, got %v", candidates)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-beta", Title: "Beta", AuthorID: "author1", TeamName: "Beta"}, candidates)
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	var stored string
+	err = db.QueryRow("SELECT t.team_name FROM pull_requests pr JOIN teams t ON t.team_id = pr.team_id WHERE pr.pull_request_id = 'pr-beta'").Scan(&stored)
+	if err != nil {
+		t.Fatalf("Failed to read the stored team: %v", err)
+	}
+	if stored != "beta" {
+		t.Errorf("Expected the PR to be stored against beta, got %s", stored)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-gamma", Title: "Gamma", AuthorID: "author1", TeamName: "gamma"}, candidates)
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown team instead of falling back, got %v", err)
+	}
+}
+
+func TestRepository_GetCandidateReviewers_ExcludeIDs(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "exclude-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "a-top", Username: "Top", IsActive: true},
+		{ID: "b-next", Username: "Next", IsActive: true},
+		{ID: "c-last", Username: "Last", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	candidates, err := candidateIDs(repo.GetCandidateReviewers(context.Background(), "author1", 2, []string{"a-top"}, 0, ""))
+	if err != nil {
+		t.Fatalf("GetCandidateReviewers failed: %v", err)
+	}
+	if contains(candidates, "a-top") {
+		t.Errorf("Excluded reviewer should not be a candidate, got %v", candidates)
+	}
+	if len(candidates) != 2 {
+		t.Errorf("Expected 2 candidates, got %v", candidates)
+	}
+	missing, err := repo.GetMissingUserIDs(context.Background(), []string{"a-top", "ghost"})
+	if err != nil {
+		t.Fatalf("GetMissingUserIDs failed: %v", err)
+	}
+	if len(missing) != 1 || missing[0] != "ghost" {
+		t.Errorf("Expected [ghost] to be missing, got %v", missing)
+	}
+}
+
+func TestRepository_GetCandidateReviewers_AuthorAliases(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "author1-old", Username: "Author1Old", IsActive: true},
+		{ID: "author1-sso", Username: "Author1SSO", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	if err := repo.CreateTeam(context.Background(), &entity.Team{Name: "alias-team"}, members); err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	candidates, err := candidateIDs(repo.GetCandidateReviewers(context.Background(), "author1", 4, nil, 0, ""))
+	if err != nil {
+		t.Fatalf("GetCandidateReviewers failed: %v", err)
+	}
+	if !contains(candidates, "author1-old") || !contains(candidates, "author1-sso") {
+		t.Errorf("Expected unlisted aliases to remain candidates, got %v", candidates)
+	}
+	aliases := []string{"author1-old", "author1-sso"}
+	candidates, err = candidateIDs(repo.GetCandidateReviewers(context.Background(), "author1", 4, aliases, 0, ""))
+	if err != nil {
+		t.Fatalf("GetCandidateReviewers failed: %v", err)
+	}
+	sort.Strings(candidates)
+	if !reflect.DeepEqual(candidates, []string{"reviewer1", "reviewer2"}) {
+		t.Errorf("Expected only reviewer1 and reviewer2 once aliases are excluded, got %v", candidates)
+	}
+}
+
+func TestRepository_GetCandidateReviewers_AvoidRecentPairings(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "pairing-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "a-recent", Username: "Recent", IsActive: true},
+		{ID: "b-busy", Username: "Busy", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-recent", Title: "Recent", AuthorID: "author1"}, []string{"a-recent"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = repo.MergePR(ctx, "pr-recent")
+	if err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-busy", Title: "Busy", AuthorID: "a-recent"}, []string{"b-busy"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	candidates, err := candidateIDs(repo.GetCandidateReviewers(ctx, "author1", 1, nil, 0, ""))
+	if err != nil {
+		t.Fatalf("GetCandidateReviewers failed: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0] != "a-recent" {
+		t.Errorf("Expected the least loaded a-recent without the option, got %v", candidates)
+	}
+	candidates, err = candidateIDs(repo.GetCandidateReviewers(ctx, "author1", 2, nil, 1, ""))
+	if err != nil {
+		t.Fatalf("GetCandidateReviewers failed: %v", err)
+	}
+	if len(candidates) != 2 || candidates[0] != "b-busy" || candidates[1] != "a-recent" {
+		t.Errorf("Expected a-recent to be ranked last, got %v", candidates)
+	}
+}
+
+func TestRepository_GetCandidateReviewers_ReportsLoad(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "load-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-load", Title: "Load", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	candidates, err := repo.GetCandidateReviewers(ctx, "author1", 2, nil, 0, "")
+	if err != nil {
+		t.Fatalf("GetCandidateReviewers failed: %v", err)
+	}
+	expected := []entity.CandidateReviewer{{UserID: "reviewer2", Load: 0}, {UserID: "reviewer1", Load: 1}}
+	if len(candidates) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, candidates)
+	}
+	for i := range expected {
+		if candidates[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, candidates)
+		}
+	}
+}
+
+func TestRepository_PreviewReassign_MatchesReassignAndWritesNothing(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "preview-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-preview", Title: "Preview", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	countRows := func() int {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM reviewers WHERE is_active = true").Scan(&count)
+		if err != nil {
+			t.Fatalf("Failed to count reviewers: %v", err)
+		}
+		return count
+	}
+	before := countRows()
+	preview, err := repo.PreviewReassign(context.Background(), "pr-preview", "reviewer1")
+	if err != nil {
+		t.Fatalf("PreviewReassign failed: %v", err)
+	}
+	if countRows() != before {
+		t.Errorf("Preview should not change reviewer rows")
+	}
+	reviewers, err := repo.GetPRReviewers(context.Background(), "pr-preview")
+	if err != nil {
+		t.Fatalf("GetPRReviewers failed: %v", err)
+	}
+	if len(reviewers) != 2 {
+		t.Errorf("Expected 2 reviewers after preview, got %d", len(reviewers))
+	}
+	actual, err := repo.ReassignReviewer(context.Background(), "pr-preview", "reviewer1")
+	if err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	if preview != actual {
+		t.Errorf("Preview chose %s but reassign chose %s", preview, actual)
+	}
+}
+
+func TestRepository_GetStats_ExcludesClosedPRAssignments(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "closed-stats-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-closed", Title: "Closed", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: "pr-still-open", Title: "Open", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = repo.ClosePR(context.Background(), "pr-closed")
+	if err != nil {
+		t.Fatalf("ClosePR failed: %v", err)
+	}
+	stats, err := repo.GetStatsPaged(context.Background(), 100, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	if stats.TotalAssignments != 1 {
+		t.Errorf("Expected only the open PR assignment to count, got %d", stats.TotalAssignments)
+	}
+	for _, uac := range stats.UserAssignmentCounts {
+		if uac.UserID == "reviewer2" && uac.Count != 0 {
+			t.Errorf("reviewer2 was only on the closed PR and should have 0 assignments, got %d", uac.Count)
+		}
+	}
+	for _, prac := range stats.PRAssignmentCounts {
+		if prac.PRID == "pr-closed" && prac.Count != 0 {
+			t.Errorf("Closed PR should have 0 active assignments, got %d", prac.Count)
+		}
+	}
+	paged, err := repo.GetStatsPaged(context.Background(), 50, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	if paged.TotalAssignments != 1 {
+		t.Errorf("Expected paged total of 1, got %d", paged.TotalAssignments)
+	}
+}
+
+func TestRepository_GetReviewersForPRs_GroupsByPR(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	team := &entity.Team{Name: "batch-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	}
+	err := repo.CreateTeam(context.Background(), team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	prs := map[string][]string{
+		"pr-batch-1": {"reviewer1", "reviewer2"},
+		"pr-batch-2": {"reviewer3"},
+		"pr-batch-3": {},
+	}
+	for prID, reviewerIDs := range prs {
+		err = repo.CreatePR(context.Background(), &entity.PullRequest{ID: prID, Title: prID, AuthorID: "author1"}, reviewerIDs)
+		if err != nil {
+			t.Fatalf("Failed to create PR %s: %v", prID, err)
+		}
+	}
+	reviewers, err := repo.GetReviewersForPRs(context.Background(), []string{"pr-batch-1", "pr-batch-2", "pr-batch-3"})
+	if err != nil {
+		t.Fatalf("GetReviewersForPRs failed: %v", err)
+	}
+	if len(reviewers["pr-batch-1"]) != 2 || reviewers["pr-batch-1"][0].ID != "reviewer1" || reviewers["pr-batch-1"][1].ID != "reviewer2" {
+		t.Errorf("Expected reviewer1 and reviewer2 on pr-batch-1, got %v", reviewers["pr-batch-1"])
+	}
+	if len(reviewers["pr-batch-2"]) != 1 || reviewers["pr-batch-2"][0].ID != "reviewer3" {
+		t.Errorf("Expected reviewer3 on pr-batch-2, got %v", reviewers["pr-batch-2"])
+	}
+	if len(reviewers["pr-batch-3"]) != 0 {
+		t.Errorf("Expected no reviewers on pr-batch-3, got %v", reviewers["pr-batch-3"])
+	}
+}
+
+func TestRepository_GetConcentration_EvenAndSkewed(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "gini-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "author2", Username: "Author2", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-even-1", Title: "Even 1", AuthorID: "author1"}, []string{"reviewer1", "author2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-even-2", Title: "Even 2", AuthorID: "author2"}, []string{"reviewer2", "author1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	even, err := repo.GetConcentration(ctx)
+	if err != nil {
+		t.Fatalf("GetConcentration failed: %v", err)
+	}
+	if len(even.UserLoads) != 4 {
+		t.Fatalf("Expected loads for 4 active users, got %d", len(even.UserLoads))
+	}
+	if math.Abs(even.GiniCoefficient) > 1e-9 {
+		t.Errorf("Expected coefficient of 0 for an even distribution, got %v", even.GiniCoefficient)
+	}
+	for i := 1; i <= 3; i++ {
+		prID := fmt.Sprintf("pr-skewed-%d", i)
+		err = repo.CreatePR(ctx, &entity.PullRequest{ID: prID, Title: prID, AuthorID: "author1"}, []string{"reviewer1"})
+		if err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+	}
+	skewed, err := repo.GetConcentration(ctx)
+	if err != nil {
+		t.Fatalf("GetConcentration failed: %v", err)
+	}
+	// Loads are now 1, 1, 1, 4.
+	if math.Abs(skewed.GiniCoefficient-9.0/28.0) > 1e-9 {
+		t.Errorf("Expected coefficient of 9/28 for a skewed distribution, got %v", skewed.GiniCoefficient)
+	}
+	if skewed.UserLoads[0].UserID != "reviewer1" || skewed.UserLoads[0].Count != 4 {
+		t.Errorf("Expected reviewer1 with load 4 first, got %+v", skewed.UserLoads[0])
+	}
+}
+
+func TestRepository_IdempotencyRecord(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "idempotency-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-idem", Title: "Idem", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, _, err = repo.GetIdempotencyRecord(ctx, "key-1")
+	if err != entity.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound for unknown key, got %v", err)
+	}
+	err = repo.SaveIdempotencyRecord(ctx, "key-1", "pr-idem", []byte(`{"pr":{}}`))
+	if err != nil {
+		t.Fatalf("SaveIdempotencyRecord failed: %v", err)
+	}
+	err = repo.SaveIdempotencyRecord(ctx, "key-1", "pr-idem", []byte(`{"other":true}`))
+	if err != nil {
+		t.Fatalf("Saving an existing key should be a no-op, got %v", err)
+	}
+	prID, response, err := repo.GetIdempotencyRecord(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("GetIdempotencyRecord failed: %v", err)
+	}
+	if prID != "pr-idem" {
+		t.Errorf("Expected pr-idem, got %s", prID)
+	}
+	if string(response) != `{"pr":{}}` {
+		t.Errorf("Expected the first stored response to be kept, got %s", response)
+	}
+}
+
+func TestRepository_GetCandidateReviewers_MaxReviewerLoad(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepositoryWithConfig(db, repository.Config{MaxReviewerLoad: 1})
+	ctx := context.Background()
+	team := &entity.Team{Name: "capped-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-cap", Title: "Cap", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	candidates, err := candidateIDs(repo.GetCandidateReviewers(ctx, "author1", 2, nil, 0, ""))
+	if err != nil {
+		t.Fatalf("GetCandidateReviewers failed: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0] != "reviewer2" {
+		t.Errorf("Expected only reviewer2 below the cap, got %v", candidates)
+	}
+	_, err = repo.MergePR(ctx, "pr-cap")
+	if err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	candidates, err = candidateIDs(repo.GetCandidateReviewers(ctx, "author1", 2, nil, 0, ""))
+	if err != nil {
+		t.Fatalf("GetCandidateReviewers failed: %v", err)
+	}
+	if len(candidates) != 2 {
+		t.Errorf("Expected reviewer1 to be offered again once the PR is merged, got %v", candidates)
+	}
+}
+
+func TestRepository_ReassignReviewer_MaxReviewerLoad(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepositoryWithConfig(db, repository.Config{MaxReviewerLoad: 1})
+	ctx := context.Background()
+	team := &entity.Team{Name: "capped-reassign"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-busy", Title: "Busy", AuthorID: "author1"}, []string{"reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-swap", Title: "Swap", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = repo.ReassignReviewer(ctx, "pr-swap", "reviewer1")
+	if err != entity.ErrNoCandidate {
+		t.Errorf("Expected ErrNoCandidate while reviewer2 is at the cap, got %v", err)
+	}
+	_, err = repo.MergePR(ctx, "pr-busy")
+	if err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	newReviewer, err := repo.ReassignReviewer(ctx, "pr-swap", "reviewer1")
+	if err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	if newReviewer != "reviewer2" {
+		t.Errorf("Expected reviewer2 once below the cap, got %s", newReviewer)
+	}
+}
+
+func TestRepository_ReopenPR(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "reopen-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-reopen", Title: "Reverted", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = repo.MergePR(ctx, "pr-reopen")
+	if err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	pr, err := repo.ReopenPR(ctx, "pr-reopen")
+	if err != nil {
+		t.Fatalf("ReopenPR failed: %v", err)
+	}
+	if pr.Status != "OPEN" {
+		t.Errorf("Expected status OPEN, got %s", pr.Status)
+	}
+	if pr.MergedAt != nil {
+		t.Errorf("Expected merged_at to be cleared, got %v", *pr.MergedAt)
+	}
+	if len(pr.AssignedReviewers) != 2 {
+		t.Errorf("Expected both reviewers to be active again, got %d", len(pr.AssignedReviewers))
+	}
+	pr, err = repo.ReopenPR(ctx, "pr-reopen")
+	if err != nil {
+		t.Fatalf("Reopening an open PR should be idempotent, got %v", err)
+	}
+	if pr.Status != "OPEN" {
+		t.Errorf("Expected status OPEN, got %s", pr.Status)
+	}
+	_, err = repo.ReopenPR(ctx, "nonexistent")
+	if err != entity.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRepository_GetReviewersForPRs_OverlappingReviewers(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "overlap-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-overlap-1", Title: "Overlap 1", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-overlap-2", Title: "Overlap 2", AuthorID: "author1"}, []string{"reviewer2", "reviewer3"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	prs, _, err := repo.GetUserReviewPRs(ctx, "reviewer2", "", entity.ReviewPage{})
+	if err != nil {
+		t.Fatalf("GetUserReviewPRs failed: %v", err)
+	}
+	prIDs := make([]string, len(prs))
+	for i, pr := range prs {
+		prIDs[i] = pr.ID
+	}
+	reviewers, err := repo.GetReviewersForPRs(ctx, prIDs)
+	if err != nil {
+		t.Fatalf("GetReviewersForPRs failed: %v", err)
+	}
+	expected := map[string][]string{
+		"pr-overlap-1": {"reviewer1", "reviewer2"},
+		"pr-overlap-2": {"reviewer2", "reviewer3"},
+	}
+	if len(reviewers) != len(expected) {
+		t.Fatalf("Expected reviewers for %d PRs, got %d", len(expected), len(reviewers))
+	}
+	for prID, ids := range expected {
+		got := reviewers[prID]
+		if len(got) != len(ids) {
+			t.Errorf("Expected %v on %s, got %v", ids, prID, got)
+			continue
+		}
+		for i, id := range ids {
+			if got[i].ID != id {
+				t.Errorf("Expected %v on %s, got %v", ids, prID, got)
+				break
+			}
+		}
+	}
+}
+
+func TestRepository_GetOperationalCounts(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "metrics-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: false},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-metrics-1", Title: "Open", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-metrics-2", Title: "Merged", AuthorID: "author1"}, []string{"reviewer1"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	_, err = repo.MergePR(ctx, "pr-metrics-2")
+	if err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	counts, err := repo.GetOperationalCounts(ctx)
+	if err != nil {
+		t.Fatalf("GetOperationalCounts failed: %v", err)
+	}
+	if counts.OpenPRs != 1 {
+		t.Errorf("Expected 1 open PR, got %d", counts.OpenPRs)
+	}
+	if counts.ActiveReviewers != 2 {
+		t.Errorf("Expected 2 active users, got %d", counts.ActiveReviewers)
+	}
+}
+
+func TestRepository_GetCandidateReviewers_RoundRobin(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepositoryWithConfig(db, repository.Config{Strategy: repository.RoundRobin{}})
+	ctx := context.Background()
+	team := &entity.Team{Name: "rotation-team"}
+	members := []entity.User{{ID: "author1", Username: "Author1", IsActive: true}}
+	for i := 1; i <= 5; i++ {
+		members = append(members, entity.User{ID: fmt.Sprintf("s%d", i), Username: fmt.Sprintf("S%d", i), IsActive: true})
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	assigned := map[string]int{}
+	var previous []string
+	for i := 1; i <= 5; i++ {
+		candidates, err := candidateIDs(repo.GetCandidateReviewers(ctx, "author1", 2, nil, 0, ""))
+		if err != nil {
+			t.Fatalf("GetCandidateReviewers failed: %v", err)
+		}
+		if len(candidates) != 2 {
+			t.Fatalf("Expected 2 candidates, got %v", candidates)
+		}
+		for _, id := range candidates {
+			for _, prev := range previous {
+				if id == prev {
+					t.Errorf("PR %d reuses %s from the previous PR: %v", i, id, candidates)
+				}
+			}
+			assigned[id]++
+		}
+		prID := fmt.Sprintf("pr-rr-%d", i)
+		err = repo.CreatePR(ctx, &entity.PullRequest{ID: prID, Title: prID, AuthorID: "author1"}, candidates)
+		if err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+		_, err = repo.MergePR(ctx, prID)
+		if err != nil {
+			t.Fatalf("MergePR failed: %v", err)
+		}
+		previous = candidates
+	}
+	for i := 1; i <= 5; i++ {
+		id := fmt.Sprintf("s%d", i)
+		if assigned[id] != 2 {
+			t.Errorf("Expected %s to review 2 of 5 PRs, got %d (%v)", id, assigned[id], assigned)
+		}
+	}
+}
+
+func TestRepository_ReviewerAssignedAt(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "assigned-at-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-assigned-at", Title: "Assigned at", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"})
+	if err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	before, err := repo.GetPRReviewers(ctx, "pr-assigned-at")
+	if err != nil {
+		t.Fatalf("GetPRReviewers failed: %v", err)
+	}
+	assignedAt := map[string]time.Time{}
+	for _, reviewer := range before {
+		if reviewer.AssignedAt == nil {
+			t.Fatalf("Expected assigned_at for %s", reviewer.ID)
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, *reviewer.AssignedAt)
+		if err != nil {
+			t.Fatalf("assigned_at %q is not RFC3339: %v", *reviewer.AssignedAt, err)
+		}
+		assignedAt[reviewer.ID] = parsed
+	}
+	newUserID, err := repo.ReassignReviewer(ctx, "pr-assigned-at", "reviewer1")
+	if err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	after, err := repo.GetPRReviewers(ctx, "pr-assigned-at")
+	if err != nil {
+		t.Fatalf("GetPRReviewers failed: %v", err)
+	}
+	for _, reviewer := range after {
+		parsed, err := time.Parse(time.RFC3339Nano, *reviewer.AssignedAt)
+		if err != nil {
+			t.Fatalf("assigned_at %q is not RFC3339: %v", *reviewer.AssignedAt, err)
+		}
+		if reviewer.ID == newUserID {
+			if !parsed.After(assignedAt["reviewer1"]) {
+				t.Errorf("Expected %s to be assigned after reviewer1 (%v), got %v", newUserID, assignedAt["reviewer1"], parsed)
+			}
+		} else if !parsed.Equal(assignedAt[reviewer.ID]) {
+			t.Errorf("Expected assigned_at of %s to be unchanged, got %v", reviewer.ID, parsed)
+		}
+	}
+}
+
+func TestRepository_GetUserReviewPRs_StatusFilter(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "status-filter-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	for _, prID := range []string{"pr-open-1", "pr-open-2", "pr-merged"} {
+		err = repo.CreatePR(ctx, &entity.PullRequest{ID: prID, Title: prID, AuthorID: "author1"}, []string{"reviewer1"})
+		if err != nil {
+			t.Fatalf("Failed to create PR %s: %v", prID, err)
+		}
+	}
+	_, err = repo.MergePR(ctx, "pr-merged")
+	if err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	open, _, err := repo.GetUserReviewPRs(ctx, "reviewer1", "OPEN", entity.ReviewPage{})
+	if err != nil {
+		t.Fatalf("GetUserReviewPRs failed: %v", err)
+	}
+	if len(open) != 2 {
+		t.Fatalf("Expected 2 open PRs, got %d", len(open))
+	}
+	for _, pr := range open {
+		if pr.Status != "OPEN" {
+			t.Errorf("Expected only OPEN PRs, got %s with status %s", pr.ID, pr.Status)
+		}
+	}
+	merged, _, err := repo.GetUserReviewPRs(ctx, "reviewer1", "MERGED", entity.ReviewPage{})
+	if err != nil {
+		t.Fatalf("GetUserReviewPRs failed: %v", err)
+	}
+	if len(merged) != 1 || merged[0].ID != "pr-merged" {
+		t.Errorf("Expected only pr-merged, got %v", merged)
+	}
+	all, _, err := repo.GetUserReviewPRs(ctx, "reviewer1", "", entity.ReviewPage{})
+	if err != nil {
+		t.Fatalf("GetUserReviewPRs failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("Expected all 3 PRs without a status filter, got %d", len(all))
+	}
+}
+
+func TestRepository_GetUserReviewPRs_Cursor(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	team := &entity.Team{Name: "cursor-team"}
+	members := []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	}
+	err := repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	createAt := func(prID string, minutes int) {
+		err := repo.CreatePR(ctx, &entity.PullRequest{ID: prID, Title: prID, AuthorID: "author1"}, []string{"reviewer1"})
+		if err != nil {
+			t.Fatalf("Failed to create PR %s: %v", prID, err)
+		}
+		_, err = db.Exec("UPDATE pull_requests SET created_at = '2024-01-01T00:00:00Z'::timestamptz + $2 * INTERVAL '1 minute' WHERE pull_request_id = $1", prID, minutes)
+		if err != nil {
+			t.Fatalf("Failed to set created_at: %v", err)
+		}
+	}
+	for i, prID := range []string{"pr-1", "pr-2", "pr-3", "pr-4", "pr-5"} {
+		createAt(prID, i)
+	}
+	ids := func(prs []entity.PullRequest) []string {
+		result := make([]string, len(prs))
+		for i, pr := range prs {
+			result[i] = pr.ID
+		}
+		return result
+	}
+	first, next, err := repo.GetUserReviewPRs(ctx, "reviewer1", "", entity.ReviewPage{Limit: 2})
+	if err != nil {
+		t.Fatalf("GetUserReviewPRs failed: %v", err)
+	}
+	if got := ids(first); len(got) != 2 || got[0] != "pr-5" || got[1] != "pr-4" {
+		t.Fatalf("Expected first page [pr-5 pr-4], got %v", got)
+	}
+	if next == nil || next.PRID != "pr-4" {
+		t.Fatalf("Expected a cursor after pr-4, got %v", next)
+	}
+	createAt("pr-new", 60)
+	second, next, err := repo.GetUserReviewPRs(ctx, "reviewer1", "", entity.ReviewPage{Limit: 2, After: next})
+	if err != nil {
+		t.Fatalf("GetUserReviewPRs failed: %v", err)
+	}
+	if got := ids(second); len(got) != 2 || got[0] != "pr-3" || got[1] != "pr-2" {
+		t.Fatalf("Expected second page [pr-3 pr-2] despite the new PR, got %v", got)
+	}
+	last, next, err := repo.GetUserReviewPRs(ctx, "reviewer1", "", entity.ReviewPage{Limit: 2, After: next})
+	if err != nil {
+		t.Fatalf("GetUserReviewPRs failed: %v", err)
+	}
+	if got := ids(last); len(got) != 1 || got[0] != "pr-1" {
+		t.Errorf("Expected last page [pr-1], got %v", got)
+	}
+	if next != nil {
+		t.Errorf("Expected no cursor on the last page, got %v", next)
+	}
+}
+
+func TestRepository_CreatePR_UnknownAuthor(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-orphan", Title: "Orphan", AuthorID: "ghost"}, nil)
+	if err != entity.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound for an unknown author, got %v", err)
+	}
+	_, err = repo.GetPR(ctx, "pr-orphan")
+	if err != entity.ErrNotFound {
+		t.Errorf("Expected no PR to be inserted, got %v", err)
+	}
+}
+
+func TestRepository_CreateTeamsBulk_PartialSuccess(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "existing"}, []entity.User{{ID: "u1", Username: "Alice", IsActive: true}})
+	if err != nil {
+		t.Fatalf("CreateTeam failed: %v", err)
+	}
+	results, err := repo.CreateTeamsBulk(ctx, []entity.TeamWithMembers{
+		{TeamName: "frontend", Members: []entity.User{{ID: "u2", Username: "Bob", IsActive: true}}},
+		{TeamName: "Existing", Members: []entity.User{{ID: "u3", Username: "Charlie", IsActive: true}}},
+		{TeamName: "mobile", Members: []entity.User{{ID: "u4", Username: "Dave", IsActive: true}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTeamsBulk failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	if results[0].Err != nil || results[0].TeamID == "" {
+		t.Errorf("Expected frontend to be created, got %+v", results[0])
+	}
+	if results[1].Err != entity.ErrTeamExists {
+		t.Errorf("Expected ErrTeamExists for the duplicate team, got %v", results[1].Err)
+	}
+	if results[2].Err != nil || results[2].TeamID == "" {
+		t.Errorf("Expected mobile to be created after the duplicate, got %+v", results[2])
+	}
+	for _, name := range []string{"frontend", "mobile"} {
+		if _, _, err := repo.GetTeam(ctx, name); err != nil {
+			t.Errorf("Expected team %s to be persisted, got %v", name, err)
+		}
+	}
+	_, err = repo.SetUserActive(ctx, "u3", true)
+	if err != entity.ErrNotFound {
+		t.Errorf("Expected members of the rejected team to be rolled back, got %v", err)
+	}
+}
+
+func TestRepository_SetTeamMembersActive(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "disbanded"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreateTeam(ctx, &entity.Team{Name: "staying"}, []entity.User{
+		{ID: "other", Username: "Other", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-open", Title: "Open", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	users, reassignments, err := repo.SetTeamMembersActive(ctx, "disbanded", false)
+	if err != nil {
+		t.Fatalf("SetTeamMembersActive failed: %v", err)
+	}
+	if len(users) != 3 {
+		t.Fatalf("Expected 3 updated users, got %+v", users)
+	}
+	for _, user := range users {
+		if user.IsActive || user.TeamName != "disbanded" {
+			t.Errorf("Expected %s to be an inactive member of disbanded, got %+v", user.ID, user)
+		}
+	}
+	if len(reassignments) != 2 {
+		t.Errorf("Expected both reviewers to be moved off pr-open, got %+v", reassignments)
+	}
+	pr, err := repo.GetPR(ctx, "pr-open")
+	if err != nil {
+		t.Fatalf("GetPR failed: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 0 {
+		t.Errorf("Expected no active reviewers on pr-open, got %+v", pr.AssignedReviewers)
+	}
+	other, err := repo.GetUser(ctx, "other")
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if !other.IsActive {
+		t.Error("Members of other teams should be left active")
+	}
+	users, reassignments, err = repo.SetTeamMembersActive(ctx, "Disbanded", true)
+	if err != nil {
+		t.Fatalf("SetTeamMembersActive failed: %v", err)
+	}
+	if len(users) != 3 || !users[0].IsActive || len(reassignments) != 0 {
+		t.Errorf("Expected reactivation without reassignments, got %+v %+v", users, reassignments)
+	}
+	_, _, err = repo.SetTeamMembersActive(ctx, "ghost-team", false)
+	if err != entity.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for unknown team, got %v", err)
+	}
+}
 
-func TestRepository_GetCandidateReviewers_Simple(t *testing.T) {
-    db := setupTestDB(t)
-    defer db.Close()
-    repo := repository.NewRepository(db)
-    team := &entity.Team{Name: "simple-team"}
-    members := []entity.User{
-        {ID: "s1", Username: "Simple1", IsActive: true},
-        {ID: "s2", Username: "Simple2", IsActive: true},
-        {ID: "s3", Username: "Simple3", IsActive: true},
-    }
-    err := repo.CreateTeam(team, members)
-    if err != nil {
-        t.Fatalf("Failed to create team: %v", err)
-    }
-    t.Run("basic assignment", func(t *testing.T) {
-        candidates, err := repo.GetCandidateReviewers("s1", 2)
-        if err != nil {
-            t.Fatalf("GetCandidateReviewers failed: %v", err)
-        }
-        if len(candidates) != 2 {
-            t.Errorf("Expected 2 candidates, got %d", len(candidates))
-        }
-        expected := []string{"s2", "s3"}
-        for _, candidate := range candidates {
-            if !contains(expected, candidate) {
-                t.Errorf("Unexpected candidate: %s, expected one of %v", candidate, expected)
-            }
-        }
-        t.Logf("Basic assignment result: %v", candidates)
-    })
-
-    t.Run("after creating PR", func(t *testing.T) {
-        pr := &entity.PullRequest{ID: "pr-simple-1", Title: "Simple PR", AuthorID: "s2"}
-        err := repo.CreatePR(pr, []string{"s1", "s3"})
-        if err != nil {
-            t.Fatalf("Failed to create PR: %v", err)
-        }
-        candidates, err := repo.GetCandidateReviewers("s1", 2)
-        if err != nil {
-            t.Fatalf("GetCandidateReviewers failed: %v", err)
-        }
-        t.Logf("Assignment after PR creation: %v", candidates)
-        foundS2 := false
-        for _, candidate := range candidates {
-            if candidate == "s2" {
-                foundS2 = true
-                break
-            }
-        }
-        if !foundS2 {
-            t.Error("s2 should be selected due to zero load")
-        }
-    })
-}
\ No newline at end of file
+func TestRepository_DeactivateAndRetire(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "dev-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-merged", Title: "Merged", AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	if _, err := repo.MergePR(ctx, "pr-merged"); err != nil {
+		t.Fatalf("Failed to merge PR: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-open", Title: "Open", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	reassignments, err := repo.DeactivateAndRetire(ctx, "reviewer1")
+	if err != nil {
+		t.Fatalf("DeactivateAndRetire failed: %v", err)
+	}
+	if len(reassignments) != 1 || reassignments[0].PRID != "pr-open" || reassignments[0].NewUserID != "reviewer3" {
+		t.Errorf("Expected pr-open to move to reviewer3, got %+v", reassignments)
+	}
+	open, err := repo.GetPR(ctx, "pr-open")
+	if err != nil {
+		t.Fatalf("GetPR failed: %v", err)
+	}
+	for _, reviewer := range open.AssignedReviewers {
+		if reviewer.ID == "reviewer1" {
+			t.Error("Retired user should no longer review pr-open")
+		}
+	}
+	merged, _, err := repo.GetUserReviewPRs(ctx, "reviewer1", "MERGED", entity.ReviewPage{})
+	if err != nil {
+		t.Fatalf("GetUserReviewPRs failed: %v", err)
+	}
+	if len(merged) != 1 || merged[0].ID != "pr-merged" {
+		t.Errorf("Expected merged history to be preserved, got %v", merged)
+	}
+	_, members, err := repo.GetTeam(ctx, "dev-team")
+	if err != nil {
+		t.Fatalf("GetTeam failed: %v", err)
+	}
+	for _, member := range members {
+		if member.ID == "reviewer1" {
+			t.Error("Retired user should be removed from the team")
+		}
+	}
+	_, err = repo.DeactivateAndRetire(ctx, "ghost")
+	if err != entity.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for unknown user, got %v", err)
+	}
+}
+
+func TestRepository_DeactivateAndRetire_FormerReviewerReplaces(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "dev-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-open", Title: "Open", AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	if newUserID, err := repo.ReassignReviewer(ctx, "pr-open", "reviewer1"); err != nil || newUserID != "reviewer2" {
+		t.Fatalf("Expected reviewer1 to be reassigned to reviewer2, got %q, %v", newUserID, err)
+	}
+	reassignments, err := repo.DeactivateAndRetire(ctx, "reviewer2")
+	if err != nil {
+		t.Fatalf("DeactivateAndRetire failed: %v", err)
+	}
+	if len(reassignments) != 1 || reassignments[0].NewUserID != "reviewer1" {
+		t.Fatalf("Expected pr-open to move back to reviewer1, got %+v", reassignments)
+	}
+	pr, err := repo.GetPR(ctx, "pr-open")
+	if err != nil {
+		t.Fatalf("GetPR failed: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 1 || pr.AssignedReviewers[0].ID != "reviewer1" {
+		t.Errorf("Expected reviewer1 to be the only active reviewer, got %+v", pr.AssignedReviewers)
+	}
+}
+
+func TestRepository_AddReviewer(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "dev-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "inactive", Username: "Inactive", IsActive: false},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreateTeam(ctx, &entity.Team{Name: "other-team"}, []entity.User{{ID: "outsider", Username: "Outsider", IsActive: true}})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-open", Title: "Open", AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-merged", Title: "Merged", AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	if _, err := repo.MergePR(ctx, "pr-merged"); err != nil {
+		t.Fatalf("Failed to merge PR: %v", err)
+	}
+	testCases := []struct {
+		name     string
+		prID     string
+		userID   string
+		expected error
+	}{
+		{"unknown PR", "pr-missing", "reviewer2", entity.ErrNotFound},
+		{"unknown user", "pr-open", "ghost", entity.ErrNotFound},
+		{"author", "pr-open", "author1", entity.ErrSelfReview},
+		{"already assigned", "pr-open", "reviewer1", entity.ErrAlreadyAssigned},
+		{"merged PR", "pr-merged", "reviewer2", entity.ErrPRMerged},
+		{"not in team", "pr-open", "outsider", entity.ErrNoCandidate},
+		{"inactive member", "pr-open", "inactive", entity.ErrNoCandidate},
+		{"eligible member", "pr-open", "reviewer2", nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := repo.AddReviewer(ctx, tc.prID, tc.userID)
+			if err != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+	pr, err := repo.GetPR(ctx, "pr-open")
+	if err != nil {
+		t.Fatalf("GetPR failed: %v", err)
+	}
+	reviewerIDs := make([]string, len(pr.AssignedReviewers))
+	for i, reviewer := range pr.AssignedReviewers {
+		reviewerIDs[i] = reviewer.ID
+	}
+	if len(reviewerIDs) != 2 || !contains(reviewerIDs, "reviewer1") || !contains(reviewerIDs, "reviewer2") {
+		t.Errorf("Expected reviewers [reviewer1, reviewer2], got %v", reviewerIDs)
+	}
+}
+
+func TestRepository_AddReviewer_Limit(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepositoryWithConfig(db, repository.Config{MaxReviewersPerPR: 3})
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "crowd-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+		{ID: "reviewer4", Username: "Reviewer4", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-crowd", Title: "Crowd", AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	for _, userID := range []string{"reviewer2", "reviewer3"} {
+		if err := repo.AddReviewer(ctx, "pr-crowd", userID); err != nil {
+			t.Fatalf("Expected %s to fit under the cap, got %v", userID, err)
+		}
+	}
+	if err := repo.AddReviewer(ctx, "pr-crowd", "reviewer4"); !errors.Is(err, entity.ErrReviewerLimit) {
+		t.Fatalf("Expected ErrReviewerLimit past the cap, got %v", err)
+	}
+	if err := repo.RemoveReviewer(ctx, "pr-crowd", "reviewer1"); err != nil {
+		t.Fatalf("RemoveReviewer failed: %v", err)
+	}
+	if err := repo.AddReviewer(ctx, "pr-crowd", "reviewer4"); err != nil {
+		t.Errorf("Expected a freed slot to be reusable, got %v", err)
+	}
+}
+
+func TestRepository_RemoveReviewer(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "dev-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-open", Title: "Open", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-merged", Title: "Merged", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	if _, err := repo.MergePR(ctx, "pr-merged"); err != nil {
+		t.Fatalf("Failed to merge PR: %v", err)
+	}
+	if err := repo.RemoveReviewer(ctx, "pr-merged", "reviewer1"); err != entity.ErrPRMerged {
+		t.Errorf("Expected ErrPRMerged, got %v", err)
+	}
+	if err := repo.RemoveReviewer(ctx, "pr-missing", "reviewer1"); err != entity.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := repo.RemoveReviewer(ctx, "pr-open", "author1"); err != entity.ErrNotAssigned {
+		t.Errorf("Expected ErrNotAssigned, got %v", err)
+	}
+	if err := repo.RemoveReviewer(ctx, "pr-open", "reviewer1"); err != nil {
+		t.Fatalf("RemoveReviewer failed: %v", err)
+	}
+	if err := repo.RemoveReviewer(ctx, "pr-open", "reviewer1"); err != entity.ErrNotAssigned {
+		t.Errorf("Expected ErrNotAssigned for an already removed reviewer, got %v", err)
+	}
+	if err := repo.RemoveReviewer(ctx, "pr-open", "reviewer2"); err != entity.ErrLastReviewer {
+		t.Errorf("Expected ErrLastReviewer, got %v", err)
+	}
+	pr, err := repo.GetPR(ctx, "pr-open")
+	if err != nil {
+		t.Fatalf("GetPR failed: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 1 || pr.AssignedReviewers[0].ID != "reviewer2" {
+		t.Errorf("Expected only reviewer2 to remain, got %v", pr.AssignedReviewers)
+	}
+}
+
+func TestRepository_GetStats_AverageTimeToMerge(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "merge-time-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	stats, err := repo.GetStatsPaged(ctx, 100, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	if stats.AverageTimeToMergeSeconds != 0 {
+		t.Errorf("Expected 0 without merged PRs, got %v", stats.AverageTimeToMergeSeconds)
+	}
+	for _, prID := range []string{"pr-fast", "pr-slow", "pr-open"} {
+		if err := repo.CreatePR(ctx, &entity.PullRequest{ID: prID, Title: prID, AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+	}
+	for _, prID := range []string{"pr-fast", "pr-slow"} {
+		if _, err := repo.MergePR(ctx, prID); err != nil {
+			t.Fatalf("Failed to merge PR: %v", err)
+		}
+	}
+	_, err = db.Exec(`UPDATE pull_requests SET created_at = '2025-01-01T00:00:00Z', merged_at = '2025-01-01T01:00:00Z' WHERE pull_request_id = 'pr-fast'`)
+	if err != nil {
+		t.Fatalf("Failed to set timestamps: %v", err)
+	}
+	_, err = db.Exec(`UPDATE pull_requests SET created_at = '2025-01-01T00:00:00Z', merged_at = '2025-01-01T03:00:00Z' WHERE pull_request_id = 'pr-slow'`)
+	if err != nil {
+		t.Fatalf("Failed to set timestamps: %v", err)
+	}
+	stats, err = repo.GetStatsPaged(ctx, 100, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	if stats.AverageTimeToMergeSeconds != 7200 {
+		t.Errorf("Expected average of 7200 seconds, got %v", stats.AverageTimeToMergeSeconds)
+	}
+	paged, err := repo.GetStatsPaged(ctx, 50, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	if paged.AverageTimeToMergeSeconds != 7200 {
+		t.Errorf("Expected paged average of 7200 seconds, got %v", paged.AverageTimeToMergeSeconds)
+	}
+}
+
+func TestRepository_GetStats_OpenMergedSplit(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "split-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	for _, prID := range []string{"pr-1", "pr-2"} {
+		if err := repo.CreatePR(ctx, &entity.PullRequest{ID: prID, Title: prID, AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+	}
+	if _, err := repo.MergePR(ctx, "pr-1"); err != nil {
+		t.Fatalf("Failed to merge PR: %v", err)
+	}
+	check := func(name string, counts []entity.UserAssignmentCount) {
+		for _, uac := range counts {
+			if uac.UserID != "reviewer1" {
+				continue
+			}
+			if uac.Count != 2 || uac.OpenCount != 1 || uac.MergedCount != 1 {
+				t.Errorf("%s: expected count=2 open=1 merged=1, got %+v", name, uac)
+			}
+			return
+		}
+		t.Errorf("%s: reviewer1 missing from %v", name, counts)
+	}
+	paged, err := repo.GetStatsPaged(ctx, 50, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	check("GetStatsPaged", paged.UserAssignmentCounts)
+	team, err := repo.GetTeamStats(ctx, "split-team")
+	if err != nil {
+		t.Fatalf("GetTeamStats failed: %v", err)
+	}
+	check("GetTeamStats", team.UserAssignmentCounts)
+}
+
+func TestRepository_GetStatsPaged_Sort(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "sort-team"}, []entity.User{
+		{ID: "author1", Username: "Zed", IsActive: true},
+		{ID: "reviewer1", Username: "Bob", IsActive: true},
+		{ID: "reviewer2", Username: "Alice", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-1", Title: "Beta", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-2", Title: "Alpha", AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	testCases := []struct {
+		sort          entity.StatsSort
+		expectedUsers []string
+		expectedPRs   []string
+	}{
+		{entity.StatsSortCountDesc, []string{"reviewer1", "reviewer2", "author1"}, []string{"pr-1", "pr-2"}},
+		{entity.StatsSortCountAsc, []string{"author1", "reviewer2", "reviewer1"}, []string{"pr-2", "pr-1"}},
+		{entity.StatsSortName, []string{"reviewer2", "reviewer1", "author1"}, []string{"pr-2", "pr-1"}},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.sort), func(t *testing.T) {
+			stats, err := repo.GetStatsPaged(ctx, 50, 0, entity.StatsFilter{Sort: tc.sort})
+			if err != nil {
+				t.Fatalf("GetStatsPaged failed: %v", err)
+			}
+			var users, prs []string
+			for _, uac := range stats.UserAssignmentCounts {
+				users = append(users, uac.UserID)
+			}
+			for _, pac := range stats.PRAssignmentCounts {
+				prs = append(prs, pac.PRID)
+			}
+			if strings.Join(users, ",") != strings.Join(tc.expectedUsers, ",") {
+				t.Errorf("Expected users %v, got %v", tc.expectedUsers, users)
+			}
+			if strings.Join(prs, ",") != strings.Join(tc.expectedPRs, ",") {
+				t.Errorf("Expected PRs %v, got %v", tc.expectedPRs, prs)
+			}
+		})
+	}
+}
+
+func TestRepository_CountActiveTeammates(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "solo-team"}, []entity.User{
+		{ID: "solo", Username: "Solo", IsActive: true},
+		{ID: "sleeper", Username: "Sleeper", IsActive: false},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	err = repo.CreateTeam(ctx, &entity.Team{Name: "pair-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	count, err := repo.CountActiveTeammates(ctx, "solo")
+	if err != nil {
+		t.Fatalf("CountActiveTeammates failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected 0 active teammates for solo, got %d", count)
+	}
+	count, err = repo.CountActiveTeammates(ctx, "author1")
+	if err != nil {
+		t.Fatalf("CountActiveTeammates failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 active teammate for author1, got %d", count)
+	}
+	count, err = repo.CountTeammates(ctx, "solo")
+	if err != nil {
+		t.Fatalf("CountTeammates failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected the inactive teammate to be counted for solo, got %d", count)
+	}
+}
+
+func TestRepository_GetStats_TotalMatchesPerPRCounts(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "total-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-1", Title: "One", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-2", Title: "Two", AuthorID: "author1"}, []string{"reviewer3"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	if _, err := repo.ReassignReviewer(ctx, "pr-1", "reviewer1"); err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	if _, err := repo.MergePR(ctx, "pr-2"); err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	stats, err := repo.GetStatsPaged(ctx, 100, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("GetStatsPaged failed: %v", err)
+	}
+	prSum := 0
+	for _, pac := range stats.PRAssignmentCounts {
+		prSum += pac.Count
+	}
+	userSum := 0
+	for _, uac := range stats.UserAssignmentCounts {
+		userSum += uac.Count
+	}
+	if stats.TotalAssignments != prSum || stats.TotalAssignments != userSum {
+		t.Errorf("Expected total %d to equal per-PR sum %d and per-user sum %d", stats.TotalAssignments, prSum, userSum)
+	}
+	if stats.TotalAssignments != 3 {
+		t.Errorf("Expected 3 active assignments, got %d", stats.TotalAssignments)
+	}
+}
+
+func TestRepository_ListPRs(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "list-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "author2", Username: "Author2", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	prs := []struct {
+		id, author, createdAt string
+		reviewers             []string
+	}{
+		{"pr-1", "author1", "2025-01-01T00:00:00Z", []string{"reviewer1"}},
+		{"pr-2", "author2", "2025-01-02T00:00:00Z", []string{"reviewer1", "author1"}},
+		{"pr-3", "author1", "2025-01-03T00:00:00Z", []string{"reviewer1"}},
+	}
+	for _, pr := range prs {
+		if err := repo.CreatePR(ctx, &entity.PullRequest{ID: pr.id, Title: pr.id, AuthorID: pr.author}, pr.reviewers); err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+		if _, err := db.Exec("UPDATE pull_requests SET created_at = $1 WHERE pull_request_id = $2", pr.createdAt, pr.id); err != nil {
+			t.Fatalf("Failed to set created_at: %v", err)
+		}
+	}
+	if _, err := repo.MergePR(ctx, "pr-1"); err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	ids := func(prs []entity.PullRequest) string {
+		var out []string
+		for _, pr := range prs {
+			out = append(out, pr.ID)
+		}
+		return strings.Join(out, ",")
+	}
+	all, err := repo.ListPRs(ctx, "", "", 50, 0)
+	if err != nil {
+		t.Fatalf("ListPRs failed: %v", err)
+	}
+	if ids(all) != "pr-3,pr-2,pr-1" {
+		t.Errorf("Expected newest first, got %s", ids(all))
+	}
+	if all[1].ReviewerCount != 2 {
+		t.Errorf("Expected pr-2 to have 2 reviewers, got %d", all[1].ReviewerCount)
+	}
+	open, err := repo.ListPRs(ctx, "OPEN", "", 50, 0)
+	if err != nil {
+		t.Fatalf("ListPRs failed: %v", err)
+	}
+	if ids(open) != "pr-3,pr-2" {
+		t.Errorf("Expected only open PRs, got %s", ids(open))
+	}
+	byAuthor, err := repo.ListPRs(ctx, "OPEN", "author1", 50, 0)
+	if err != nil {
+		t.Fatalf("ListPRs failed: %v", err)
+	}
+	if ids(byAuthor) != "pr-3" {
+		t.Errorf("Expected only author1's open PR, got %s", ids(byAuthor))
+	}
+	page, err := repo.ListPRs(ctx, "", "", 2, 2)
+	if err != nil {
+		t.Fatalf("ListPRs failed: %v", err)
+	}
+	if ids(page) != "pr-1" {
+		t.Errorf("Expected the last page to hold pr-1, got %s", ids(page))
+	}
+	beyond, err := repo.ListPRs(ctx, "", "", 2, 10)
+	if err != nil {
+		t.Fatalf("ListPRs failed: %v", err)
+	}
+	if beyond == nil || len(beyond) != 0 {
+		t.Errorf("Expected an empty non-nil slice past the end, got %#v", beyond)
+	}
+}
+
+func TestRepository_SearchPRs(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "search-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	prs := []struct {
+		id, title, createdAt string
+	}{
+		{"pr-1", "Add search endpoint", "2025-01-01T00:00:00Z"},
+		{"pr-2", "Fix login bug", "2025-01-02T00:00:00Z"},
+		{"pr-3", "Speed up SEARCH index", "2025-01-03T00:00:00Z"},
+		{"pr-4", "Raise limit to 100%", "2025-01-04T00:00:00Z"},
+	}
+	for _, pr := range prs {
+		if err := repo.CreatePR(ctx, &entity.PullRequest{ID: pr.id, Title: pr.title, AuthorID: "author1"}, []string{"reviewer1"}); err != nil {
+			t.Fatalf("Failed to create PR: %v", err)
+		}
+		if _, err := db.Exec("UPDATE pull_requests SET created_at = $1 WHERE pull_request_id = $2", pr.createdAt, pr.id); err != nil {
+			t.Fatalf("Failed to set created_at: %v", err)
+		}
+	}
+	ids := func(prs []entity.PullRequest) string {
+		var out []string
+		for _, pr := range prs {
+			out = append(out, pr.ID)
+		}
+		return strings.Join(out, ",")
+	}
+	testCases := []struct {
+		query string
+		limit int
+		want  string
+	}{
+		{"search", 100, "pr-3,pr-1"},
+		{"SeArCh", 1, "pr-3"},
+		{"0%", 100, "pr-4"},
+		{"%", 100, "pr-4"},
+		{"deploy", 100, ""},
+	}
+	for _, tc := range testCases {
+		found, err := repo.SearchPRs(ctx, tc.query, tc.limit)
+		if err != nil {
+			t.Fatalf("SearchPRs(%q) failed: %v", tc.query, err)
+		}
+		if ids(found) != tc.want {
+			t.Errorf("SearchPRs(%q, %d): expected %q, got %q", tc.query, tc.limit, tc.want, ids(found))
+		}
+	}
+}
+
+func TestRepository_ReassignReviewer_ConcurrentOnSamePR(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	ctx := context.Background()
+	err := repo.CreateTeam(ctx, &entity.Team{Name: "concurrent-team"}, []entity.User{
+		{ID: "author1", Username: "Author1", IsActive: true},
+		{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+		{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+		{ID: "reviewer3", Username: "Reviewer3", IsActive: true},
+		{ID: "reviewer4", Username: "Reviewer4", IsActive: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create team: %v", err)
+	}
+	if err := repo.CreatePR(ctx, &entity.PullRequest{ID: "pr-race", Title: "Race", AuthorID: "author1"}, []string{"reviewer1", "reviewer2"}); err != nil {
+		t.Fatalf("Failed to create PR: %v", err)
+	}
+	oldReviewers := []string{"reviewer1", "reviewer2"}
+	replacements := make([]string, len(oldReviewers))
+	errs := make([]error, len(oldReviewers))
+	var wg sync.WaitGroup
+	for i, oldUserID := range oldReviewers {
+		wg.Add(1)
+		go func(i int, oldUserID string) {
+			defer wg.Done()
+			replacements[i], errs[i] = repo.ReassignReviewer(ctx, "pr-race", oldUserID)
+		}(i, oldUserID)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Reassignment of %s failed: %v", oldReviewers[i], err)
+		}
+	}
+	if replacements[0] == replacements[1] {
+		t.Errorf("Expected distinct replacements, both got %s", replacements[0])
+	}
+	var activeRows, distinctUsers int
+	err = db.QueryRow(`
+		SELECT COUNT(*), COUNT(DISTINCT user_id)
+		FROM reviewers
+		WHERE pull_request_id = 'pr-race' AND is_active = true
+	`).Scan(&activeRows, &distinctUsers)
+	if err != nil {
+		t.Fatalf("Failed to count reviewers: %v", err)
+	}
+	if activeRows != 2 || distinctUsers != 2 {
+		t.Errorf("Expected 2 distinct active reviewers, got %d rows for %d users", activeRows, distinctUsers)
+	}
+}
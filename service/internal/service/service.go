@@ -1,123 +1,820 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"service/internal/entity"
+	"service/internal/notifier"
 	"service/internal/repository"
 )
 
+const DefaultReviewersCount = 2
+
+// MaxReviewersCount caps every reviewer count setting, global, per team or per
+// request.
+const MaxReviewersCount = 10
+
+// DefaultMinTeamSize is the fewest active members, author included, a team
+// needs before its authors may create PRs.
+const DefaultMinTeamSize = 2
+
+// DefaultPRIDPattern is the pull request id format accepted when Config leaves
+// PRIDPattern unset.
+const DefaultPRIDPattern = `^[A-Za-z0-9_-]{1,64}$`
+
+var defaultPRIDRegexp = regexp.MustCompile(DefaultPRIDPattern)
+
 type Service interface {
-	CreateTeam(teamName string, members []entity.User) (*entity.Team, error)
-	GetTeam(teamName string) (*entity.Team, []entity.User, error)
-	SetUserActive(userID string, isActive bool) (*entity.User, error)
-	GetUserReviewPRs(userID string) ([]entity.PullRequest, error)
-	CreatePR(prID, title, authorID string) (*entity.PullRequest, error)
-	MergePR(prID string) (*entity.PullRequest, error)
-	ReassignReviewer(prID, oldUserID string) (*entity.PullRequest, string, error)
-	GetPR(prID string) (*entity.PullRequest, error)
-	GetStats() (*entity.Stats, error)
+	CreateTeam(ctx context.Context, teamName string, members []entity.User, defaultReviewers int) (*entity.Team, error)
+	SetTeamReviewerCount(ctx context.Context, teamName string, count int) error
+	ImportTeams(ctx context.Context, teams []entity.TeamWithMembers) ([]entity.ImportResult, error)
+	GetTeam(ctx context.Context, teamName string) (*entity.Team, []entity.User, error)
+	RenameTeam(ctx context.Context, oldName, newName string) (*entity.Team, error)
+	GetUser(ctx context.Context, userID string) (*entity.User, error)
+	GetUserLoad(ctx context.Context, userID string) (open, total int, err error)
+	GetUserTeams(ctx context.Context, userID string) ([]entity.Team, error)
+	SetUserActive(ctx context.Context, userID string, isActive bool) (*entity.User, []string, error)
+	RetireUser(ctx context.Context, userID string) ([]entity.Reassignment, error)
+	ReassignAllForUser(ctx context.Context, userID string, bestEffort bool) ([]entity.ReassignResult, error)
+	SetTeamActive(ctx context.Context, teamName string, isActive bool) ([]entity.User, []entity.Reassignment, error)
+	GetUserReviewPRs(ctx context.Context, userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error)
+	GetReviewersForPRs(ctx context.Context, prIDs []string) (map[string][]entity.User, error)
+	CreatePR(ctx context.Context, prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error)
+	GetIdempotentResponse(ctx context.Context, key, prID string) ([]byte, error)
+	SaveIdempotentResponse(ctx context.Context, key, prID string, response []byte) error
+	MergePR(ctx context.Context, prID string) (*entity.PullRequest, error)
+	ClosePR(ctx context.Context, prID string) (*entity.PullRequest, error)
+	ReopenPR(ctx context.Context, prID string) (*entity.PullRequest, error)
+	ReassignReviewer(ctx context.Context, prID, oldUserID string) (*entity.PullRequest, string, error)
+	PreviewReassign(ctx context.Context, prID, oldUserID string) (string, error)
+	PreviewReviewers(ctx context.Context, authorID string, count int) ([]entity.CandidateReviewer, error)
+	GetReassignmentHistory(ctx context.Context, prID string) ([]entity.Reassignment, error)
+	GetPRTimeline(ctx context.Context, prID string) ([]entity.TimelineEvent, error)
+	AddReviewer(ctx context.Context, prID, userID string) (*entity.PullRequest, error)
+	RemoveReviewer(ctx context.Context, prID, userID string) (*entity.PullRequest, error)
+	RespondReview(ctx context.Context, prID, userID string, accept bool) (string, error)
+	GetPR(ctx context.Context, prID string) (*entity.PullRequest, error)
+	ListPRs(ctx context.Context, status, authorID string, limit, offset int) ([]entity.PullRequest, error)
+	SearchPRs(ctx context.Context, query string, limit int) ([]entity.PullRequest, error)
+	GetStats(ctx context.Context, limit, offset int, filter entity.StatsFilter) (*entity.Stats, error)
+	GetTeamStats(ctx context.Context, teamName string) (*entity.Stats, error)
+	GetUserStats(ctx context.Context, userID string) (*entity.UserAssignmentCount, error)
+	GetConcentration(ctx context.Context) (*entity.Concentration, error)
+	GetOperationalCounts(ctx context.Context) (*entity.OperationalCounts, error)
+	RequiredTeamSize(policy entity.ReviewPolicy) (int, error)
+	GetReviewerWeeklySummary(ctx context.Context, userID string, weeks int) ([]entity.WeekCount, error)
 }
 
 type ServiceImpl struct {
-	repo repository.Repository
+	repo           repository.Repository
+	notifier       notifier.Notifier
+	reviewersCount int
+	minTeamSize    int
+	prIDPattern    *regexp.Regexp
+}
+
+type Config struct {
+	Notifier notifier.Notifier
+	// ReviewersCount is how many reviewers CreatePR asks for; DefaultReviewersCount when 0.
+	ReviewersCount int
+	// MinTeamSize is the active member count below which CreatePR fails with
+	// ErrTeamTooSmall; DefaultMinTeamSize when 0.
+	MinTeamSize int
+	// PRIDPattern is the format pull request ids must match;
+	// DefaultPRIDPattern when nil.
+	PRIDPattern *regexp.Regexp
+}
+
+func NewService(repo repository.Repository) Service {
+	return NewServiceWithNotifier(repo, notifier.NopNotifier{})
 }
 
-func NewService(repo repository.Repository) Service {  
-	return &ServiceImpl{repo: repo}
+func NewServiceWithNotifier(repo repository.Repository, n notifier.Notifier) Service {
+	return NewServiceWithConfig(repo, Config{Notifier: n})
+}
+
+func NewServiceWithConfig(repo repository.Repository, cfg Config) Service {
+	if cfg.Notifier == nil {
+		cfg.Notifier = notifier.NopNotifier{}
+	}
+	if cfg.ReviewersCount == 0 {
+		cfg.ReviewersCount = DefaultReviewersCount
+	}
+	if cfg.MinTeamSize == 0 {
+		cfg.MinTeamSize = DefaultMinTeamSize
+	}
+	if cfg.PRIDPattern == nil {
+		cfg.PRIDPattern = defaultPRIDRegexp
+	}
+	return &ServiceImpl{repo: repo, notifier: cfg.Notifier, reviewersCount: cfg.ReviewersCount, minTeamSize: cfg.MinTeamSize, prIDPattern: cfg.PRIDPattern}
+}
+
+// validatePRID rejects ids that do not match the configured pattern before
+// they reach logs or the pull_requests primary key.
+func (s *ServiceImpl) validatePRID(prID string) error {
+	if !s.prIDPattern.MatchString(prID) {
+		return entity.ErrInvalidPRID
+	}
+	return nil
 }
 
-func (s *ServiceImpl) CreateTeam(teamName string, members []entity.User) (*entity.Team, error) {
-	team := &entity.Team{Name: teamName}
-	err := s.repo.CreateTeam(team, members)
+// CreateTeam creates teamName with members. The name is stored trimmed, so
+// padded names cannot slip past the repository's case-insensitive duplicate
+// check. defaultReviewers sets how many reviewers the team's PRs get; 0 leaves
+// it to the global default.
+func (s *ServiceImpl) CreateTeam(ctx context.Context, teamName string, members []entity.User, defaultReviewers int) (*entity.Team, error) {
+	teamName, err := normalizeTeamName(teamName)
 	if err != nil {
 		return nil, err
 	}
+	if defaultReviewers < 0 || defaultReviewers > MaxReviewersCount {
+		return nil, entity.ErrInvalidReviewerCount
+	}
+	if err := checkMemberIDs(members); err != nil {
+		return nil, err
+	}
+	team := &entity.Team{Name: teamName, DefaultReviewers: defaultReviewers}
+	err = s.repo.CreateTeam(ctx, team, members)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) > 0 {
+		s.notifier.MembershipChanged(team.Name, memberIDs(members), nil)
+	}
 	return team, nil
 }
 
-func (s *ServiceImpl) GetTeam(teamName string) (*entity.Team, []entity.User, error) {
-	return s.repo.GetTeam(teamName)
+// ImportTeams validates every team like CreateTeam; invalid teams are
+// reported in their result without reaching the repository. The members of
+// every created team are reported to the notifier.
+func (s *ServiceImpl) ImportTeams(ctx context.Context, teams []entity.TeamWithMembers) ([]entity.ImportResult, error) {
+	results := make([]entity.ImportResult, len(teams))
+	valid := make([]entity.TeamWithMembers, 0, len(teams))
+	positions := make([]int, 0, len(teams))
+	for i, team := range teams {
+		name, err := normalizeTeamName(team.TeamName)
+		if err == nil {
+			err = checkMemberIDs(team.Members)
+		}
+		if err != nil {
+			results[i] = entity.ImportResult{TeamName: team.TeamName, Err: err}
+			continue
+		}
+		team.TeamName = name
+		valid = append(valid, team)
+		positions = append(positions, i)
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
+	created, err := s.repo.CreateTeamsBulk(ctx, valid)
+	if err != nil {
+		return nil, err
+	}
+	for j, result := range created {
+		results[positions[j]] = result
+		if result.Err == nil && len(valid[j].Members) > 0 {
+			s.notifier.MembershipChanged(result.TeamName, memberIDs(valid[j].Members), nil)
+		}
+	}
+	return results, nil
+}
+
+// normalizeTeamName trims name and rejects one that is left empty.
+func normalizeTeamName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", entity.ErrEmptyTeamName
+	}
+	return name, nil
+}
+
+// checkMemberIDs rejects members whose id is blank.
+func checkMemberIDs(members []entity.User) error {
+	for _, member := range members {
+		if isBlank(member.ID) {
+			return entity.ErrBlankUserID
+		}
+	}
+	return nil
+}
+
+func (s *ServiceImpl) GetTeam(ctx context.Context, teamName string) (*entity.Team, []entity.User, error) {
+	return s.repo.GetTeam(ctx, teamName)
+}
+
+func (s *ServiceImpl) SetTeamReviewerCount(ctx context.Context, teamName string, count int) error {
+	if count < 1 || count > MaxReviewersCount {
+		return entity.ErrInvalidReviewerCount
+	}
+	return s.repo.SetTeamReviewerCount(ctx, teamName, count)
+}
+
+// RenameTeam trims both names like CreateTeam and returns the team under its
+// new name.
+func (s *ServiceImpl) RenameTeam(ctx context.Context, oldName, newName string) (*entity.Team, error) {
+	oldName, err := normalizeTeamName(oldName)
+	if err != nil {
+		return nil, err
+	}
+	newName, err = normalizeTeamName(newName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RenameTeam(ctx, oldName, newName); err != nil {
+		return nil, err
+	}
+	return &entity.Team{Name: newName}, nil
 }
 
-func (s *ServiceImpl) SetUserActive(userID string, isActive bool) (*entity.User, error) {
-	return s.repo.SetUserActive(userID, isActive)
+func (s *ServiceImpl) GetUser(ctx context.Context, userID string) (*entity.User, error) {
+	return s.repo.GetUser(ctx, userID)
 }
 
-func (s *ServiceImpl) GetUserReviewPRs(userID string) ([]entity.PullRequest, error) {
-	return s.repo.GetUserReviewPRs(userID)
+func (s *ServiceImpl) GetUserLoad(ctx context.Context, userID string) (open, total int, err error) {
+	return s.repo.GetUserLoad(ctx, userID)
+}
+
+func (s *ServiceImpl) GetUserTeams(ctx context.Context, userID string) ([]entity.Team, error) {
+	return s.repo.GetUserTeams(ctx, userID)
+}
+
+// SetUserActive updates userID's flag. Deactivating also reassigns the user
+// off every OPEN PR they review and returns the ids of the PRs that got a
+// replacement; PRs without an eligible teammate keep the user assigned.
+func (s *ServiceImpl) SetUserActive(ctx context.Context, userID string, isActive bool) (*entity.User, []string, error) {
+	if isBlank(userID) {
+		return nil, nil, entity.ErrBlankUserID
+	}
+	user, err := s.repo.SetUserActive(ctx, userID, isActive)
+	if err != nil {
+		return nil, nil, err
+	}
+	affected := []string{}
+	if isActive {
+		return user, affected, nil
+	}
+	prs, _, err := s.repo.GetUserReviewPRs(ctx, userID, "OPEN", entity.ReviewPage{})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, pr := range prs {
+		newUserID, err := s.repo.ReassignReviewer(ctx, pr.ID, userID)
+		switch err {
+		case nil:
+		case entity.ErrNoCandidate, entity.ErrAuthorNoTeam, entity.ErrNotAssigned, entity.ErrPRMerged, entity.ErrPRClosed:
+			continue
+		default:
+			return nil, nil, err
+		}
+		s.notifier.ReviewerAssigned(pr.ID, newUserID)
+		affected = append(affected, pr.ID)
+	}
+	return user, affected, nil
 }
 
-func (s *ServiceImpl) CreatePR(prID, title, authorID string) (*entity.PullRequest, error) {
-	author, err := s.repo.SetUserActive(authorID, true)
+// RetireUser reports the user's removal from each team they belonged to. The
+// teams are read before retiring, since retiring deletes the memberships.
+func (s *ServiceImpl) RetireUser(ctx context.Context, userID string) ([]entity.Reassignment, error) {
+	teams, err := s.repo.GetUserTeams(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("author not found: %w", entity.ErrNotFound)
+		return nil, err
+	}
+	reassignments, err := s.repo.DeactivateAndRetire(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, team := range teams {
+		s.notifier.MembershipChanged(team.Name, nil, []string{userID})
+	}
+	for _, reassignment := range reassignments {
+		if reassignment.NewUserID != "" {
+			s.notifier.ReviewerAssigned(reassignment.PRID, reassignment.NewUserID)
+		}
+	}
+	return reassignments, nil
+}
+
+func (s *ServiceImpl) ReassignAllForUser(ctx context.Context, userID string, bestEffort bool) ([]entity.ReassignResult, error) {
+	results, err := s.repo.ReassignAllForUser(ctx, userID, bestEffort)
+	if err != nil {
+		return results, err
+	}
+	for _, result := range results {
+		if result.NewUserID != "" {
+			s.notifier.ReviewerAssigned(result.PRID, result.NewUserID)
+		}
+	}
+	return results, nil
+}
+
+func (s *ServiceImpl) SetTeamActive(ctx context.Context, teamName string, isActive bool) ([]entity.User, []entity.Reassignment, error) {
+	users, reassignments, err := s.repo.SetTeamMembersActive(ctx, teamName, isActive)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, reassignment := range reassignments {
+		if reassignment.NewUserID != "" {
+			s.notifier.ReviewerAssigned(reassignment.PRID, reassignment.NewUserID)
+		}
+	}
+	return users, reassignments, nil
+}
+
+func (s *ServiceImpl) GetUserReviewPRs(ctx context.Context, userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+	return s.repo.GetUserReviewPRs(ctx, userID, status, page)
+}
+
+func (s *ServiceImpl) GetReviewersForPRs(ctx context.Context, prIDs []string) (map[string][]entity.User, error) {
+	return s.repo.GetReviewersForPRs(ctx, prIDs)
+}
+
+func (s *ServiceImpl) CreatePR(ctx context.Context, prID, title, authorID string, opts entity.CreatePROptions) (*entity.PullRequest, error) {
+	if err := s.validatePRID(prID); err != nil {
+		return nil, err
+	}
+	if isBlank(authorID) {
+		return nil, entity.ErrBlankUserID
+	}
+	for _, id := range opts.ExcludeReviewers {
+		if isBlank(id) {
+			return nil, entity.ErrBlankUserID
+		}
+	}
+	for _, id := range opts.AuthorAliases {
+		if isBlank(id) {
+			return nil, entity.ErrBlankUserID
+		}
+	}
+	author, err := s.repo.GetUser(ctx, authorID)
+	if err == entity.ErrNotFound {
+		return nil, entity.ErrAuthorNotFound
+	}
+	if err != nil {
+		return nil, err
 	}
 	if !author.IsActive {
 		return nil, fmt.Errorf("author is inactive")
 	}
-	candidateIDs, err := s.repo.GetCandidateReviewers(authorID, 2)
+	teamName, err := s.resolveAuthorTeam(ctx, authorID, opts.TeamName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get candidate reviewers: %w", err)
+		return nil, err
 	}
-	if len(candidateIDs) == 0 {
-		return nil, entity.ErrNoCandidate
+	teammates, err := s.repo.CountActiveTeammates(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	if teammates == 0 {
+		return nil, s.noCandidateError(ctx, authorID, entity.ErrSoloAuthor)
+	}
+	if teamName != "" {
+		members, err := s.repo.CountActiveMembers(ctx, teamName)
+		if err != nil {
+			return nil, err
+		}
+		if members < s.minTeamSize {
+			return nil, entity.ErrTeamTooSmall
+		}
+	}
+	missingIDs, err := s.repo.GetMissingUserIDs(ctx, opts.ExcludeReviewers)
+	if err != nil {
+		return nil, err
+	}
+	if len(missingIDs) > 0 {
+		return nil, entity.ErrUnknownUser
+	}
+	reviewersCount, err := s.resolveReviewersCount(ctx, teamName, opts.ReviewersCount)
+	if err != nil {
+		return nil, err
+	}
+	excludeIDs := append(append([]string{}, opts.ExcludeReviewers...), opts.AuthorAliases...)
+	candidates, err := s.getCandidateReviewers(ctx, authorID, reviewersCount, excludeIDs, opts.AvoidRecentPairings, teamName)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, s.noCandidateError(ctx, authorID, entity.ErrNoCandidate)
+	}
+	candidateIDs := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		candidateIDs[i] = candidate.UserID
+	}
+	var evaluations []entity.CandidateEvaluation
+	if opts.Debug {
+		evaluations, err = s.evaluateCandidates(ctx, authorID, excludeIDs, opts.AuthorAliases, teamName, candidateIDs)
+		if err != nil {
+			return nil, err
+		}
 	}
 	pr := &entity.PullRequest{
 		ID:       prID,
 		Title:    title,
 		AuthorID: authorID,
 		Status:   "OPEN",
+		TeamName: teamName,
+	}
+	if err := checkNotSelfReview(pr, candidateIDs); err != nil {
+		return nil, err
 	}
-	err = s.repo.CreatePR(pr, candidateIDs)
+	err = s.repo.CreatePR(ctx, pr, candidateIDs)
 	if err != nil {
 		return nil, err
 	}
-	return s.repo.GetPR(prID)
+	for _, reviewerID := range candidateIDs {
+		s.notifier.ReviewerAssigned(prID, reviewerID)
+	}
+	created, err := s.repo.GetPR(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	created.ReviewerLoads = candidates
+	created.RequestedReviewers = reviewersCount
+	created.CandidateEvaluations = evaluations
+	return created, nil
 }
 
-func (s *ServiceImpl) MergePR(prID string) (*entity.PullRequest, error) {
-	pr, err := s.repo.MergePR(prID)
+// evaluateCandidates explains a CreatePR selection: the selected ids are
+// marked, author aliases are reported as the author, and eligible teammates
+// who were not picked lost on ranking.
+func (s *ServiceImpl) evaluateCandidates(ctx context.Context, authorID string, excludeIDs, aliases []string, teamName string, selectedIDs []string) ([]entity.CandidateEvaluation, error) {
+	evaluations, err := s.repo.EvaluateCandidates(ctx, authorID, excludeIDs, teamName)
 	if err != nil {
 		return nil, err
 	}
-	return pr, nil
+	selected := make(map[string]bool, len(selectedIDs))
+	for _, id := range selectedIDs {
+		selected[id] = true
+	}
+	alias := make(map[string]bool, len(aliases))
+	for _, id := range aliases {
+		alias[id] = true
+	}
+	for i := range evaluations {
+		if alias[evaluations[i].UserID] {
+			evaluations[i].SkipReason = entity.SkipAuthor
+		} else if selected[evaluations[i].UserID] {
+			evaluations[i].Selected = true
+			evaluations[i].SkipReason = ""
+		} else if evaluations[i].SkipReason == "" {
+			evaluations[i].SkipReason = entity.SkipRankedLower
+		}
+	}
+	return evaluations, nil
+}
+
+// resolveReviewersCount picks how many reviewers a PR gets: the team's own
+// setting wins, then the count asked for in the request, then the global
+// default.
+func (s *ServiceImpl) resolveReviewersCount(ctx context.Context, teamName string, requested int) (int, error) {
+	if requested < 0 || requested > MaxReviewersCount {
+		return 0, entity.ErrInvalidReviewerCount
+	}
+	if teamName != "" {
+		teamCount, err := s.repo.GetTeamReviewerCount(ctx, teamName)
+		if err != nil {
+			return 0, err
+		}
+		if teamCount > 0 {
+			return teamCount, nil
+		}
+	}
+	if requested > 0 {
+		return requested, nil
+	}
+	return s.reviewersCount, nil
 }
 
-func (s *ServiceImpl) ReassignReviewer(prID, oldUserID string) (*entity.PullRequest, string, error) {
-	pr, err := s.repo.GetPR(prID)
+// isBlank reports whether a client-supplied id is empty or only whitespace.
+func isBlank(id string) bool {
+	return strings.TrimSpace(id) == ""
+}
+
+func memberIDs(members []entity.User) []string {
+	ids := make([]string, len(members))
+	for i, member := range members {
+		ids[i] = member.ID
+	}
+	return ids
+}
+
+// noCandidateError returns ErrAllInactive when the author has teammates but
+// every one of them is deactivated, so the caller can suggest reactivating
+// someone; otherwise it returns fallback.
+func (s *ServiceImpl) noCandidateError(ctx context.Context, authorID string, fallback error) error {
+	active, err := s.repo.CountActiveTeammates(ctx, authorID)
 	if err != nil {
-		return nil, "", err
+		return err
+	}
+	if active > 0 {
+		return fallback
 	}
+	total, err := s.repo.CountTeammates(ctx, authorID)
+	if err != nil {
+		return err
+	}
+	if total > 0 {
+		return entity.ErrAllInactive
+	}
+	return fallback
+}
 
-	if pr.Status != "OPEN" {
-		return nil, "", entity.ErrPRMerged
+// GetIdempotentResponse returns the stored response for key, ErrNotFound when the
+// key is new, or ErrIdempotencyKeyReused when it belongs to another pull request.
+func (s *ServiceImpl) GetIdempotentResponse(ctx context.Context, key, prID string) ([]byte, error) {
+	storedPRID, response, err := s.repo.GetIdempotencyRecord(ctx, key)
+	if err != nil {
+		return nil, err
 	}
-	isAssigned := false
-	for _, reviewer := range pr.AssignedReviewers {
-		if reviewer.ID == oldUserID {
-			isAssigned = true
-			break
+	if storedPRID != prID {
+		return nil, entity.ErrIdempotencyKeyReused
+	}
+	return response, nil
+}
+
+func (s *ServiceImpl) SaveIdempotentResponse(ctx context.Context, key, prID string, response []byte) error {
+	return s.repo.SaveIdempotencyRecord(ctx, key, prID, response)
+}
+
+// assertPRMutable rejects reviewer changes on any PR that is not OPEN.
+func assertPRMutable(pr *entity.PullRequest) error {
+	switch pr.Status {
+	case "OPEN":
+		return nil
+	case "CLOSED":
+		return entity.ErrPRClosed
+	default:
+		return entity.ErrPRMerged
+	}
+}
+
+// checkNotSelfReview is the single place that refuses to make a PR's author one
+// of its reviewers; every path that assigns reviewers must go through it.
+func checkNotSelfReview(pr *entity.PullRequest, reviewerIDs []string) error {
+	for _, reviewerID := range reviewerIDs {
+		if reviewerID == pr.AuthorID {
+			return entity.ErrSelfReview
+		}
+	}
+	return nil
+}
+
+// resolveAuthorTeam picks the team whose members review the author's PR: the
+// requested one, which the author must belong to, or else the author's only
+// team. An author without a team resolves to "" and is caught by the solo check.
+func (s *ServiceImpl) resolveAuthorTeam(ctx context.Context, authorID, requested string) (string, error) {
+	teams, err := s.repo.GetUserTeams(ctx, authorID)
+	if err != nil {
+		return "", err
+	}
+	if requested != "" {
+		for _, team := range teams {
+			if strings.EqualFold(team.Name, requested) {
+				return team.Name, nil
+			}
 		}
+		return "", entity.ErrAuthorNotInTeam
+	}
+	if len(teams) > 1 {
+		return "", entity.ErrAmbiguousTeam
+	}
+	if len(teams) == 1 {
+		return teams[0].Name, nil
+	}
+	return "", nil
+}
+
+func (s *ServiceImpl) getCandidateReviewers(ctx context.Context, authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+	if limit < 1 {
+		return nil, entity.ErrInvalidReviewerCount
 	}
-	if !isAssigned {
-		return nil, "", entity.ErrNotAssigned
+	candidates, err := s.repo.GetCandidateReviewers(ctx, authorID, limit, excludeIDs, avoidRecent, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candidate reviewers: %w", err)
+	}
+	return candidates, nil
+}
+
+func (s *ServiceImpl) MergePR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	if err := s.validatePRID(prID); err != nil {
+		return nil, err
+	}
+	pr, err := s.repo.MergePR(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+func (s *ServiceImpl) ClosePR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	return s.repo.ClosePR(ctx, prID)
+}
+
+func (s *ServiceImpl) ReopenPR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	return s.repo.ReopenPR(ctx, prID)
+}
+
+func (s *ServiceImpl) ReassignReviewer(ctx context.Context, prID, oldUserID string) (*entity.PullRequest, string, error) {
+	if err := s.validatePRID(prID); err != nil {
+		return nil, "", err
 	}
-	newUserID, err := s.repo.ReassignReviewer(prID, oldUserID)
+	if err := s.validateReassign(ctx, prID, oldUserID); err != nil {
+		return nil, "", err
+	}
+	newUserID, err := s.repo.ReassignReviewer(ctx, prID, oldUserID)
 	if err != nil {
 		return nil, "", err
 	}
-	updatedPR, err := s.repo.GetPR(prID)
+	s.notifier.ReviewerAssigned(prID, newUserID)
+	updatedPR, err := s.repo.GetPR(ctx, prID)
 	if err != nil {
 		return nil, "", err
 	}
+	updatedPR.Reassignment = &entity.Reassignment{
+		PRID:        prID,
+		OldUserID:   oldUserID,
+		NewUserID:   newUserID,
+		OldUsername: s.username(ctx, oldUserID),
+		NewUsername: s.username(ctx, newUserID),
+	}
 	return updatedPR, newUserID, nil
 }
 
-func (s *ServiceImpl) GetPR(prID string) (*entity.PullRequest, error) {
-	return s.repo.GetPR(prID)
+// username looks up userID's display name for responses. The reassignment has
+// already been committed by the time it runs, so a failed lookup yields "".
+func (s *ServiceImpl) username(ctx context.Context, userID string) string {
+	user, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		return ""
+	}
+	return user.Username
 }
 
-func (s *ServiceImpl) GetStats() (*entity.Stats, error) {
-    return s.repo.GetStats()
-}
\ No newline at end of file
+func (s *ServiceImpl) PreviewReassign(ctx context.Context, prID, oldUserID string) (string, error) {
+	if err := s.validateReassign(ctx, prID, oldUserID); err != nil {
+		return "", err
+	}
+	return s.repo.PreviewReassign(ctx, prID, oldUserID)
+}
+
+// PreviewReviewers returns the reviewers CreatePR would currently pick for
+// authorID, with their open review counts, without assigning anyone. A zero
+// count uses the configured default.
+func (s *ServiceImpl) PreviewReviewers(ctx context.Context, authorID string, count int) ([]entity.CandidateReviewer, error) {
+	if _, err := s.repo.GetUser(ctx, authorID); err != nil {
+		return nil, err
+	}
+	teamName, err := s.resolveAuthorTeam(ctx, authorID, "")
+	if err != nil {
+		return nil, err
+	}
+	count, err = s.resolveReviewersCount(ctx, teamName, count)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := s.getCandidateReviewers(ctx, authorID, count, nil, 0, teamName)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, entity.ErrNoCandidate
+	}
+	return candidates, nil
+}
+
+func (s *ServiceImpl) GetReassignmentHistory(ctx context.Context, prID string) ([]entity.Reassignment, error) {
+	return s.repo.GetReassignmentHistory(ctx, prID)
+}
+
+func (s *ServiceImpl) GetPRTimeline(ctx context.Context, prID string) ([]entity.TimelineEvent, error) {
+	return s.repo.GetPRTimeline(ctx, prID)
+}
+
+func (s *ServiceImpl) AddReviewer(ctx context.Context, prID, userID string) (*entity.PullRequest, error) {
+	pr, err := s.repo.GetPR(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if err := assertPRMutable(pr); err != nil {
+		return nil, err
+	}
+	if err := checkNotSelfReview(pr, []string{userID}); err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddReviewer(ctx, prID, userID); err != nil {
+		return nil, err
+	}
+	s.notifier.ReviewerAssigned(prID, userID)
+	return s.repo.GetPR(ctx, prID)
+}
+
+func (s *ServiceImpl) RemoveReviewer(ctx context.Context, prID, userID string) (*entity.PullRequest, error) {
+	pr, err := s.repo.GetPR(ctx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if err := assertPRMutable(pr); err != nil {
+		return nil, err
+	}
+	if err := s.repo.RemoveReviewer(ctx, prID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPR(ctx, prID)
+}
+
+// RespondReview accepts or declines userID's review of prID and returns the
+// teammate who took over a declined slot, if any.
+func (s *ServiceImpl) RespondReview(ctx context.Context, prID, userID string, accept bool) (string, error) {
+	if isBlank(userID) {
+		return "", entity.ErrBlankUserID
+	}
+	pr, err := s.repo.GetPR(ctx, prID)
+	if err != nil {
+		return "", err
+	}
+	if err := assertPRMutable(pr); err != nil {
+		return "", err
+	}
+	newUserID, err := s.repo.RespondReview(ctx, prID, userID, accept)
+	if err != nil {
+		return "", err
+	}
+	if newUserID != "" {
+		s.notifier.ReviewerAssigned(prID, newUserID)
+	}
+	return newUserID, nil
+}
+
+func (s *ServiceImpl) validateReassign(ctx context.Context, prID, oldUserID string) error {
+	if isBlank(oldUserID) {
+		return entity.ErrBlankUserID
+	}
+	pr, err := s.repo.GetPR(ctx, prID)
+	if err != nil {
+		return err
+	}
+	if err := assertPRMutable(pr); err != nil {
+		return err
+	}
+	for _, reviewer := range pr.AssignedReviewers {
+		if reviewer.ID == oldUserID {
+			return nil
+		}
+	}
+	return entity.ErrNotAssigned
+}
+
+func (s *ServiceImpl) GetPR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	if err := s.validatePRID(prID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPR(ctx, prID)
+}
+
+func (s *ServiceImpl) ListPRs(ctx context.Context, status, authorID string, limit, offset int) ([]entity.PullRequest, error) {
+	return s.repo.ListPRs(ctx, status, authorID, limit, offset)
+}
+
+func (s *ServiceImpl) SearchPRs(ctx context.Context, query string, limit int) ([]entity.PullRequest, error) {
+	return s.repo.SearchPRs(ctx, query, limit)
+}
+
+func (s *ServiceImpl) GetStats(ctx context.Context, limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+	return s.repo.GetStatsPaged(ctx, limit, offset, filter)
+}
+
+func (s *ServiceImpl) GetTeamStats(ctx context.Context, teamName string) (*entity.Stats, error) {
+	return s.repo.GetTeamStats(ctx, teamName)
+}
+
+func (s *ServiceImpl) GetUserStats(ctx context.Context, userID string) (*entity.UserAssignmentCount, error) {
+	return s.repo.GetUserStats(ctx, userID)
+}
+
+func (s *ServiceImpl) GetConcentration(ctx context.Context) (*entity.Concentration, error) {
+	return s.repo.GetConcentration(ctx)
+}
+
+func (s *ServiceImpl) GetOperationalCounts(ctx context.Context) (*entity.OperationalCounts, error) {
+	return s.repo.GetOperationalCounts(ctx)
+}
+
+// RequiredTeamSize returns the headcount policy needs: the author, the
+// desired reviewers limited by Cap when set, and Reserve spares for
+// reassignment.
+func (s *ServiceImpl) RequiredTeamSize(policy entity.ReviewPolicy) (int, error) {
+	if policy.DesiredReviewers < 1 || policy.Reserve < 0 || policy.Cap < 0 {
+		return 0, entity.ErrInvalidPolicy
+	}
+	reviewers := policy.DesiredReviewers
+	if policy.Cap > 0 && policy.Cap < reviewers {
+		reviewers = policy.Cap
+	}
+	return 1 + reviewers + policy.Reserve, nil
+}
+
+func (s *ServiceImpl) GetReviewerWeeklySummary(ctx context.Context, userID string, weeks int) ([]entity.WeekCount, error) {
+	return s.repo.GetReviewerWeeklySummary(ctx, userID, weeks)
+}
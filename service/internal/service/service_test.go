@@ -1,681 +1,2307 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 
 	"service/internal/entity"
 )
 
 type mockRepo struct {
-    createTeamFunc        func(team *entity.Team, members []entity.User) error
-    getTeamFunc           func(teamName string) (*entity.Team, []entity.User, error)
-    setUserActiveFunc     func(userID string, isActive bool) (*entity.User, error)
-    getUserReviewPRsFunc  func(userID string) ([]entity.PullRequest, error)
-    createPRFunc          func(pr *entity.PullRequest, reviewerIDs []string) error
-    mergePRFunc           func(prID string) (*entity.PullRequest, error)
-    getPRFunc             func(prID string) (*entity.PullRequest, error)
-    reassignReviewerFunc  func(prID, oldUserID string) (string, error)
-    getCandidateReviewersFunc func(authorID string, limit int) ([]string, error)
-    getStatsFunc          func() (*entity.Stats, error) 
+	createTeamFunc               func(team *entity.Team, members []entity.User) error
+	createTeamsBulkFunc          func(teams []entity.TeamWithMembers) ([]entity.ImportResult, error)
+	renameTeamFunc               func(oldName, newName string) error
+	getTeamFunc                  func(teamName string) (*entity.Team, []entity.User, error)
+	getUserFunc                  func(userID string) (*entity.User, error)
+	setUserActiveFunc            func(userID string, isActive bool) (*entity.User, error)
+	deactivateAndRetireFunc      func(userID string) ([]entity.Reassignment, error)
+	getUserReviewPRsFunc         func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error)
+	createPRFunc                 func(pr *entity.PullRequest, reviewerIDs []string) error
+	mergePRFunc                  func(prID string) (*entity.PullRequest, error)
+	closePRFunc                  func(prID string) (*entity.PullRequest, error)
+	reopenPRFunc                 func(prID string) (*entity.PullRequest, error)
+	getPRFunc                    func(prID string) (*entity.PullRequest, error)
+	listPRsFunc                  func(status, authorID string, limit, offset int) ([]entity.PullRequest, error)
+	reassignReviewerFunc         func(prID, oldUserID string) (string, error)
+	previewReassignFunc          func(prID, oldUserID string) (string, error)
+	addReviewerFunc              func(prID, userID string) error
+	removeReviewerFunc           func(prID, userID string) error
+	respondReviewFunc            func(prID, userID string, accept bool) (string, error)
+	getIdempotencyRecordFunc     func(key string) (string, []byte, error)
+	getCandidateReviewersFunc    func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error)
+	getMissingUserIDsFunc        func(userIDs []string) ([]string, error)
+	countActiveTeammatesFunc     func(userID string) (int, error)
+	countTeammatesFunc           func(userID string) (int, error)
+	countActiveMembersFunc       func(teamName string) (int, error)
+	getTeamReviewerCountFunc     func(teamName string) (int, error)
+	evaluateCandidatesFunc       func(authorID string, excludeIDs []string, teamName string) ([]entity.CandidateEvaluation, error)
+	getUserTeamsFunc             func(userID string) ([]string, error)
+	getStatsFunc                 func() (*entity.Stats, error)
+	getStatsPagedFunc            func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error)
+	getTeamStatsFunc             func(teamName string) (*entity.Stats, error)
+	getUserStatsFunc             func(userID string) (*entity.UserAssignmentCount, error)
+	getReviewerWeeklySummaryFunc func(userID string, weeks int) ([]entity.WeekCount, error)
 }
 
-func (m *mockRepo) CreateTeam(team *entity.Team, members []entity.User) error {
-    if m.createTeamFunc != nil {
-        return m.createTeamFunc(team, members)
-    }
-    return nil
+func (m *mockRepo) CreateTeam(ctx context.Context, team *entity.Team, members []entity.User) error {
+	if m.createTeamFunc != nil {
+		return m.createTeamFunc(team, members)
+	}
+	return nil
 }
 
-func (m *mockRepo) GetTeam(teamName string) (*entity.Team, []entity.User, error) {
-    if m.getTeamFunc != nil {
-        return m.getTeamFunc(teamName)
-    }
-    return &entity.Team{Name: teamName}, []entity.User{}, nil
+func (m *mockRepo) CreateTeamsBulk(ctx context.Context, teams []entity.TeamWithMembers) ([]entity.ImportResult, error) {
+	if m.createTeamsBulkFunc != nil {
+		return m.createTeamsBulkFunc(teams)
+	}
+	results := make([]entity.ImportResult, 0, len(teams))
+	for _, t := range teams {
+		results = append(results, entity.ImportResult{TeamName: t.TeamName})
+	}
+	return results, nil
 }
 
-func (m *mockRepo) SetUserActive(userID string, isActive bool) (*entity.User, error) {
-    if m.setUserActiveFunc != nil {
-        return m.setUserActiveFunc(userID, isActive)
-    }
-    return &entity.User{ID: userID, IsActive: isActive}, nil
+func (m *mockRepo) GetTeam(ctx context.Context, teamName string) (*entity.Team, []entity.User, error) {
+	if m.getTeamFunc != nil {
+		return m.getTeamFunc(teamName)
+	}
+	return &entity.Team{Name: teamName}, []entity.User{}, nil
 }
 
-func (m *mockRepo) GetUserReviewPRs(userID string) ([]entity.PullRequest, error) {
-    if m.getUserReviewPRsFunc != nil {
-        return m.getUserReviewPRsFunc(userID)
-    }
-    return []entity.PullRequest{}, nil
+func (m *mockRepo) RenameTeam(ctx context.Context, oldName, newName string) error {
+	if m.renameTeamFunc != nil {
+		return m.renameTeamFunc(oldName, newName)
+	}
+	return nil
 }
 
-func (m *mockRepo) CreatePR(pr *entity.PullRequest, reviewerIDs []string) error {
-    if m.createPRFunc != nil {
-        return m.createPRFunc(pr, reviewerIDs)
-    }
-    return nil
+func (m *mockRepo) GetUser(ctx context.Context, userID string) (*entity.User, error) {
+	if m.getUserFunc != nil {
+		return m.getUserFunc(userID)
+	}
+	return &entity.User{ID: userID, IsActive: true}, nil
 }
 
-func (m *mockRepo) MergePR(prID string) (*entity.PullRequest, error) {
-    if m.mergePRFunc != nil {
-        return m.mergePRFunc(prID)
-    }
-    return &entity.PullRequest{ID: prID, Status: "MERGED"}, nil
+func (m *mockRepo) GetUserLoad(ctx context.Context, userID string) (open, total int, err error) {
+	return 0, 0, nil
 }
 
-func (m *mockRepo) GetPR(prID string) (*entity.PullRequest, error) {
-    if m.getPRFunc != nil {
-        return m.getPRFunc(prID)
-    }
-    return &entity.PullRequest{ID: prID}, nil
+func (m *mockRepo) SetUserActive(ctx context.Context, userID string, isActive bool) (*entity.User, error) {
+	if m.setUserActiveFunc != nil {
+		return m.setUserActiveFunc(userID, isActive)
+	}
+	return &entity.User{ID: userID, IsActive: isActive}, nil
 }
 
-func (m *mockRepo) ReassignReviewer(prID, oldUserID string) (string, error) {
-    if m.reassignReviewerFunc != nil {
-        return m.reassignReviewerFunc(prID, oldUserID)
-    }
-    return "new-user", nil
+func (m *mockRepo) DeactivateAndRetire(ctx context.Context, userID string) ([]entity.Reassignment, error) {
+	if m.deactivateAndRetireFunc != nil {
+		return m.deactivateAndRetireFunc(userID)
+	}
+	return []entity.Reassignment{}, nil
 }
 
-func (m *mockRepo) GetCandidateReviewers(authorID string, limit int) ([]string, error) {
-    if m.getCandidateReviewersFunc != nil {
-        return m.getCandidateReviewersFunc(authorID, limit)
-    }
-    return []string{"reviewer1", "reviewer2"}, nil
+func (m *mockRepo) GetUserReviewPRs(ctx context.Context, userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+	if m.getUserReviewPRsFunc != nil {
+		return m.getUserReviewPRsFunc(userID, status, page)
+	}
+	return []entity.PullRequest{}, nil, nil
 }
 
-func (m *mockRepo) GetPRReviewers(prID string) ([]entity.User, error) {
-    return []entity.User{}, nil
+func (m *mockRepo) CreatePR(ctx context.Context, pr *entity.PullRequest, reviewerIDs []string) error {
+	if m.createPRFunc != nil {
+		return m.createPRFunc(pr, reviewerIDs)
+	}
+	return nil
 }
 
-func (m *mockRepo) GetStats() (*entity.Stats, error) {
-    if m.getStatsFunc != nil {
-        return m.getStatsFunc()
-    }
-    return &entity.Stats{
-        UserAssignmentCounts: []entity.UserAssignmentCount{},
-        PRAssignmentCounts:   []entity.PRAssignmentCount{},
-        TotalAssignments:     0,
-    }, nil
+func (m *mockRepo) GetIdempotencyRecord(ctx context.Context, key string) (string, []byte, error) {
+	if m.getIdempotencyRecordFunc != nil {
+		return m.getIdempotencyRecordFunc(key)
+	}
+	return "", nil, entity.ErrNotFound
+}
+
+func (m *mockRepo) SaveIdempotencyRecord(ctx context.Context, key, prID string, response []byte) error {
+	return nil
+}
+
+func (m *mockRepo) MergePR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	if m.mergePRFunc != nil {
+		return m.mergePRFunc(prID)
+	}
+	return &entity.PullRequest{ID: prID, Status: "MERGED"}, nil
+}
+
+func (m *mockRepo) ClosePR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	if m.closePRFunc != nil {
+		return m.closePRFunc(prID)
+	}
+	return &entity.PullRequest{ID: prID, Status: "CLOSED"}, nil
+}
+
+func (m *mockRepo) ReopenPR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	if m.reopenPRFunc != nil {
+		return m.reopenPRFunc(prID)
+	}
+	return &entity.PullRequest{ID: prID, Status: "OPEN"}, nil
+}
+
+func (m *mockRepo) GetPR(ctx context.Context, prID string) (*entity.PullRequest, error) {
+	if m.getPRFunc != nil {
+		return m.getPRFunc(prID)
+	}
+	return &entity.PullRequest{ID: prID, Status: "OPEN"}, nil
+}
+
+func (m *mockRepo) ReassignReviewer(ctx context.Context, prID, oldUserID string) (string, error) {
+	if m.reassignReviewerFunc != nil {
+		return m.reassignReviewerFunc(prID, oldUserID)
+	}
+	return "new-user", nil
+}
+
+func (m *mockRepo) PreviewReassign(ctx context.Context, prID, oldUserID string) (string, error) {
+	if m.previewReassignFunc != nil {
+		return m.previewReassignFunc(prID, oldUserID)
+	}
+	return "new-user", nil
+}
+
+func (m *mockRepo) GetReassignmentHistory(ctx context.Context, prID string) ([]entity.Reassignment, error) {
+	return []entity.Reassignment{}, nil
+}
+
+func (m *mockRepo) GetPRTimeline(ctx context.Context, prID string) ([]entity.TimelineEvent, error) {
+	return []entity.TimelineEvent{}, nil
+}
+
+func (m *mockRepo) AddReviewer(ctx context.Context, prID, userID string) error {
+	if m.addReviewerFunc != nil {
+		return m.addReviewerFunc(prID, userID)
+	}
+	return nil
+}
+
+func (m *mockRepo) RespondReview(ctx context.Context, prID, userID string, accept bool) (string, error) {
+	if m.respondReviewFunc != nil {
+		return m.respondReviewFunc(prID, userID, accept)
+	}
+	return "", nil
+}
+
+func (m *mockRepo) RemoveReviewer(ctx context.Context, prID, userID string) error {
+	if m.removeReviewerFunc != nil {
+		return m.removeReviewerFunc(prID, userID)
+	}
+	return nil
+}
+
+func (m *mockRepo) ListPRs(ctx context.Context, status, authorID string, limit, offset int) ([]entity.PullRequest, error) {
+	if m.listPRsFunc != nil {
+		return m.listPRsFunc(status, authorID, limit, offset)
+	}
+	return []entity.PullRequest{}, nil
+}
+
+func (m *mockRepo) SearchPRs(ctx context.Context, query string, limit int) ([]entity.PullRequest, error) {
+	return []entity.PullRequest{}, nil
+}
+
+func (m *mockRepo) GetCandidateReviewers(ctx context.Context, authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+	if m.getCandidateReviewersFunc != nil {
+		return m.getCandidateReviewersFunc(authorID, limit, excludeIDs, avoidRecent, teamName)
+	}
+	return candidates("reviewer1", "reviewer2"), nil
+}
+
+func candidates(ids ...string) []entity.CandidateReviewer {
+	result := make([]entity.CandidateReviewer, len(ids))
+	for i, id := range ids {
+		result[i] = entity.CandidateReviewer{UserID: id}
+	}
+	return result
+}
+
+func (m *mockRepo) CountActiveTeammates(ctx context.Context, userID string) (int, error) {
+	if m.countActiveTeammatesFunc != nil {
+		return m.countActiveTeammatesFunc(userID)
+	}
+	return 2, nil
+}
+
+func (m *mockRepo) CountTeammates(ctx context.Context, userID string) (int, error) {
+	if m.countTeammatesFunc != nil {
+		return m.countTeammatesFunc(userID)
+	}
+	return m.CountActiveTeammates(ctx, userID)
+}
+
+func (m *mockRepo) CountActiveMembers(ctx context.Context, teamName string) (int, error) {
+	if m.countActiveMembersFunc != nil {
+		return m.countActiveMembersFunc(teamName)
+	}
+	return 3, nil
+}
+
+func (m *mockRepo) GetTeamReviewerCount(ctx context.Context, teamName string) (int, error) {
+	if m.getTeamReviewerCountFunc != nil {
+		return m.getTeamReviewerCountFunc(teamName)
+	}
+	return 0, nil
+}
+
+func (m *mockRepo) SetTeamReviewerCount(ctx context.Context, teamName string, count int) error {
+	return nil
+}
+
+func (m *mockRepo) ReassignAllForUser(ctx context.Context, userID string, bestEffort bool) ([]entity.ReassignResult, error) {
+	return []entity.ReassignResult{}, nil
+}
+
+func (m *mockRepo) SetTeamMembersActive(ctx context.Context, teamName string, isActive bool) ([]entity.User, []entity.Reassignment, error) {
+	return []entity.User{}, []entity.Reassignment{}, nil
+}
+
+func (m *mockRepo) FetchUnpublishedEvents(ctx context.Context, limit int) ([]entity.Event, error) {
+	return []entity.Event{}, nil
+}
+
+func (m *mockRepo) MarkEventPublished(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *mockRepo) GetUserTeams(ctx context.Context, userID string) ([]entity.Team, error) {
+	names := []string{"backend"}
+	if m.getUserTeamsFunc != nil {
+		var err error
+		if names, err = m.getUserTeamsFunc(userID); err != nil {
+			return nil, err
+		}
+	}
+	teams := make([]entity.Team, len(names))
+	for i, name := range names {
+		teams[i] = entity.Team{Name: name}
+	}
+	return teams, nil
+}
+
+func (m *mockRepo) EvaluateCandidates(ctx context.Context, authorID string, excludeIDs []string, teamName string) ([]entity.CandidateEvaluation, error) {
+	if m.evaluateCandidatesFunc != nil {
+		return m.evaluateCandidatesFunc(authorID, excludeIDs, teamName)
+	}
+	return []entity.CandidateEvaluation{}, nil
+}
+
+func (m *mockRepo) GetMissingUserIDs(ctx context.Context, userIDs []string) ([]string, error) {
+	if m.getMissingUserIDsFunc != nil {
+		return m.getMissingUserIDsFunc(userIDs)
+	}
+	return nil, nil
+}
+
+func (m *mockRepo) GetPRReviewers(ctx context.Context, prID string) ([]entity.User, error) {
+	return []entity.User{}, nil
+}
+
+func (m *mockRepo) GetReviewersForPRs(ctx context.Context, prIDs []string) (map[string][]entity.User, error) {
+	return map[string][]entity.User{}, nil
+}
+
+func (m *mockRepo) GetStatsPaged(ctx context.Context, limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+	if m.getStatsPagedFunc != nil {
+		return m.getStatsPagedFunc(limit, offset, filter)
+	}
+	if m.getStatsFunc != nil {
+		return m.getStatsFunc()
+	}
+	return &entity.Stats{
+		UserAssignmentCounts: []entity.UserAssignmentCount{},
+		PRAssignmentCounts:   []entity.PRAssignmentCount{},
+		TotalAssignments:     0,
+	}, nil
+}
+
+func (m *mockRepo) GetTeamStats(ctx context.Context, teamName string) (*entity.Stats, error) {
+	if m.getTeamStatsFunc != nil {
+		return m.getTeamStatsFunc(teamName)
+	}
+	return &entity.Stats{}, nil
+}
+
+func (m *mockRepo) GetUserStats(ctx context.Context, userID string) (*entity.UserAssignmentCount, error) {
+	if m.getUserStatsFunc != nil {
+		return m.getUserStatsFunc(userID)
+	}
+	return &entity.UserAssignmentCount{UserID: userID}, nil
+}
+
+func (m *mockRepo) GetConcentration(ctx context.Context) (*entity.Concentration, error) {
+	return &entity.Concentration{UserLoads: []entity.UserAssignmentCount{}}, nil
+}
+
+func (m *mockRepo) GetOperationalCounts(ctx context.Context) (*entity.OperationalCounts, error) {
+	return &entity.OperationalCounts{}, nil
+}
+
+func (m *mockRepo) GetReviewerWeeklySummary(ctx context.Context, userID string, weeks int) ([]entity.WeekCount, error) {
+	if m.getReviewerWeeklySummaryFunc != nil {
+		return m.getReviewerWeeklySummaryFunc(userID, weeks)
+	}
+	return []entity.WeekCount{}, nil
 }
 
 func TestService_CreateTeam_Success(t *testing.T) {
-    mockRepo := &mockRepo{
-        createTeamFunc: func(team *entity.Team, members []entity.User) error {
-            return nil
-        },
-    }
-    service := NewService(mockRepo)
-    members := []entity.User{
-        {ID: "u1", Username: "Alice", IsActive: true},
-        {ID: "u2", Username: "Bob", IsActive: true},
-    }
-    team, err := service.CreateTeam("backend", members)
-    if err != nil {
-        t.Fatalf("Expected no error, got %v", err)
-    }
-    if team.Name != "backend" {
-        t.Errorf("Expected team name 'backend', got %s", team.Name)
-    }
+	mockRepo := &mockRepo{
+		createTeamFunc: func(team *entity.Team, members []entity.User) error {
+			return nil
+		},
+	}
+	service := NewService(mockRepo)
+	members := []entity.User{
+		{ID: "u1", Username: "Alice", IsActive: true},
+		{ID: "u2", Username: "Bob", IsActive: true},
+	}
+	team, err := service.CreateTeam(context.Background(), "backend", members, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if team.Name != "backend" {
+		t.Errorf("Expected team name 'backend', got %s", team.Name)
+	}
 }
 
 func TestService_CreateTeam_RepositoryError(t *testing.T) {
-    mockRepo := &mockRepo{
-        createTeamFunc: func(team *entity.Team, members []entity.User) error {
-            return entity.ErrTeamExists
-        },
-    }
-    service := NewService(mockRepo)
-    _, err := service.CreateTeam("backend", []entity.User{})
-    if !errors.Is(err, entity.ErrTeamExists) {
-        t.Errorf("Expected ErrTeamExists, got %v", err)
-    }
+	mockRepo := &mockRepo{
+		createTeamFunc: func(team *entity.Team, members []entity.User) error {
+			return entity.ErrTeamExists
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreateTeam(context.Background(), "backend", []entity.User{}, 0)
+	if !errors.Is(err, entity.ErrTeamExists) {
+		t.Errorf("Expected ErrTeamExists, got %v", err)
+	}
+}
+
+func TestService_CreateTeam_TeamName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		teamName string
+		wantErr  error
+	}{
+		{"Empty", "", entity.ErrEmptyTeamName},
+		{"Whitespace", "  \t ", entity.ErrEmptyTeamName},
+		{"Trimmed", "  payments ", nil},
+		{"PaddedDuplicate", " backend  ", entity.ErrTeamExists},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stored []string
+			mockRepo := &mockRepo{
+				createTeamFunc: func(team *entity.Team, members []entity.User) error {
+					stored = append(stored, team.Name)
+					if strings.EqualFold(team.Name, "backend") {
+						return entity.ErrTeamExists
+					}
+					return nil
+				},
+			}
+			service := NewService(mockRepo)
+			team, err := service.CreateTeam(context.Background(), tc.teamName, []entity.User{}, 0)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Expected %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr == entity.ErrEmptyTeamName && len(stored) != 0 {
+				t.Errorf("Repository should not be called, got %v", stored)
+			}
+			if len(stored) > 0 && stored[0] != strings.TrimSpace(tc.teamName) {
+				t.Errorf("Expected trimmed name %q, got %q", strings.TrimSpace(tc.teamName), stored[0])
+			}
+			if err == nil && team.Name != "payments" {
+				t.Errorf("Expected team name 'payments', got %q", team.Name)
+			}
+		})
+	}
+}
+
+func TestService_ImportTeams_TeamName(t *testing.T) {
+	var stored []string
+	mockRepo := &mockRepo{
+		createTeamsBulkFunc: func(teams []entity.TeamWithMembers) ([]entity.ImportResult, error) {
+			results := make([]entity.ImportResult, 0, len(teams))
+			for _, team := range teams {
+				stored = append(stored, team.TeamName)
+				results = append(results, entity.ImportResult{TeamName: team.TeamName})
+			}
+			return results, nil
+		},
+	}
+	service := NewService(mockRepo)
+	results, err := service.ImportTeams(context.Background(), []entity.TeamWithMembers{
+		{TeamName: "  backend "},
+		{TeamName: ""},
+		{TeamName: " \t"},
+		{TeamName: "payments"},
+		{TeamName: "blank-member", Members: []entity.User{{ID: "u1"}, {ID: "  "}}},
+	})
+	if err != nil {
+		t.Fatalf("ImportTeams failed: %v", err)
+	}
+	if !reflect.DeepEqual(stored, []string{"backend", "payments"}) {
+		t.Errorf("Expected only trimmed valid names to reach the repository, got %q", stored)
+	}
+	want := []entity.ImportResult{
+		{TeamName: "backend"},
+		{TeamName: "", Err: entity.ErrEmptyTeamName},
+		{TeamName: " \t", Err: entity.ErrEmptyTeamName},
+		{TeamName: "payments"},
+		{TeamName: "blank-member", Err: entity.ErrBlankUserID},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Expected %+v, got %+v", want, results)
+	}
+}
+
+func TestService_RenameTeam_TeamName(t *testing.T) {
+	var renamed [][2]string
+	mockRepo := &mockRepo{
+		renameTeamFunc: func(oldName, newName string) error {
+			renamed = append(renamed, [2]string{oldName, newName})
+			return nil
+		},
+	}
+	service := NewService(mockRepo)
+	team, err := service.RenameTeam(context.Background(), " payments ", " billing ")
+	if err != nil {
+		t.Fatalf("RenameTeam failed: %v", err)
+	}
+	if team.Name != "billing" {
+		t.Errorf("Expected the trimmed new name, got %q", team.Name)
+	}
+	for _, names := range [][2]string{{"payments", ""}, {"payments", "   "}, {" \t", "billing"}} {
+		_, err := service.RenameTeam(context.Background(), names[0], names[1])
+		if err != entity.ErrEmptyTeamName {
+			t.Errorf("%q: expected ErrEmptyTeamName, got %v", names, err)
+		}
+	}
+	if !reflect.DeepEqual(renamed, [][2]string{{"payments", "billing"}}) {
+		t.Errorf("Expected only the trimmed valid rename to reach the repository, got %q", renamed)
+	}
+}
+
+func TestService_BlankUserIDs(t *testing.T) {
+	calls := 0
+	mockRepo := &mockRepo{
+		createTeamFunc: func(team *entity.Team, members []entity.User) error {
+			calls++
+			return nil
+		},
+		setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
+			calls++
+			return &entity.User{ID: userID, IsActive: isActive}, nil
+		},
+		getUserFunc: func(userID string) (*entity.User, error) {
+			calls++
+			return &entity.User{ID: userID, IsActive: true}, nil
+		},
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			calls++
+			return &entity.PullRequest{ID: prID, Status: "OPEN"}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	ctx := context.Background()
+	for _, id := range []string{"", "  \t"} {
+		entryPoints := map[string]func() error{
+			"CreateTeam": func() error {
+				_, err := service.CreateTeam(ctx, "backend", []entity.User{{ID: "u1", Username: "Alice"}, {ID: id, Username: "Bob"}}, 0)
+				return err
+			},
+			"SetUserActive": func() error {
+				_, _, err := service.SetUserActive(ctx, id, false)
+				return err
+			},
+			"CreatePR author": func() error {
+				_, err := service.CreatePR(ctx, "pr-1", "Title", id, entity.CreatePROptions{})
+				return err
+			},
+			"CreatePR exclude": func() error {
+				_, err := service.CreatePR(ctx, "pr-1", "Title", "u1", entity.CreatePROptions{ExcludeReviewers: []string{id}})
+				return err
+			},
+			"ReassignReviewer": func() error {
+				_, _, err := service.ReassignReviewer(ctx, "pr-1", id)
+				return err
+			},
+			"PreviewReassign": func() error {
+				_, err := service.PreviewReassign(ctx, "pr-1", id)
+				return err
+			},
+		}
+		for name, call := range entryPoints {
+			t.Run(fmt.Sprintf("%s/%q", name, id), func(t *testing.T) {
+				calls = 0
+				if err := call(); !errors.Is(err, entity.ErrBlankUserID) {
+					t.Errorf("Expected ErrBlankUserID, got %v", err)
+				}
+				if calls != 0 {
+					t.Errorf("Expected no repository calls, got %d", calls)
+				}
+			})
+		}
+	}
 }
 
 func TestService_SetUserActive_Success(t *testing.T) {
-    mockRepo := &mockRepo{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return &entity.User{ID: userID, Username: "testuser", IsActive: isActive}, nil
-        },
-    }
-    service := NewService(mockRepo)
-    user, err := service.SetUserActive("u1", true)
-    if err != nil {
-        t.Fatalf("Expected no error, got %v", err)
-    }
-
-    if user.ID != "u1" {
-        t.Errorf("Expected user ID 'u1', got %s", user.ID)
-    }
-    if !user.IsActive {
-        t.Error("Expected user to be active")
-    }
+	mockRepo := &mockRepo{
+		setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
+			return &entity.User{ID: userID, Username: "testuser", IsActive: isActive}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	user, _, err := service.SetUserActive(context.Background(), "u1", true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if user.ID != "u1" {
+		t.Errorf("Expected user ID 'u1', got %s", user.ID)
+	}
+	if !user.IsActive {
+		t.Error("Expected user to be active")
+	}
 }
 
 func TestService_CreatePR_Success(t *testing.T) {
-    mockRepo := &mockRepo{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return &entity.User{ID: userID, Username: "author", IsActive: true}, nil
-        },
-        getCandidateReviewersFunc: func(authorID string, limit int) ([]string, error) {
-            return []string{"reviewer1", "reviewer2"}, nil
-        },
-        createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
-            return nil
-        },
-        getPRFunc: func(prID string) (*entity.PullRequest, error) {
-            return &entity.PullRequest{
-                ID:       prID,
-                Title:    "Test PR",
-                AuthorID: "author1",
-                Status:   "OPEN",
-                AssignedReviewers: []entity.User{
-                    {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-                    {ID: "reviewer2", Username: "Reviewer2", IsActive: true},
-                },
-            }, nil
-        },
-    }
-    service := NewService(mockRepo)
-    pr, err := service.CreatePR("pr-1", "Test PR", "author1")
-    if err != nil {
-        t.Fatalf("Expected no error, got %v", err)
-    }
-    if pr.ID != "pr-1" {
-        t.Errorf("Expected PR ID 'pr-1', got %s", pr.ID)
-    }
-    if pr.Status != "OPEN" {
-        t.Errorf("Expected status 'OPEN', got %s", pr.Status)
-    }
-    if len(pr.AssignedReviewers) != 2 {
-        t.Errorf("Expected 2 assigned reviewers, got %d", len(pr.AssignedReviewers))
-    }
+	mockRepo := &mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return &entity.User{ID: userID, Username: "author", IsActive: true}, nil
+		},
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return candidates("reviewer1", "reviewer2"), nil
+		},
+		createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
+			return nil
+		},
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:       prID,
+				Title:    "Test PR",
+				AuthorID: "author1",
+				Status:   "OPEN",
+				AssignedReviewers: []entity.User{
+					{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+					{ID: "reviewer2", Username: "Reviewer2", IsActive: true},
+				},
+			}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	pr, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pr.ID != "pr-1" {
+		t.Errorf("Expected PR ID 'pr-1', got %s", pr.ID)
+	}
+	if pr.Status != "OPEN" {
+		t.Errorf("Expected status 'OPEN', got %s", pr.Status)
+	}
+	if len(pr.AssignedReviewers) != 2 {
+		t.Errorf("Expected 2 assigned reviewers, got %d", len(pr.AssignedReviewers))
+	}
+}
+
+func TestService_CreatePR_ConfiguredReviewersCount(t *testing.T) {
+	testCases := []struct {
+		name  string
+		count int
+		want  int
+	}{
+		{"Default", 0, DefaultReviewersCount},
+		{"Custom", 3, 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotLimit int
+			mockRepo := &mockRepo{
+				getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+					gotLimit = limit
+					return candidates("reviewer1"), nil
+				},
+			}
+			service := NewServiceWithConfig(mockRepo, Config{ReviewersCount: tc.count})
+			pr, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if gotLimit != tc.want {
+				t.Errorf("Expected candidate limit %d, got %d", tc.want, gotLimit)
+			}
+			if pr.RequestedReviewers != tc.want {
+				t.Errorf("Expected RequestedReviewers %d, got %d", tc.want, pr.RequestedReviewers)
+			}
+		})
+	}
+}
+
+func TestService_CreatePR_ReviewersCountPrecedence(t *testing.T) {
+	testCases := []struct {
+		name      string
+		teamCount int
+		requested int
+		want      int
+	}{
+		{"Team setting wins over request", 3, 1, 3},
+		{"Team setting without request", 1, 0, 1},
+		{"Request without team setting", 0, 4, 4},
+		{"Global default", 0, 0, 5},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotLimit int
+			mockRepo := &mockRepo{
+				getTeamReviewerCountFunc: func(teamName string) (int, error) {
+					if teamName != "backend" {
+						t.Errorf("Expected the author's team backend, got %q", teamName)
+					}
+					return tc.teamCount, nil
+				},
+				getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+					gotLimit = limit
+					return candidates("reviewer1"), nil
+				},
+			}
+			service := NewServiceWithConfig(mockRepo, Config{ReviewersCount: 5})
+			pr, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{ReviewersCount: tc.requested})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if gotLimit != tc.want || pr.RequestedReviewers != tc.want {
+				t.Errorf("Expected %d reviewers, got limit %d and RequestedReviewers %d", tc.want, gotLimit, pr.RequestedReviewers)
+			}
+		})
+	}
+}
+
+func TestService_CreatePR_Debug(t *testing.T) {
+	var evaluated bool
+	mockRepo := &mockRepo{
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return candidates("reviewer1", "reviewer2"), nil
+		},
+		evaluateCandidatesFunc: func(authorID string, excludeIDs []string, teamName string) ([]entity.CandidateEvaluation, error) {
+			evaluated = true
+			if teamName != "backend" || !reflect.DeepEqual(excludeIDs, []string{"reviewer5"}) {
+				t.Errorf("Expected the CreatePR pool and exclusions, got %q %v", teamName, excludeIDs)
+			}
+			return []entity.CandidateEvaluation{
+				{UserID: "author1", SkipReason: entity.SkipAuthor},
+				{UserID: "reviewer1", Load: 0},
+				{UserID: "reviewer2", Load: 1},
+				{UserID: "reviewer3", Load: 2},
+				{UserID: "reviewer4", SkipReason: entity.SkipInactive},
+				{UserID: "reviewer5", SkipReason: entity.SkipExcluded},
+			}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	pr, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if err != nil {
+		t.Fatalf("CreatePR failed: %v", err)
+	}
+	if evaluated || pr.CandidateEvaluations != nil {
+		t.Fatalf("Expected no evaluation without Debug, got %v", pr.CandidateEvaluations)
+	}
+	pr, err = service.CreatePR(context.Background(), "pr-2", "Test PR", "author1", entity.CreatePROptions{Debug: true, ExcludeReviewers: []string{"reviewer5"}})
+	if err != nil {
+		t.Fatalf("CreatePR failed: %v", err)
+	}
+	want := []entity.CandidateEvaluation{
+		{UserID: "author1", SkipReason: entity.SkipAuthor},
+		{UserID: "reviewer1", Load: 0, Selected: true},
+		{UserID: "reviewer2", Load: 1, Selected: true},
+		{UserID: "reviewer3", Load: 2, SkipReason: entity.SkipRankedLower},
+		{UserID: "reviewer4", SkipReason: entity.SkipInactive},
+		{UserID: "reviewer5", SkipReason: entity.SkipExcluded},
+	}
+	if !reflect.DeepEqual(pr.CandidateEvaluations, want) {
+		t.Errorf("Expected %+v, got %+v", want, pr.CandidateEvaluations)
+	}
+}
+
+func TestService_CreatePR_ExcludesAuthorAliases(t *testing.T) {
+	var gotExclude []string
+	mockRepo := &mockRepo{
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			gotExclude = excludeIDs
+			return candidates("reviewer1", "reviewer2"), nil
+		},
+		evaluateCandidatesFunc: func(authorID string, excludeIDs []string, teamName string) ([]entity.CandidateEvaluation, error) {
+			return []entity.CandidateEvaluation{
+				{UserID: "author1", SkipReason: entity.SkipAuthor},
+				{UserID: "author1-old", SkipReason: entity.SkipExcluded},
+				{UserID: "reviewer1"},
+				{UserID: "reviewer2"},
+			}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	if _, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{}); err != nil {
+		t.Fatalf("CreatePR failed: %v", err)
+	}
+	if len(gotExclude) != 0 {
+		t.Errorf("Expected no exclusions without aliases, got %v", gotExclude)
+	}
+	pr, err := service.CreatePR(context.Background(), "pr-2", "Test PR", "author1", entity.CreatePROptions{
+		ExcludeReviewers: []string{"reviewer3"},
+		AuthorAliases:    []string{"author1-old"},
+		Debug:            true,
+	})
+	if err != nil {
+		t.Fatalf("CreatePR failed: %v", err)
+	}
+	if !reflect.DeepEqual(gotExclude, []string{"reviewer3", "author1-old"}) {
+		t.Errorf("Expected aliases to be excluded alongside exclude_reviewers, got %v", gotExclude)
+	}
+	if pr.CandidateEvaluations[1].SkipReason != entity.SkipAuthor {
+		t.Errorf("Expected the alias to be reported as the author, got %+v", pr.CandidateEvaluations[1])
+	}
+	_, err = service.CreatePR(context.Background(), "pr-3", "Test PR", "author1", entity.CreatePROptions{AuthorAliases: []string{" "}})
+	if err != entity.ErrBlankUserID {
+		t.Errorf("Expected ErrBlankUserID for a blank alias, got %v", err)
+	}
+}
+
+func TestService_TeamReviewerCount_Validation(t *testing.T) {
+	service := NewService(&mockRepo{})
+	ctx := context.Background()
+	if _, err := service.CreateTeam(ctx, "infra", nil, MaxReviewersCount+1); err != entity.ErrInvalidReviewerCount {
+		t.Errorf("Expected ErrInvalidReviewerCount from CreateTeam, got %v", err)
+	}
+	for _, count := range []int{0, MaxReviewersCount + 1} {
+		if err := service.SetTeamReviewerCount(ctx, "infra", count); err != entity.ErrInvalidReviewerCount {
+			t.Errorf("Expected ErrInvalidReviewerCount for %d, got %v", count, err)
+		}
+	}
+	if _, err := service.CreatePR(ctx, "pr-1", "Test PR", "author1", entity.CreatePROptions{ReviewersCount: -1}); err != entity.ErrInvalidReviewerCount {
+		t.Errorf("Expected ErrInvalidReviewerCount from CreatePR, got %v", err)
+	}
+}
+
+func TestService_PreviewReviewers_MatchesCreatePR(t *testing.T) {
+	type call struct {
+		authorID, teamName string
+		limit              int
+	}
+	var calls []call
+	mockRepo := &mockRepo{
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			calls = append(calls, call{authorID, teamName, limit})
+			return candidates("reviewer1", "reviewer2"), nil
+		},
+		createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
+			t.Error("Preview must not create anything")
+			return nil
+		},
+	}
+	service := NewService(mockRepo)
+	preview, err := service.PreviewReviewers(context.Background(), "author1", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mockRepo.createPRFunc = nil
+	pr, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(calls) != 2 || calls[0] != calls[1] {
+		t.Errorf("Expected preview and CreatePR to query candidates identically, got %v", calls)
+	}
+	if !reflect.DeepEqual(preview, pr.ReviewerLoads) {
+		t.Errorf("Preview %v does not match assignment %v", preview, pr.ReviewerLoads)
+	}
+}
+
+func TestService_PreviewReviewers_Errors(t *testing.T) {
+	service := NewService(&mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return nil, entity.ErrNotFound
+		},
+	})
+	if _, err := service.PreviewReviewers(context.Background(), "ghost", 2); !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	service = NewService(&mockRepo{
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return nil, nil
+		},
+	})
+	if _, err := service.PreviewReviewers(context.Background(), "author1", 2); !errors.Is(err, entity.ErrNoCandidate) {
+		t.Errorf("Expected ErrNoCandidate, got %v", err)
+	}
+}
+
+func TestService_CreatePR_MinTeamSize(t *testing.T) {
+	testCases := []struct {
+		name    string
+		members int
+		wantErr error
+	}{
+		{"Below", 2, entity.ErrTeamTooSmall},
+		{"At", 3, nil},
+		{"Above", 4, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			created := false
+			mockRepo := &mockRepo{
+				countActiveMembersFunc: func(teamName string) (int, error) {
+					if teamName != "backend" {
+						t.Errorf("Expected the author's team, got %q", teamName)
+					}
+					return tc.members, nil
+				},
+				createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
+					created = true
+					return nil
+				},
+			}
+			service := NewServiceWithConfig(mockRepo, Config{MinTeamSize: 3})
+			_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Expected %v, got %v", tc.wantErr, err)
+			}
+			if created != (tc.wantErr == nil) {
+				t.Errorf("Expected PR created=%v, got %v", tc.wantErr == nil, created)
+			}
+		})
+	}
 }
 
 func TestService_CreatePR_AuthorNotFound(t *testing.T) {
-    mockRepo := &mockRepo{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return nil, entity.ErrNotFound
-        },
-    }
-    service := NewService(mockRepo)
-    _, err := service.CreatePR("pr-1", "Test PR", "nonexistent")
-    if !errors.Is(err, entity.ErrNotFound) {
-        t.Errorf("Expected ErrNotFound, got %v", err)
-    }
+	mockRepo := &mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return nil, entity.ErrNotFound
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "nonexistent", entity.CreatePROptions{})
+	if !errors.Is(err, entity.ErrAuthorNotFound) {
+		t.Errorf("Expected ErrAuthorNotFound, got %v", err)
+	}
+}
+
+func TestService_CreatePR_OtherNotFoundStaysGeneric(t *testing.T) {
+	mockRepo := &mockRepo{
+		createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
+			return entity.ErrNotFound
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if !errors.Is(err, entity.ErrNotFound) || errors.Is(err, entity.ErrAuthorNotFound) {
+		t.Errorf("Expected plain ErrNotFound, got %v", err)
+	}
 }
 
 func TestService_CreatePR_AuthorInactive(t *testing.T) {
-    mockRepo := &mockRepo{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return &entity.User{ID: userID, Username: "author", IsActive: false}, nil
-        },
-    }
-    service := NewService(mockRepo)
-    _, err := service.CreatePR("pr-1", "Test PR", "inactive-author")
-    if err == nil {
-        t.Error("Expected error for inactive author")
-    }
+	mockRepo := &mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return &entity.User{ID: userID, Username: "author", IsActive: false}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "inactive-author", entity.CreatePROptions{})
+	if err == nil {
+		t.Error("Expected error for inactive author")
+	}
 }
 
 func TestService_CreatePR_NoCandidateReviewers(t *testing.T) {
-    mockRepo := &mockRepo{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return &entity.User{ID: userID, Username: "author", IsActive: true}, nil
-        },
-        getCandidateReviewersFunc: func(authorID string, limit int) ([]string, error) {
-            return []string{}, nil
-        },
-    }
-    service := NewService(mockRepo)
-    _, err := service.CreatePR("pr-1", "Test PR", "author1")
-    if !errors.Is(err, entity.ErrNoCandidate) {
-        t.Errorf("Expected ErrNoCandidate, got %v", err)
-    }
+	mockRepo := &mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return &entity.User{ID: userID, Username: "author", IsActive: true}, nil
+		},
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return candidates(), nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if !errors.Is(err, entity.ErrNoCandidate) {
+		t.Errorf("Expected ErrNoCandidate, got %v", err)
+	}
 }
 
 func TestService_CreatePR_CandidateReviewersError(t *testing.T) {
-    mockRepo := &mockRepo{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return &entity.User{ID: userID, Username: "author", IsActive: true}, nil
-        },
-        getCandidateReviewersFunc: func(authorID string, limit int) ([]string, error) {
-            return nil, errors.New("database error")
-        },
-    }
-    service := NewService(mockRepo)
-    _, err := service.CreatePR("pr-1", "Test PR", "author1")
-    if err == nil {
-        t.Error("Expected error from candidate reviewers")
-    }
+	mockRepo := &mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return &entity.User{ID: userID, Username: "author", IsActive: true}, nil
+		},
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return nil, errors.New("database error")
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if err == nil {
+		t.Error("Expected error from candidate reviewers")
+	}
 }
 
 func TestService_MergePR_Success(t *testing.T) {
-    mockRepo := &mockRepo{
-        mergePRFunc: func(prID string) (*entity.PullRequest, error) {
-            return &entity.PullRequest{ID: prID, Status: "MERGED"}, nil
-        },
-    }
-    service := NewService(mockRepo)
-    pr, err := service.MergePR("pr-1")
-    if err != nil {
-        t.Fatalf("Expected no error, got %v", err)
-    }
-
-    if pr.Status != "MERGED" {
-        t.Errorf("Expected status 'MERGED', got %s", pr.Status)
-    }
+	mockRepo := &mockRepo{
+		mergePRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{ID: prID, Status: "MERGED"}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	pr, err := service.MergePR(context.Background(), "pr-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if pr.Status != "MERGED" {
+		t.Errorf("Expected status 'MERGED', got %s", pr.Status)
+	}
 }
 
 func TestService_ReassignReviewer_Success(t *testing.T) {
-    mockRepo := &mockRepo{
-        getPRFunc: func(prID string) (*entity.PullRequest, error) {
-            return &entity.PullRequest{
-                ID:     prID,
-                Status: "OPEN",
-                AssignedReviewers: []entity.User{
-                    {ID: "old-reviewer", Username: "Old Reviewer", IsActive: true},
-                    {ID: "other-reviewer", Username: "Other Reviewer", IsActive: true},
-                },
-            }, nil
-        },
-        reassignReviewerFunc: func(prID, oldUserID string) (string, error) {
-            return "new-reviewer", nil
-        },
-    }
-    service := NewService(mockRepo)
-    updatedPR, newUserID, err := service.ReassignReviewer("pr-1", "old-reviewer")
-    if err != nil {
-        t.Fatalf("Expected no error, got %v", err)
-    }
-    if newUserID != "new-reviewer" {
-        t.Errorf("Expected new reviewer 'new-reviewer', got %s", newUserID)
-    }
-    if updatedPR == nil {
-        t.Error("Expected updated PR to be returned")
-    }
+	mockRepo := &mockRepo{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:     prID,
+				Status: "OPEN",
+				AssignedReviewers: []entity.User{
+					{ID: "old-reviewer", Username: "Old Reviewer", IsActive: true},
+					{ID: "other-reviewer", Username: "Other Reviewer", IsActive: true},
+				},
+			}, nil
+		},
+		reassignReviewerFunc: func(prID, oldUserID string) (string, error) {
+			return "new-reviewer", nil
+		},
+	}
+	service := NewService(mockRepo)
+	updatedPR, newUserID, err := service.ReassignReviewer(context.Background(), "pr-1", "old-reviewer")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if newUserID != "new-reviewer" {
+		t.Errorf("Expected new reviewer 'new-reviewer', got %s", newUserID)
+	}
+	if updatedPR == nil {
+		t.Error("Expected updated PR to be returned")
+	}
+}
+
+func TestService_ReassignReviewer_ResolvesUsernames(t *testing.T) {
+	mockRepo := &mockRepo{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:                prID,
+				Status:            "OPEN",
+				AssignedReviewers: []entity.User{{ID: "old-reviewer", IsActive: true}},
+			}, nil
+		},
+		getUserFunc: func(userID string) (*entity.User, error) {
+			if userID == "old-reviewer" {
+				return &entity.User{ID: userID, Username: "Bob"}, nil
+			}
+			return &entity.User{ID: userID, Username: "Eve"}, nil
+		},
+		reassignReviewerFunc: func(prID, oldUserID string) (string, error) {
+			return "new-reviewer", nil
+		},
+	}
+	service := NewService(mockRepo)
+	updatedPR, _, err := service.ReassignReviewer(context.Background(), "pr-1", "old-reviewer")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got := updatedPR.Reassignment
+	if got == nil || got.OldUserID != "old-reviewer" || got.OldUsername != "Bob" || got.NewUserID != "new-reviewer" || got.NewUsername != "Eve" {
+		t.Errorf("Unexpected reassignment: %+v", got)
+	}
 }
 
 func TestService_ReassignReviewer_PRNotFound(t *testing.T) {
-    mockRepo := &mockRepo{
-        getPRFunc: func(prID string) (*entity.PullRequest, error) {
-            return nil, entity.ErrNotFound
-        },
-    }
-    service := NewService(mockRepo)
-    _, _, err := service.ReassignReviewer("nonexistent-pr", "reviewer1")
-    if !errors.Is(err, entity.ErrNotFound) {
-        t.Errorf("Expected ErrNotFound, got %v", err)
-    }
+	mockRepo := &mockRepo{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return nil, entity.ErrNotFound
+		},
+	}
+	service := NewService(mockRepo)
+	_, _, err := service.ReassignReviewer(context.Background(), "nonexistent-pr", "reviewer1")
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
 }
 
 func TestService_ReassignReviewer_PRAlreadyMerged(t *testing.T) {
-    mockRepo := &mockRepo{
-        getPRFunc: func(prID string) (*entity.PullRequest, error) {
-            return &entity.PullRequest{
-                ID:     prID,
-                Status: "MERGED",
-                AssignedReviewers: []entity.User{
-                    {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-                },
-            }, nil
-        },
-    }
-    service := NewService(mockRepo)
-    _, _, err := service.ReassignReviewer("pr-1", "reviewer1")
-    if !errors.Is(err, entity.ErrPRMerged) {
-        t.Errorf("Expected ErrPRMerged, got %v", err)
-    }
+	mockRepo := &mockRepo{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:     prID,
+				Status: "MERGED",
+				AssignedReviewers: []entity.User{
+					{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+				},
+			}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, _, err := service.ReassignReviewer(context.Background(), "pr-1", "reviewer1")
+	if !errors.Is(err, entity.ErrPRMerged) {
+		t.Errorf("Expected ErrPRMerged, got %v", err)
+	}
 }
 
 func TestService_ReassignReviewer_ReviewerNotAssigned(t *testing.T) {
-    mockRepo := &mockRepo{
-        getPRFunc: func(prID string) (*entity.PullRequest, error) {
-            return &entity.PullRequest{
-                ID:     prID,
-                Status: "OPEN",
-                AssignedReviewers: []entity.User{
-                    {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-                },
-            }, nil
-        },
-    }
-    service := NewService(mockRepo)
-    _, _, err := service.ReassignReviewer("pr-1", "not-assigned-reviewer")
-    if !errors.Is(err, entity.ErrNotAssigned) {
-        t.Errorf("Expected ErrNotAssigned, got %v", err)
-    }
+	mockRepo := &mockRepo{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:     prID,
+				Status: "OPEN",
+				AssignedReviewers: []entity.User{
+					{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+				},
+			}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, _, err := service.ReassignReviewer(context.Background(), "pr-1", "not-assigned-reviewer")
+	if !errors.Is(err, entity.ErrNotAssigned) {
+		t.Errorf("Expected ErrNotAssigned, got %v", err)
+	}
 }
 
 func TestService_ReassignReviewer_ReassignmentError(t *testing.T) {
-    mockRepo := &mockRepo{
-        getPRFunc: func(prID string) (*entity.PullRequest, error) {
-            return &entity.PullRequest{
-                ID:     prID,
-                Status: "OPEN",
-                AssignedReviewers: []entity.User{
-                    {ID: "reviewer1", Username: "Reviewer1", IsActive: true},
-                },
-            }, nil
-        },
-        reassignReviewerFunc: func(prID, oldUserID string) (string, error) {
-            return "", entity.ErrNoCandidate
-        },
-    }
-    service := NewService(mockRepo)
-    _, _, err := service.ReassignReviewer("pr-1", "reviewer1")
-    if !errors.Is(err, entity.ErrNoCandidate) {
-        t.Errorf("Expected ErrNoCandidate, got %v", err)
-    }
+	mockRepo := &mockRepo{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:     prID,
+				Status: "OPEN",
+				AssignedReviewers: []entity.User{
+					{ID: "reviewer1", Username: "Reviewer1", IsActive: true},
+				},
+			}, nil
+		},
+		reassignReviewerFunc: func(prID, oldUserID string) (string, error) {
+			return "", entity.ErrNoCandidate
+		},
+	}
+	service := NewService(mockRepo)
+	_, _, err := service.ReassignReviewer(context.Background(), "pr-1", "reviewer1")
+	if !errors.Is(err, entity.ErrNoCandidate) {
+		t.Errorf("Expected ErrNoCandidate, got %v", err)
+	}
 }
 
 func TestService_GetPR_Success(t *testing.T) {
-    mockRepo := &mockRepo{
-        getPRFunc: func(prID string) (*entity.PullRequest, error) {
-            return &entity.PullRequest{
-                ID:       prID,
-                Title:    "Test PR",
-                AuthorID: "author1",
-                Status:   "OPEN",
-            }, nil
-        },
-    }
-    service := NewService(mockRepo)
-    pr, err := service.GetPR("pr-1")
-    if err != nil {
-        t.Fatalf("Expected no error, got %v", err)
-    }
-    if pr.ID != "pr-1" {
-        t.Errorf("Expected PR ID 'pr-1', got %s", pr.ID)
-    }
+	mockRepo := &mockRepo{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:       prID,
+				Title:    "Test PR",
+				AuthorID: "author1",
+				Status:   "OPEN",
+			}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	pr, err := service.GetPR(context.Background(), "pr-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pr.ID != "pr-1" {
+		t.Errorf("Expected PR ID 'pr-1', got %s", pr.ID)
+	}
 }
 
 func TestService_GetPR_NotFound(t *testing.T) {
-    mockRepo := &mockRepo{
-        getPRFunc: func(prID string) (*entity.PullRequest, error) {
-            return nil, entity.ErrNotFound
-        },
-    }
-    service := NewService(mockRepo)
-    _, err := service.GetPR("nonexistent-pr")
-    if !errors.Is(err, entity.ErrNotFound) {
-        t.Errorf("Expected ErrNotFound, got %v", err)
-    }
+	mockRepo := &mockRepo{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return nil, entity.ErrNotFound
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.GetPR(context.Background(), "nonexistent-pr")
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
 }
 
 func TestService_GetTeam_Success(t *testing.T) {
-    expectedTeam := &entity.Team{Name: "backend"}
-    expectedMembers := []entity.User{
-        {ID: "u1", Username: "Alice", IsActive: true},
-        {ID: "u2", Username: "Bob", IsActive: true},
-    }
-
-    mockRepo := &mockRepo{
-        getTeamFunc: func(teamName string) (*entity.Team, []entity.User, error) {
-            return expectedTeam, expectedMembers, nil
-        },
-    }
-
-    service := NewService(mockRepo)
-    team, members, err := service.GetTeam("backend")
-    if err != nil {
-        t.Fatalf("Expected no error, got %v", err)
-    }
-
-    if team.Name != "backend" {
-        t.Errorf("Expected team name 'backend', got %s", team.Name)
-    }
-    if len(members) != 2 {
-        t.Errorf("Expected 2 members, got %d", len(members))
-    }
+	expectedTeam := &entity.Team{Name: "backend"}
+	expectedMembers := []entity.User{
+		{ID: "u1", Username: "Alice", IsActive: true},
+		{ID: "u2", Username: "Bob", IsActive: true},
+	}
+
+	mockRepo := &mockRepo{
+		getTeamFunc: func(teamName string) (*entity.Team, []entity.User, error) {
+			return expectedTeam, expectedMembers, nil
+		},
+	}
+
+	service := NewService(mockRepo)
+	team, members, err := service.GetTeam(context.Background(), "backend")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if team.Name != "backend" {
+		t.Errorf("Expected team name 'backend', got %s", team.Name)
+	}
+	if len(members) != 2 {
+		t.Errorf("Expected 2 members, got %d", len(members))
+	}
 }
 
 func TestService_GetTeam_NotFound(t *testing.T) {
-    mockRepo := &mockRepo{
-        getTeamFunc: func(teamName string) (*entity.Team, []entity.User, error) {
-            return nil, nil, entity.ErrNotFound
-        },
-    }
-
-    service := NewService(mockRepo)
-    _, _, err := service.GetTeam("nonexistent")
-    if !errors.Is(err, entity.ErrNotFound) {
-        t.Errorf("Expected ErrNotFound, got %v", err)
-    }
+	mockRepo := &mockRepo{
+		getTeamFunc: func(teamName string) (*entity.Team, []entity.User, error) {
+			return nil, nil, entity.ErrNotFound
+		},
+	}
+
+	service := NewService(mockRepo)
+	_, _, err := service.GetTeam(context.Background(), "nonexistent")
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
 }
 
 func TestService_GetUserReviewPRs_Success(t *testing.T) {
-    expectedPRs := []entity.PullRequest{
-        {
-            ID:       "pr-1",
-            Title:    "Feature A",
-            AuthorID: "author1",
-            Status:   "OPEN",
-        },
-        {
-            ID:       "pr-2",
-            Title:    "Feature B",
-            AuthorID: "author2",
-            Status:   "OPEN",
-        },
-    }
-
-    mockRepo := &mockRepo{
-        getUserReviewPRsFunc: func(userID string) ([]entity.PullRequest, error) {
-            return expectedPRs, nil
-        },
-    }
-
-    service := NewService(mockRepo)
-    prs, err := service.GetUserReviewPRs("reviewer1")
-    if err != nil {
-        t.Fatalf("Expected no error, got %v", err)
-    }
-
-    if len(prs) != 2 {
-        t.Errorf("Expected 2 PRs, got %d", len(prs))
-    }
+	expectedPRs := []entity.PullRequest{
+		{
+			ID:       "pr-1",
+			Title:    "Feature A",
+			AuthorID: "author1",
+			Status:   "OPEN",
+		},
+		{
+			ID:       "pr-2",
+			Title:    "Feature B",
+			AuthorID: "author2",
+			Status:   "OPEN",
+		},
+	}
+
+	mockRepo := &mockRepo{
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			return expectedPRs, nil, nil
+		},
+	}
+
+	service := NewService(mockRepo)
+	prs, _, err := service.GetUserReviewPRs(context.Background(), "reviewer1", "", entity.ReviewPage{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(prs) != 2 {
+		t.Errorf("Expected 2 PRs, got %d", len(prs))
+	}
 }
 
 func TestService_GetUserReviewPRs_Empty(t *testing.T) {
-    mockRepo := &mockRepo{
-        getUserReviewPRsFunc: func(userID string) ([]entity.PullRequest, error) {
-            return []entity.PullRequest{}, nil
-        },
-    }
-
-    service := NewService(mockRepo)
-    prs, err := service.GetUserReviewPRs("new-reviewer")
-    if err != nil {
-        t.Fatalf("Expected no error, got %v", err)
-    }
-
-    if len(prs) != 0 {
-        t.Errorf("Expected 0 PRs for new reviewer, got %d", len(prs))
-    }
+	mockRepo := &mockRepo{
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			return []entity.PullRequest{}, nil, nil
+		},
+	}
+
+	service := NewService(mockRepo)
+	prs, _, err := service.GetUserReviewPRs(context.Background(), "new-reviewer", "", entity.ReviewPage{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(prs) != 0 {
+		t.Errorf("Expected 0 PRs for new reviewer, got %d", len(prs))
+	}
 }
 
 func TestService_GetUserReviewPRs_RepositoryError(t *testing.T) {
-    mockRepo := &mockRepo{
-        getUserReviewPRsFunc: func(userID string) ([]entity.PullRequest, error) {
-            return nil, errors.New("database error")
-        },
-    }
-
-    service := NewService(mockRepo)
-    _, err := service.GetUserReviewPRs("reviewer1")
-    if err == nil {
-        t.Error("Expected error from repository")
-    }
+	mockRepo := &mockRepo{
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			return nil, nil, errors.New("database error")
+		},
+	}
+
+	service := NewService(mockRepo)
+	_, _, err := service.GetUserReviewPRs(context.Background(), "reviewer1", "", entity.ReviewPage{})
+	if err == nil {
+		t.Error("Expected error from repository")
+	}
 }
 
 func TestService_CreatePR_DuplicatePR(t *testing.T) {
-    mockRepo := &mockRepo{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return &entity.User{ID: userID, Username: "author", IsActive: true}, nil
-        },
-        getCandidateReviewersFunc: func(authorID string, limit int) ([]string, error) {
-            return []string{"reviewer1", "reviewer2"}, nil
-        },
-        createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
-            return entity.ErrPRExists
-        },
-    }
-
-    service := NewService(mockRepo)
-    _, err := service.CreatePR("pr-1", "Test PR", "author1")
-    if !errors.Is(err, entity.ErrPRExists) {
-        t.Errorf("Expected ErrPRExists, got %v", err)
-    }
+	mockRepo := &mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return &entity.User{ID: userID, Username: "author", IsActive: true}, nil
+		},
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return candidates("reviewer1", "reviewer2"), nil
+		},
+		createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
+			return entity.ErrPRExists
+		},
+	}
+
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if !errors.Is(err, entity.ErrPRExists) {
+		t.Errorf("Expected ErrPRExists, got %v", err)
+	}
 }
 
 func TestService_CreatePR_CreateError(t *testing.T) {
-    mockRepo := &mockRepo{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return &entity.User{ID: userID, Username: "author", IsActive: true}, nil
-        },
-        getCandidateReviewersFunc: func(authorID string, limit int) ([]string, error) {
-            return []string{"reviewer1", "reviewer2"}, nil
-        },
-        createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
-            return errors.New("create failed")
-        },
-    }
-
-    service := NewService(mockRepo)
-    _, err := service.CreatePR("pr-1", "Test PR", "author1")
-    if err == nil {
-        t.Error("Expected error from PR creation")
-    }
+	mockRepo := &mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return &entity.User{ID: userID, Username: "author", IsActive: true}, nil
+		},
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return candidates("reviewer1", "reviewer2"), nil
+		},
+		createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
+			return errors.New("create failed")
+		},
+	}
+
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if err == nil {
+		t.Error("Expected error from PR creation")
+	}
 }
 
 func TestService_MergePR_NotFound(t *testing.T) {
-    mockRepo := &mockRepo{
-        mergePRFunc: func(prID string) (*entity.PullRequest, error) {
-            return nil, entity.ErrNotFound
-        },
-    }
-
-    service := NewService(mockRepo)
-    _, err := service.MergePR("nonexistent-pr")
-    if !errors.Is(err, entity.ErrNotFound) {
-        t.Errorf("Expected ErrNotFound, got %v", err)
-    }
+	mockRepo := &mockRepo{
+		mergePRFunc: func(prID string) (*entity.PullRequest, error) {
+			return nil, entity.ErrNotFound
+		},
+	}
+
+	service := NewService(mockRepo)
+	_, err := service.MergePR(context.Background(), "nonexistent-pr")
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
 }
 
 func TestService_MergePR_AlreadyMerged(t *testing.T) {
-    mockRepo := &mockRepo{
-        mergePRFunc: func(prID string) (*entity.PullRequest, error) {
-            return &entity.PullRequest{ID: prID, Status: "MERGED"}, nil
-        },
-    }
-
-    service := NewService(mockRepo)
-    pr, err := service.MergePR("already-merged-pr")
-    if err != nil {
-        t.Fatalf("Should handle already merged PR gracefully, got error: %v", err)
-    }
-    if pr.Status != "MERGED" {
-        t.Errorf("Expected status MERGED, got %s", pr.Status)
-    }
+	mockRepo := &mockRepo{
+		mergePRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{ID: prID, Status: "MERGED"}, nil
+		},
+	}
+
+	service := NewService(mockRepo)
+	pr, err := service.MergePR(context.Background(), "already-merged-pr")
+	if err != nil {
+		t.Fatalf("Should handle already merged PR gracefully, got error: %v", err)
+	}
+	if pr.Status != "MERGED" {
+		t.Errorf("Expected status MERGED, got %s", pr.Status)
+	}
 }
 
-func TestService_SetUserActive_NotFound(t *testing.T) {
-    mockRepo := &mockRepo{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return nil, entity.ErrNotFound
-        },
-    }
+func TestService_SetUserActive_DeactivateReassignsOpenPRs(t *testing.T) {
+	var reassigned []string
+	mockRepo := &mockRepo{
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			if status != "OPEN" {
+				t.Errorf("Expected OPEN PRs to be fetched, got status %q", status)
+			}
+			return []entity.PullRequest{{ID: "pr-1", Status: "OPEN"}, {ID: "pr-2", Status: "OPEN"}}, nil, nil
+		},
+		reassignReviewerFunc: func(prID, oldUserID string) (string, error) {
+			if oldUserID != "u1" {
+				t.Errorf("Expected u1 to be reassigned, got %s", oldUserID)
+			}
+			reassigned = append(reassigned, prID)
+			return "u2", nil
+		},
+	}
+	service := NewService(mockRepo)
+	user, affected, err := service.SetUserActive(context.Background(), "u1", false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if user.IsActive {
+		t.Error("Expected user to be inactive")
+	}
+	if strings.Join(affected, ",") != "pr-1,pr-2" {
+		t.Errorf("Expected affected PRs pr-1,pr-2, got %v", affected)
+	}
+	if strings.Join(reassigned, ",") != "pr-1,pr-2" {
+		t.Errorf("Expected both PRs to be reassigned, got %v", reassigned)
+	}
+}
 
-    service := NewService(mockRepo)
-    _, err := service.SetUserActive("nonexistent", true)
-    if !errors.Is(err, entity.ErrNotFound) {
-        t.Errorf("Expected ErrNotFound, got %v", err)
-    }
+func TestService_SetUserActive_DeactivateSkipsPRsWithoutCandidate(t *testing.T) {
+	mockRepo := &mockRepo{
+		getUserReviewPRsFunc: func(userID, status string, page entity.ReviewPage) ([]entity.PullRequest, *entity.ReviewCursor, error) {
+			return []entity.PullRequest{{ID: "pr-1", Status: "OPEN"}, {ID: "pr-2", Status: "OPEN"}}, nil, nil
+		},
+		reassignReviewerFunc: func(prID, oldUserID string) (string, error) {
+			if prID == "pr-1" {
+				return "", entity.ErrNoCandidate
+			}
+			return "u2", nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, affected, err := service.SetUserActive(context.Background(), "u1", false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(affected, ",") != "pr-2" {
+		t.Errorf("Expected only pr-2 to be affected, got %v", affected)
+	}
 }
 
-func TestService_SetUserActive_RepositoryError(t *testing.T) {
-    mockRepo := &mockRepo{
-        setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
-            return nil, errors.New("database error")
-        },
-    }
+func TestService_SetUserActive_NotFound(t *testing.T) {
+	mockRepo := &mockRepo{
+		setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
+			return nil, entity.ErrNotFound
+		},
+	}
+
+	service := NewService(mockRepo)
+	_, _, err := service.SetUserActive(context.Background(), "nonexistent", true)
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
 
-    service := NewService(mockRepo)
-    _, err := service.SetUserActive("user1", true)
-    if err == nil {
-        t.Error("Expected error from repository")
-    }
+func TestService_SetUserActive_RepositoryError(t *testing.T) {
+	mockRepo := &mockRepo{
+		setUserActiveFunc: func(userID string, isActive bool) (*entity.User, error) {
+			return nil, errors.New("database error")
+		},
+	}
+
+	service := NewService(mockRepo)
+	_, _, err := service.SetUserActive(context.Background(), "user1", true)
+	if err == nil {
+		t.Error("Expected error from repository")
+	}
 }
 
 func TestService_GetStats_Success(t *testing.T) {
-    expectedStats := &entity.Stats{
-        UserAssignmentCounts: []entity.UserAssignmentCount{
-            {UserID: "u1", Username: "Alice", Count: 10},
-            {UserID: "u2", Username: "Bob", Count: 8},
-        },
-        PRAssignmentCounts: []entity.PRAssignmentCount{
-            {PRID: "pr-1", Title: "Feature A", Count: 3},
-            {PRID: "pr-2", Title: "Feature B", Count: 2},
-        },
-        TotalAssignments: 18,
-    }
-
-    mockRepo := &mockRepo{
-        getStatsFunc: func() (*entity.Stats, error) {
-            return expectedStats, nil
-        },
-    }
-
-    service := NewService(mockRepo)
-    stats, err := service.GetStats()
-    if err != nil {
-        t.Fatalf("Expected no error, got %v", err)
-    }
-
-    if stats.TotalAssignments != 18 {
-        t.Errorf("Expected total assignments 18, got %d", stats.TotalAssignments)
-    }
-    if len(stats.UserAssignmentCounts) != 2 {
-        t.Errorf("Expected 2 user assignment counts, got %d", len(stats.UserAssignmentCounts))
-    }
-    if len(stats.PRAssignmentCounts) != 2 {
-        t.Errorf("Expected 2 PR assignment counts, got %d", len(stats.PRAssignmentCounts))
-    }
+	expectedStats := &entity.Stats{
+		UserAssignmentCounts: []entity.UserAssignmentCount{
+			{UserID: "u1", Username: "Alice", Count: 10},
+			{UserID: "u2", Username: "Bob", Count: 8},
+		},
+		PRAssignmentCounts: []entity.PRAssignmentCount{
+			{PRID: "pr-1", Title: "Feature A", Count: 3},
+			{PRID: "pr-2", Title: "Feature B", Count: 2},
+		},
+		TotalAssignments: 18,
+	}
+
+	mockRepo := &mockRepo{
+		getStatsFunc: func() (*entity.Stats, error) {
+			return expectedStats, nil
+		},
+	}
+
+	service := NewService(mockRepo)
+	stats, err := service.GetStats(context.Background(), 50, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if stats.TotalAssignments != 18 {
+		t.Errorf("Expected total assignments 18, got %d", stats.TotalAssignments)
+	}
+	if len(stats.UserAssignmentCounts) != 2 {
+		t.Errorf("Expected 2 user assignment counts, got %d", len(stats.UserAssignmentCounts))
+	}
+	if len(stats.PRAssignmentCounts) != 2 {
+		t.Errorf("Expected 2 PR assignment counts, got %d", len(stats.PRAssignmentCounts))
+	}
 }
 
 func TestService_GetStats_Empty(t *testing.T) {
-    mockRepo := &mockRepo{
-        getStatsFunc: func() (*entity.Stats, error) {
-            return &entity.Stats{
-                UserAssignmentCounts: []entity.UserAssignmentCount{},
-                PRAssignmentCounts:   []entity.PRAssignmentCount{},
-                TotalAssignments:     0,
-            }, nil
-        },
-    }
-    service := NewService(mockRepo)
-    stats, err := service.GetStats()
-    if err != nil {
-        t.Fatalf("Expected no error, got %v", err)
-    }
-    if stats.TotalAssignments != 0 {
-        t.Errorf("Expected 0 total assignments, got %d", stats.TotalAssignments)
-    }
-    if len(stats.UserAssignmentCounts) != 0 {
-        t.Errorf("Expected 0 user assignment counts, got %d", len(stats.UserAssignmentCounts))
-    }
+	mockRepo := &mockRepo{
+		getStatsFunc: func() (*entity.Stats, error) {
+			return &entity.Stats{
+				UserAssignmentCounts: []entity.UserAssignmentCount{},
+				PRAssignmentCounts:   []entity.PRAssignmentCount{},
+				TotalAssignments:     0,
+			}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	stats, err := service.GetStats(context.Background(), 50, 0, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stats.TotalAssignments != 0 {
+		t.Errorf("Expected 0 total assignments, got %d", stats.TotalAssignments)
+	}
+	if len(stats.UserAssignmentCounts) != 0 {
+		t.Errorf("Expected 0 user assignment counts, got %d", len(stats.UserAssignmentCounts))
+	}
 }
 
 func TestService_GetStats_RepositoryError(t *testing.T) {
-    mockRepo := &mockRepo{
-        getStatsFunc: func() (*entity.Stats, error) {
-            return nil, errors.New("stats query failed")
-        },
-    }
-    service := NewService(mockRepo)
-    _, err := service.GetStats()
-    if err == nil {
-        t.Error("Expected error from repository")
-    }
+	mockRepo := &mockRepo{
+		getStatsFunc: func() (*entity.Stats, error) {
+			return nil, errors.New("stats query failed")
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.GetStats(context.Background(), 50, 0, entity.StatsFilter{})
+	if err == nil {
+		t.Error("Expected error from repository")
+	}
+}
+
+func TestService_GetStats_PassesPagination(t *testing.T) {
+	var gotLimit, gotOffset int
+	mockRepo := &mockRepo{
+		getStatsPagedFunc: func(limit, offset int, filter entity.StatsFilter) (*entity.Stats, error) {
+			gotLimit, gotOffset = limit, offset
+			return &entity.Stats{TotalAssignments: 7}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	stats, err := service.GetStats(context.Background(), 10, 20, entity.StatsFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if gotLimit != 10 || gotOffset != 20 {
+		t.Errorf("Expected limit 10 and offset 20, got %d and %d", gotLimit, gotOffset)
+	}
+	if stats.TotalAssignments != 7 {
+		t.Errorf("Expected total assignments 7, got %d", stats.TotalAssignments)
+	}
+}
+
+func TestService_ValidatesPRID(t *testing.T) {
+	testCases := []struct {
+		name string
+		prID string
+		want error
+	}{
+		{"Valid", "pr-1001_a", nil},
+		{"Max length", strings.Repeat("a", 64), nil},
+		{"Too long", strings.Repeat("a", 65), entity.ErrInvalidPRID},
+		{"Empty", "", entity.ErrInvalidPRID},
+		{"Newline", "pr-1\nINFO forged", entity.ErrInvalidPRID},
+		{"Illegal characters", "pr/1 OR 1=1", entity.ErrInvalidPRID},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoCalls := 0
+			mockRepo := &mockRepo{
+				getPRFunc: func(prID string) (*entity.PullRequest, error) {
+					repoCalls++
+					return &entity.PullRequest{ID: prID}, nil
+				},
+				mergePRFunc: func(prID string) (*entity.PullRequest, error) {
+					repoCalls++
+					return &entity.PullRequest{ID: prID, Status: "MERGED"}, nil
+				},
+			}
+			service := NewService(mockRepo)
+			if _, err := service.GetPR(context.Background(), tc.prID); err != tc.want {
+				t.Errorf("GetPR: expected %v, got %v", tc.want, err)
+			}
+			if _, err := service.MergePR(context.Background(), tc.prID); err != tc.want {
+				t.Errorf("MergePR: expected %v, got %v", tc.want, err)
+			}
+			if tc.want == nil {
+				return
+			}
+			if _, err := service.CreatePR(context.Background(), tc.prID, "Title", "author1", entity.CreatePROptions{}); err != tc.want {
+				t.Errorf("CreatePR: expected %v, got %v", tc.want, err)
+			}
+			if _, _, err := service.ReassignReviewer(context.Background(), tc.prID, "reviewer1"); err != tc.want {
+				t.Errorf("ReassignReviewer: expected %v, got %v", tc.want, err)
+			}
+			if repoCalls != 0 {
+				t.Errorf("Expected invalid id to be rejected before reaching the repository, got %d calls", repoCalls)
+			}
+		})
+	}
+}
+
+func TestService_ValidatesPRID_CustomPattern(t *testing.T) {
+	service := NewServiceWithConfig(&mockRepo{}, Config{PRIDPattern: regexp.MustCompile(`^[A-Z]+/[0-9]+$`)})
+	if _, err := service.GetPR(context.Background(), "PROJ/42"); err != nil {
+		t.Errorf("Expected custom id to be accepted, got %v", err)
+	}
+	if _, err := service.GetPR(context.Background(), "pr-42"); err != entity.ErrInvalidPRID {
+		t.Errorf("Expected ErrInvalidPRID, got %v", err)
+	}
+}
+
+func TestService_GetTeamStats_NotFound(t *testing.T) {
+	mockRepo := &mockRepo{
+		getTeamStatsFunc: func(teamName string) (*entity.Stats, error) {
+			return nil, entity.ErrNotFound
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.GetTeamStats(context.Background(), "ghost-team")
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestService_RequiredTeamSize(t *testing.T) {
+	service := NewService(&mockRepo{})
+	testCases := []struct {
+		name     string
+		policy   entity.ReviewPolicy
+		expected int
+	}{
+		{"default two reviewers", entity.ReviewPolicy{DesiredReviewers: 2}, 3},
+		{"single reviewer", entity.ReviewPolicy{DesiredReviewers: 1}, 2},
+		{"with reserve", entity.ReviewPolicy{DesiredReviewers: 2, Reserve: 1}, 4},
+		{"cap below desired", entity.ReviewPolicy{DesiredReviewers: 3, Cap: 1}, 2},
+		{"cap above desired", entity.ReviewPolicy{DesiredReviewers: 2, Cap: 5}, 3},
+		{"cap and reserve", entity.ReviewPolicy{DesiredReviewers: 4, Reserve: 2, Cap: 3}, 6},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			size, err := service.RequiredTeamSize(tc.policy)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if size != tc.expected {
+				t.Errorf("Expected required team size %d, got %d", tc.expected, size)
+			}
+		})
+	}
+}
+
+func TestService_RequiredTeamSize_InvalidPolicy(t *testing.T) {
+	service := NewService(&mockRepo{})
+	policies := []entity.ReviewPolicy{
+		{DesiredReviewers: 0},
+		{DesiredReviewers: 2, Reserve: -1},
+		{DesiredReviewers: 2, Cap: -1},
+	}
+	for _, policy := range policies {
+		_, err := service.RequiredTeamSize(policy)
+		if !errors.Is(err, entity.ErrInvalidPolicy) {
+			t.Errorf("Expected ErrInvalidPolicy for %+v, got %v", policy, err)
+		}
+	}
+}
+
+func TestService_ClosePR_Success(t *testing.T) {
+	mockRepo := &mockRepo{
+		closePRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{ID: prID, Status: "CLOSED"}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	pr, err := service.ClosePR(context.Background(), "pr-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pr.Status != "CLOSED" {
+		t.Errorf("Expected status 'CLOSED', got %s", pr.Status)
+	}
+}
+
+func TestService_ReassignReviewer_PRClosed(t *testing.T) {
+	mockRepo := &mockRepo{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{ID: prID, Status: "CLOSED"}, nil
+		},
+		reassignReviewerFunc: func(prID, oldUserID string) (string, error) {
+			t.Error("ReassignReviewer should not be called for closed PR")
+			return "", nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, _, err := service.ReassignReviewer(context.Background(), "pr-1", "reviewer1")
+	if !errors.Is(err, entity.ErrPRClosed) {
+		t.Errorf("Expected ErrPRClosed, got %v", err)
+	}
+}
+
+func TestService_GetCandidateReviewers_NonPositiveLimit(t *testing.T) {
+	mockRepo := &mockRepo{
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			t.Errorf("Repository should not be called with limit %d", limit)
+			return nil, nil
+		},
+	}
+	service := &ServiceImpl{repo: mockRepo}
+	for _, limit := range []int{0, -1} {
+		_, err := service.getCandidateReviewers(context.Background(), "author1", limit, nil, 0, "")
+		if !errors.Is(err, entity.ErrInvalidReviewerCount) {
+			t.Errorf("Expected ErrInvalidReviewerCount for limit %d, got %v", limit, err)
+		}
+	}
+}
+
+func TestService_CreatePR_ExcludeReviewers(t *testing.T) {
+	var createdWith []string
+	mockRepo := &mockRepo{
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			ranked := []string{"top-candidate", "reviewer2", "reviewer3"}
+			var picked []string
+			for _, id := range ranked {
+				excluded := false
+				for _, excludedID := range excludeIDs {
+					if id == excludedID {
+						excluded = true
+					}
+				}
+				if !excluded && len(picked) < limit {
+					picked = append(picked, id)
+				}
+			}
+			return candidates(picked...), nil
+		},
+		createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
+			createdWith = reviewerIDs
+			return nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{
+		ExcludeReviewers: []string{"top-candidate"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(createdWith) != 2 || createdWith[0] != "reviewer2" || createdWith[1] != "reviewer3" {
+		t.Errorf("Expected reviewers [reviewer2 reviewer3], got %v", createdWith)
+	}
+}
+
+func TestService_CreatePR_AvoidRecentPairings(t *testing.T) {
+	var passed int
+	mockRepo := &mockRepo{
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			passed = avoidRecent
+			return candidates("reviewer1", "reviewer2"), nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{
+		AvoidRecentPairings: 3,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if passed != 3 {
+		t.Errorf("Expected avoidRecent 3 to reach the repository, got %d", passed)
+	}
+}
+
+func TestService_CreatePR_ReturnsReviewerLoads(t *testing.T) {
+	mockRepo := &mockRepo{
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return []entity.CandidateReviewer{{UserID: "reviewer1", Load: 0}, {UserID: "reviewer2", Load: 3}}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	pr, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(pr.ReviewerLoads) != 2 || pr.ReviewerLoads[1].UserID != "reviewer2" || pr.ReviewerLoads[1].Load != 3 {
+		t.Errorf("Expected reviewer loads to be returned, got %v", pr.ReviewerLoads)
+	}
 }
 
+func TestService_CreatePR_ExcludeUnknownReviewer(t *testing.T) {
+	mockRepo := &mockRepo{
+		getMissingUserIDsFunc: func(userIDs []string) ([]string, error) {
+			return []string{"ghost"}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{
+		ExcludeReviewers: []string{"ghost"},
+	})
+	if !errors.Is(err, entity.ErrUnknownUser) {
+		t.Errorf("Expected ErrUnknownUser, got %v", err)
+	}
+}
+
+func TestService_PreviewReassign_Success(t *testing.T) {
+	mockRepo := &mockRepo{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:                prID,
+				Status:            "OPEN",
+				AssignedReviewers: []entity.User{{ID: "old-reviewer", IsActive: true}},
+			}, nil
+		},
+		previewReassignFunc: func(prID, oldUserID string) (string, error) {
+			return "new-reviewer", nil
+		},
+		reassignReviewerFunc: func(prID, oldUserID string) (string, error) {
+			t.Error("Preview must not perform the reassignment")
+			return "", nil
+		},
+	}
+	service := NewService(mockRepo)
+	newUserID, err := service.PreviewReassign(context.Background(), "pr-1", "old-reviewer")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if newUserID != "new-reviewer" {
+		t.Errorf("Expected 'new-reviewer', got %s", newUserID)
+	}
+}
+
+func TestService_PreviewReassign_ValidatesLikeReassign(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pr       *entity.PullRequest
+		expected error
+	}{
+		{"merged", &entity.PullRequest{Status: "MERGED"}, entity.ErrPRMerged},
+		{"closed", &entity.PullRequest{Status: "CLOSED"}, entity.ErrPRClosed},
+		{"not assigned", &entity.PullRequest{Status: "OPEN"}, entity.ErrNotAssigned},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := &mockRepo{
+				getPRFunc: func(prID string) (*entity.PullRequest, error) {
+					return tc.pr, nil
+				},
+			}
+			service := NewService(mockRepo)
+			_, err := service.PreviewReassign(context.Background(), "pr-1", "old-reviewer")
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestService_ReviewerMutations_RequireOpenPR(t *testing.T) {
+	mutations := []struct {
+		name string
+		call func(s Service) error
+	}{
+		{"ReassignReviewer", func(s Service) error {
+			_, _, err := s.ReassignReviewer(context.Background(), "pr-1", "reviewer1")
+			return err
+		}},
+		{"AddReviewer", func(s Service) error {
+			_, err := s.AddReviewer(context.Background(), "pr-1", "reviewer2")
+			return err
+		}},
+		{"RemoveReviewer", func(s Service) error {
+			_, err := s.RemoveReviewer(context.Background(), "pr-1", "reviewer1")
+			return err
+		}},
+		{"RespondReview", func(s Service) error {
+			_, err := s.RespondReview(context.Background(), "pr-1", "reviewer1", false)
+			return err
+		}},
+	}
+	statuses := []struct {
+		status   string
+		expected error
+	}{
+		{"MERGED", entity.ErrPRMerged},
+		{"CLOSED", entity.ErrPRClosed},
+	}
+	for _, mutation := range mutations {
+		for _, tc := range statuses {
+			t.Run(mutation.name+"/"+tc.status, func(t *testing.T) {
+				mutated := false
+				mockRepo := &mockRepo{
+					getPRFunc: func(prID string) (*entity.PullRequest, error) {
+						return &entity.PullRequest{ID: prID, AuthorID: "author1", Status: tc.status, AssignedReviewers: []entity.User{{ID: "reviewer1"}}}, nil
+					},
+					reassignReviewerFunc: func(prID, oldUserID string) (string, error) {
+						mutated = true
+						return "reviewer3", nil
+					},
+					addReviewerFunc: func(prID, userID string) error {
+						mutated = true
+						return nil
+					},
+					removeReviewerFunc: func(prID, userID string) error {
+						mutated = true
+						return nil
+					},
+					respondReviewFunc: func(prID, userID string, accept bool) (string, error) {
+						mutated = true
+						return "", nil
+					},
+				}
+				err := mutation.call(NewService(mockRepo))
+				if !errors.Is(err, tc.expected) {
+					t.Errorf("Expected %v, got %v", tc.expected, err)
+				}
+				if mutated {
+					t.Error("Expected the repository mutation not to run")
+				}
+			})
+		}
+	}
+}
+
+func TestService_ReopenPR_Success(t *testing.T) {
+	mockRepo := &mockRepo{
+		reopenPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{ID: prID, Status: "OPEN"}, nil
+		},
+	}
+	service := NewService(mockRepo)
+	pr, err := service.ReopenPR(context.Background(), "pr-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pr.Status != "OPEN" {
+		t.Errorf("Expected status OPEN, got %s", pr.Status)
+	}
+}
+
+func TestService_ClosePR_AlreadyMerged(t *testing.T) {
+	mockRepo := &mockRepo{
+		closePRFunc: func(prID string) (*entity.PullRequest, error) {
+			return nil, entity.ErrPRMerged
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.ClosePR(context.Background(), "pr-1")
+	if !errors.Is(err, entity.ErrPRMerged) {
+		t.Errorf("Expected ErrPRMerged, got %v", err)
+	}
+}
+
+func TestService_GetIdempotentResponse(t *testing.T) {
+	mockRepo := &mockRepo{
+		getIdempotencyRecordFunc: func(key string) (string, []byte, error) {
+			if key != "key-1" {
+				return "", nil, entity.ErrNotFound
+			}
+			return "pr-1", []byte(`{"pr":{"pull_request_id":"pr-1"}}`), nil
+		},
+	}
+	service := NewService(mockRepo)
+	response, err := service.GetIdempotentResponse(context.Background(), "key-1", "pr-1")
+	if err != nil {
+		t.Fatalf("Expected stored response, got error: %v", err)
+	}
+	if string(response) != `{"pr":{"pull_request_id":"pr-1"}}` {
+		t.Errorf("Unexpected stored response: %s", response)
+	}
+	_, err = service.GetIdempotentResponse(context.Background(), "key-1", "pr-2")
+	if !errors.Is(err, entity.ErrIdempotencyKeyReused) {
+		t.Errorf("Expected ErrIdempotencyKeyReused for a different PR, got %v", err)
+	}
+	_, err = service.GetIdempotentResponse(context.Background(), "key-2", "pr-1")
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a new key, got %v", err)
+	}
+}
+
+type fakeNotifier struct {
+	assigned   []string
+	membership []membershipChange
+}
+
+type membershipChange struct {
+	team    string
+	added   []string
+	removed []string
+}
+
+func (n *fakeNotifier) ReviewerAssigned(prID, userID string) {
+	n.assigned = append(n.assigned, prID+":"+userID)
+}
+
+func (n *fakeNotifier) MembershipChanged(teamName string, added, removed []string) {
+	n.membership = append(n.membership, membershipChange{team: teamName, added: added, removed: removed})
+}
+
+func TestService_NotifiesMembershipChanges(t *testing.T) {
+	t.Run("CreateTeam", func(t *testing.T) {
+		notifier := &fakeNotifier{}
+		service := NewServiceWithNotifier(&mockRepo{}, notifier)
+		members := []entity.User{{ID: "u1", Username: "Alice"}, {ID: "u2", Username: "Bob"}}
+		if _, err := service.CreateTeam(context.Background(), "backend", members, 0); err != nil {
+			t.Fatalf("CreateTeam failed: %v", err)
+		}
+		expected := []membershipChange{{team: "backend", added: []string{"u1", "u2"}}}
+		if !reflect.DeepEqual(notifier.membership, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, notifier.membership)
+		}
+	})
+	t.Run("CreateTeam failure", func(t *testing.T) {
+		notifier := &fakeNotifier{}
+		mockRepo := &mockRepo{
+			createTeamFunc: func(team *entity.Team, members []entity.User) error {
+				return entity.ErrTeamExists
+			},
+		}
+		service := NewServiceWithNotifier(mockRepo, notifier)
+		service.CreateTeam(context.Background(), "backend", []entity.User{{ID: "u1"}}, 0)
+		if len(notifier.membership) != 0 {
+			t.Errorf("Expected no notification for a failed create, got %+v", notifier.membership)
+		}
+	})
+	t.Run("ImportTeams", func(t *testing.T) {
+		notifier := &fakeNotifier{}
+		mockRepo := &mockRepo{
+			createTeamsBulkFunc: func(teams []entity.TeamWithMembers) ([]entity.ImportResult, error) {
+				return []entity.ImportResult{
+					{TeamName: "backend"},
+					{TeamName: "frontend", Err: entity.ErrTeamExists},
+				}, nil
+			},
+		}
+		service := NewServiceWithNotifier(mockRepo, notifier)
+		_, err := service.ImportTeams(context.Background(), []entity.TeamWithMembers{
+			{TeamName: "backend", Members: []entity.User{{ID: "u1"}}},
+			{TeamName: "frontend", Members: []entity.User{{ID: "u2"}}},
+		})
+		if err != nil {
+			t.Fatalf("ImportTeams failed: %v", err)
+		}
+		expected := []membershipChange{{team: "backend", added: []string{"u1"}}}
+		if !reflect.DeepEqual(notifier.membership, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, notifier.membership)
+		}
+	})
+	t.Run("RetireUser", func(t *testing.T) {
+		notifier := &fakeNotifier{}
+		mockRepo := &mockRepo{
+			getUserTeamsFunc: func(userID string) ([]string, error) {
+				return []string{"backend", "platform"}, nil
+			},
+		}
+		service := NewServiceWithNotifier(mockRepo, notifier)
+		if _, err := service.RetireUser(context.Background(), "u1"); err != nil {
+			t.Fatalf("RetireUser failed: %v", err)
+		}
+		expected := []membershipChange{
+			{team: "backend", removed: []string{"u1"}},
+			{team: "platform", removed: []string{"u1"}},
+		}
+		if !reflect.DeepEqual(notifier.membership, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, notifier.membership)
+		}
+	})
+}
+
+func TestService_NotifiesAssignedReviewers(t *testing.T) {
+	created := false
+	mockRepo := &mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return &entity.User{ID: userID, IsActive: true}, nil
+		},
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return candidates("reviewer1", "reviewer2"), nil
+		},
+		createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
+			created = true
+			return nil
+		},
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			return &entity.PullRequest{
+				ID:                prID,
+				Status:            "OPEN",
+				AssignedReviewers: []entity.User{{ID: "reviewer1"}, {ID: "reviewer2"}},
+			}, nil
+		},
+		reassignReviewerFunc: func(prID, oldUserID string) (string, error) {
+			return "reviewer3", nil
+		},
+	}
+	notifier := &fakeNotifier{}
+	service := NewServiceWithNotifier(mockRepo, notifier)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if err != nil {
+		t.Fatalf("CreatePR failed: %v", err)
+	}
+	if !created {
+		t.Fatal("Expected the PR to be stored before notifying")
+	}
+	_, _, err = service.ReassignReviewer(context.Background(), "pr-1", "reviewer1")
+	if err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	expected := []string{"pr-1:reviewer1", "pr-1:reviewer2", "pr-1:reviewer3"}
+	if len(notifier.assigned) != len(expected) {
+		t.Fatalf("Expected notifications %v, got %v", expected, notifier.assigned)
+	}
+	for i := range expected {
+		if notifier.assigned[i] != expected[i] {
+			t.Errorf("Expected notification %s, got %s", expected[i], notifier.assigned[i])
+		}
+	}
+}
+
+func TestService_NoNotificationOnFailedCreate(t *testing.T) {
+	mockRepo := &mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return &entity.User{ID: userID, IsActive: true}, nil
+		},
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return candidates("reviewer1"), nil
+		},
+		createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
+			return entity.ErrPRExists
+		},
+	}
+	notifier := &fakeNotifier{}
+	service := NewServiceWithNotifier(mockRepo, notifier)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if !errors.Is(err, entity.ErrPRExists) {
+		t.Fatalf("Expected ErrPRExists, got %v", err)
+	}
+	if len(notifier.assigned) != 0 {
+		t.Errorf("Expected no notifications, got %v", notifier.assigned)
+	}
+}
+
+func TestService_RespondReview_NotifiesReplacement(t *testing.T) {
+	mockRepo := &mockRepo{
+		respondReviewFunc: func(prID, userID string, accept bool) (string, error) {
+			if accept || userID == "reviewer3" {
+				return "", nil
+			}
+			return "reviewer2", nil
+		},
+	}
+	notifier := &fakeNotifier{}
+	service := NewServiceWithNotifier(mockRepo, notifier)
+	for _, tc := range []struct {
+		userID string
+		accept bool
+		want   string
+	}{
+		{"reviewer1", true, ""},
+		{"reviewer1", false, "reviewer2"},
+		{"reviewer3", false, ""},
+	} {
+		newUserID, err := service.RespondReview(context.Background(), "pr-1", tc.userID, tc.accept)
+		if err != nil {
+			t.Fatalf("RespondReview failed: %v", err)
+		}
+		if newUserID != tc.want {
+			t.Errorf("Expected replacement %q, got %q", tc.want, newUserID)
+		}
+	}
+	if len(notifier.assigned) != 1 || notifier.assigned[0] != "pr-1:reviewer2" {
+		t.Errorf("Expected a single notification for pr-1:reviewer2, got %v", notifier.assigned)
+	}
+	if _, err := service.RespondReview(context.Background(), "pr-1", " ", true); err != entity.ErrBlankUserID {
+		t.Errorf("Expected ErrBlankUserID, got %v", err)
+	}
+}
+
+func TestService_RetireUser_NotifiesReplacements(t *testing.T) {
+	mockRepo := &mockRepo{
+		deactivateAndRetireFunc: func(userID string) ([]entity.Reassignment, error) {
+			return []entity.Reassignment{
+				{PRID: "pr-1", NewUserID: "reviewer2"},
+				{PRID: "pr-2"},
+			}, nil
+		},
+	}
+	notifier := &fakeNotifier{}
+	service := NewServiceWithNotifier(mockRepo, notifier)
+	reassignments, err := service.RetireUser(context.Background(), "reviewer1")
+	if err != nil {
+		t.Fatalf("RetireUser failed: %v", err)
+	}
+	if len(reassignments) != 2 {
+		t.Fatalf("Expected 2 reassignments, got %v", reassignments)
+	}
+	if len(notifier.assigned) != 1 || notifier.assigned[0] != "pr-1:reviewer2" {
+		t.Errorf("Expected a single notification for pr-1:reviewer2, got %v", notifier.assigned)
+	}
+}
+
+func TestService_CheckNotSelfReview(t *testing.T) {
+	pr := &entity.PullRequest{ID: "pr-1", AuthorID: "author1", Status: "OPEN"}
+	if err := checkNotSelfReview(pr, []string{"reviewer1", "author1"}); !errors.Is(err, entity.ErrSelfReview) {
+		t.Errorf("Expected ErrSelfReview when assigning the author, got %v", err)
+	}
+	if err := checkNotSelfReview(pr, []string{"reviewer1", "reviewer2"}); err != nil {
+		t.Errorf("Expected no error for other reviewers, got %v", err)
+	}
+}
+
+func TestService_CreatePR_RejectsAuthorAsCandidate(t *testing.T) {
+	created := false
+	mockRepo := &mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return &entity.User{ID: userID, IsActive: true}, nil
+		},
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return candidates("reviewer1", authorID), nil
+		},
+		createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
+			created = true
+			return nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if !errors.Is(err, entity.ErrSelfReview) {
+		t.Fatalf("Expected ErrSelfReview, got %v", err)
+	}
+	if created {
+		t.Error("Expected the PR not to be stored")
+	}
+}
+
+func TestService_AddReviewer(t *testing.T) {
+	added := ""
+	mockRepo := &mockRepo{
+		getPRFunc: func(prID string) (*entity.PullRequest, error) {
+			reviewers := []entity.User{{ID: "reviewer1"}}
+			if added != "" {
+				reviewers = append(reviewers, entity.User{ID: added})
+			}
+			return &entity.PullRequest{ID: prID, AuthorID: "author1", Status: "OPEN", AssignedReviewers: reviewers}, nil
+		},
+		addReviewerFunc: func(prID, userID string) error {
+			added = userID
+			return nil
+		},
+	}
+	notifier := &fakeNotifier{}
+	service := NewServiceWithNotifier(mockRepo, notifier)
+	_, err := service.AddReviewer(context.Background(), "pr-1", "author1")
+	if !errors.Is(err, entity.ErrSelfReview) {
+		t.Fatalf("Expected ErrSelfReview, got %v", err)
+	}
+	if added != "" {
+		t.Fatal("Expected the author not to reach the repository")
+	}
+	pr, err := service.AddReviewer(context.Background(), "pr-1", "reviewer2")
+	if err != nil {
+		t.Fatalf("AddReviewer failed: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 2 || pr.AssignedReviewers[1].ID != "reviewer2" {
+		t.Errorf("Expected reviewer2 in the updated PR, got %v", pr.AssignedReviewers)
+	}
+	if len(notifier.assigned) != 1 || notifier.assigned[0] != "pr-1:reviewer2" {
+		t.Errorf("Expected a notification for reviewer2, got %v", notifier.assigned)
+	}
+}
+
+func TestService_CreatePR_SoloAuthor(t *testing.T) {
+	candidatesRequested := false
+	mockRepo := &mockRepo{
+		getUserFunc: func(userID string) (*entity.User, error) {
+			return &entity.User{ID: userID, IsActive: true, TeamName: "solo-team"}, nil
+		},
+		countActiveTeammatesFunc: func(userID string) (int, error) {
+			return 0, nil
+		},
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			candidatesRequested = true
+			return candidates(), nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if !errors.Is(err, entity.ErrSoloAuthor) {
+		t.Fatalf("Expected ErrSoloAuthor, got %v", err)
+	}
+	if candidatesRequested {
+		t.Error("Expected the solo check to short-circuit candidate selection")
+	}
+}
+
+func TestService_CreatePR_AllTeammatesInactive(t *testing.T) {
+	mockRepo := &mockRepo{
+		countActiveTeammatesFunc: func(userID string) (int, error) {
+			return 0, nil
+		},
+		countTeammatesFunc: func(userID string) (int, error) {
+			return 3, nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{})
+	if !errors.Is(err, entity.ErrAllInactive) {
+		t.Fatalf("Expected ErrAllInactive, got %v", err)
+	}
+}
+
+func TestService_CreatePR_NoCandidateWithActiveTeammates(t *testing.T) {
+	mockRepo := &mockRepo{
+		countActiveTeammatesFunc: func(userID string) (int, error) {
+			return 2, nil
+		},
+		countTeammatesFunc: func(userID string) (int, error) {
+			return 2, nil
+		},
+		getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+			return candidates(), nil
+		},
+	}
+	service := NewService(mockRepo)
+	_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{ExcludeReviewers: []string{"user2", "user3"}})
+	if !errors.Is(err, entity.ErrNoCandidate) {
+		t.Fatalf("Expected ErrNoCandidate, got %v", err)
+	}
+}
+
+func TestService_CreatePR_TeamSelection(t *testing.T) {
+	testCases := []struct {
+		name      string
+		teams     []string
+		requested string
+		wantTeam  string
+		wantErr   error
+	}{
+		{"single team", []string{"backend"}, "", "backend", nil},
+		{"multi team specified", []string{"backend", "payments"}, "payments", "payments", nil},
+		{"multi team different case", []string{"backend", "payments"}, "Payments", "payments", nil},
+		{"multi team ambiguous", []string{"backend", "payments"}, "", "", entity.ErrAmbiguousTeam},
+		{"not a member", []string{"backend"}, "payments", "", entity.ErrAuthorNotInTeam},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var poolTeam, storedTeam string
+			mockRepo := &mockRepo{
+				getUserTeamsFunc: func(userID string) ([]string, error) {
+					return tc.teams, nil
+				},
+				getCandidateReviewersFunc: func(authorID string, limit int, excludeIDs []string, avoidRecent int, teamName string) ([]entity.CandidateReviewer, error) {
+					poolTeam = teamName
+					return candidates("reviewer1"), nil
+				},
+				createPRFunc: func(pr *entity.PullRequest, reviewerIDs []string) error {
+					storedTeam = pr.TeamName
+					return nil
+				},
+			}
+			service := NewService(mockRepo)
+			_, err := service.CreatePR(context.Background(), "pr-1", "Test PR", "author1", entity.CreatePROptions{TeamName: tc.requested})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Expected %v, got %v", tc.wantErr, err)
+			}
+			if poolTeam != tc.wantTeam || storedTeam != tc.wantTeam {
+				t.Errorf("Expected team %q for the pool and the PR, got %q and %q", tc.wantTeam, poolTeam, storedTeam)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ CREATE TABLE IF NOT EXISTS teams (
     team_name VARCHAR(100) UNIQUE NOT NULL
 );
 
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS default_reviewers INT CHECK (default_reviewers BETWEEN 1 AND 10);
+
 CREATE TABLE IF NOT EXISTS users (
     user_id TEXT PRIMARY KEY,
     username VARCHAR(100) NOT NULL,          
@@ -20,14 +22,55 @@ CREATE TABLE IF NOT EXISTS pull_requests (
     pull_request_id TEXT PRIMARY KEY, 
     pull_request_name VARCHAR(200) NOT NULL,
     author_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
-    status VARCHAR(20) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'MERGED')),
+    status VARCHAR(20) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'MERGED', 'CLOSED')),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    merged_at TIMESTAMP WITH TIME ZONE NULL
+    merged_at TIMESTAMP WITH TIME ZONE NULL,
+    team_id INT REFERENCES teams(team_id) ON DELETE SET NULL
 );
 
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS team_id INT REFERENCES teams(team_id) ON DELETE SET NULL;
+
+ALTER TABLE pull_requests DROP CONSTRAINT IF EXISTS pull_requests_status_check;
+ALTER TABLE pull_requests ADD CONSTRAINT pull_requests_status_check CHECK (status IN ('OPEN', 'MERGED', 'CLOSED'));
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_pull_requests_name_trgm ON pull_requests USING gin (pull_request_name gin_trgm_ops);
+
 CREATE TABLE IF NOT EXISTS reviewers (
     pull_request_id TEXT REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
     user_id TEXT REFERENCES users(user_id) ON DELETE CASCADE,
     is_active BOOLEAN NOT NULL DEFAULT true,
     PRIMARY KEY (pull_request_id, user_id)
-);
\ No newline at end of file
+);
+
+ALTER TABLE reviewers ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE reviewers ADD COLUMN IF NOT EXISTS state VARCHAR(20) NOT NULL DEFAULT 'PENDING' CHECK (state IN ('PENDING', 'ACCEPTED', 'DECLINED'));
+
+CREATE TABLE IF NOT EXISTS reassignment_log (
+    id SERIAL PRIMARY KEY,
+    pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    old_user_id TEXT NOT NULL,
+    new_user_id TEXT NOT NULL,
+    reassigned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reassignment_log_pr ON reassignment_log (pull_request_id);
+
+CREATE TABLE IF NOT EXISTS events (
+    id BIGSERIAL PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_unpublished ON events (id) WHERE published = false;
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key TEXT PRIMARY KEY,
+    pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    response BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);